@@ -0,0 +1,158 @@
+package main
+
+import (
+	"abtprj/internal/app"
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+var stdin io.Reader = os.Stdin
+
+// runAdmin implements `abtprj admin create|reset-password|disable-2fa|list`.
+func runAdmin(svc *app.DefaultAppService, args []string) error {
+	const adminUsage = "usage: abtprj admin create LOGIN | reset-password LOGIN | disable-2fa LOGIN | list"
+	if len(args) == 0 {
+		return errors.New(adminUsage)
+	}
+
+	switch {
+	case args[0] == "list" && len(args) == 1:
+		admins, err := svc.ListAdmins()
+		if err != nil {
+			return err
+		}
+		if len(admins) == 0 {
+			fmt.Fprintln(stdout, "No admin accounts; create one with `abtprj admin create LOGIN`.")
+		}
+		for _, a := range admins {
+			fmt.Fprintf(stdout, "%-20s created %s\n", a.Login, a.CreatedAt.Format("2006-01-02 15:04"))
+		}
+		return nil
+	case args[0] == "create" && len(args) == 2:
+		password, err := readNewPassword(args[1])
+		if err != nil {
+			return err
+		}
+		if err := svc.CreateAdmin(args[1], password); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "Admin %q created.\n", args[1])
+		return nil
+	case args[0] == "reset-password" && len(args) == 2:
+		password, err := readNewPassword(args[1])
+		if err != nil {
+			return err
+		}
+		if err := svc.ResetAdminPassword(args[1], password); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "Password of %q changed and its sessions ended.\n", args[1])
+		return nil
+	case args[0] == "disable-2fa" && len(args) == 2:
+		if err := svc.ResetAdminTOTP(args[1]); err != nil {
+			return err
+		}
+		fmt.Fprintf(stdout, "Two-factor authentication of %q turned off.\n", args[1])
+		return nil
+	default:
+		return errors.New(adminUsage)
+	}
+}
+
+// userService scopes svc to the tracker data of login, or of the first account
+// when login is empty.
+func userService(svc *app.DefaultAppService, login string) (*app.DefaultAppService, error) {
+	var user app.Admin
+	var err error
+	if login == "" {
+		user, err = svc.GetOwner()
+	} else {
+		user, err = svc.GetUser(login)
+	}
+	if errors.Is(err, app.ErrNoUser) {
+		if login == "" {
+			return nil, errors.New("no accounts yet; create one with `abtprj admin create LOGIN`")
+		}
+		return nil, fmt.Errorf("no account %q", login)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return svc.WithUser(user.ID), nil
+}
+
+// readNewPassword reads a password from stdin. At a terminal it prompts, reads
+// without echoing and asks for confirmation; piped input is taken from the first
+// line, so scripts can use `echo "$PASSWORD" | abtprj admin create alice`.
+func readNewPassword(login string) (string, error) {
+	fd, interactive := -1, false
+	if f, ok := stdin.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		fd, interactive = int(f.Fd()), true
+	}
+
+	r := bufio.NewReader(stdin)
+	readLine := func(prompt string) (string, error) {
+		if interactive {
+			fmt.Fprint(os.Stderr, prompt)
+			line, err := term.ReadPassword(fd)
+			fmt.Fprintln(os.Stderr)
+			if err != nil {
+				return "", fmt.Errorf("reading password: %w", err)
+			}
+			return string(line), nil
+		}
+		line, err := r.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return "", fmt.Errorf("reading password: %w", err)
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	password, err := readLine("New password: ")
+	if err != nil {
+		return "", err
+	}
+	if err := app.ValidatePassword(login, password); err != nil {
+		return "", err
+	}
+	if interactive {
+		again, err := readLine("Repeat password: ")
+		if err != nil {
+			return "", err
+		}
+		if again != password {
+			return "", fmt.Errorf("passwords do not match")
+		}
+	}
+	return password, nil
+}
+
+// runSeed implements `abtprj seed [-user LOGIN]`.
+func runSeed(svc *app.DefaultAppService, args []string) error {
+	fs := flag.NewFlagSet("seed", flag.ContinueOnError)
+	user := fs.String("user", "", "fill this account (default: the first account)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("usage: abtprj seed [-user LOGIN]")
+	}
+	svc, err := userService(svc, *user)
+	if err != nil {
+		return err
+	}
+	sum, err := svc.Seed(time.Now())
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Added %d work sessions, %d tasks and %d goals.\n", sum.Sessions, sum.Tasks, sum.Goals)
+	return nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"abtprj/internal/app"
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// runBackup implements `abtprj backup [-o FILE]`.
+func runBackup(svc *app.DefaultAppService, args []string) error {
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	out := fs.String("o", "", "write to this file instead of abtprj-backup-<time>.tar.gz; - for stdout")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: abtprj backup [-o FILE]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	name := *out
+	if name == "" {
+		name = "abtprj-backup-" + time.Now().Format("2006-01-02-150405") + ".tar.gz"
+	}
+	var w io.Writer = os.Stdout
+	if name != "-" {
+		f, err := os.Create(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	bw := bufio.NewWriter(w)
+	if err := svc.Backup(bw); err != nil {
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	if name != "-" {
+		log.Printf("backup written to %s", name)
+	}
+	return nil
+}
+
+// runRestore implements `abtprj restore FILE`. It must run before the schema is
+// migrated to the latest version, so the backup can be loaded at its own version.
+func runRestore(svc *app.DefaultAppService, args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: abtprj restore FILE   (- reads stdin)")
+		fmt.Fprintln(fs.Output(), "The database must be empty and not migrated past the backup, such as a new one.")
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("missing backup file")
+	}
+
+	var r io.Reader = os.Stdin
+	if name := fs.Arg(0); name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	summary, err := svc.Restore(bufio.NewReader(r))
+	if err != nil {
+		return err
+	}
+	var counts []string
+	for _, table := range []string{"work_sessions", "tasks", "goals", "settings", "admin"} {
+		counts = append(counts, fmt.Sprintf("%d %s", summary.Rows[table], table))
+	}
+	log.Printf("restored schema version %d backup: %s", summary.SchemaVersion, strings.Join(counts, ", "))
+	if len(summary.Dropped) > 0 {
+		log.Printf("columns no longer in the schema were not restored: %s", strings.Join(summary.Dropped, ", "))
+	}
+	return nil
+}
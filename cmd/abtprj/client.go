@@ -0,0 +1,313 @@
+package main
+
+import (
+	"abtprj/internal/api"
+	"abtprj/internal/client"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clientCommands talk to a running server through the API and need no database.
+var clientCommands = map[string]func(args []string) error{
+	"login":  runLogin,
+	"start":  runStart,
+	"stop":   runStop,
+	"status": runStatus,
+	"today":  runToday,
+	"task":   runTask,
+	"goal":   runGoal,
+}
+
+var stdout io.Writer = os.Stdout
+
+// parseArgs parses flags wherever they appear, so `task add Fix login -d notes`
+// works, and returns the remaining arguments.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+func newClient() (*client.Client, error) {
+	cfg, err := client.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("reading client config: %w", err)
+	}
+	return client.New(cfg), nil
+}
+
+func printJSON(v any) error {
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// runLogin implements `abtprj login -server URL -token TOKEN`.
+func runLogin(args []string) error {
+	fs := flag.NewFlagSet("login", flag.ContinueOnError)
+	server := fs.String("server", "", "server base URL, e.g. https://log.example.com")
+	token := fs.String("token", "", "API token from the admin page")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	if *server == "" || *token == "" {
+		fs.Usage()
+		return fmt.Errorf("both -server and -token are required")
+	}
+
+	cfg := client.Config{Server: strings.TrimRight(*server, "/"), Token: *token}
+	if _, err := client.New(cfg).Status(); err != nil {
+		return fmt.Errorf("checking the token: %w", err)
+	}
+	path, err := client.SaveConfig(cfg)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "Logged in to %s; settings saved in %s.\n", cfg.Server, path)
+	return nil
+}
+
+func runStart(args []string) error {
+	return runSessionCommand("start", args, (*client.Client).Start)
+}
+
+func runStop(args []string) error {
+	return runSessionCommand("stop", args, (*client.Client).Stop)
+}
+
+func runStatus(args []string) error {
+	return runSessionCommand("status", args, (*client.Client).Status)
+}
+
+func runSessionCommand(name string, args []string, call func(*client.Client) (api.Status, error)) error {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print the server's JSON answer")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	st, err := call(c)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return printJSON(st)
+	}
+
+	if st.Working && st.SessionStart != nil {
+		fmt.Fprintf(stdout, "Working since %s (%s).\n", st.SessionStart.Format("15:04"),
+			humanDuration(time.Since(*st.SessionStart)))
+	} else {
+		fmt.Fprintln(stdout, "Not working.")
+	}
+	fmt.Fprintf(stdout, "Today: %s worked, %d tasks done, %d open.\n",
+		humanDuration(time.Duration(st.WorkedTodayMins)*time.Minute), st.TasksDoneToday, st.OpenTasks)
+	return nil
+}
+
+func runToday(args []string) error {
+	fs := flag.NewFlagSet("today", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print the server's JSON answer")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	today, err := c.Today()
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return printJSON(today)
+	}
+
+	fmt.Fprintf(stdout, "%s: %s worked\n", today.Date, humanDuration(time.Duration(today.WorkedMinutes)*time.Minute))
+	if len(today.Sessions) > 0 {
+		fmt.Fprintln(stdout, "\nSessions:")
+		for _, s := range today.Sessions {
+			end := "now  "
+			if s.End != nil {
+				end = s.End.Format("15:04")
+			}
+			fmt.Fprintf(stdout, "  %s–%s  %s\n", s.Start.Format("15:04"), end, humanDuration(time.Duration(s.Minutes)*time.Minute))
+		}
+	}
+	if len(today.Tasks) > 0 {
+		fmt.Fprintln(stdout, "\nDone:")
+		for _, t := range today.Tasks {
+			at := ""
+			if t.DoneAt != nil {
+				at = " (" + t.DoneAt.Format("15:04") + ")"
+			}
+			fmt.Fprintf(stdout, "  - %s%s\n", t.Name, at)
+		}
+	}
+	return nil
+}
+
+// runTask implements `abtprj task add|done|list`.
+func runTask(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: abtprj task add NAME [-d DESCRIPTION] [-e ESTIMATE] | done NAME | list")
+	}
+	sub, args := args[0], args[1:]
+
+	fs := flag.NewFlagSet("task "+sub, flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print the server's JSON answer")
+	var description, estimate *string
+	if sub == "add" {
+		description = fs.String("d", "", "description")
+		estimate = fs.String("e", "", "estimate in minutes or as a duration like 1h30m")
+	}
+	rest, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	name := strings.Join(rest, " ")
+
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	switch sub {
+	case "add":
+		if name == "" {
+			return fmt.Errorf("usage: abtprj task add NAME [-d DESCRIPTION] [-e ESTIMATE]")
+		}
+		minutes, err := parseMinutes(*estimate)
+		if err != nil {
+			return err
+		}
+		task, err := c.AddTask(api.NewTask{Name: name, Description: *description, EstimateMinutes: minutes})
+		if err != nil {
+			return err
+		}
+		if *asJSON {
+			return printJSON(task)
+		}
+		fmt.Fprintf(stdout, "Added %q.\n", task.Name)
+	case "done":
+		if name == "" {
+			return fmt.Errorf("usage: abtprj task done NAME")
+		}
+		task, err := c.CompleteTask(name)
+		if err != nil {
+			return err
+		}
+		if *asJSON {
+			return printJSON(task)
+		}
+		fmt.Fprintf(stdout, "Done: %s\n", task.Name)
+	case "list":
+		tasks, err := c.Tasks()
+		if err != nil {
+			return err
+		}
+		if *asJSON {
+			return printJSON(tasks)
+		}
+		if len(tasks) == 0 {
+			fmt.Fprintln(stdout, "No open tasks.")
+		}
+		for _, t := range tasks {
+			line := "- " + t.Name
+			if t.EstimateMinutes > 0 {
+				line += " (est. " + humanDuration(time.Duration(t.EstimateMinutes)*time.Minute) + ")"
+			}
+			if t.Description != "" {
+				line += " — " + strings.ReplaceAll(t.Description, "\n", " ")
+			}
+			fmt.Fprintln(stdout, line)
+		}
+	default:
+		return fmt.Errorf("unknown task command %q, expected add, done or list", sub)
+	}
+	return nil
+}
+
+// runGoal implements `abtprj goal list [-all]`.
+func runGoal(args []string) error {
+	if len(args) == 0 || args[0] != "list" {
+		return fmt.Errorf("usage: abtprj goal list [-all]")
+	}
+	fs := flag.NewFlagSet("goal list", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print the server's JSON answer")
+	all := fs.Bool("all", false, "include completed goals")
+	if _, err := parseArgs(fs, args[1:]); err != nil {
+		return err
+	}
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+	goals, err := c.Goals(*all)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return printJSON(goals)
+	}
+
+	if len(goals) == 0 {
+		fmt.Fprintln(stdout, "No goals.")
+	}
+	for _, g := range goals {
+		box := "[ ]"
+		if g.Status == "done" {
+			box = "[x]"
+		}
+		line := box + " " + g.Name
+		if g.DueAt != nil {
+			line += " — due " + g.DueAt.Format("2006-01-02")
+		}
+		if g.State != "" {
+			line += " (" + g.State + ")"
+		}
+		fmt.Fprintln(stdout, line)
+	}
+	return nil
+}
+
+// parseMinutes reads an estimate given as whole minutes or a Go duration.
+func parseMinutes(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if m, err := strconv.Atoi(s); err == nil && m >= 0 {
+		return m, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid estimate %q, use minutes or a duration like 1h30m", s)
+	}
+	return int(d / time.Minute), nil
+}
+
+func humanDuration(d time.Duration) string {
+	m := int(d / time.Minute)
+	if m < 60 {
+		return fmt.Sprintf("%dm", m)
+	}
+	return fmt.Sprintf("%dh %02dm", m/60, m%60)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"abtprj/internal/app"
+	"abtprj/internal/repository"
+	"abtprj/internal/views"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// defaultDatabaseURL has no credentials: the server's local authentication
+// decides, as it does for psql. check-config warns when it is used.
+const defaultDatabaseURL = "postgres://localhost:5432/abtprj?sslmode=disable"
+
+type config struct {
+	DatabaseURL string
+	Addr        string
+	PublicURL   string // as set; see parsePublicURL
+}
+
+func loadConfig() config {
+	cfg := config{
+		DatabaseURL: os.Getenv("ABTPRJ_DATABASE_URL"),
+		Addr:        os.Getenv("ABTPRJ_ADDR"),
+		PublicURL:   os.Getenv("ABTPRJ_PUBLIC_URL"),
+	}
+	if cfg.DatabaseURL == "" {
+		cfg.DatabaseURL = defaultDatabaseURL
+	}
+	if cfg.Addr == "" {
+		cfg.Addr = ":8080"
+	}
+	return cfg
+}
+
+// parsePublicURL checks ABTPRJ_PUBLIC_URL, the scheme and host the instance is
+// reached on, and returns it without a trailing slash. Pages are served from
+// the root, so it may not have a path.
+func parsePublicURL(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	u, err := url.Parse(strings.TrimSuffix(s, "/"))
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" || u.Path != "" || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return "", errors.New("want a scheme and host only, such as https://log.example.com")
+	}
+	return u.String(), nil
+}
+
+// runCheckConfig implements `abtprj check-config`: every problem is reported, and
+// the command fails if any was found.
+func runCheckConfig(cfg config, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("usage: abtprj check-config")
+	}
+
+	problems := 0
+	report := func(ok bool, format string, a ...any) {
+		mark := "ok  "
+		if !ok {
+			mark = "FAIL"
+			problems++
+		}
+		fmt.Fprintf(stdout, "%s %s\n", mark, fmt.Sprintf(format, a...))
+	}
+	warn := func(format string, a ...any) {
+		fmt.Fprintf(stdout, "warn %s\n", fmt.Sprintf(format, a...))
+	}
+
+	if cfg.DatabaseURL == defaultDatabaseURL {
+		warn("ABTPRJ_DATABASE_URL is not set, using %s", defaultDatabaseURL)
+	}
+	report(true, "listen address %s", cfg.Addr)
+	if cfg.PublicURL == "" {
+		warn("ABTPRJ_PUBLIC_URL is not set, feed and invite links use the Host header of each request")
+	} else {
+		_, err := parsePublicURL(cfg.PublicURL)
+		report(err == nil, "public URL %s%s", cfg.PublicURL, errSuffix(err))
+	}
+
+	for _, dir := range []string{"templates", "static"} {
+		_, err := os.Stat(dir)
+		report(err == nil, "%s directory in %s", dir, mustGetwd())
+	}
+	pages, err := views.Load("templates")
+	if err != nil {
+		report(false, "templates parse: %v", err)
+	} else {
+		report(len(pages) > 0, "%d page templates parse", len(pages))
+	}
+
+	_, err = time.LoadLocation(app.DefaultLocation)
+	report(err == nil, "time zone %s available", app.DefaultLocation)
+
+	db, err := sql.Open("postgres", cfg.DatabaseURL)
+	if err == nil {
+		defer db.Close()
+		err = db.Ping()
+	}
+	report(err == nil, "database reachable%s", errSuffix(err))
+	if err == nil {
+		version, verr := repository.SchemaVersion(db)
+		if verr != nil || version == 0 {
+			report(false, "schema not initialised; run `abtprj migrate`%s", errSuffix(verr))
+		} else {
+			latest := repository.LatestSchemaVersion()
+			report(version == latest, "schema version %d of %d", version, latest)
+			admins, aerr := repository.ListAdmins(db)
+			report(aerr == nil && len(admins) > 0, "%d admin accounts%s", len(admins), errSuffix(aerr))
+		}
+	}
+
+	if problems > 0 {
+		return fmt.Errorf("%d problem(s) found", problems)
+	}
+	return nil
+}
+
+func errSuffix(err error) string {
+	if err == nil {
+		return ""
+	}
+	return ": " + err.Error()
+}
+
+func mustGetwd() string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return "."
+	}
+	return wd
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"abtprj/internal/app"
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// runExport implements `abtprj export <tasks|goals|sessions> [flags]`.
+func runExport(svc *app.DefaultAppService, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", app.ExportCSV, "output format: csv or json")
+	from := fs.String("from", "", "first day to include, YYYY-MM-DD")
+	to := fs.String("to", "", "last day to include, YYYY-MM-DD")
+	status := fs.String("status", "", "only rows with this status (todo|done, sessions: active|finished)")
+	out := fs.String("o", "", "write to this file instead of stdout")
+	user := fs.String("user", "", "export this account's data (default: the first account)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: abtprj export <tasks|goals|sessions> [flags]")
+		fs.PrintDefaults()
+	}
+
+	if len(args) == 0 {
+		fs.Usage()
+		return fmt.Errorf("missing what to export")
+	}
+	kind := args[0]
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	filter, err := app.ParseExportFilter(*from, *to, *status)
+	if err != nil {
+		return err
+	}
+	if err := app.ValidateExport(kind, *format, filter); err != nil {
+		return err
+	}
+	svc, err = userService(svc, *user)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	bw := bufio.NewWriter(w)
+	if err := svc.Export(bw, kind, *format, filter); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
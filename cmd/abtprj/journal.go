@@ -0,0 +1,52 @@
+package main
+
+import (
+	"abtprj/internal/app"
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// runJournal implements `abtprj journal [-from DATE] [-to DATE] [-o FILE] [-user LOGIN]`.
+func runJournal(svc *app.DefaultAppService, args []string) error {
+	fs := flag.NewFlagSet("journal", flag.ContinueOnError)
+	from := fs.String("from", "", "first day, YYYY-MM-DD (default: Monday of this week)")
+	to := fs.String("to", "", "last day, YYYY-MM-DD (default: today)")
+	out := fs.String("o", "", "write to this file instead of stdout")
+	user := fs.String("user", "", "write this account's journal (default: the first account)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: abtprj journal [flags]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	svc, err := userService(svc, *user)
+	if err != nil {
+		return err
+	}
+
+	start, end, err := app.ParseJournalRange(*from, *to, svc.Location(), time.Now())
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	bw := bufio.NewWriter(w)
+	if err := svc.WriteJournal(bw, start, end); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"abtprj/internal/app"
+	"abtprj/internal/handlers"
+	"abtprj/internal/views"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// runServe implements `abtprj serve [-addr ADDR]`.
+func runServe(svc *app.DefaultAppService, args []string) error {
+	cfg := loadConfig()
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	addr := fs.String("addr", cfg.Addr, "address to listen on")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	publicURL, err := parsePublicURL(cfg.PublicURL)
+	if err != nil {
+		return fmt.Errorf("ABTPRJ_PUBLIC_URL %q: %w", cfg.PublicURL, err)
+	}
+
+	exists, err := svc.CheckIfAdminExists()
+	if err != nil {
+		return err
+	}
+	if !exists {
+		log.Printf("no admin account exists; create one with `abtprj admin create LOGIN`")
+	}
+
+	templates, err := views.Load("templates")
+	if err != nil {
+		return fmt.Errorf("failed to parse templates: %w", err)
+	}
+
+	h := handlers.NewHandler(svc.DB, templates, svc)
+	h.PublicURL = publicURL
+
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+	mux.Handle("/static/",
+		http.StripPrefix("/static/", http.FileServer(http.Dir("static"))),
+	)
+
+	log.Printf("Listening on %s…", *addr)
+	return http.ListenAndServe(*addr, handlers.SecurityHeaders(mux))
+}
@@ -0,0 +1,95 @@
+// Package api holds the JSON bodies of the /api/ endpoints, shared by the server
+// handlers and the command-line client.
+package api
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type Error struct {
+	Error string `json:"error"`
+}
+
+type Status struct {
+	Working         bool       `json:"working"`
+	SessionStart    *time.Time `json:"session_start,omitempty"`
+	WorkedTodayMins int        `json:"worked_today_minutes"`
+	TasksDoneToday  int        `json:"tasks_done_today"`
+	OpenTasks       int        `json:"open_tasks"`
+}
+
+type Task struct {
+	ID              int        `json:"id,omitempty"` // left out when a task is added
+	Name            string     `json:"name"`
+	Description     string     `json:"description"`
+	Status          string     `json:"status"`
+	DoneAt          *time.Time `json:"done_at,omitempty"`
+	EstimateMinutes int        `json:"estimate_minutes,omitempty"`
+	Visibility      string     `json:"visibility,omitempty"` // empty when it follows the default
+}
+
+// NewTask is the body of POST /api/tasks. Visibility is public, private or
+// redacted-title, and the account's default when left out.
+type NewTask struct {
+	Name            string `json:"name"`
+	Description     string `json:"description,omitempty"`
+	EstimateMinutes int    `json:"estimate_minutes,omitempty"`
+	Visibility      string `json:"visibility,omitempty"`
+}
+
+// TaskRef is the body of POST /api/tasks/done.
+type TaskRef struct {
+	Name string `json:"name"`
+}
+
+// VisibilityChange is the body of POST /api/tasks/visibility and
+// /api/goals/visibility. An empty Visibility makes the item follow the
+// account's default again.
+type VisibilityChange struct {
+	ID         int    `json:"id"`
+	Visibility string `json:"visibility"`
+}
+
+// UndoRef is the body of POST /api/undo: the Undo-Token header of the
+// change to revert.
+type UndoRef struct {
+	Token string `json:"token"`
+}
+
+type Goal struct {
+	ID          int        `json:"id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Status      string     `json:"status"`
+	State       string     `json:"state"`
+	DueAt       *time.Time `json:"due_at,omitempty"`
+	Visibility  string     `json:"visibility,omitempty"` // empty when it follows the default
+}
+
+type Session struct {
+	Start   time.Time  `json:"start"`
+	End     *time.Time `json:"end,omitempty"`
+	Minutes int        `json:"minutes"`
+}
+
+type Today struct {
+	Date          string    `json:"date"`
+	WorkedMinutes int       `json:"worked_minutes"`
+	Sessions      []Session `json:"sessions"`
+	Tasks         []Task    `json:"tasks"`
+}
+
+// AuditEntry is one change in the audit log. Before and After are the changed
+// thing as it was and became, left out when it did not exist.
+type AuditEntry struct {
+	ID       int64           `json:"id"`
+	At       time.Time       `json:"at"`
+	Actor    string          `json:"actor"`
+	IP       string          `json:"ip,omitempty"`
+	Action   string          `json:"action"`
+	Entity   string          `json:"entity"`
+	EntityID string          `json:"entity_id,omitempty"`
+	Before   json.RawMessage `json:"before,omitempty"`
+	After    json.RawMessage `json:"after,omitempty"`
+}
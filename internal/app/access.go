@@ -0,0 +1,198 @@
+package app
+
+import (
+	"abtprj/internal/repository"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+// RoleEditor is the account role between RoleViewer and RoleOwner. The three
+// are the roles an account can give other accounts on its tracker.
+const RoleEditor = "editor"
+
+// Permission is something a role on an account allows.
+type Permission string
+
+const (
+	// PermNone is needed by pages that only touch the logged-in admin's own
+	// things: password, two-factor, invites, teams.
+	PermNone Permission = ""
+	// PermView allows the private admin pages and exports of the account.
+	PermView Permission = "view"
+	// PermEdit allows changing tasks, goals and work sessions.
+	PermEdit Permission = "edit"
+	// PermManage allows the account's settings and tokens and who has access.
+	PermManage Permission = "manage"
+)
+
+var rolePermissions = map[string][]Permission{
+	RoleViewer: {PermView},
+	RoleEditor: {PermView, PermEdit},
+	RoleOwner:  {PermView, PermEdit, PermManage},
+}
+
+// Can reports whether the session's role on its account allows p.
+func (s AdminSession) Can(p Permission) bool {
+	if p == PermNone {
+		return true
+	}
+	for _, have := range rolePermissions[s.Role] {
+		if have == p {
+			return true
+		}
+	}
+	return false
+}
+
+// ActingForOther reports whether the session works on another account's tracker.
+func (s AdminSession) ActingForOther() bool { return s.AccountID != s.AdminID }
+
+// AccountAccess is a role one account has on another's tracker.
+type AccountAccess struct {
+	Login     string // the account on the other side
+	Role      string
+	GrantedAt time.Time
+}
+
+func validAccountRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+func convertAccess(rows []repository.AccountAccess, loc *time.Location) []AccountAccess {
+	access := make([]AccountAccess, len(rows))
+	for i, g := range rows {
+		access[i] = AccountAccess{Login: g.Login, Role: g.Role, GrantedAt: g.GrantedAt.In(loc)}
+	}
+	return access
+}
+
+// ListAccountAccess returns who has access to the account session works on.
+func (s *DefaultAppService) ListAccountAccess(session AdminSession) ([]AccountAccess, error) {
+	if !session.Can(PermManage) {
+		return nil, ErrNotAllowed
+	}
+	rows, err := repository.ListAccessToAccount(s.db(), session.AccountID)
+	if err != nil {
+		log.Printf("ListAccountAccess exec error: %v", err)
+		return nil, err
+	}
+	return convertAccess(rows, s.loc), nil
+}
+
+// ListAccessibleAccounts returns the other accounts session's admin can work on.
+func (s *DefaultAppService) ListAccessibleAccounts(session AdminSession) ([]AccountAccess, error) {
+	rows, err := repository.ListAccessOfUser(s.db(), session.AdminID)
+	if err != nil {
+		log.Printf("ListAccessibleAccounts exec error: %v", err)
+		return nil, err
+	}
+	return convertAccess(rows, s.loc), nil
+}
+
+// GrantAccess gives the account login role on the account session works on,
+// or changes the role it has.
+func (s *DefaultAppService) GrantAccess(session AdminSession, login, role string) error {
+	if !session.Can(PermManage) {
+		return ErrNotAllowed
+	}
+	if !validAccountRole(role) {
+		return fmt.Errorf("%w: use viewer, editor or owner", ErrInvalidRole)
+	}
+	user, err := s.GetUser(login)
+	if err != nil {
+		return err
+	}
+	if user.ID == session.AccountID {
+		return fmt.Errorf("%w: an account always owns itself", ErrInvalidRole)
+	}
+	return s.by(session, session.AccountID).inTx(func(s *DefaultAppService) error {
+		before, err := repository.GetAccountRole(s.db(), session.AccountID, user.ID)
+		if err != nil {
+			return err
+		}
+		if err := repository.SetAccountAccess(s.db(), session.AccountID, user.ID, role); err != nil {
+			log.Printf("GrantAccess exec error: %v", err)
+			return err
+		}
+		_, err = s.audit("grant", AuditAccess, login, auditRole(before), auditRole(role))
+		return err
+	})
+}
+
+// auditRole is how the audit log shows a role, which is nothing for no access.
+func auditRole(role string) any {
+	if role == "" {
+		return nil
+	}
+	return map[string]string{"role": role}
+}
+
+// RevokeAccess takes away login's access to the account session works on.
+func (s *DefaultAppService) RevokeAccess(session AdminSession, login string) error {
+	if !session.Can(PermManage) {
+		return ErrNotAllowed
+	}
+	user, err := s.GetUser(login)
+	if err != nil {
+		return err
+	}
+	return s.by(session, session.AccountID).inTx(func(s *DefaultAppService) error {
+		before, err := repository.GetAccountRole(s.db(), session.AccountID, user.ID)
+		if err != nil {
+			return err
+		}
+		removed, err := repository.RemoveAccountAccess(s.db(), session.AccountID, user.ID)
+		if err != nil {
+			log.Printf("RevokeAccess exec error: %v", err)
+			return err
+		}
+		if !removed {
+			return ErrNoUser
+		}
+		_, err = s.audit("revoke", AuditAccess, login, auditRole(before), nil)
+		return err
+	})
+}
+
+// AccountRole returns adminID's role on accountID: RoleOwner on their own, the
+// role accountID gave them, or "" without access.
+func (s *DefaultAppService) AccountRole(adminID, accountID int) (string, error) {
+	if adminID == accountID {
+		return RoleOwner, nil
+	}
+	role, err := repository.GetAccountRole(s.db(), accountID, adminID)
+	if err != nil {
+		log.Printf("AccountRole exec error: %v", err)
+		return "", err
+	}
+	return role, nil
+}
+
+// SwitchAccount makes session work on the account login, which must have given
+// its admin access, or on the admin's own account when login is theirs or empty.
+func (s *DefaultAppService) SwitchAccount(session AdminSession, login string) error {
+	var account sql.NullInt64
+	if login != "" && login != session.Login {
+		user, err := s.GetUser(login)
+		if err != nil {
+			return err
+		}
+		role, err := repository.GetAccountRole(s.db(), user.ID, session.AdminID)
+		if err != nil {
+			log.Printf("SwitchAccount GetAccountRole error: %v", err)
+			return err
+		}
+		if role == "" {
+			return ErrNotAllowed
+		}
+		account = sql.NullInt64{Int64: int64(user.ID), Valid: true}
+	}
+	if err := repository.SetSessionAccount(s.db(), hashToken(session.Token), account); err != nil {
+		log.Printf("SwitchAccount exec error: %v", err)
+		return err
+	}
+	return nil
+}
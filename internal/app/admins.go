@@ -0,0 +1,124 @@
+package app
+
+import (
+	"abtprj/internal/repository"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const MinPasswordLength = 8
+
+// ErrWeakPassword is wrapped by every password policy violation.
+var ErrWeakPassword = errors.New("password too weak")
+
+// commonPasswords are refused outright, whatever their length.
+var commonPasswords = map[string]bool{
+	"password": true, "password1": true, "12345678": true, "123456789": true, "1234567890": true,
+	"qwertyui": true, "qwerty123": true, "11111111": true, "iloveyou": true, "admin123": true,
+	"adminadmin": true, "letmein1": true, "changeme": true, "abc12345": true,
+}
+
+type Admin struct {
+	ID        int
+	Login     string
+	CreatedAt time.Time
+}
+
+// ValidatePassword enforces the password policy for admin accounts: at least
+// MinPasswordLength characters, not the login and not a well-known password.
+func ValidatePassword(login, password string) error {
+	if len([]rune(password)) < MinPasswordLength {
+		return fmt.Errorf("%w: use at least %d characters", ErrWeakPassword, MinPasswordLength)
+	}
+	lower := strings.ToLower(password)
+	if login != "" && strings.Contains(lower, strings.ToLower(login)) {
+		return fmt.Errorf("%w: it must not contain the login", ErrWeakPassword)
+	}
+	if commonPasswords[lower] {
+		return fmt.Errorf("%w: it is one of the most common passwords", ErrWeakPassword)
+	}
+	return nil
+}
+
+// CreateAdmin adds an account. Logins are unique. Tracker data without an owner,
+// left from before there were accounts, goes to the first one.
+func (s *DefaultAppService) CreateAdmin(login, password string) error {
+	login = strings.TrimSpace(login)
+	if err := ValidateLogin(login); err != nil {
+		return err
+	}
+	if err := ValidatePassword(login, password); err != nil {
+		return err
+	}
+	existing, err := repository.GetAdminByLogin(s.db(), login)
+	if err != nil {
+		return err
+	}
+	if existing.Id != 0 {
+		return fmt.Errorf("admin %q already exists", login)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	err = s.inTx(func(s *DefaultAppService) error {
+		if err := repository.GenerateAdmin(s.db(), login, hash); err != nil {
+			return err
+		}
+		account, err := repository.GetAdminByLogin(s.db(), login)
+		if err != nil {
+			return err
+		}
+		_, err = s.fromCLI(account.Id).audit("create", AuditAccount, login, nil, map[string]string{"login": login})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return repository.AssignUnownedData(s.DB)
+}
+
+// ResetAdminPassword sets a new password for an existing admin and ends every
+// session opened with the old one.
+func (s *DefaultAppService) ResetAdminPassword(login, password string) error {
+	if err := ValidatePassword(login, password); err != nil {
+		return err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	admin, err := repository.GetAdminByLogin(s.db(), login)
+	if err != nil {
+		return err
+	}
+	if admin.Id == 0 {
+		return fmt.Errorf("no admin %q", login)
+	}
+	return s.fromCLI(admin.Id).inTx(func(s *DefaultAppService) error {
+		if err := repository.UpdateAdminPassword(s.db(), admin.Id, hash); err != nil {
+			return err
+		}
+		if _, err := s.audit("change-password", AuditAccount, login, nil, nil); err != nil {
+			return err
+		}
+		return repository.DeleteAdminSessions(s.db(), admin.Id)
+	})
+}
+
+func (s *DefaultAppService) ListAdmins() ([]Admin, error) {
+	rows, err := repository.ListAdmins(s.db())
+	if err != nil {
+		return nil, err
+	}
+	admins := make([]Admin, len(rows))
+	for i, a := range rows {
+		admins[i] = Admin{ID: a.Id, Login: a.Login, CreatedAt: a.CreatedAt.In(s.loc)}
+	}
+	return admins, nil
+}
@@ -0,0 +1,74 @@
+package app
+
+import (
+	"abtprj/internal/repository"
+	"errors"
+	"testing"
+)
+
+// TestResetAdminPassword_EndsSessions logs the admin out everywhere, as whoever
+// held the old password may still have a session. It needs a PostgreSQL database.
+func TestResetAdminPassword_EndsSessions(t *testing.T) {
+	db := testSchemaDB(t, "reset_password")
+	if err := repository.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	s := NewDefaultAppService(db)
+	if err := s.CreateAdmin("alice", "plum-harbour-fidget"); err != nil {
+		t.Fatal(err)
+	}
+	session, err := s.LoginAdmin("alice", "plum-harbour-fidget", "192.0.2.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.ResetAdminPassword("alice", "quartz-meadow-lantern"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetAdminSession(session.Token); !errors.Is(err, ErrNoSession) {
+		t.Errorf("session after the reset: %v; want ErrNoSession", err)
+	}
+	if _, err := s.LoginAdmin("alice", "quartz-meadow-lantern", "192.0.2.1"); err != nil {
+		t.Errorf("login with the new password: %v", err)
+	}
+	if err := s.ResetAdminPassword("nobody", "quartz-meadow-lantern"); err == nil {
+		t.Error("resetting an unknown login should fail")
+	}
+}
+
+// TestAdminCommands_AreAudited records what `abtprj admin` changes on the account
+// it changes, as made by "cli". It needs a PostgreSQL database.
+func TestAdminCommands_AreAudited(t *testing.T) {
+	db := testSchemaDB(t, "cli_audit")
+	if err := repository.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	s := NewDefaultAppService(db)
+	if err := s.CreateAdmin("alice", "plum-harbour-fidget"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ResetAdminPassword("alice", "quartz-meadow-lantern"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ResetAdminTOTP("alice"); err != nil {
+		t.Fatal(err)
+	}
+	alice, err := s.GetUser("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := s.WithUser(alice.ID).ListAudit(AuditFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"disable-2fa", "change-password", "create"}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries; want %v", len(entries), want)
+	}
+	for i, e := range entries {
+		if e.Action != want[i] || e.Entity != AuditAccount || e.EntityID != "alice" || e.Actor != "cli" {
+			t.Errorf("entry %d = %+v; want %s of alice by cli", i, e, want[i])
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package app
+
+import (
+	"abtprj/internal/repository"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+)
+
+// What the audit log records changes to.
+const (
+	AuditTask       = "task"
+	AuditGoal       = "goal"
+	AuditSession    = "work_session"
+	AuditSetting    = "setting"
+	AuditToken      = "token"
+	AuditImport     = "import"
+	AuditAccess     = "access"
+	AuditAccount    = "account"
+	AuditInvite     = "invite"
+	AuditTeam       = "team"
+	AuditTeamMember = "team_member"
+	AuditProject    = "project"
+	AuditSharedTask = "shared_task"
+	AuditSharedGoal = "shared_goal"
+)
+
+const (
+	defaultAuditPage = 50
+	maxAuditPage     = 500
+)
+
+// AuditEntities lists the entities the audit log can be filtered by.
+var AuditEntities = []string{AuditTask, AuditGoal, AuditSession, AuditSetting, AuditToken, AuditImport,
+	AuditAccess, AuditAccount, AuditInvite, AuditTeam, AuditTeamMember, AuditProject, AuditSharedTask, AuditSharedGoal}
+
+// AuditActions lists the actions the audit log can be filtered by.
+var AuditActions = []string{"create", "complete", "start", "end", "undo", "update", "regenerate", "import",
+	"grant", "revoke", "remove", "register", "change-password", "enable-2fa", "disable-2fa"}
+
+// Actor is who a change is made by, as recorded in the audit log.
+type Actor struct {
+	UserID int // 0 when nobody is logged in, as when registering
+	Login  string
+	IP     string
+}
+
+// AuditEntry is one change in the audit log. Before and After are the JSON of
+// the changed thing, and empty for what did not exist before or after.
+type AuditEntry struct {
+	ID       int64
+	At       time.Time
+	Actor    string
+	IP       string
+	Action   string
+	Entity   string
+	EntityID string
+	Before   string
+	After    string
+}
+
+type AuditFilter = repository.AuditFilter
+
+// As returns a service that records the changes it makes as done by actor.
+// The receiver is left unchanged.
+func (s *DefaultAppService) As(actor Actor) AppService {
+	scoped := *s
+	scoped.actor = actor
+	return &scoped
+}
+
+// by is the service for a change session makes to accountID, recorded as the
+// session's admin when the service has no actor of its own.
+func (s *DefaultAppService) by(session AdminSession, accountID int) *DefaultAppService {
+	scoped := s.WithUser(accountID)
+	if scoped.actor.UserID == 0 {
+		scoped.actor.UserID, scoped.actor.Login = session.AdminID, session.Login
+	}
+	return scoped
+}
+
+// fromCLI is the service for a change `abtprj admin` makes to accountID, which
+// the audit log records as made by "cli".
+func (s *DefaultAppService) fromCLI(accountID int) *DefaultAppService {
+	scoped := s.WithUser(accountID)
+	scoped.actor = Actor{Login: "cli"}
+	return scoped
+}
+
+// audit appends a change of the service's account to the audit log and
+// returns the entry. before and after are stored as JSON, nil for nothing; a
+// change that left the entity as it was is not recorded and returns an entry
+// without an Id. Record it in the change's transaction (see inTx), so that a
+// change that cannot be recorded is not made either.
+func (s *DefaultAppService) audit(action, entity string, entityID any, before, after any) (repository.AuditEntry, error) {
+	b, a := auditJSON(before), auditJSON(after)
+	if b.Valid && a.Valid && b.String == a.String {
+		return repository.AuditEntry{}, nil
+	}
+	var id string
+	if entityID != nil {
+		id = fmt.Sprint(entityID)
+	}
+	e := repository.AuditEntry{
+		AccountId:  s.userID,
+		ActorId:    sql.NullInt64{Int64: int64(s.actor.UserID), Valid: s.actor.UserID != 0},
+		ActorLogin: s.actor.Login,
+		IP:         s.actor.IP,
+		Action:     action,
+		Entity:     entity,
+		EntityId:   id,
+		Before:     b,
+		After:      a,
+	}
+	var err error
+	if e.Id, err = repository.AddAuditEntry(s.db(), e); err != nil {
+		log.Printf("audit %s %s %s error: %v", action, entity, id, err)
+		return e, err
+	}
+	return e, nil
+}
+
+// auditTask records action on a task, as it was before and is now.
+func (s *DefaultAppService) auditTask(action string, before repository.Task) (repository.AuditEntry, error) {
+	after, err := repository.GetTask(s.db(), s.userID, before.Id)
+	if err != nil {
+		log.Printf("audit GetTask error: %v", err)
+		return repository.AuditEntry{}, err
+	}
+	return s.audit(action, AuditTask, before.Id, newTaskRecord(before), newTaskRecord(after))
+}
+
+// auditGoal is auditTask for goals.
+func (s *DefaultAppService) auditGoal(action string, before repository.Goal) (repository.AuditEntry, error) {
+	after, err := repository.GetGoal(s.db(), s.userID, before.Id)
+	if err != nil {
+		log.Printf("audit GetGoal error: %v", err)
+		return repository.AuditEntry{}, err
+	}
+	return s.audit(action, AuditGoal, before.Id, newGoalRecord(before), newGoalRecord(after))
+}
+
+// auditSession is auditTask for work sessions.
+func (s *DefaultAppService) auditSession(action string, before repository.WorkSession) (repository.AuditEntry, error) {
+	after, err := repository.GetWorkSession(s.db(), s.userID, before.Id)
+	if err != nil {
+		log.Printf("audit GetWorkSession error: %v", err)
+		return repository.AuditEntry{}, err
+	}
+	return s.audit(action, AuditSession, before.Id, newSessionRecord(before), newSessionRecord(after))
+}
+
+func auditJSON(v any) sql.NullString {
+	if v == nil {
+		return sql.NullString{}
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("audit marshal error: %v", err)
+		return sql.NullString{}
+	}
+	return sql.NullString{String: string(b), Valid: true}
+}
+
+// ListAudit returns the account's audit log entries matching filter, newest
+// first, filter.Limit of them (50 when unset).
+func (s *DefaultAppService) ListAudit(filter AuditFilter) ([]AuditEntry, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultAuditPage
+	}
+	filter.Limit = min(filter.Limit, maxAuditPage)
+	rows, err := repository.ListAuditEntries(s.db(), s.userID, filter)
+	if err != nil {
+		log.Printf("ListAudit exec error: %v", err)
+		return nil, err
+	}
+	entries := make([]AuditEntry, len(rows))
+	for i, r := range rows {
+		entries[i] = AuditEntry{
+			ID:       r.Id,
+			At:       r.At.In(s.loc),
+			Actor:    r.ActorLogin,
+			IP:       r.IP,
+			Action:   r.Action,
+			Entity:   r.Entity,
+			EntityID: r.EntityId,
+			Before:   r.Before.String,
+			After:    r.After.String,
+		}
+	}
+	return entries, nil
+}
+
+// The audit log's JSON of tasks, goals and work sessions, with the column
+// names of the exports.
+type (
+	taskRecord struct {
+		ID              int        `json:"id"`
+		Name            string     `json:"name"`
+		Description     string     `json:"description,omitempty"`
+		Status          string     `json:"status"`
+		EstimateMinutes int64      `json:"estimate_minutes,omitempty"`
+		DoneAt          *time.Time `json:"done_at,omitempty"`
+		SessionID       int64      `json:"session_id,omitempty"`
+		Visibility      string     `json:"visibility,omitempty"`
+	}
+	goalRecord struct {
+		ID          int        `json:"id"`
+		Name        string     `json:"name"`
+		Description string     `json:"description,omitempty"`
+		Status      string     `json:"status"`
+		DueAt       *time.Time `json:"due_at,omitempty"`
+		DoneAt      *time.Time `json:"done_at,omitempty"`
+		Visibility  string     `json:"visibility,omitempty"`
+	}
+	sessionRecord struct {
+		ID        int        `json:"id"`
+		StartTime time.Time  `json:"start_time"`
+		EndTime   *time.Time `json:"end_time,omitempty"`
+	}
+)
+
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func newTaskRecord(t repository.Task) taskRecord {
+	return taskRecord{
+		ID:              t.Id,
+		Name:            t.Name,
+		Description:     t.Description,
+		Status:          t.Status,
+		EstimateMinutes: t.Estimate.Int64,
+		DoneAt:          nullTimePtr(t.DoneAt),
+		SessionID:       t.SessionId.Int64,
+		Visibility:      t.Visibility.String,
+	}
+}
+
+func newGoalRecord(g repository.Goal) goalRecord {
+	return goalRecord{
+		ID:          g.Id,
+		Name:        g.Name,
+		Description: g.Description,
+		Status:      g.Status,
+		DueAt:       nullTimePtr(g.DueAt),
+		DoneAt:      nullTimePtr(g.DoneAt),
+		Visibility:  g.Visibility.String,
+	}
+}
+
+func newSessionRecord(ws repository.WorkSession) sessionRecord {
+	return sessionRecord{ID: ws.Id, StartTime: ws.StartTime, EndTime: nullTimePtr(ws.EndTime)}
+}
@@ -0,0 +1,200 @@
+package app
+
+import (
+	"abtprj/internal/repository"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	AdminSessionTTL = 7 * 24 * time.Hour
+	// PendingTOTPTTL is how long a correct password waits for its TOTP code.
+	PendingTOTPTTL = 5 * time.Minute
+)
+
+var (
+	ErrInvalidLogin  = errors.New("invalid login or password")
+	ErrNoSession     = errors.New("not logged in")
+	ErrWrongPassword = errors.New("current password is wrong")
+)
+
+// AdminSession is a logged-in admin. Token is only known to the browser holding it.
+type AdminSession struct {
+	Token              string
+	AdminID            int
+	Login              string
+	MustChangePassword bool
+	TOTPPending        bool // password checked, TOTP code still missing
+	Owner              bool // the first account, which runs the instance
+	ExpiresAt          time.Time
+
+	// The account whose tracker the session works on: the admin's own, with
+	// RoleOwner, or one that gave them access with the role it gave.
+	AccountID    int
+	AccountLogin string
+	Role         string
+}
+
+// dummyHash is compared against when the login does not exist, so both failures
+// take as long.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("not a real password"), bcrypt.DefaultCost)
+
+// LoginAdmin checks the credentials and opens a session. An account whose password
+// no longer meets the policy, such as an old admin/admin default, is flagged to
+// change it before doing anything else. With TOTP enabled the session is pending
+// until CompleteTOTPLogin. Repeated failures from ip or for login are throttled
+// by s.Guard.
+func (s *DefaultAppService) LoginAdmin(login, password, ip string) (AdminSession, error) {
+	now := time.Now()
+	if err := s.checkThrottle(ip, login, now); err != nil {
+		return AdminSession{}, err
+	}
+	admin, err := repository.GetAdminByLogin(s.db(), login)
+	if err != nil {
+		return AdminSession{}, err
+	}
+	if admin.Id == 0 {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		s.Guard.Failed(FailedLogin{At: now, IP: ip, Login: login, Reason: "unknown login"})
+		return AdminSession{}, ErrInvalidLogin
+	}
+	if bcrypt.CompareHashAndPassword([]byte(admin.PasswordHash), []byte(password)) != nil {
+		s.Guard.Failed(FailedLogin{At: now, IP: ip, Login: login, Reason: "wrong password"})
+		return AdminSession{}, ErrInvalidLogin
+	}
+
+	if !admin.MustChangePassword && ValidatePassword(admin.Login, password) != nil {
+		if err := repository.SetMustChangePassword(s.db(), admin.Id); err != nil {
+			log.Printf("LoginAdmin SetMustChangePassword error: %v", err)
+			return AdminSession{}, err
+		}
+		admin.MustChangePassword = true
+	}
+
+	if !admin.TOTPEnabled {
+		s.Guard.Succeeded(ip, login)
+	}
+	return s.openSession(admin.Id, admin.Login, admin.MustChangePassword, admin.TOTPEnabled)
+}
+
+// openSession stores a new session under a random token. A totpPending session
+// only lives for PendingTOTPTTL and only lets the admin enter a code.
+func (s *DefaultAppService) openSession(adminID int, login string, mustChange, totpPending bool) (AdminSession, error) {
+	if err := repository.DeleteExpiredAdminSessions(s.db()); err != nil {
+		log.Printf("openSession DeleteExpiredAdminSessions error: %v", err)
+	}
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return AdminSession{}, err
+	}
+	ttl := AdminSessionTTL
+	if totpPending {
+		ttl = PendingTOTPTTL
+	}
+	session := AdminSession{
+		Token:              hex.EncodeToString(buf),
+		AdminID:            adminID,
+		Login:              login,
+		MustChangePassword: mustChange,
+		TOTPPending:        totpPending,
+		ExpiresAt:          time.Now().Add(ttl),
+		AccountID:          adminID,
+		AccountLogin:       login,
+		Role:               RoleOwner,
+	}
+	if err := repository.CreateAdminSession(s.db(), hashToken(session.Token), adminID, session.ExpiresAt, totpPending); err != nil {
+		log.Printf("openSession CreateAdminSession error: %v", err)
+		return AdminSession{}, err
+	}
+	return session, nil
+}
+
+// GetAdminSession returns the session for a cookie token, or ErrNoSession.
+func (s *DefaultAppService) GetAdminSession(token string) (AdminSession, error) {
+	if token == "" {
+		return AdminSession{}, ErrNoSession
+	}
+	row, ok, err := repository.GetAdminSession(s.db(), hashToken(token))
+	if err != nil {
+		log.Printf("GetAdminSession error: %v", err)
+		return AdminSession{}, err
+	}
+	if !ok {
+		return AdminSession{}, ErrNoSession
+	}
+	session := AdminSession{
+		Token:              token,
+		AdminID:            row.AdminId,
+		Login:              row.Login,
+		MustChangePassword: row.MustChangePassword,
+		Owner:              row.Owner,
+		ExpiresAt:          row.ExpiresAt,
+		AccountID:          row.AccountId,
+		AccountLogin:       row.AccountLogin,
+		Role:               row.Role,
+	}
+	if session.Role == "" {
+		// the access was taken away; back to the admin's own account
+		session.AccountID, session.AccountLogin, session.Role = row.AdminId, row.Login, RoleOwner
+	}
+	return session, nil
+}
+
+func (s *DefaultAppService) LogoutAdmin(token string) error {
+	return repository.DeleteAdminSession(s.db(), hashToken(token))
+}
+
+// ChangeAdminPassword replaces the session admin's password after checking the
+// current one, and signs out every other session of that admin.
+func (s *DefaultAppService) ChangeAdminPassword(session AdminSession, current, newPassword string) error {
+	hash, err := repository.GetAdminPasswordHash(s.db(), session.AdminID)
+	if err != nil {
+		return err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(current)) != nil {
+		return ErrWrongPassword
+	}
+	if newPassword == current {
+		return fmt.Errorf("%w: choose a password different from the current one", ErrWeakPassword)
+	}
+	if err := ValidatePassword(session.Login, newPassword); err != nil {
+		return err
+	}
+
+	newHash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	return s.by(session, session.AdminID).inTx(func(s *DefaultAppService) error {
+		if err := repository.UpdateAdminPassword(s.db(), session.AdminID, newHash); err != nil {
+			log.Printf("ChangeAdminPassword UpdateAdminPassword error: %v", err)
+			return err
+		}
+		if _, err := s.audit("change-password", AuditAccount, session.Login, nil, nil); err != nil {
+			return err
+		}
+		return repository.DeleteOtherAdminSessions(s.db(), session.AdminID, hashToken(session.Token))
+	})
+}
+
+// CSRFToken is the token state-changing admin requests must carry. It is tied
+// to the session, so it changes on every login and cannot be guessed without
+// the session cookie.
+func (a AdminSession) CSRFToken() string {
+	mac := hmac.New(sha256.New, []byte(a.Token))
+	mac.Write([]byte("csrf"))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,232 @@
+package app
+
+import (
+	"abtprj/internal/repository"
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+)
+
+const (
+	backupFormat        = "abtprj-backup"
+	backupLayoutVersion = 1 // bump when the archive layout itself changes
+	backupManifestFile  = "manifest.json"
+)
+
+// BackupManifest is the first file of a backup archive. SchemaVersion is the
+// migration the data was taken at; restore replays migrations from there.
+type BackupManifest struct {
+	Format        string        `json:"format"`
+	Version       int           `json:"version"`
+	SchemaVersion int           `json:"schema_version"`
+	CreatedAt     time.Time     `json:"created_at"`
+	Tables        []BackupTable `json:"tables"`
+}
+
+type BackupTable struct {
+	Name   string `json:"name"`
+	File   string `json:"file"`
+	Rows   int    `json:"rows"`
+	SHA256 string `json:"sha256"`
+}
+
+type RestoreSummary struct {
+	SchemaVersion int            // schema of the backup
+	Rows          map[string]int // rows restored per table
+	Dropped       []string       // table.column values the current schema has no place for
+}
+
+// Backup writes every table as a gzipped tar archive: manifest.json followed by one
+// JSON lines file per table.
+func (s *DefaultAppService) Backup(w io.Writer) error {
+	version, err := repository.SchemaVersion(s.DB)
+	if err != nil {
+		log.Printf("Backup SchemaVersion error: %v", err)
+		return err
+	}
+
+	files := make(map[string]*bytes.Buffer)
+	counts := make(map[string]int)
+	encoders := make(map[string]*json.Encoder)
+	for _, table := range repository.BackupTables {
+		files[table] = new(bytes.Buffer)
+		encoders[table] = json.NewEncoder(files[table])
+	}
+	err = repository.DumpTables(s.DB, func(table string, row map[string]any) error {
+		counts[table]++
+		return encoders[table].Encode(row)
+	})
+	if err != nil {
+		log.Printf("Backup DumpTables error: %v", err)
+		return err
+	}
+
+	manifest := BackupManifest{
+		Format:        backupFormat,
+		Version:       backupLayoutVersion,
+		SchemaVersion: version,
+		CreatedAt:     time.Now().UTC().Truncate(time.Second),
+	}
+	for _, table := range repository.BackupTables {
+		sum := sha256.Sum256(files[table].Bytes())
+		manifest.Tables = append(manifest.Tables, BackupTable{
+			Name:   table,
+			File:   table + ".jsonl",
+			Rows:   counts[table],
+			SHA256: hex.EncodeToString(sum[:]),
+		})
+	}
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	add := func(name string, data []byte) error {
+		hdr := &tar.Header{Name: name, Mode: 0o600, Size: int64(len(data)), ModTime: manifest.CreatedAt}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+	if err := add(backupManifestFile, append(manifestJSON, '\n')); err != nil {
+		return err
+	}
+	for _, t := range manifest.Tables {
+		if err := add(t.File, files[t.Name].Bytes()); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// ReadBackup unpacks an archive written by Backup and checks every file against
+// the manifest before returning the rows of each table.
+func ReadBackup(r io.Reader) (BackupManifest, map[string][]map[string]any, error) {
+	var manifest BackupManifest
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return manifest, nil, fmt.Errorf("not a backup archive: %w", err)
+	}
+	files := make(map[string][]byte)
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return manifest, nil, fmt.Errorf("reading archive: %w", err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return manifest, nil, fmt.Errorf("reading %s: %w", hdr.Name, err)
+		}
+		files[hdr.Name] = data
+	}
+
+	raw, ok := files[backupManifestFile]
+	if !ok {
+		return manifest, nil, errors.New("archive has no manifest.json")
+	}
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		return manifest, nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	if manifest.Format != backupFormat {
+		return manifest, nil, fmt.Errorf("not an abtprj backup (format %q)", manifest.Format)
+	}
+	if manifest.Version > backupLayoutVersion {
+		return manifest, nil, fmt.Errorf("backup layout version %d is newer than this build understands", manifest.Version)
+	}
+
+	tables := make(map[string][]map[string]any)
+	for _, t := range manifest.Tables {
+		data, ok := files[t.File]
+		if !ok {
+			return manifest, nil, fmt.Errorf("%s is listed in the manifest but missing", t.File)
+		}
+		sum := sha256.Sum256(data)
+		if hex.EncodeToString(sum[:]) != t.SHA256 {
+			return manifest, nil, fmt.Errorf("%s is corrupt: checksum mismatch", t.File)
+		}
+
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		var rows []map[string]any
+		for {
+			var row map[string]any
+			if err := dec.Decode(&row); err == io.EOF {
+				break
+			} else if err != nil {
+				return manifest, nil, fmt.Errorf("%s row %d: %w", t.File, len(rows)+1, err)
+			}
+			rows = append(rows, row)
+		}
+		if len(rows) != t.Rows {
+			return manifest, nil, fmt.Errorf("%s has %d rows, manifest says %d", t.File, len(rows), t.Rows)
+		}
+		tables[t.Name] = rows
+	}
+	return manifest, tables, nil
+}
+
+// Restore loads a backup into an empty database whose schema is not past the
+// backup's, such as a new one. The schema is first migrated to the backup's
+// version so the rows fit as they were taken, then up to the latest, so every
+// later migration is applied to the restored rows.
+func (s *DefaultAppService) Restore(r io.Reader) (RestoreSummary, error) {
+	manifest, tables, err := ReadBackup(r)
+	if err != nil {
+		return RestoreSummary{}, err
+	}
+	summary := RestoreSummary{SchemaVersion: manifest.SchemaVersion, Rows: make(map[string]int)}
+	if manifest.SchemaVersion > repository.LatestSchemaVersion() {
+		return summary, fmt.Errorf("backup is at schema version %d, this build only knows up to %d; upgrade abtprj first",
+			manifest.SchemaVersion, repository.LatestSchemaVersion())
+	}
+
+	current, err := repository.SchemaVersion(s.DB)
+	if err != nil {
+		return summary, err
+	}
+	if current > manifest.SchemaVersion {
+		return summary, fmt.Errorf("database is at schema version %d, past the backup's %d, and migrations only go forward; "+
+			"restore into a new database", current, manifest.SchemaVersion)
+	}
+	hasData, err := repository.HasTrackerData(s.DB)
+	if err != nil {
+		return summary, err
+	}
+	if hasData {
+		return summary, errors.New("database already has accounts, tracker data, settings or audit log entries; " +
+			"restore into an empty database")
+	}
+
+	if err := repository.MigrateTo(s.DB, manifest.SchemaVersion); err != nil {
+		return summary, err
+	}
+
+	if summary.Dropped, err = repository.RestoreTables(s.DB, tables); err != nil {
+		return summary, err
+	}
+	for name, rows := range tables {
+		summary.Rows[name] = len(rows)
+	}
+	if err := repository.Migrate(s.DB); err != nil {
+		return summary, err
+	}
+	return summary, repository.AssignUnownedData(s.DB)
+}
@@ -0,0 +1,171 @@
+package app
+
+import (
+	"abtprj/internal/repository"
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+func TestRestore_RefusesNewerBackup(t *testing.T) {
+	old := &DefaultAppService{loc: time.UTC}
+	var buf bytes.Buffer
+	writeTestBackup(t, &buf, repository.LatestSchemaVersion()+1, nil)
+
+	// refused from the manifest alone, before the database is touched
+	_, err := old.Restore(&buf)
+	if err == nil || !strings.Contains(err.Error(), "upgrade abtprj first") {
+		t.Fatalf("Restore = %v; want the backup refused as too new", err)
+	}
+}
+
+// writeTestBackup writes an archive as Backup does, of the tables taken at
+// schema version.
+func writeTestBackup(t *testing.T, w io.Writer, version int, tables map[string][]map[string]any) {
+	t.Helper()
+	manifest := BackupManifest{Format: backupFormat, Version: backupLayoutVersion, SchemaVersion: version, CreatedAt: time.Now().UTC()}
+	files := make(map[string][]byte)
+	for _, name := range slices.Sorted(maps.Keys(tables)) {
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		for _, row := range tables[name] {
+			if err := enc.Encode(row); err != nil {
+				t.Fatal(err)
+			}
+		}
+		sum := sha256.Sum256(buf.Bytes())
+		table := BackupTable{Name: name, File: name + ".jsonl", Rows: len(tables[name]), SHA256: hex.EncodeToString(sum[:])}
+		manifest.Tables = append(manifest.Tables, table)
+		files[table.File] = buf.Bytes()
+	}
+	manifestJSON, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	add := func(name string, data []byte) {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o600, Size: int64(len(data))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	add(backupManifestFile, manifestJSON)
+	for _, table := range manifest.Tables {
+		add(table.File, files[table.File])
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testSchemaDB returns a database whose tables live in a schema of their own,
+// dropped when the test ends. It needs ABTPRJ_TEST_DATABASE_URL.
+func testSchemaDB(t *testing.T, name string) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("ABTPRJ_TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("ABTPRJ_TEST_DATABASE_URL is not set")
+	}
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := fmt.Sprintf("%s_%d", name, time.Now().UnixNano())
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+		admin.Close()
+	})
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	q.Set("search_path", schema)
+	u.RawQuery = q.Encode()
+	db, err := sql.Open("postgres", u.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// TestRestore_BackupFromBeforeAccounts restores a backup taken at schema
+// version 5, before tracker data and settings had an owner, into a new
+// database, and refuses it for one already migrated past it. It needs a
+// PostgreSQL database.
+func TestRestore_BackupFromBeforeAccounts(t *testing.T) {
+	var backup bytes.Buffer
+	writeTestBackup(t, &backup, 5, map[string][]map[string]any{
+		"admin":         {{"id": 1, "login": "owner", "password_hash": "x", "must_change_password": false}},
+		"work_sessions": {{"id": 1, "start_time": "2024-03-01T09:00:00Z", "end_time": "2024-03-01T10:00:00Z"}},
+		"tasks":         {{"id": 1, "name": "Taxes", "status": "done", "done_at": "2024-03-01T09:30:00Z", "session_id": 1}},
+		"settings":      {{"key": "feeds.token", "value": "secret"}},
+	})
+	archive := backup.Bytes()
+
+	migrated := testSchemaDB(t, "restore_migrated")
+	if err := repository.Migrate(migrated); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewDefaultAppService(migrated).Restore(bytes.NewReader(archive)); err == nil ||
+		!strings.Contains(err.Error(), "past the backup") {
+		t.Errorf("Restore into a migrated database = %v; want it refused", err)
+	}
+
+	target := testSchemaDB(t, "restore_target")
+	summary, err := NewDefaultAppService(target).Restore(bytes.NewReader(archive))
+	if err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if summary.SchemaVersion != 5 || summary.Rows["settings"] != 1 || summary.Rows["tasks"] != 1 {
+		t.Errorf("summary = %+v; want version 5 with one setting and one task", summary)
+	}
+	if v, err := repository.SchemaVersion(target); err != nil || v != repository.LatestSchemaVersion() {
+		t.Errorf("schema version = %d, %v; want %d", v, err, repository.LatestSchemaVersion())
+	}
+
+	var owner int
+	if err := target.QueryRow("SELECT id FROM admin WHERE login = 'owner'").Scan(&owner); err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range []string{"settings", "tasks", "work_sessions"} {
+		var unowned int
+		if err := target.QueryRow("SELECT COUNT(*) FROM "+table+" WHERE user_id IS DISTINCT FROM $1", owner).Scan(&unowned); err != nil {
+			t.Fatal(err)
+		}
+		if unowned != 0 {
+			t.Errorf("%s: %d rows not owned by the first account", table, unowned)
+		}
+	}
+	token, ok, err := repository.GetSetting(target, owner, "feeds.token")
+	if err != nil || !ok || token != "secret" {
+		t.Errorf("feed token = %q, %v, %v; want the backed up one", token, ok, err)
+	}
+}
@@ -0,0 +1,120 @@
+package app
+
+import (
+	"abtprj/internal/repository"
+	"log"
+	"strconv"
+	"time"
+)
+
+type CalendarDay struct {
+	Date      string // "2006-01-02"
+	Day       int
+	InMonth   bool // false for the padding days of the neighbouring months
+	IsToday   bool
+	Worked    time.Duration
+	TasksDone int
+	GoalsDue  int
+}
+
+type MonthCalendar struct {
+	Title string // "January 2025"
+	Month string // "2025-01"
+	Prev  string
+	Next  string
+	Weeks [][]CalendarDay // Monday first
+}
+
+// GetMonthCalendar aggregates one month of tasks, sessions and goal deadlines per day.
+// Each kind of data is loaded with a single range query.
+func (s *DefaultAppService) GetMonthCalendar(year int, month time.Month) (MonthCalendar, error) {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, s.loc)
+	gridStart := startOfWeek(first)
+	gridEnd := startOfWeek(first.AddDate(0, 1, 0).Add(-time.Nanosecond)).AddDate(0, 0, 7)
+
+	tasks, err := repository.GetDoneTasks(s.db(), s.userID, gridStart.UTC(), gridEnd.UTC())
+	if err != nil {
+		log.Printf("GetMonthCalendar GetDoneTasks error: %v", err)
+		return MonthCalendar{}, err
+	}
+	if tasks, err = s.visibleTasks(tasks); err != nil {
+		return MonthCalendar{}, err
+	}
+	sessions, err := repository.GetWorkingSessions(s.db(), s.userID, gridStart.UTC(), gridEnd.UTC())
+	if err != nil {
+		log.Printf("GetMonthCalendar GetWorkingSessions error: %v", err)
+		return MonthCalendar{}, err
+	}
+	// due dates are stored as UTC midnight of the chosen day
+	goals, err := repository.GetGoalsDueBetween(s.db(), s.userID,
+		time.Date(gridStart.Year(), gridStart.Month(), gridStart.Day(), 0, 0, 0, 0, time.UTC),
+		time.Date(gridEnd.Year(), gridEnd.Month(), gridEnd.Day(), 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		log.Printf("GetMonthCalendar GetGoalsDueBetween error: %v", err)
+		return MonthCalendar{}, err
+	}
+	if goals, err = s.visibleGoals(goals); err != nil {
+		return MonthCalendar{}, err
+	}
+
+	days := make(map[string]*CalendarDay)
+	today := time.Now().In(s.loc).Format("2006-01-02")
+	cal := MonthCalendar{
+		Title: first.Format("January 2006"),
+		Month: first.Format("2006-01"),
+		Prev:  first.AddDate(0, -1, 0).Format("2006-01"),
+		Next:  first.AddDate(0, 1, 0).Format("2006-01"),
+	}
+	for d := gridStart; d.Before(gridEnd); d = d.AddDate(0, 0, 7) {
+		week := make([]CalendarDay, 7)
+		for i := range week {
+			day := d.AddDate(0, 0, i)
+			week[i] = CalendarDay{
+				Date:    day.Format("2006-01-02"),
+				Day:     day.Day(),
+				InMonth: day.Month() == month,
+				IsToday: day.Format("2006-01-02") == today,
+			}
+		}
+		cal.Weeks = append(cal.Weeks, week)
+	}
+	for w := range cal.Weeks {
+		for i := range cal.Weeks[w] {
+			days[cal.Weeks[w][i].Date] = &cal.Weeks[w][i]
+		}
+	}
+
+	for _, t := range tasks {
+		if !t.DoneAt.Valid {
+			continue
+		}
+		if day, ok := days[t.DoneAt.Time.In(s.loc).Format("2006-01-02")]; ok {
+			day.TasksDone++
+		}
+	}
+	for _, sess := range sessions {
+		if !sess.EndTime.Valid {
+			continue
+		}
+		if day, ok := days[sess.StartTime.In(s.loc).Format("2006-01-02")]; ok {
+			day.Worked += sess.EndTime.Time.Sub(sess.StartTime)
+		}
+	}
+	for _, g := range goals {
+		if !g.DueAt.Valid {
+			continue
+		}
+		if day, ok := days[g.DueAt.Time.UTC().Format("2006-01-02")]; ok {
+			day.GoalsDue++
+		}
+	}
+	return cal, nil
+}
+
+// Hours formats the worked time as fractional hours, e.g. "2.5h".
+func (d CalendarDay) Hours() string {
+	if d.Worked <= 0 {
+		return ""
+	}
+	return strconv.FormatFloat(d.Worked.Round(6*time.Minute).Hours(), 'f', -1, 64) + "h"
+}
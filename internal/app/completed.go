@@ -0,0 +1,81 @@
+package app
+
+import (
+	"abtprj/internal/repository"
+	"database/sql"
+	"log"
+	"sort"
+	"time"
+)
+
+const (
+	CompletedTask = "task"
+	CompletedGoal = "goal"
+)
+
+// CompletedItem is a done task or goal, as listed in the worklog feed.
+type CompletedItem struct {
+	Kind        string // CompletedTask or CompletedGoal
+	ID          int
+	Name        string
+	Description string
+	DoneAt      time.Time
+	CreatedAt   time.Time // zero for goals
+}
+
+// GetRecentlyCompleted returns the last limit completed tasks and goals together, newest first.
+// A public view leaves private items out in the query, so they do not take up the limit.
+func (s *DefaultAppService) GetRecentlyCompleted(limit int) ([]CompletedItem, error) {
+	var def sql.NullString
+	if s.public {
+		v, err := s.GetDefaultVisibility()
+		if err != nil {
+			log.Printf("GetRecentlyCompleted GetDefaultVisibility error: %v", err)
+			return nil, err
+		}
+		def = sql.NullString{String: v, Valid: true}
+	}
+	tasks, err := repository.GetRecentDoneTasks(s.db(), s.userID, limit, def)
+	if err != nil {
+		log.Printf("GetRecentlyCompleted GetRecentDoneTasks error: %v", err)
+		return nil, err
+	}
+	goals, err := repository.GetRecentDoneGoals(s.db(), s.userID, limit, def)
+	if err != nil {
+		log.Printf("GetRecentlyCompleted GetRecentDoneGoals error: %v", err)
+		return nil, err
+	}
+	if tasks, err = s.visibleTasks(tasks); err != nil {
+		return nil, err
+	}
+	if goals, err = s.visibleGoals(goals); err != nil {
+		return nil, err
+	}
+
+	items := make([]CompletedItem, 0, len(tasks)+len(goals))
+	for _, t := range tasks {
+		items = append(items, CompletedItem{
+			Kind:        CompletedTask,
+			ID:          t.Id,
+			Name:        t.Name,
+			Description: t.Description,
+			DoneAt:      t.DoneAt.Time,
+			CreatedAt:   t.CreatedAt,
+		})
+	}
+	for _, g := range goals {
+		items = append(items, CompletedItem{
+			Kind:        CompletedGoal,
+			ID:          g.Id,
+			Name:        g.Name,
+			Description: g.Description,
+			DoneAt:      g.DoneAt.Time,
+		})
+	}
+
+	sort.SliceStable(items, func(i, j int) bool { return items[i].DoneAt.After(items[j].DoneAt) })
+	if len(items) > limit {
+		items = items[:limit]
+	}
+	return items, nil
+}
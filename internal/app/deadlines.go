@@ -0,0 +1,132 @@
+package app
+
+import (
+	"abtprj/internal/repository"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	GoalUpcoming   = "upcoming"
+	GoalDueSoon    = "due soon"
+	GoalOverdue    = "overdue"
+	GoalDoneOnTime = "done on time"
+	GoalDoneLate   = "done late"
+
+	// dueSoonWindow is how close a deadline has to be to count as due soon.
+	dueSoonWindow = 3 * 24 * time.Hour
+)
+
+type GoalDeadlineStats struct {
+	DoneOnTime int
+	DoneLate   int
+	Overdue    int
+	Open       int
+	OnTimeRate float64 // share of completed goals done by their due date, 0–1
+}
+
+// GoalState compares a goal's due date with its completion, or with now if it is still open.
+// A goal due on a given day is on time for the whole of that day in loc.
+func GoalState(g Goal, now time.Time, loc *time.Location) string {
+	if g.DueAt == nil || g.DueAt.IsZero() {
+		if g.Status == "done" {
+			return GoalDoneOnTime
+		}
+		return GoalUpcoming
+	}
+	deadline := dueDeadline(*g.DueAt, loc)
+
+	if g.Status == "done" {
+		if g.DoneAt != nil && g.DoneAt.Valid && g.DoneAt.Time.After(deadline) {
+			return GoalDoneLate
+		}
+		return GoalDoneOnTime
+	}
+
+	switch {
+	case !now.Before(deadline):
+		return GoalOverdue
+	case deadline.Sub(now) <= dueSoonWindow:
+		return GoalDueSoon
+	default:
+		return GoalUpcoming
+	}
+}
+
+// dueDeadline is the end of the day a goal is due in loc. Due dates are
+// stored as midnight UTC of the day.
+func dueDeadline(dueAt time.Time, loc *time.Location) time.Time {
+	y, m, d := dueAt.UTC().Date()
+	return time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+}
+
+// StateClass is the goal's state in a form usable as a CSS class suffix.
+func (g Goal) StateClass() string {
+	return strings.ReplaceAll(g.State, " ", "-")
+}
+
+// HasOverdueGoal reports whether any goal due on this day is overdue.
+func (d DayTasksStat) HasOverdueGoal() bool {
+	for _, g := range d.Goals {
+		if g.State == GoalOverdue {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *DefaultAppService) GetGoalDeadlineStats() (GoalDeadlineStats, error) {
+	repoGoals, err := repository.GetGoals(s.db(), s.userID)
+	if err != nil {
+		log.Printf("GetGoalDeadlineStats exec error: %v", err)
+		return GoalDeadlineStats{}, err
+	}
+	if repoGoals, err = s.visibleGoals(repoGoals); err != nil {
+		return GoalDeadlineStats{}, err
+	}
+
+	var stats GoalDeadlineStats
+	for _, g := range ConvertRepoGoals(repoGoals, s.loc) {
+		switch g.State {
+		case GoalDoneOnTime:
+			stats.DoneOnTime++
+		case GoalDoneLate:
+			stats.DoneLate++
+		case GoalOverdue:
+			stats.Overdue++
+			stats.Open++
+		default:
+			stats.Open++
+		}
+	}
+	if done := stats.DoneOnTime + stats.DoneLate; done > 0 {
+		stats.OnTimeRate = float64(stats.DoneOnTime) / float64(done)
+	}
+	return stats, nil
+}
+
+// GetUpcomingDeadlines returns open goals ordered by due date, overdue ones first.
+func (s *DefaultAppService) GetUpcomingDeadlines() ([]Goal, error) {
+	repoGoals, err := repository.GetTodoGoals(s.db(), s.userID)
+	if err != nil {
+		log.Printf("GetUpcomingDeadlines exec error: %v", err)
+		return nil, err
+	}
+	if repoGoals, err = s.visibleGoals(repoGoals); err != nil {
+		return nil, err
+	}
+
+	goals := ConvertRepoGoals(repoGoals, s.loc)
+	sort.SliceStable(goals, func(i, j int) bool {
+		if goals[i].DueAt == nil {
+			return false
+		}
+		if goals[j].DueAt == nil {
+			return true
+		}
+		return goals[i].DueAt.Before(*goals[j].DueAt)
+	})
+	return goals, nil
+}
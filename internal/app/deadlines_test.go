@@ -0,0 +1,36 @@
+package app
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func TestGoalState_CountsDaysInLocation(t *testing.T) {
+	due := time.Date(2025, time.March, 10, 0, 0, 0, 0, time.UTC)
+	moscow := time.FixedZone("MSK", 3*60*60)
+	newYork := time.FixedZone("EST", -5*60*60)
+	at := func(day, hour int) time.Time { return time.Date(2025, time.March, day, hour, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name string
+		goal Goal
+		now  time.Time
+		loc  *time.Location
+		want string
+	}{
+		{"due day, UTC", Goal{Status: "todo", DueAt: &due}, at(10, 22), time.UTC, GoalDueSoon},
+		{"after local midnight", Goal{Status: "todo", DueAt: &due}, at(10, 22), moscow, GoalOverdue},
+		{"before local midnight", Goal{Status: "todo", DueAt: &due}, at(11, 2), newYork, GoalDueSoon},
+		{"after UTC midnight", Goal{Status: "todo", DueAt: &due}, at(11, 2), time.UTC, GoalOverdue},
+		{"done late locally", Goal{Status: "done", DueAt: &due, DoneAt: &sql.NullTime{Time: at(10, 22), Valid: true}}, at(12, 0), moscow, GoalDoneLate},
+		{"done on the local day", Goal{Status: "done", DueAt: &due, DoneAt: &sql.NullTime{Time: at(11, 2), Valid: true}}, at(12, 0), newYork, GoalDoneOnTime},
+		{"far ahead", Goal{Status: "todo", DueAt: &due}, at(1, 0), moscow, GoalUpcoming},
+		{"no due date", Goal{Status: "todo"}, at(1, 0), moscow, GoalUpcoming},
+	}
+	for _, tt := range tests {
+		if got := GoalState(tt.goal, tt.now, tt.loc); got != tt.want {
+			t.Errorf("%s: GoalState = %q; want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package app
+
+import (
+	"abtprj/internal/repository"
+	"database/sql"
+	"log"
+	"time"
+)
+
+// TaskEstimate compares a completed task's estimate with the time actually worked on it.
+type TaskEstimate struct {
+	Name     string
+	DoneAt   time.Time
+	Estimate time.Duration
+	Actual   time.Duration
+	Overrun  time.Duration // Actual - Estimate; negative when the task took less
+	Accuracy float64       // min(Estimate, Actual) / max(Estimate, Actual), 0–1
+}
+
+// EstimateWeek is the average accuracy of the tasks completed in one week.
+type EstimateWeek struct {
+	WeekStart time.Time
+	Tasks     int
+	Accuracy  float64
+}
+
+type EstimateReport struct {
+	Tasks    []TaskEstimate
+	Weeks    []EstimateWeek
+	Accuracy float64 // average over all estimated tasks
+}
+
+// GetEstimateReport builds the estimate-vs-actual report for tasks completed in [from, to).
+//
+// Work time is attributed to a task from whichever is later — its creation or the
+// completion of the previous task, which can be before from — until its own
+// completion. A task completed during a work session counts only the time in that
+// session; one without, such as an imported task, counts time in any session.
+func (s *DefaultAppService) GetEstimateReport(from, to time.Time) (EstimateReport, error) {
+	tasks, err := repository.GetDoneTasks(s.db(), s.userID, from, to)
+	if err != nil {
+		log.Printf("GetEstimateReport GetDoneTasks error: %v", err)
+		return EstimateReport{}, err
+	}
+	prevDone, err := repository.GetLastDoneBefore(s.db(), s.userID, from)
+	if err != nil {
+		log.Printf("GetEstimateReport GetLastDoneBefore error: %v", err)
+		return EstimateReport{}, err
+	}
+
+	sessionsFrom := from
+	for _, t := range tasks {
+		if t.CreatedAt.Before(sessionsFrom) {
+			sessionsFrom = t.CreatedAt
+		}
+	}
+	sessions, err := repository.GetSessionsOverlapping(s.db(), s.userID, sessionsFrom, to)
+	if err != nil {
+		log.Printf("GetEstimateReport GetSessionsOverlapping error: %v", err)
+		return EstimateReport{}, err
+	}
+
+	return buildEstimateReport(tasks, prevDone.Time, sessions, s.loc), nil
+}
+
+// buildEstimateReport works out the report of tasks, ordered by completion.
+// prevDone is the completion of the task before the first, zero if none.
+func buildEstimateReport(tasks []repository.Task, prevDone time.Time, sessions []repository.WorkSession, loc *time.Location) EstimateReport {
+	var report EstimateReport
+	var total float64
+
+	for _, t := range tasks {
+		if !t.DoneAt.Valid {
+			continue
+		}
+		windowStart := t.CreatedAt
+		if prevDone.After(windowStart) {
+			windowStart = prevDone
+		}
+		prevDone = t.DoneAt.Time
+
+		if !t.Estimate.Valid || t.Estimate.Int64 <= 0 {
+			continue
+		}
+
+		estimate := time.Duration(t.Estimate.Int64) * time.Minute
+		actual := workedBetween(taskSessions(sessions, t.SessionId), windowStart, t.DoneAt.Time)
+		te := TaskEstimate{
+			Name:     t.Name,
+			DoneAt:   t.DoneAt.Time.In(loc),
+			Estimate: estimate,
+			Actual:   actual.Truncate(time.Minute),
+			Overrun:  (actual - estimate).Truncate(time.Minute),
+			Accuracy: accuracy(estimate, actual),
+		}
+		report.Tasks = append(report.Tasks, te)
+		total += te.Accuracy
+
+		weekStart := startOfWeek(te.DoneAt)
+		if n := len(report.Weeks); n == 0 || !report.Weeks[n-1].WeekStart.Equal(weekStart) {
+			report.Weeks = append(report.Weeks, EstimateWeek{WeekStart: weekStart})
+		}
+		w := &report.Weeks[len(report.Weeks)-1]
+		w.Accuracy = (w.Accuracy*float64(w.Tasks) + te.Accuracy) / float64(w.Tasks+1)
+		w.Tasks++
+	}
+
+	if len(report.Tasks) > 0 {
+		report.Accuracy = total / float64(len(report.Tasks))
+	}
+	return report
+}
+
+// taskSessions narrows sessions to the one a task was completed in, if it
+// was completed in one.
+func taskSessions(sessions []repository.WorkSession, sessionID sql.NullInt64) []repository.WorkSession {
+	if !sessionID.Valid {
+		return sessions
+	}
+	for _, sess := range sessions {
+		if int64(sess.Id) == sessionID.Int64 {
+			return []repository.WorkSession{sess}
+		}
+	}
+	return nil
+}
+
+// workedBetween sums the parts of finished sessions that overlap [from, to).
+func workedBetween(sessions []repository.WorkSession, from, to time.Time) time.Duration {
+	var total time.Duration
+	for _, sess := range sessions {
+		end := to
+		if sess.EndTime.Valid && sess.EndTime.Time.Before(end) {
+			end = sess.EndTime.Time
+		}
+		start := sess.StartTime
+		if from.After(start) {
+			start = from
+		}
+		if end.After(start) {
+			total += end.Sub(start)
+		}
+	}
+	return total
+}
+
+func accuracy(estimate, actual time.Duration) float64 {
+	if estimate <= 0 || actual <= 0 {
+		return 0
+	}
+	if estimate < actual {
+		return float64(estimate) / float64(actual)
+	}
+	return float64(actual) / float64(estimate)
+}
+
+func startOfWeek(t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+}
+
+// Percent rounds a 0–1 ratio to a whole percentage.
+func Percent(ratio float64) int {
+	return int(ratio*100 + 0.5)
+}
+
+func (te TaskEstimate) AccuracyPercent() int  { return Percent(te.Accuracy) }
+func (w EstimateWeek) AccuracyPercent() int   { return Percent(w.Accuracy) }
+func (r EstimateReport) AccuracyPercent() int { return Percent(r.Accuracy) }
+func (te TaskEstimate) IsOverrun() bool       { return te.Overrun > 0 }
@@ -0,0 +1,53 @@
+package app
+
+import (
+	"abtprj/internal/repository"
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func TestBuildEstimateReport(t *testing.T) {
+	at := func(hour, min int) time.Time { return time.Date(2025, time.March, 3, hour, min, 0, 0, time.UTC) }
+	session := func(id int, from, to time.Time) repository.WorkSession {
+		return repository.WorkSession{Id: id, StartTime: from, EndTime: sql.NullTime{Time: to, Valid: true}}
+	}
+	task := func(name string, created, done time.Time, estimate int64, sessionID int64) repository.Task {
+		return repository.Task{
+			Name:      name,
+			CreatedAt: created,
+			DoneAt:    sql.NullTime{Time: done, Valid: true},
+			Estimate:  sql.NullInt64{Int64: estimate, Valid: true},
+			SessionId: sql.NullInt64{Int64: sessionID, Valid: sessionID != 0},
+		}
+	}
+	sessions := []repository.WorkSession{
+		session(1, at(8, 0), at(9, 0)),
+		session(2, at(10, 0), at(12, 0)),
+	}
+	tasks := []repository.Task{
+		// created at 7, but the task before it in the range was done at 10:30
+		task("linked", at(7, 0), at(11, 0), 30, 2),
+		// no session recorded: counts time in any session since 11:00
+		task("imported", at(7, 0), at(11, 30), 30, 0),
+	}
+
+	report := buildEstimateReport(tasks, at(10, 30), sessions, time.UTC)
+
+	if len(report.Tasks) != 2 {
+		t.Fatalf("got %d tasks; want 2", len(report.Tasks))
+	}
+	if got := report.Tasks[0].Actual; got != 30*time.Minute {
+		t.Errorf("linked task actual = %v; want 30m from the previous completion within session 2", got)
+	}
+	if got := report.Tasks[1].Actual; got != 30*time.Minute {
+		t.Errorf("imported task actual = %v; want 30m", got)
+	}
+
+	// without the completion before the range, the linked task counts all of
+	// session 2 up to 11:00 but none of session 1, which it was not done in
+	report = buildEstimateReport(tasks[:1], time.Time{}, sessions, time.UTC)
+	if got := report.Tasks[0].Actual; got != time.Hour {
+		t.Errorf("linked task actual = %v; want 1h", got)
+	}
+}
@@ -0,0 +1,227 @@
+package app
+
+import (
+	"abtprj/internal/repository"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+const (
+	ExportCSV  = "csv"
+	ExportJSON = "json"
+
+	ExportTasks    = "tasks"
+	ExportGoals    = "goals"
+	ExportSessions = "sessions"
+)
+
+type ExportFilter = repository.ExportFilter
+
+// exportColumns is the stable column order of every export. Only ever append to it;
+// spreadsheets and scripts address the columns by position.
+var exportColumns = map[string][]string{
+	ExportTasks:    {"id", "name", "description", "status", "estimate_minutes", "created_at", "done_at", "session_id"},
+	ExportGoals:    {"id", "name", "description", "status", "due_at", "done_at", "created_at"},
+	ExportSessions: {"id", "start_time", "end_time", "duration_minutes", "status", "created_at"},
+}
+
+var exportStatuses = map[string][]string{
+	ExportTasks:    {"todo", "done"},
+	ExportGoals:    {"todo", "done"},
+	ExportSessions: {"active", "finished"},
+}
+
+// ValidateExport reports whether kind, format and filter describe an export we can produce.
+func ValidateExport(kind, format string, filter ExportFilter) error {
+	if _, ok := exportColumns[kind]; !ok {
+		return fmt.Errorf("unknown export %q, expected tasks, goals or sessions", kind)
+	}
+	if format != ExportCSV && format != ExportJSON {
+		return fmt.Errorf("unknown format %q, expected csv or json", format)
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && filter.To.Before(filter.From) {
+		return errors.New("end of range is before its start")
+	}
+	if filter.Status == "" {
+		return nil
+	}
+	for _, s := range exportStatuses[kind] {
+		if s == filter.Status {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown status %q for %s", filter.Status, kind)
+}
+
+// ParseExportFilter builds a filter from YYYY-MM-DD dates, both inclusive and in UTC.
+// Empty values leave that part of the filter open.
+func ParseExportFilter(from, to, status string) (ExportFilter, error) {
+	filter := ExportFilter{Status: status}
+	if from != "" {
+		t, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			return filter, fmt.Errorf("invalid from date %q", from)
+		}
+		filter.From = t
+	}
+	if to != "" {
+		t, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			return filter, fmt.Errorf("invalid to date %q", to)
+		}
+		filter.To = t.Add(24 * time.Hour)
+	}
+	return filter, nil
+}
+
+// Export writes every matching row of kind to w as it is read from the database.
+func (s *DefaultAppService) Export(w io.Writer, kind, format string, filter ExportFilter) error {
+	if err := ValidateExport(kind, format, filter); err != nil {
+		return err
+	}
+
+	var rw exportWriter
+	if format == ExportCSV {
+		rw = &csvExportWriter{w: csv.NewWriter(w)}
+	} else {
+		rw = &jsonExportWriter{w: w}
+	}
+	columns := exportColumns[kind]
+	if err := rw.Begin(columns); err != nil {
+		return err
+	}
+
+	var err error
+	switch kind {
+	case ExportTasks:
+		err = repository.StreamTasks(s.db(), s.userID, filter, func(t repository.Task) error {
+			return rw.Row(columns, []any{t.Id, t.Name, t.Description, t.Status, t.Estimate, t.CreatedAt, t.DoneAt, t.SessionId})
+		})
+	case ExportGoals:
+		err = repository.StreamGoals(s.db(), s.userID, filter, func(g repository.Goal) error {
+			return rw.Row(columns, []any{g.Id, g.Name, g.Description, g.Status, g.DueAt, g.DoneAt, g.CreatedAt})
+		})
+	case ExportSessions:
+		err = repository.StreamWorkSessions(s.db(), s.userID, filter, func(ws repository.WorkSession) error {
+			var minutes sql.NullInt64
+			if ws.EndTime.Valid {
+				minutes = sql.NullInt64{Int64: int64(ws.EndTime.Time.Sub(ws.StartTime) / time.Minute), Valid: true}
+			}
+			return rw.Row(columns, []any{ws.Id, ws.StartTime, ws.EndTime, minutes, ws.Status, ws.CreatedAt})
+		})
+	}
+	if err != nil {
+		return err
+	}
+	return rw.End()
+}
+
+type exportWriter interface {
+	Begin(columns []string) error
+	Row(columns []string, values []any) error
+	End() error
+}
+
+type csvExportWriter struct {
+	w      *csv.Writer
+	record []string
+}
+
+func (c *csvExportWriter) Begin(columns []string) error {
+	c.record = make([]string, len(columns))
+	return c.w.Write(columns)
+}
+
+func (c *csvExportWriter) Row(_ []string, values []any) error {
+	for i, v := range values {
+		c.record[i] = exportText(v)
+	}
+	return c.w.Write(c.record)
+}
+
+func (c *csvExportWriter) End() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// jsonExportWriter streams a JSON array of objects, one element per row.
+type jsonExportWriter struct {
+	w    io.Writer
+	rows int
+}
+
+func (j *jsonExportWriter) Begin(_ []string) error {
+	_, err := io.WriteString(j.w, "[")
+	return err
+}
+
+func (j *jsonExportWriter) Row(columns []string, values []any) error {
+	buf := []byte("\n{")
+	if j.rows > 0 {
+		buf = []byte(",\n{")
+	}
+	for i, col := range columns {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		key, _ := json.Marshal(col)
+		val, err := json.Marshal(exportJSONValue(values[i]))
+		if err != nil {
+			return err
+		}
+		buf = append(buf, key...)
+		buf = append(buf, ':')
+		buf = append(buf, val...)
+	}
+	buf = append(buf, '}')
+	j.rows++
+	_, err := j.w.Write(buf)
+	return err
+}
+
+func (j *jsonExportWriter) End() error {
+	_, err := io.WriteString(j.w, "\n]\n")
+	return err
+}
+
+// exportJSONValue turns database values into plain JSON values; NULLs become null
+// and timestamps RFC 3339 strings in UTC.
+func exportJSONValue(v any) any {
+	switch x := v.(type) {
+	case sql.NullTime:
+		if !x.Valid {
+			return nil
+		}
+		return x.Time.UTC().Format(time.RFC3339)
+	case time.Time:
+		return x.UTC().Format(time.RFC3339)
+	case sql.NullInt64:
+		if !x.Valid {
+			return nil
+		}
+		return x.Int64
+	default:
+		return v
+	}
+}
+
+func exportText(v any) string {
+	switch x := exportJSONValue(v).(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case int:
+		return strconv.Itoa(x)
+	case int64:
+		return strconv.FormatInt(x, 10)
+	default:
+		return fmt.Sprint(x)
+	}
+}
@@ -0,0 +1,99 @@
+package app
+
+import (
+	"abtprj/internal/repository"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"time"
+)
+
+const (
+	feedTokenKey = "feeds.token"
+	apiTokenKey  = "api.token"
+)
+
+// GetFeedToken returns the secret that unlocks the calendar feeds, creating it on first use.
+func (s *DefaultAppService) GetFeedToken() (string, error) {
+	return s.getToken(feedTokenKey)
+}
+
+// RegenerateFeedToken replaces the feed secret, invalidating every subscribed URL.
+func (s *DefaultAppService) RegenerateFeedToken() (string, error) {
+	return s.replaceToken(feedTokenKey)
+}
+
+// GetAPIToken returns the bearer token the command-line client uses, creating it on first use.
+func (s *DefaultAppService) GetAPIToken() (string, error) {
+	return s.getToken(apiTokenKey)
+}
+
+// RegenerateAPIToken replaces the API token, logging out every configured client.
+func (s *DefaultAppService) RegenerateAPIToken() (string, error) {
+	return s.replaceToken(apiTokenKey)
+}
+
+func (s *DefaultAppService) getToken(key string) (string, error) {
+	token, ok, err := repository.GetSetting(s.db(), s.userID, key)
+	if err != nil {
+		log.Printf("getToken %s exec error: %v", key, err)
+		return "", err
+	}
+	if ok && token != "" {
+		return token, nil
+	}
+	return s.regenerateToken(key)
+}
+
+func (s *DefaultAppService) regenerateToken(key string) (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+	if err := repository.SetSetting(s.db(), s.userID, key, token); err != nil {
+		log.Printf("regenerateToken %s exec error: %v", key, err)
+		return "", err
+	}
+	return token, nil
+}
+
+// replaceToken is regenerateToken on request, recorded in the audit log.
+func (s *DefaultAppService) replaceToken(key string) (string, error) {
+	var token string
+	err := s.inTx(func(s *DefaultAppService) error {
+		var err error
+		if token, err = s.regenerateToken(key); err != nil {
+			return err
+		}
+		_, err = s.audit("regenerate", AuditToken, key, nil, nil)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// GetWorkSessionsBetween returns the sessions started in [from, to), in the service's time zone.
+func (s *DefaultAppService) GetWorkSessionsBetween(from, to time.Time) ([]WorkSession, error) {
+	repoSessions, err := repository.GetWorkingSessions(s.db(), s.userID, from.UTC(), to.UTC())
+	if err != nil {
+		log.Printf("GetWorkSessionsBetween exec error: %v", err)
+		return nil, err
+	}
+	sessions := ConvertRepoSessions(repoSessions)
+	for i := range sessions {
+		sessions[i].StartTime = sessions[i].StartTime.In(s.loc)
+		if sessions[i].EndTime != nil {
+			t := sessions[i].EndTime.In(s.loc)
+			sessions[i].EndTime = &t
+		}
+	}
+	return sessions, nil
+}
+
+// Location is the time zone days and sessions are reported in.
+func (s *DefaultAppService) Location() *time.Location {
+	return s.loc
+}
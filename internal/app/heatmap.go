@@ -0,0 +1,194 @@
+package app
+
+import (
+	"abtprj/internal/repository"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	ScaleFixed    = "fixed"
+	ScaleQuantile = "quantile"
+
+	taskScaleKey    = "heatmap.tasks"
+	sessionScaleKey = "heatmap.sessions"
+)
+
+// HeatmapScale decides how a day's value maps to a shade level 0–4.
+// In fixed mode a day gets one level for every threshold its value exceeds.
+// In quantile mode any non-zero day is level 1 and moves up a level for every
+// quartile of the year's non-zero values it exceeds; Thresholds is ignored.
+type HeatmapScale struct {
+	Mode       string
+	Thresholds []float64 // 4 ascending cutoffs; tasks count, sessions hours
+}
+
+type HeatmapSettings struct {
+	Tasks    HeatmapScale
+	Sessions HeatmapScale
+}
+
+func DefaultHeatmapSettings() HeatmapSettings {
+	return HeatmapSettings{
+		Tasks:    HeatmapScale{Mode: ScaleFixed, Thresholds: []float64{0, 1, 2, 3}},
+		Sessions: HeatmapScale{Mode: ScaleFixed, Thresholds: []float64{0, 2, 4, 8}},
+	}
+}
+
+func (sc HeatmapScale) Validate() error {
+	switch sc.Mode {
+	case ScaleQuantile:
+		return nil
+	case ScaleFixed:
+	default:
+		return fmt.Errorf("unknown heatmap mode %q", sc.Mode)
+	}
+	if len(sc.Thresholds) != 4 {
+		return errors.New("exactly 4 thresholds are required")
+	}
+	for i := 1; i < len(sc.Thresholds); i++ {
+		if sc.Thresholds[i] <= sc.Thresholds[i-1] {
+			return errors.New("thresholds must be strictly ascending")
+		}
+	}
+	if sc.Thresholds[0] < 0 {
+		return errors.New("thresholds must not be negative")
+	}
+	return nil
+}
+
+// ThresholdsString renders thresholds the way the settings form accepts them.
+func (sc HeatmapScale) ThresholdsString() string {
+	parts := make([]string, len(sc.Thresholds))
+	for i, t := range sc.Thresholds {
+		parts[i] = strconv.FormatFloat(t, 'f', -1, 64)
+	}
+	return strings.Join(parts, ",")
+}
+
+// ParseThresholds parses a comma separated list such as "0,2,4,8".
+func ParseThresholds(s string) ([]float64, error) {
+	var out []float64
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid threshold %q", part)
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+// levels returns the shade level for each value.
+func (sc HeatmapScale) levels(values []float64) []int {
+	cutoffs := sc.Thresholds
+	if sc.Mode == ScaleQuantile {
+		cutoffs = quantileCutoffs(values)
+	}
+
+	out := make([]int, len(values))
+	for i, v := range values {
+		if v <= 0 {
+			continue
+		}
+		lvl := 0
+		for _, c := range cutoffs {
+			if v > c {
+				lvl++
+			}
+		}
+		if sc.Mode == ScaleQuantile {
+			lvl++
+		}
+		if lvl > 4 {
+			lvl = 4
+		}
+		out[i] = lvl
+	}
+	return out
+}
+
+// quantileCutoffs returns the 25th, 50th and 75th percentiles of the non-zero values.
+func quantileCutoffs(values []float64) []float64 {
+	var nonZero []float64
+	for _, v := range values {
+		if v > 0 {
+			nonZero = append(nonZero, v)
+		}
+	}
+	if len(nonZero) == 0 {
+		return nil
+	}
+	sort.Float64s(nonZero)
+
+	cutoffs := make([]float64, 0, 3)
+	for _, q := range []float64{0.25, 0.5, 0.75} {
+		cutoffs = append(cutoffs, nonZero[int(q*float64(len(nonZero)-1))])
+	}
+	return cutoffs
+}
+
+func (s *DefaultAppService) GetHeatmapSettings() (HeatmapSettings, error) {
+	settings := DefaultHeatmapSettings()
+	if err := s.loadScale(taskScaleKey, &settings.Tasks); err != nil {
+		return settings, err
+	}
+	if err := s.loadScale(sessionScaleKey, &settings.Sessions); err != nil {
+		return settings, err
+	}
+	return settings, nil
+}
+
+func (s *DefaultAppService) UpdateHeatmapSettings(settings HeatmapSettings) error {
+	if err := settings.Tasks.Validate(); err != nil {
+		return fmt.Errorf("tasks heatmap: %w", err)
+	}
+	if err := settings.Sessions.Validate(); err != nil {
+		return fmt.Errorf("sessions heatmap: %w", err)
+	}
+	return s.inTx(func(s *DefaultAppService) error {
+		before, err := s.GetHeatmapSettings()
+		if err != nil {
+			return err
+		}
+		if err := s.saveScale(taskScaleKey, settings.Tasks); err != nil {
+			return err
+		}
+		if err := s.saveScale(sessionScaleKey, settings.Sessions); err != nil {
+			return err
+		}
+		_, err = s.audit("update", AuditSetting, "heatmap", before, settings)
+		return err
+	})
+}
+
+func (s *DefaultAppService) loadScale(key string, dst *HeatmapScale) error {
+	raw, ok, err := repository.GetSetting(s.db(), s.userID, key)
+	if err != nil || !ok {
+		return err
+	}
+	var sc HeatmapScale
+	if err := json.Unmarshal([]byte(raw), &sc); err != nil {
+		return err
+	}
+	if sc.Validate() == nil {
+		*dst = sc
+	}
+	return nil
+}
+
+func (s *DefaultAppService) saveScale(key string, sc HeatmapScale) error {
+	raw, err := json.Marshal(sc)
+	if err != nil {
+		return err
+	}
+	return repository.SetSetting(s.db(), s.userID, key, string(raw))
+}
@@ -0,0 +1,52 @@
+package app
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestQuantileCutoffs(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		want   []float64
+	}{
+		{"empty", nil, nil},
+		{"all zero", []float64{0, 0, 0}, nil},
+		{"one day", []float64{0, 5, 0}, []float64{5, 5, 5}},
+		{"all equal", []float64{3, 3, 3, 3}, []float64{3, 3, 3}},
+		{"spread", []float64{5, 1, 4, 2, 3}, []float64{2, 3, 4}},
+		{"skewed", []float64{1, 1, 1, 1, 1, 1, 1, 100}, []float64{1, 1, 1}},
+		{"zeros ignored", []float64{0, 0, 0, 0, 1, 2, 3, 4, 5}, []float64{2, 3, 4}},
+	}
+	for _, tt := range tests {
+		if got := quantileCutoffs(tt.values); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: quantileCutoffs(%v) = %v; want %v", tt.name, tt.values, got, tt.want)
+		}
+	}
+}
+
+func TestHeatmapScaleLevels(t *testing.T) {
+	quantile := HeatmapScale{Mode: ScaleQuantile}
+	fixed := HeatmapScale{Mode: ScaleFixed, Thresholds: []float64{0, 1, 2, 3}}
+	tests := []struct {
+		name   string
+		scale  HeatmapScale
+		values []float64
+		want   []int
+	}{
+		{"quantile empty", quantile, nil, []int{}},
+		{"quantile all zero", quantile, []float64{0, 0}, []int{0, 0}},
+		{"quantile all equal", quantile, []float64{3, 3, 3, 3}, []int{1, 1, 1, 1}},
+		{"quantile spread", quantile, []float64{0, 1, 2, 3, 4, 5}, []int{0, 1, 1, 2, 3, 4}},
+		{"quantile skewed", quantile, []float64{1, 1, 1, 1, 1, 1, 1, 100}, []int{1, 1, 1, 1, 1, 1, 1, 4}},
+		{"fixed", fixed, []float64{0, 0.5, 1, 2, 5}, []int{0, 1, 1, 2, 4}},
+		{"fixed all equal", fixed, []float64{2, 2}, []int{2, 2}},
+		{"fixed negative", fixed, []float64{-1}, []int{0}},
+	}
+	for _, tt := range tests {
+		if got := tt.scale.levels(tt.values); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: levels(%v) = %v; want %v", tt.name, tt.values, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package app
+
+import (
+	"abtprj/internal/importer"
+	"abtprj/internal/repository"
+	"database/sql"
+	"log"
+	"strings"
+	"time"
+)
+
+type TaskImportItem struct {
+	importer.Task
+	Duplicate bool // a task with the same name exists already or earlier in the file
+}
+
+type TaskImportPlan struct {
+	Items      []TaskImportItem
+	New        int
+	Done       int // how many of the new tasks arrive completed
+	Duplicates int
+}
+
+// PlanTaskImport works out what ImportTasks would do without changing anything.
+func (s *DefaultAppService) PlanTaskImport(tasks []importer.Task) (TaskImportPlan, error) {
+	names, err := repository.GetTaskNames(s.db(), s.userID)
+	if err != nil {
+		log.Printf("PlanTaskImport GetTaskNames error: %v", err)
+		return TaskImportPlan{}, err
+	}
+	return planTaskImport(tasks, names), nil
+}
+
+// ImportTasks creates every task that is not a duplicate. Completed tasks keep their
+// done date, or fall back to their creation date when the source has none. The
+// import is one transaction: a task that cannot be created leaves none behind.
+func (s *DefaultAppService) ImportTasks(tasks []importer.Task) (TaskImportPlan, error) {
+	var plan TaskImportPlan
+	err := s.inTx(func(s *DefaultAppService) error {
+		var err error
+		if plan, err = s.PlanTaskImport(tasks); err != nil {
+			return err
+		}
+
+		for _, item := range plan.Items {
+			if item.Duplicate {
+				continue
+			}
+			status := "todo"
+			var doneAt sql.NullTime
+			if item.Done {
+				status = "done"
+				switch {
+				case item.DoneAt != nil:
+					doneAt = sql.NullTime{Time: *item.DoneAt, Valid: true}
+				case item.CreatedAt != nil:
+					doneAt = sql.NullTime{Time: *item.CreatedAt, Valid: true}
+				default:
+					doneAt = sql.NullTime{Time: time.Now(), Valid: true}
+				}
+			}
+			var createdAt time.Time
+			if item.CreatedAt != nil {
+				createdAt = *item.CreatedAt
+			}
+			if err := repository.ImportTask(s.db(), s.userID, item.Name, item.Description, status, doneAt, createdAt, sql.NullInt64{}); err != nil {
+				log.Printf("ImportTasks ImportTask error on line %d: %v", item.Line, err)
+				return err
+			}
+		}
+		_, err = s.audit("import", AuditImport, "tasks", nil, map[string]int{"new": plan.New, "done": plan.Done, "duplicates": plan.Duplicates})
+		return err
+	})
+	return plan, err
+}
+
+func planTaskImport(tasks []importer.Task, existing []string) TaskImportPlan {
+	seen := make(map[string]bool, len(existing)+len(tasks))
+	for _, name := range existing {
+		seen[normalizeTaskName(name)] = true
+	}
+
+	var plan TaskImportPlan
+	for _, t := range tasks {
+		key := normalizeTaskName(t.Name)
+		item := TaskImportItem{Task: t, Duplicate: seen[key]}
+		seen[key] = true
+
+		switch {
+		case item.Duplicate:
+			plan.Duplicates++
+		case t.Done:
+			plan.New++
+			plan.Done++
+		default:
+			plan.New++
+		}
+		plan.Items = append(plan.Items, item)
+	}
+	return plan
+}
+
+func normalizeTaskName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
@@ -0,0 +1,281 @@
+package app
+
+import (
+	"abtprj/internal/importer"
+	"abtprj/internal/repository"
+	"database/sql"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"time"
+)
+
+const (
+	OverlapSkip  = "skip"  // drop entries that overlap a recorded session
+	OverlapMerge = "merge" // widen the recorded session to cover the entry
+)
+
+type TimeImportRow struct {
+	importer.TimeEntry
+	Outcome string // imported, merged or skipped
+}
+
+type TimeImportSummary struct {
+	Rows          []TimeImportRow
+	Invalid       []importer.RowError
+	Imported      int
+	Merged        int
+	Skipped       int // overlapping a recorded session
+	SkippedInFile int // overlapping an earlier entry of the file
+	TasksCreated  int
+	Worked        time.Duration // time added by imported rows
+}
+
+// sessionSpan is a work session as the import sees it, recorded or about to be.
+type sessionSpan struct {
+	id         int // 0 until inserted
+	start, end time.Time
+	running    bool
+	existing   bool
+	dirty      bool
+}
+
+func (sp *sessionSpan) overlaps(start, end time.Time) bool {
+	return sp.start.Before(end) && start.Before(sp.end)
+}
+
+// ImportTimeEntries turns time entries from another tracker into work sessions.
+// Every distinct description is matched to a task of the same name or becomes a new,
+// completed task. With dryRun set nothing is written and the summary shows what would
+// happen; otherwise the import is one transaction.
+func (s *DefaultAppService) ImportTimeEntries(entries []importer.TimeEntry, overlap string, dryRun bool) (TimeImportSummary, error) {
+	if overlap != OverlapSkip && overlap != OverlapMerge {
+		return TimeImportSummary{}, fmt.Errorf("unknown overlap handling %q", overlap)
+	}
+	if dryRun {
+		return s.importTimeEntries(entries, overlap, true)
+	}
+	var summary TimeImportSummary
+	err := s.inTx(func(s *DefaultAppService) error {
+		var err error
+		summary, err = s.importTimeEntries(entries, overlap, false)
+		return err
+	})
+	return summary, err
+}
+
+func (s *DefaultAppService) importTimeEntries(entries []importer.TimeEntry, overlap string, dryRun bool) (TimeImportSummary, error) {
+	var summary TimeImportSummary
+	if len(entries) == 0 {
+		return summary, nil
+	}
+
+	sorted := make([]importer.TimeEntry, len(entries))
+	copy(sorted, entries)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Start.Before(sorted[j].Start) })
+
+	minStart, maxEnd := sorted[0].Start, sorted[0].End
+	for _, e := range sorted {
+		if e.End.After(maxEnd) {
+			maxEnd = e.End
+		}
+	}
+	recorded, err := repository.GetSessionsOverlapping(s.db(), s.userID, minStart.UTC(), maxEnd.UTC())
+	if err != nil {
+		log.Printf("ImportTimeEntries GetSessionsOverlapping error: %v", err)
+		return summary, err
+	}
+
+	var spans []*sessionSpan
+	for _, ws := range recorded {
+		sp := &sessionSpan{id: ws.Id, start: ws.StartTime, end: ws.EndTime.Time, existing: true}
+		if !ws.EndTime.Valid {
+			sp.end, sp.running = time.Now(), true
+		}
+		spans = append(spans, sp)
+	}
+	spans, entrySpan := placeEntries(sorted, spans, overlap, &summary)
+
+	if !dryRun {
+		for _, sp := range spans {
+			if !sp.dirty {
+				continue
+			}
+			if sp.existing {
+				err = repository.UpdateWorkSessionTimes(s.db(), s.userID, sp.id, sp.start.UTC(), sp.end.UTC())
+			} else {
+				sp.id, err = repository.InsertWorkSession(s.db(), s.userID, sp.start.UTC(), sp.end.UTC())
+			}
+			if err != nil {
+				log.Printf("ImportTimeEntries session write error: %v", err)
+				return summary, err
+			}
+		}
+	}
+
+	if err := s.importEntryTasks(sorted, entrySpan, &summary, dryRun); err != nil {
+		return summary, err
+	}
+	if !dryRun {
+		_, err := s.audit("import", AuditImport, "time-entries", nil, map[string]int{
+			"imported":        summary.Imported,
+			"merged":          summary.Merged,
+			"skipped":         summary.Skipped,
+			"skipped_in_file": summary.SkippedInFile,
+			"tasks_created":   summary.TasksCreated,
+		})
+		if err != nil {
+			return summary, err
+		}
+	}
+	return summary, nil
+}
+
+// placeEntries decides what becomes of each of the sorted entries, given the
+// recorded sessions in spans, and records it in summary. It returns spans with
+// the sessions to create added and those to widen marked dirty, and the span
+// each imported or merged entry ended up in, by index.
+//
+// Merging widens a session, which can make it reach further sessions; those
+// are taken in too until none is left, so no two sessions overlap afterwards.
+// Two recorded sessions are never joined, and the running one is never touched.
+func placeEntries(entries []importer.TimeEntry, spans []*sessionSpan, overlap string, summary *TimeImportSummary) ([]*sessionSpan, map[int]*sessionSpan) {
+	entrySpan := make(map[int]*sessionSpan, len(entries))
+	for i, e := range entries {
+		row := TimeImportRow{TimeEntry: e}
+
+		start, end := e.Start, e.End
+		var group []*sessionSpan
+		inGroup := make(map[*sessionSpan]bool)
+		for grown := true; grown; {
+			grown = false
+			for _, sp := range spans {
+				if inGroup[sp] || !sp.overlaps(start, end) {
+					continue
+				}
+				group = append(group, sp)
+				inGroup[sp] = true
+				if sp.start.Before(start) {
+					start = sp.start
+				}
+				if sp.end.After(end) {
+					end = sp.end
+				}
+				grown = true
+			}
+		}
+
+		var target *sessionSpan
+		recordedHits, running := 0, false
+		for _, sp := range group {
+			if sp.existing {
+				recordedHits++
+				running = running || sp.running
+				target = sp
+			}
+		}
+
+		switch {
+		case len(group) == 0:
+			sp := &sessionSpan{start: e.Start, end: e.End, dirty: true}
+			spans = append(spans, sp)
+			entrySpan[i] = sp
+			row.Outcome = "imported"
+			summary.Imported++
+			summary.Worked += e.End.Sub(e.Start)
+		case recordedHits == 0 && overlap == OverlapSkip:
+			row.Outcome = "skipped: overlaps an earlier entry of the file"
+			summary.SkippedInFile++
+		case overlap == OverlapSkip:
+			row.Outcome = "skipped: overlaps a recorded session"
+			summary.Skipped++
+		case running:
+			row.Outcome = "skipped: overlaps the running session"
+			summary.Skipped++
+		case recordedHits > 1:
+			row.Outcome = "skipped: would join recorded sessions"
+			summary.Skipped++
+		default:
+			if target == nil {
+				target = group[0]
+			}
+			if !target.start.Equal(start) || !target.end.Equal(end) {
+				target.start, target.end, target.dirty = start, end, true
+			}
+			spans = slices.DeleteFunc(spans, func(sp *sessionSpan) bool { return inGroup[sp] && sp != target })
+			for j, sp := range entrySpan {
+				if inGroup[sp] {
+					entrySpan[j] = target
+				}
+			}
+			entrySpan[i] = target
+			row.Outcome = "merged"
+			summary.Merged++
+		}
+		summary.Rows = append(summary.Rows, row)
+	}
+	return spans, entrySpan
+}
+
+// importEntryTasks creates a done task for every distinct entry description
+// that no task of the account has yet. Existing tasks are left as they are.
+func (s *DefaultAppService) importEntryTasks(entries []importer.TimeEntry, entrySpan map[int]*sessionSpan, summary *TimeImportSummary, dryRun bool) error {
+	names, err := repository.GetTaskNames(s.db(), s.userID)
+	if err != nil {
+		log.Printf("ImportTimeEntries GetTaskNames error: %v", err)
+		return err
+	}
+	existing := make(map[string]bool, len(names))
+	for _, n := range names {
+		existing[normalizeTaskName(n)] = true
+	}
+
+	type newTask struct {
+		name, project string
+		first, last   time.Time
+		span          *sessionSpan
+	}
+	var order []string
+	created := make(map[string]*newTask)
+
+	for i, e := range entries {
+		sp, ok := entrySpan[i]
+		if !ok || e.Description == "" {
+			continue
+		}
+		key := normalizeTaskName(e.Description)
+		if existing[key] {
+			continue
+		}
+		nt, ok := created[key]
+		if !ok {
+			nt = &newTask{name: e.Description, project: e.Project, first: e.Start}
+			created[key] = nt
+			order = append(order, key)
+		}
+		if e.End.After(nt.last) {
+			nt.last, nt.span = e.End, sp
+		}
+	}
+	summary.TasksCreated = len(created)
+
+	if dryRun {
+		return nil
+	}
+	for _, key := range order {
+		nt := created[key]
+		description := "Imported time entry"
+		if nt.project != "" {
+			description += ", project " + nt.project
+		}
+		err := repository.ImportTask(s.db(), s.userID, nt.name, description, "done",
+			sql.NullTime{Time: nt.last.UTC(), Valid: true}, nt.first.UTC(),
+			sql.NullInt64{Int64: int64(nt.span.id), Valid: nt.span.id != 0})
+		if err != nil {
+			log.Printf("ImportTimeEntries ImportTask error: %v", err)
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package app
+
+import (
+	"abtprj/internal/importer"
+	"testing"
+	"time"
+)
+
+func TestPlaceEntries(t *testing.T) {
+	at := func(hour, min int) time.Time { return time.Date(2024, time.March, 1, hour, min, 0, 0, time.UTC) }
+	entry := func(from, to time.Time) importer.TimeEntry { return importer.TimeEntry{Start: from, End: to} }
+	recorded := func() []*sessionSpan {
+		return []*sessionSpan{
+			{id: 1, start: at(9, 0), end: at(10, 0), existing: true},
+			{id: 2, start: at(12, 0), end: at(13, 0), existing: true},
+			{id: 3, start: at(17, 0), end: at(18, 0), existing: true, running: true},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		entries  []importer.TimeEntry
+		overlap  string
+		outcomes []string
+		spans    int // after the import
+	}{
+		{
+			"skip recorded and earlier entries apart",
+			[]importer.TimeEntry{entry(at(9, 30), at(10, 30)), entry(at(14, 0), at(15, 0)), entry(at(14, 30), at(15, 30))},
+			OverlapSkip,
+			[]string{"skipped: overlaps a recorded session", "imported", "skipped: overlaps an earlier entry of the file"},
+			4,
+		},
+		{
+			// the second entry widens the first one's session into the third's
+			"merge until nothing overlaps",
+			[]importer.TimeEntry{entry(at(14, 0), at(14, 30)), entry(at(14, 15), at(15, 15)), entry(at(15, 0), at(16, 0))},
+			OverlapMerge,
+			[]string{"imported", "merged", "merged"},
+			4,
+		},
+		{
+			"merge a chain of entries into a recorded session",
+			[]importer.TimeEntry{entry(at(10, 30), at(11, 30)), entry(at(9, 30), at(10, 45))},
+			OverlapMerge,
+			[]string{"imported", "merged"},
+			3,
+		},
+		{
+			"never join recorded sessions",
+			[]importer.TimeEntry{entry(at(9, 30), at(12, 30))},
+			OverlapMerge,
+			[]string{"skipped: would join recorded sessions"},
+			3,
+		},
+		{
+			"never touch the running session",
+			[]importer.TimeEntry{entry(at(16, 30), at(17, 30))},
+			OverlapMerge,
+			[]string{"skipped: overlaps the running session"},
+			3,
+		},
+	}
+	for _, tt := range tests {
+		var summary TimeImportSummary
+		spans, entrySpan := placeEntries(tt.entries, recorded(), tt.overlap, &summary)
+		if len(summary.Rows) != len(tt.outcomes) {
+			t.Fatalf("%s: got %d rows; want %d", tt.name, len(summary.Rows), len(tt.outcomes))
+		}
+		for i, row := range summary.Rows {
+			if row.Outcome != tt.outcomes[i] {
+				t.Errorf("%s: row %d outcome = %q; want %q", tt.name, i, row.Outcome, tt.outcomes[i])
+			}
+		}
+		if len(spans) != tt.spans {
+			t.Errorf("%s: got %d sessions; want %d", tt.name, len(spans), tt.spans)
+		}
+		for i, a := range spans {
+			for _, b := range spans[i+1:] {
+				if a.overlaps(b.start, b.end) {
+					t.Errorf("%s: sessions %v-%v and %v-%v overlap", tt.name, a.start, a.end, b.start, b.end)
+				}
+			}
+		}
+		for i, sp := range entrySpan {
+			e := tt.entries[i]
+			if e.Start.Before(sp.start) || e.End.After(sp.end) {
+				t.Errorf("%s: entry %d is not inside its session %v-%v", tt.name, i, sp.start, sp.end)
+			}
+		}
+	}
+}
+
+func TestPlaceEntries_MergeWidensRecordedSession(t *testing.T) {
+	at := func(hour, min int) time.Time { return time.Date(2024, time.March, 1, hour, min, 0, 0, time.UTC) }
+	spans := []*sessionSpan{{id: 1, start: at(9, 0), end: at(10, 0), existing: true}}
+	entries := []importer.TimeEntry{
+		{Start: at(10, 30), End: at(11, 0)},
+		{Start: at(9, 45), End: at(10, 45)},
+	}
+
+	var summary TimeImportSummary
+	spans, entrySpan := placeEntries(entries, spans, OverlapMerge, &summary)
+
+	if len(spans) != 1 {
+		t.Fatalf("got %d sessions; want the recorded one only", len(spans))
+	}
+	sp := spans[0]
+	if sp.id != 1 || !sp.dirty || !sp.start.Equal(at(9, 0)) || !sp.end.Equal(at(11, 0)) {
+		t.Errorf("session = %+v; want session 1 widened to 09:00-11:00", *sp)
+	}
+	if entrySpan[0] != sp || entrySpan[1] != sp {
+		t.Error("both entries should end up in the recorded session")
+	}
+	if summary.Imported != 1 || summary.Merged != 1 {
+		t.Errorf("imported %d, merged %d; want 1 and 1", summary.Imported, summary.Merged)
+	}
+}
@@ -0,0 +1,190 @@
+package app
+
+import (
+	"abtprj/internal/repository"
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+)
+
+// MaxJournalDays caps a journal export; every day costs two queries.
+const MaxJournalDays = 366
+
+// ParseJournalRange reads inclusive YYYY-MM-DD dates in loc. An empty from starts
+// the current week on Monday, an empty to ends today.
+func ParseJournalRange(from, to string, loc *time.Location, now time.Time) (time.Time, time.Time, error) {
+	today := now.In(loc)
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, loc)
+
+	start, end := startOfWeek(today), today
+	var err error
+	if from != "" {
+		if start, err = time.ParseInLocation("2006-01-02", from, loc); err != nil {
+			return start, end, fmt.Errorf("invalid from date %q", from)
+		}
+	}
+	if to != "" {
+		if end, err = time.ParseInLocation("2006-01-02", to, loc); err != nil {
+			return start, end, fmt.Errorf("invalid to date %q", to)
+		}
+	}
+	if end.Before(start) {
+		return start, end, errors.New("end of range is before its start")
+	}
+	if days := int(end.Sub(start).Hours()/24) + 1; days > MaxJournalDays {
+		return start, end, fmt.Errorf("range spans %d days, at most %d are allowed", days, MaxJournalDays)
+	}
+	return start, end, nil
+}
+
+// WriteJournal renders the days from..to (inclusive, both midnights in the service
+// location) as Markdown: one section per day with its sessions, completed tasks,
+// goals completed or due, and totals.
+func (s *DefaultAppService) WriteJournal(w io.Writer, from, to time.Time) error {
+	from, to = from.In(s.loc), to.In(s.loc)
+	after := to.AddDate(0, 0, 1)
+
+	done, err := repository.GetGoalsDoneBetween(s.db(), s.userID, from.UTC(), after.UTC())
+	if err != nil {
+		log.Printf("WriteJournal GetGoalsDoneBetween error: %v", err)
+		return err
+	}
+	// due dates are stored as UTC midnight of the chosen day
+	due, err := repository.GetGoalsDueBetween(s.db(), s.userID,
+		time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC),
+		time.Date(after.Year(), after.Month(), after.Day(), 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		log.Printf("WriteJournal GetGoalsDueBetween error: %v", err)
+		return err
+	}
+	goalsDone := make(map[string][]repository.Goal)
+	for _, g := range done {
+		day := g.DoneAt.Time.In(s.loc).Format("2006-01-02")
+		goalsDone[day] = append(goalsDone[day], g)
+	}
+	goalsDue := make(map[string][]repository.Goal)
+	for _, g := range due {
+		day := g.DueAt.Time.UTC().Format("2006-01-02")
+		goalsDue[day] = append(goalsDue[day], g)
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# Journal %s – %s\n", from.Format("2006-01-02"), to.Format("2006-01-02"))
+
+	now := time.Now()
+	var total time.Duration
+	var totalTasks, totalGoals int
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		date := day.Format("2006-01-02")
+		tasks, err := s.GetTasksForDate(date)
+		if err != nil {
+			return err
+		}
+		sessions, err := s.GetWorkSessionsForDate(date)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(bw, "\n## %s\n\n", day.Format("Monday, 2 January 2006"))
+		next := day.AddDate(0, 0, 1)
+		var lines []string
+		var worked time.Duration
+		for _, ws := range sessions {
+			if !ws.StartTime.Before(next) {
+				continue // a running session started on a later day
+			}
+			end, running := now, ws.EndTime == nil
+			if !running {
+				end = *ws.EndTime
+			}
+			d := end.Sub(ws.StartTime).Truncate(time.Minute)
+			worked += d
+			if running {
+				lines = append(lines, fmt.Sprintf("- %s–now (%s so far)", ws.StartTime.Format("15:04"), journalDuration(d)))
+			} else {
+				lines = append(lines, fmt.Sprintf("- %s–%s (%s)", ws.StartTime.Format("15:04"), end.In(s.loc).Format("15:04"), journalDuration(d)))
+			}
+		}
+		dayDone, dayDue := goalsDone[date], goalsDue[date]
+
+		if len(lines) == 0 && len(tasks) == 0 && len(dayDone) == 0 && len(dayDue) == 0 {
+			bw.WriteString("_Nothing recorded._\n")
+			continue
+		}
+		fmt.Fprintf(bw, "**Worked %s** in %d sessions · %d tasks done · %d goals completed\n",
+			journalDuration(worked), len(lines), len(tasks), len(dayDone))
+
+		if len(lines) > 0 {
+			bw.WriteString("\n### Sessions\n\n" + strings.Join(lines, "\n") + "\n")
+		}
+		if len(tasks) > 0 {
+			bw.WriteString("\n### Tasks\n\n")
+			for _, t := range tasks {
+				at := ""
+				if t.DoneAt != nil {
+					at = " (" + t.DoneAt.Format("15:04") + ")"
+				}
+				bw.WriteString("- **" + markdownEscape(t.Name) + "**" + at + markdownDescription(t.Description) + "\n")
+			}
+		}
+		if len(dayDone) > 0 || len(dayDue) > 0 {
+			bw.WriteString("\n### Goals\n\n")
+			for _, g := range dayDone {
+				bw.WriteString("- Completed: **" + markdownEscape(g.Name) + "**" + markdownDescription(g.Description) + "\n")
+			}
+			for _, g := range dayDue {
+				state := "open"
+				if g.Status == "done" {
+					state = "done"
+				} else if next.Before(now) {
+					state = "missed"
+				}
+				bw.WriteString("- Due: **" + markdownEscape(g.Name) + "** (" + state + ")\n")
+			}
+		}
+
+		total += worked
+		totalTasks += len(tasks)
+		totalGoals += len(dayDone)
+	}
+
+	fmt.Fprintf(bw, "\n---\n\n**Total:** worked %s, %d tasks done, %d goals completed.\n",
+		journalDuration(total), totalTasks, totalGoals)
+	return bw.Flush()
+}
+
+// journalDuration formats whole minutes as "2h 05m", or "45m" under an hour.
+func journalDuration(d time.Duration) string {
+	m := int(d / time.Minute)
+	if m < 60 {
+		return fmt.Sprintf("%dm", m)
+	}
+	return fmt.Sprintf("%dh %02dm", m/60, m%60)
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`, "<", `\<`, ">", `\>`, "#", `\#`,
+)
+
+// markdownEscape keeps user text from being read as Markdown syntax.
+func markdownEscape(s string) string {
+	return markdownEscaper.Replace(s)
+}
+
+// markdownDescription renders a description after a list item's title, with any
+// further lines indented so they stay inside the item.
+func markdownDescription(s string) string {
+	s = strings.TrimSpace(strings.ReplaceAll(s, "\r\n", "\n"))
+	if s == "" {
+		return ""
+	}
+	lines := strings.Split(s, "\n")
+	for i := range lines {
+		lines[i] = markdownEscape(lines[i])
+	}
+	return " — " + strings.Join(lines, "\n  ")
+}
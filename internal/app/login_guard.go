@@ -0,0 +1,140 @@
+package app
+
+import (
+	"abtprj/internal/ratelimit"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrTooManyAttempts is wrapped by ThrottleError.
+var ErrTooManyAttempts = errors.New("too many failed login attempts")
+
+// ThrottleError refuses a login attempt without checking it.
+type ThrottleError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottleError) Error() string {
+	return fmt.Sprintf("%v, retry in %s", ErrTooManyAttempts, e.RetryAfter.Round(time.Second))
+}
+
+func (e *ThrottleError) Unwrap() error { return ErrTooManyAttempts }
+
+type FailedLogin struct {
+	At     time.Time
+	IP     string
+	Login  string
+	Reason string
+}
+
+// LoginGuard throttles login attempts per source address and per account, and
+// remembers recent failures. DefaultAppService uses MemoryLoginGuard; set
+// DefaultAppService.Guard to share the state between several servers.
+type LoginGuard interface {
+	// Attempt returns how long ip or login has to wait before the next attempt,
+	// or zero after taking one: it counts as failed until Succeeded, so attempts
+	// checked at the same time cannot all get through.
+	Attempt(ip, login string, now time.Time) time.Duration
+	// Failed records an attempt that failed, which Attempt has counted already.
+	Failed(attempt FailedLogin)
+	Succeeded(ip, login string)
+	// RecentFailures returns up to limit failures, newest first.
+	RecentFailures(limit int) []FailedLogin
+}
+
+var (
+	// AccountPolicy throttles one login name. It is stricter than IPPolicy, since
+	// an attacker can spread guesses over many addresses.
+	AccountPolicy = ratelimit.Policy{
+		Free: 3, BaseDelay: 2 * time.Second, MaxDelay: 5 * time.Minute,
+		LockAfter: 10, LockFor: 15 * time.Minute, Forget: time.Hour,
+	}
+	// IPPolicy throttles one source address, which may be shared behind a NAT.
+	IPPolicy = ratelimit.Policy{
+		Free: 10, BaseDelay: time.Second, MaxDelay: 5 * time.Minute,
+		LockAfter: 50, LockFor: time.Hour, Forget: time.Hour,
+	}
+)
+
+// recentFailuresKept is how many failures MemoryLoginGuard remembers for the admin view.
+const recentFailuresKept = 200
+
+type MemoryLoginGuard struct {
+	ByIP      ratelimit.Limiter
+	ByAccount ratelimit.Limiter
+
+	attempts sync.Mutex // takes an attempt from both limiters at once
+	mu       sync.Mutex
+	recent   []FailedLogin // oldest first
+}
+
+func NewMemoryLoginGuard() *MemoryLoginGuard {
+	return &MemoryLoginGuard{
+		ByIP:      ratelimit.NewMemory(IPPolicy),
+		ByAccount: ratelimit.NewMemory(AccountPolicy),
+	}
+}
+
+func (g *MemoryLoginGuard) Attempt(ip, login string, now time.Time) time.Duration {
+	g.attempts.Lock()
+	defer g.attempts.Unlock()
+
+	if wait := max(g.ByIP.Wait(ip, now), g.ByAccount.Wait(accountKey(login), now)); wait > 0 {
+		return wait
+	}
+	return max(g.ByIP.Attempt(ip, now), g.ByAccount.Attempt(accountKey(login), now))
+}
+
+func (g *MemoryLoginGuard) Failed(attempt FailedLogin) {
+	wait := max(g.ByIP.Wait(attempt.IP, attempt.At), g.ByAccount.Wait(accountKey(attempt.Login), attempt.At))
+	log.Printf("failed login for %q from %s: %s; next attempt allowed in %s",
+		attempt.Login, attempt.IP, attempt.Reason, wait)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.recent = append(g.recent, attempt)
+	if len(g.recent) > recentFailuresKept {
+		g.recent = append(g.recent[:0], g.recent[len(g.recent)-recentFailuresKept:]...)
+	}
+}
+
+func (g *MemoryLoginGuard) Succeeded(ip, login string) {
+	g.ByIP.Reset(ip)
+	g.ByAccount.Reset(accountKey(login))
+}
+
+func (g *MemoryLoginGuard) RecentFailures(limit int) []FailedLogin {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var out []FailedLogin
+	for i := len(g.recent) - 1; i >= 0 && len(out) < limit; i-- {
+		out = append(out, g.recent[i])
+	}
+	return out
+}
+
+func accountKey(login string) string {
+	return strings.ToLower(strings.TrimSpace(login))
+}
+
+// checkThrottle returns a ThrottleError while ip or login has to wait, and
+// otherwise takes an attempt that counts as failed until s.Guard.Succeeded.
+func (s *DefaultAppService) checkThrottle(ip, login string, now time.Time) error {
+	if wait := s.Guard.Attempt(ip, login, now); wait > 0 {
+		log.Printf("login for %q from %s refused: throttled for %s", login, ip, wait)
+		return &ThrottleError{RetryAfter: wait}
+	}
+	return nil
+}
+
+func (s *DefaultAppService) RecentFailedLogins(limit int) ([]FailedLogin, error) {
+	failures := s.Guard.RecentFailures(limit)
+	for i := range failures {
+		failures[i].At = failures[i].At.In(s.loc)
+	}
+	return failures, nil
+}
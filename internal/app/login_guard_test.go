@@ -0,0 +1,42 @@
+package app
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemoryLoginGuard_ParallelAttempts(t *testing.T) {
+	g := NewMemoryLoginGuard()
+	now := time.Now()
+
+	// guesses for one login from many addresses, all checked before any fails
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	through := 0
+	for i := 0; i < 40; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if g.Attempt(fmt.Sprintf("10.0.0.%d", i), "Owner", now) == 0 {
+				mu.Lock()
+				through++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if want := AccountPolicy.Free + 1; through != want {
+		t.Errorf("%d parallel attempts got through; want %d", through, want)
+	}
+	if wait := g.Attempt("10.0.1.1", "owner", now); wait == 0 {
+		t.Error("the login should be throttled whatever its case")
+	}
+
+	g.Succeeded("10.0.0.1", "owner")
+	if wait := g.Attempt("10.0.0.1", "owner", now); wait != 0 {
+		t.Errorf("after a success the login waits %s; want none", wait)
+	}
+}
@@ -0,0 +1,99 @@
+package app
+
+import (
+	"abtprj/internal/repository"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type SeedSummary struct {
+	Sessions int
+	Tasks    int
+	Goals    int
+}
+
+var seedTasks = []string{
+	"Review pull requests", "Fix flaky login test", "Write weekly notes", "Refactor heatmap colours",
+	"Update dependencies", "Profile slow stats query", "Draft API docs", "Triage bug reports",
+}
+
+// Seed fills the user's empty tracker with three weeks of demo sessions, tasks and
+// goals ending at now, so the pages have something to show.
+func (s *DefaultAppService) Seed(now time.Time) (SeedSummary, error) {
+	var sum SeedSummary
+	if s.userID == 0 {
+		return sum, errors.New("no account to seed; create one first")
+	}
+	hasData, err := repository.HasTrackerDataFor(s.DB, s.userID)
+	if err != nil {
+		return sum, err
+	}
+	if hasData {
+		return sum, errors.New("account already has tasks, goals or work sessions; seed only fills an empty one")
+	}
+
+	now = now.In(s.loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, s.loc)
+	n := 0
+	for day := today.AddDate(0, 0, -21); day.Before(today); day = day.AddDate(0, 0, 1) {
+		if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+			continue
+		}
+		// two sessions a day whose length varies with the day, same every run
+		morning := day.Add(9*time.Hour + time.Duration(day.Day()%4)*15*time.Minute)
+		afternoon := day.Add(14 * time.Hour)
+		spans := [][2]time.Time{
+			{morning, morning.Add(2*time.Hour + time.Duration(day.Day()%3)*30*time.Minute)},
+			{afternoon, afternoon.Add(time.Hour + time.Duration(day.YearDay()%5)*20*time.Minute)},
+		}
+		for _, span := range spans {
+			id, err := repository.InsertWorkSession(s.db(), s.userID, span[0].UTC(), span[1].UTC())
+			if err != nil {
+				return sum, err
+			}
+			sum.Sessions++
+
+			name := fmt.Sprintf("%s #%d", seedTasks[n%len(seedTasks)], n/len(seedTasks)+1)
+			n++
+			err = repository.ImportTask(s.db(), s.userID, name, "Demo task", "done",
+				sql.NullTime{Time: span[1].Add(-10 * time.Minute).UTC(), Valid: true},
+				span[0].AddDate(0, 0, -1).UTC(), sql.NullInt64{Int64: int64(id), Valid: true})
+			if err != nil {
+				return sum, err
+			}
+			sum.Tasks++
+		}
+	}
+
+	for i, name := range []string{"Plan next sprint", "Write release notes", "Clean up backlog"} {
+		est := sql.NullInt64{Int64: int64(30 * (i + 1)), Valid: true}
+		if _, err := repository.AddTask(s.db(), s.userID, name, "Demo task", est, sql.NullString{}); err != nil {
+			return sum, err
+		}
+		sum.Tasks++
+	}
+
+	utcDay := func(offset int) sql.NullTime {
+		d := today.AddDate(0, 0, offset)
+		return sql.NullTime{Time: time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.UTC), Valid: true}
+	}
+	goals := []struct {
+		name, status string
+		due          sql.NullTime
+		done         sql.NullTime
+	}{
+		{"Ship the calendar view", "done", utcDay(-10), sql.NullTime{Time: today.AddDate(0, 0, -11).Add(16 * time.Hour).UTC(), Valid: true}},
+		{"Finish the data export", "todo", utcDay(-2), sql.NullTime{}},
+		{"Release version 1.0", "todo", utcDay(2), sql.NullTime{}},
+		{"Write the user guide", "todo", utcDay(20), sql.NullTime{}},
+	}
+	for _, g := range goals {
+		if err := repository.InsertGoal(s.db(), s.userID, g.name, "Demo goal", g.status, g.due, g.done, today.AddDate(0, 0, -21).UTC()); err != nil {
+			return sum, err
+		}
+		sum.Goals++
+	}
+	return sum, nil
+}
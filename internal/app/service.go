@@ -1,55 +1,169 @@
 package app
 
 import (
+	"abtprj/internal/importer"
 	"abtprj/internal/repository"
 	"database/sql"
-	"errors"
-	"golang.org/x/crypto/bcrypt"
+	"io"
 	"log"
 	"time"
 )
 
 type AppService interface {
-	LoginAdmin(login, password string) error
-	AddTask(name, description string) error
-	CompleteTask(name string) error
+	LoginAdmin(login, password, ip string) (AdminSession, error)
+	GetAdminSession(token string) (AdminSession, error)
+	LogoutAdmin(token string) error
+	ChangeAdminPassword(session AdminSession, current, newPassword string) error
+	CompleteTOTPLogin(token, code, ip string) (AdminSession, error)
+	RecentFailedLogins(limit int) ([]FailedLogin, error)
+
+	ForUser(userID int) AppService
+	PublicView() AppService
+	As(actor Actor) AppService
+	GetUser(login string) (Admin, error)
+	GetOwner() (Admin, error)
+	UserForAPIToken(token string) (Admin, error)
+	CreateInvite(session AdminSession) (string, error)
+	ListInvites(session AdminSession) ([]Invite, error)
+	CheckInvite(token string) error
+	Register(invite, login, password string) error
+
+	ListAccountAccess(session AdminSession) ([]AccountAccess, error)
+	ListAccessibleAccounts(session AdminSession) ([]AccountAccess, error)
+	GrantAccess(session AdminSession, login, role string) error
+	RevokeAccess(session AdminSession, login string) error
+	SwitchAccount(session AdminSession, login string) error
+	AccountRole(adminID, accountID int) (string, error)
+
+	ListTeams() ([]Team, error)
+	CreateTeam(name string) error
+	GetTeam(name string) (TeamOverview, error)
+	GetTeamStats(team string, year int) (TeamStats, error)
+	SetTeamMember(team, login, role string) error
+	RemoveTeamMember(team, login string) error
+	CreateProject(team, name string) error
+	AddSharedTask(team string, projectID int, task Task) error
+	CompleteSharedTask(team string, id int) error
+	CreateSharedGoal(team string, projectID int, goal Goal) error
+	CompleteSharedGoal(team string, id int) error
+
+	GetTOTPStatus(session AdminSession) (TOTPStatus, error)
+	BeginTOTPEnrollment(session AdminSession) (TOTPEnrollment, error)
+	EnableTOTP(session AdminSession, code string) ([]string, error)
+	DisableTOTP(session AdminSession, password, code string) error
+	AddTask(task Task) error
+	CompleteTask(name string) (Undo, error)
 	GetTasksForDate(date string) ([]Task, error)
 	GetWorkSessionsForDate(date string) ([]WorkSession, error)
-	StartWorkSession() error
-	EndWorkSession() error
+	StartWorkSession() (Undo, error)
+	EndWorkSession() (Undo, error)
 	GetGoals() ([]Goal, error)
 	GetTodoGoals() ([]Goal, error)
-	CompleteGoal(id int) error
+	CompleteGoal(id int) (Undo, error)
 	CreateGoal(goal Goal) error
+	GetUndo(token string) (Undo, error)
+	UndoChange(token string) error
 
 	IsWorking() (bool, error)
+	GetStatus() (TrackerStatus, error)
 
 	CheckIfAdminExists() (bool, error)
 	GetTodoTasks() ([]Task, error)
 
 	GetDayTaskStats(year int) ([]DayTasksStat, error)
 	GetDaySessionStats(year int) ([]DaySessionsStat, error)
+
+	GetHeatmapSettings() (HeatmapSettings, error)
+	UpdateHeatmapSettings(settings HeatmapSettings) error
+	GetDefaultVisibility() (string, error)
+	SetDefaultVisibility(visibility string) error
+	SetTaskVisibility(id int, visibility string) error
+	SetGoalVisibility(id int, visibility string) error
+
+	GetGoalDeadlineStats() (GoalDeadlineStats, error)
+	GetUpcomingDeadlines() ([]Goal, error)
+
+	GetEstimateReport(from, to time.Time) (EstimateReport, error)
+
+	GetMonthCalendar(year int, month time.Month) (MonthCalendar, error)
+
+	Export(w io.Writer, kind, format string, filter ExportFilter) error
+	Backup(w io.Writer) error
+	GetRecentlyCompleted(limit int) ([]CompletedItem, error)
+	WriteJournal(w io.Writer, from, to time.Time) error
+
+	GetFeedToken() (string, error)
+	RegenerateFeedToken() (string, error)
+	GetAPIToken() (string, error)
+	RegenerateAPIToken() (string, error)
+	GetWorkSessionsBetween(from, to time.Time) ([]WorkSession, error)
+	Location() *time.Location
+	ListAudit(filter AuditFilter) ([]AuditEntry, error)
+
+	PlanTaskImport(tasks []importer.Task) (TaskImportPlan, error)
+	ImportTasks(tasks []importer.Task) (TaskImportPlan, error)
+	ImportTimeEntries(entries []importer.TimeEntry, overlap string, dryRun bool) (TimeImportSummary, error)
 }
 
+// DefaultAppService reads and writes the tracker data of one user, set with
+// ForUser; accounts, sessions and invites are shared by all.
 type DefaultAppService struct {
-	DB  *sql.DB
-	loc *time.Location
+	DB     *sql.DB
+	Guard  LoginGuard
+	loc    *time.Location
+	userID int
+	public bool    // a PublicView: hide what visitors may not see
+	actor  Actor   // who the changes are recorded as made by
+	tx     *sql.Tx // the change being made, see inTx
+}
+
+// db is what the service's queries run on: the transaction of the change
+// being made, or the database.
+func (s *DefaultAppService) db() repository.DBTX {
+	if s.tx != nil {
+		return s.tx
+	}
+	return s.DB
+}
+
+// inTx makes a change and its audit log entry in one transaction. change gets
+// a copy of the service whose queries all run in it; an error from change,
+// including a failure to record the entry, rolls everything back.
+func (s *DefaultAppService) inTx(change func(s *DefaultAppService) error) error {
+	if s.tx != nil {
+		return change(s)
+	}
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	scoped := *s
+	scoped.tx = tx
+	if err := change(&scoped); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
+// DefaultLocation is the time zone days are counted in.
+const DefaultLocation = "Europe/Moscow"
+
 func NewDefaultAppService(db *sql.DB) *DefaultAppService {
-	loc, err := time.LoadLocation("Europe/Moscow")
+	loc, err := time.LoadLocation(DefaultLocation)
 	if err != nil {
 		loc = time.UTC
 	}
-	return &DefaultAppService{DB: db, loc: loc}
+	return &DefaultAppService{DB: db, Guard: NewMemoryLoginGuard(), loc: loc}
 }
 
 type DayTasksStat struct {
 	Date  string // "2023-06-01"
 	Count int    // how many tasks completed that day
 	Level int    // shade level 0–4
-	Row   int    // grid‐row (2–8): 2=Sunday, 3=Monday … 8=Saturday
-	Col   int    // grid‐column (2–54): week index + 2
+	Row   int    // grid‐row (2–8): 2=Monday, 3=Tuesday … 8=Sunday
+	Col   int    // grid‐column (2–55): week index + 2
 	Goals []Goal
 }
 
@@ -57,27 +171,59 @@ type DaySessionsStat struct {
 	Date       string // "2023-06-01"
 	SessionDur time.Duration
 	Level      int // 0–4 shade
-	Row        int // grid‐row (2–8): 2=Sunday, 3=Monday … 8=Saturday
-	Col        int // grid‐column (2–54): week index + 2
-}
-
-func (s *DefaultAppService) AddTask(name string, description string) error {
-	return repository.AddTask(s.DB, name, description)
+	Row        int // grid‐row (2–8): 2=Monday, 3=Tuesday … 8=Sunday
+	Col        int // grid‐column (2–55): week index + 2
 }
 
-func (s *DefaultAppService) CompleteTask(name string) error {
-	return repository.CompleteTask(s.DB, name)
-}
-
-func (s *DefaultAppService) LoginAdmin(login, password string) error {
-	admin, err := repository.GetAdminByLogin(s.DB, login)
+func (s *DefaultAppService) AddTask(task Task) error {
+	var estimate sql.NullInt64
+	if task.Estimate > 0 {
+		estimate = sql.NullInt64{Int64: int64(task.Estimate / time.Minute), Valid: true}
+	}
+	visibility, err := nullVisibility(task.Visibility)
 	if err != nil {
 		return err
 	}
-	if bcrypt.CompareHashAndPassword([]byte(admin.PasswordHash), []byte(password)) != nil {
-		return errors.New("invalid password")
+	return s.inTx(func(s *DefaultAppService) error {
+		id, err := repository.AddTask(s.db(), s.userID, task.Name, task.Description, estimate, visibility)
+		if err != nil {
+			return err
+		}
+		_, err = s.audit("create", AuditTask, id, nil, taskRecord{
+			ID:              id,
+			Name:            task.Name,
+			Description:     task.Description,
+			Status:          "todo",
+			EstimateMinutes: estimate.Int64,
+			Visibility:      visibility.String,
+		})
+		return err
+	})
+}
+
+// CompleteTask marks the task name done, if a work session is running. Of
+// several tasks with that name only the one FindTaskByName picks is completed.
+func (s *DefaultAppService) CompleteTask(name string) (Undo, error) {
+	var e repository.AuditEntry
+	err := s.inTx(func(s *DefaultAppService) error {
+		before, found, err := repository.FindTaskByName(s.db(), s.userID, name)
+		if err != nil {
+			log.Printf("CompleteTask FindTaskByName error: %v", err)
+			return err
+		}
+		if !found {
+			return nil
+		}
+		if err := repository.CompleteTask(s.db(), s.userID, before.Id); err != nil {
+			return err
+		}
+		e, err = s.auditTask("complete", before)
+		return err
+	})
+	if err != nil {
+		return Undo{}, err
 	}
-	return nil
+	return s.undoable(e), nil
 }
 
 func (s *DefaultAppService) GetTasksForDate(date string) ([]Task, error) {
@@ -88,10 +234,13 @@ func (s *DefaultAppService) GetTasksForDate(date string) ([]Task, error) {
 	startUTC := day.UTC()
 	endUTC := day.Add(24 * time.Hour).UTC()
 
-	repoTasks, err := repository.GetDoneTasks(s.DB, startUTC, endUTC)
+	repoTasks, err := repository.GetDoneTasks(s.db(), s.userID, startUTC, endUTC)
 	if err != nil {
 		return nil, err
 	}
+	if repoTasks, err = s.visibleTasks(repoTasks); err != nil {
+		return nil, err
+	}
 	tasks := ConvertRepoTasks(repoTasks)
 	for i := range tasks {
 		if tasks[i].DoneAt != nil {
@@ -110,7 +259,7 @@ func (s *DefaultAppService) GetWorkSessionsForDate(date string) ([]WorkSession,
 	startUTC := day.UTC()
 	endUTC := day.Add(24 * time.Hour).UTC()
 
-	repoSessions, err := repository.GetWorkingSessionsForDay(s.DB, startUTC, endUTC)
+	repoSessions, err := repository.GetWorkingSessionsForDay(s.db(), s.userID, startUTC, endUTC)
 	if err != nil {
 		return nil, err
 	}
@@ -127,56 +276,57 @@ func (s *DefaultAppService) GetWorkSessionsForDate(date string) ([]WorkSession,
 }
 
 func (s *DefaultAppService) GetDayTaskStats(year int) ([]DayTasksStat, error) {
-	start := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
-	end := time.Date(year, time.December, 31, 23, 59, 59, 0, time.UTC)
-	tasks, err := repository.GetDoneTasks(s.DB, start, end)
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, s.loc)
+	end := start.AddDate(1, 0, 0)
+	tasks, err := repository.GetDoneTasks(s.db(), s.userID, start, end)
 	if err != nil {
 		return nil, err
 	}
+	if tasks, err = s.visibleTasks(tasks); err != nil {
+		return nil, err
+	}
 
-	empty := generateEmptyDayStats()
-	stats := make([]DayTasksStat, len(empty))
-	copy(stats, empty)
+	stats := generateEmptyDayStats(year)
 	for _, task := range tasks {
 		if !task.DoneAt.Valid {
 			continue
 		}
-		d := task.DoneAt.Time
-		_, isoWeek := d.ISOWeek()
-		weekIdx := isoWeek - 1
-		dowIdx := (int(d.Weekday()) + 6) % 7
-		idx := weekIdx*7 + dowIdx
-		if idx < 0 || idx >= len(stats) {
+		// a task counts on the day it was done in the service's time zone
+		d := task.DoneAt.Time.In(s.loc)
+		if d.Year() != year {
 			continue
 		}
+		idx := StatIndex(d)
 		stats[idx].Date = d.Format("2006-01-02")
 		stats[idx].Count++
-		lvl := stats[idx].Count
-		if lvl > 4 {
-			lvl = 4
-		}
-		stats[idx].Level = lvl
 	}
 
-	empty = generateEmptyDayStats()
-	copy(stats, stats)
-	repoGoals, err := repository.GetGoals(s.DB)
-	goals := ConvertRepoGoals(repoGoals)
+	settings, err := s.GetHeatmapSettings()
+	if err != nil {
+		log.Printf("GetHeatmapSettings error, using defaults: %v", err)
+	}
+	counts := make([]float64, len(stats))
+	for i := range stats {
+		counts[i] = float64(stats[i].Count)
+	}
+	for i, lvl := range settings.Tasks.levels(counts) {
+		stats[i].Level = lvl
+	}
+
+	repoGoals, err := repository.GetGoals(s.db(), s.userID)
 	if err != nil {
 		return nil, err
 	}
+	if repoGoals, err = s.visibleGoals(repoGoals); err != nil {
+		return nil, err
+	}
+	goals := ConvertRepoGoals(repoGoals, s.loc)
 	for _, goal := range goals {
-		if goal.DueAt == nil {
+		if goal.DueAt == nil || goal.DueAt.Year() != year {
 			continue
 		}
 		d := goal.DueAt
-		_, isoWeek := d.ISOWeek()
-		weekIdx := isoWeek - 1
-		dowIdx := (int(d.Weekday()) + 6) % 7
-		idx := weekIdx*7 + dowIdx
-		if idx < 0 || idx >= len(stats) {
-			continue
-		}
+		idx := StatIndex(*d)
 		stats[idx].Date = d.Format("2006-01-02")
 		stats[idx].Goals = append(stats[idx].Goals, goal)
 	}
@@ -184,92 +334,124 @@ func (s *DefaultAppService) GetDayTaskStats(year int) ([]DayTasksStat, error) {
 }
 
 func (s *DefaultAppService) GetDaySessionStats(year int) ([]DaySessionsStat, error) {
-	start := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
-	end := time.Date(year, time.December, 31, 23, 59, 59, 0, time.UTC)
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, s.loc)
+	end := start.AddDate(1, 0, 0)
 
-	sessions, err := repository.GetWorkingSessions(s.DB, start, end)
+	sessions, err := repository.GetWorkingSessions(s.db(), s.userID, start, end)
 	if err != nil {
 		return nil, err
 	}
+	// on the grid, a session counts on the day it ended in s.loc
+	for i := range sessions {
+		sessions[i].StartTime = sessions[i].StartTime.In(s.loc)
+		sessions[i].EndTime.Time = sessions[i].EndTime.Time.In(s.loc)
+	}
+
+	settings, err := s.GetHeatmapSettings()
+	if err != nil {
+		log.Printf("GetHeatmapSettings error, using defaults: %v", err)
+	}
+	return sessionHeatmap(sessions, year, settings.Sessions), nil
+}
 
-	empty := generateEmptySessionStats()
-	stats := make([]DaySessionsStat, len(empty))
-	copy(stats, empty)
+// sessionHeatmap adds up finished sessions on the grid day of year they ended
+// and shades each day with scale.
+func sessionHeatmap(sessions []repository.WorkSession, year int, scale HeatmapScale) []DaySessionsStat {
+	stats := generateEmptySessionStats(year)
 
 	for _, sess := range sessions {
-		if !sess.EndTime.Valid {
+		if !sess.EndTime.Valid || sess.EndTime.Time.Year() != year {
 			continue
 		}
 		d := sess.EndTime.Time
-
-		_, isoWeek := d.ISOWeek()
-		weekIdx := isoWeek - 1
-
-		// convert Go’s Sunday=0…Saturday=6 → Monday=0…Sunday=6
-		dowIdx := (int(d.Weekday()) + 6) % 7
-
-		idx := weekIdx*7 + dowIdx
-		if idx < 0 || idx >= len(stats) {
-			continue
-		}
+		idx := StatIndex(d)
 
 		stats[idx].Date = d.Format("2006-01-02")
 		stats[idx].SessionDur += sess.EndTime.Time.Sub(sess.StartTime)
-		switch {
-		case stats[idx].SessionDur > 8*time.Hour:
-			stats[idx].Level = 4
-		case stats[idx].SessionDur > 4*time.Hour:
-			stats[idx].Level = 3
-		case stats[idx].SessionDur > 2*time.Hour:
-			stats[idx].Level = 2
-		case stats[idx].SessionDur > 0:
-			stats[idx].Level = 1
-		default:
-			stats[idx].Level = 0
-		}
-		if stats[idx].Level > 4 {
-			stats[idx].Level = 4
-		}
 	}
 
-	return stats, nil
+	hours := make([]float64, len(stats))
+	for i := range stats {
+		hours[i] = stats[i].SessionDur.Hours()
+	}
+	for i, lvl := range scale.levels(hours) {
+		stats[i].Level = lvl
+	}
+	return stats
 }
 
-func (s *DefaultAppService) StartWorkSession() error {
-	if err := repository.StartWorkSession(s.DB); err != nil {
-		log.Printf("startWorkSession exec error: %v", err)
+func (s *DefaultAppService) StartWorkSession() (Undo, error) {
+	var e repository.AuditEntry
+	err := s.inTx(func(s *DefaultAppService) error {
+		wasWorking, _, err := repository.CheckIfActiveSessions(s.db(), s.userID)
+		if err != nil {
+			return err
+		}
+		if err := repository.StartWorkSession(s.db(), s.userID); err != nil {
+			log.Printf("startWorkSession exec error: %v", err)
+			return err
+		}
+		working, started, err := repository.CheckIfActiveSessions(s.db(), s.userID)
+		if err != nil {
+			return err
+		}
+		if !working || wasWorking {
+			return nil
+		}
+		e, err = s.audit("start", AuditSession, started.Id, nil, newSessionRecord(*started))
 		return err
+	})
+	if err != nil {
+		return Undo{}, err
 	}
-	return nil
+	return s.undoable(e), nil
 }
 
 func (s *DefaultAppService) IsWorking() (bool, error) {
-	isWorking, _, err := repository.CheckIfActiveSessions(s.DB)
+	isWorking, _, err := repository.CheckIfActiveSessions(s.db(), s.userID)
 	if err != nil {
 		return false, err
 	}
 	return isWorking, nil
 }
 
-func (s *DefaultAppService) EndWorkSession() error {
-	if err := repository.EndWorkSession(s.DB); err != nil {
-		log.Printf("endWorkSession exec error: %v", err)
+func (s *DefaultAppService) EndWorkSession() (Undo, error) {
+	var e repository.AuditEntry
+	err := s.inTx(func(s *DefaultAppService) error {
+		working, active, err := repository.CheckIfActiveSessions(s.db(), s.userID)
+		if err != nil {
+			return err
+		}
+		if err := repository.EndWorkSession(s.db(), s.userID); err != nil {
+			log.Printf("endWorkSession exec error: %v", err)
+			return err
+		}
+		if !working {
+			return nil
+		}
+		e, err = s.auditSession("end", *active)
 		return err
+	})
+	if err != nil {
+		return Undo{}, err
 	}
-	return nil
+	return s.undoable(e), nil
 }
 
 func (s *DefaultAppService) GetTodoTasks() ([]Task, error) {
-	repoTasks, err := repository.GetTodoTasks(s.DB)
+	repoTasks, err := repository.GetTodoTasks(s.db(), s.userID)
 	if err != nil {
 		log.Printf("GetTodoTasks exec error: %v", err)
 		return nil, err
 	}
+	if repoTasks, err = s.visibleTasks(repoTasks); err != nil {
+		return nil, err
+	}
 	return ConvertRepoTasks(repoTasks), nil
 }
 
 func (s *DefaultAppService) CheckIfAdminExists() (bool, error) {
-	exists, err := repository.CheckIfAdminExists(s.DB)
+	exists, err := repository.CheckIfAdminExists(s.db())
 	if err != nil {
 		log.Printf("CheckIfAdminExists exec error: %v", err)
 		return false, err
@@ -278,55 +460,90 @@ func (s *DefaultAppService) CheckIfAdminExists() (bool, error) {
 }
 
 func (s *DefaultAppService) GetGoals() ([]Goal, error) {
-	goals, err := repository.GetGoals(s.DB)
+	goals, err := repository.GetGoals(s.db(), s.userID)
 	if err != nil {
 		log.Printf("GetGoal exec error: %v", err)
 		return nil, err
 	}
-	return ConvertRepoGoals(goals), err
+	if goals, err = s.visibleGoals(goals); err != nil {
+		return nil, err
+	}
+	return ConvertRepoGoals(goals, s.loc), err
 }
 
 func (s *DefaultAppService) GetTodoGoals() ([]Goal, error) {
-	todoGoals, err := repository.GetTodoGoals(s.DB)
+	todoGoals, err := repository.GetTodoGoals(s.db(), s.userID)
 	if err != nil {
 		log.Printf("GetTodoGoal exec error: %v", err)
 		return nil, err
 	}
-	return ConvertRepoGoals(todoGoals), err
+	if todoGoals, err = s.visibleGoals(todoGoals); err != nil {
+		return nil, err
+	}
+	return ConvertRepoGoals(todoGoals, s.loc), err
 }
 
-func (s *DefaultAppService) CompleteGoal(id int) error {
-	err := repository.CompleteGoal(s.DB, id)
-	if err != nil {
-		log.Printf("CompleteGoal exec error: %v", err)
+func (s *DefaultAppService) CompleteGoal(id int) (Undo, error) {
+	var e repository.AuditEntry
+	err := s.inTx(func(s *DefaultAppService) error {
+		before, beforeErr := repository.GetGoal(s.db(), s.userID, id)
+		if err := repository.CompleteGoal(s.db(), s.userID, id); err != nil {
+			log.Printf("CompleteGoal exec error: %v", err)
+			return err
+		}
+		if beforeErr != nil {
+			return nil
+		}
+		var err error
+		e, err = s.auditGoal("complete", before)
 		return err
+	})
+	if err != nil {
+		return Undo{}, err
 	}
-	return nil
+	return s.undoable(e), nil
 }
 
 func (s *DefaultAppService) CreateGoal(goal Goal) error {
-	err := repository.CreateGoal(s.DB, goal.Name, goal.Description, *goal.DueAt)
+	visibility, err := nullVisibility(goal.Visibility)
 	if err != nil {
-		log.Printf("CreateGoal exec error: %v", err)
+		return err
 	}
-	return nil
+	return s.inTx(func(s *DefaultAppService) error {
+		id, err := repository.CreateGoal(s.db(), s.userID, goal.Name, goal.Description, *goal.DueAt, visibility)
+		if err != nil {
+			log.Printf("CreateGoal exec error: %v", err)
+			return err
+		}
+		_, err = s.audit("create", AuditGoal, id, nil, goalRecord{
+			ID:          id,
+			Name:        goal.Name,
+			Description: goal.Description,
+			Status:      "todo",
+			DueAt:       goal.DueAt,
+			Visibility:  visibility.String,
+		})
+		return err
+	})
 }
 
-func generateEmptyDayStats() []DayTasksStat {
-	days := make([]DayTasksStat, 0, 52*7)
-	for week := 1; week <= 52; week++ {
+func generateEmptyDayStats(year int) []DayTasksStat {
+	weeks := GridWeeks(year)
+	days := make([]DayTasksStat, 0, weeks*7)
+	for week := 1; week <= weeks; week++ {
 		for dow := 0; dow < 7; dow++ {
-			days = append(days, DayTasksStat{"", 0, 0, dow + 2, week + 1, nil})
+			days = append(days, DayTasksStat{gridDate(year, len(days)), 0, 0, dow + 2, week + 1, nil})
 		}
 	}
 	return days
 }
 
-func generateEmptySessionStats() []DaySessionsStat {
-	days := make([]DaySessionsStat, 0, 52*7)
-	for week := 1; week <= 52; week++ {
+func generateEmptySessionStats(year int) []DaySessionsStat {
+	weeks := GridWeeks(year)
+	days := make([]DaySessionsStat, 0, weeks*7)
+	for week := 1; week <= weeks; week++ {
 		for dow := 0; dow < 7; dow++ {
-			days = append(days, DaySessionsStat{"", time.Second * 0, 0, dow + 2, week + 1})
+			days = append(days, DaySessionsStat{gridDate(year, len(days)), time.Second * 0, 0, dow + 2, week + 1})
 		}
 	}
 	return days
@@ -0,0 +1,63 @@
+package app
+
+import (
+	"abtprj/internal/repository"
+	"log"
+	"time"
+)
+
+// TrackerStatus is the at-a-glance state shown by the command-line client.
+type TrackerStatus struct {
+	Working        bool
+	SessionStart   *time.Time // start of the running session, in the service location
+	WorkedToday    time.Duration
+	TasksDoneToday int
+	OpenTasks      int
+}
+
+// GetStatus reports whether a session is running and how today is going so far.
+func (s *DefaultAppService) GetStatus() (TrackerStatus, error) {
+	var st TrackerStatus
+	working, active, err := repository.CheckIfActiveSessions(s.db(), s.userID)
+	if err != nil {
+		log.Printf("GetStatus CheckIfActiveSessions error: %v", err)
+		return st, err
+	}
+	if working {
+		start := active.StartTime.In(s.loc)
+		st.Working, st.SessionStart = true, &start
+	}
+
+	now := time.Now().In(s.loc)
+	today := now.Format("2006-01-02")
+	sessions, err := s.GetWorkSessionsForDate(today)
+	if err != nil {
+		return st, err
+	}
+	for _, ws := range sessions {
+		if ws.EndTime != nil {
+			st.WorkedToday += ws.EndTime.Sub(ws.StartTime)
+		}
+	}
+	if st.SessionStart != nil {
+		// a session running since yesterday only counts from midnight
+		since := *st.SessionStart
+		if midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, s.loc); since.Before(midnight) {
+			since = midnight
+		}
+		st.WorkedToday += now.Sub(since)
+	}
+
+	done, err := s.GetTasksForDate(today)
+	if err != nil {
+		return st, err
+	}
+	st.TasksDoneToday = len(done)
+
+	open, err := s.GetTodoTasks()
+	if err != nil {
+		return st, err
+	}
+	st.OpenTasks = len(open)
+	return st, nil
+}
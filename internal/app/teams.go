@@ -0,0 +1,529 @@
+package app
+
+import (
+	"abtprj/internal/repository"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Team roles. Owners manage members and projects, members also create and
+// complete shared tasks and goals, viewers only look.
+const (
+	RoleOwner  = "owner"
+	RoleMember = "member"
+	RoleViewer = "viewer"
+)
+
+// teamListLimit caps the completed tasks and goals shown on a team page.
+const teamListLimit = 20
+
+var (
+	// ErrNoTeam is returned for teams that do not exist and for teams the user
+	// is not in, so names of other teams do not leak.
+	ErrNoTeam         = errors.New("no such team")
+	ErrNotAllowed     = errors.New("your role does not allow that")
+	ErrLastOwner      = errors.New("a team needs at least one owner")
+	ErrTeamTaken      = errors.New("team name is already taken")
+	ErrInvalidRole    = errors.New("role must be owner, member or viewer")
+	ErrNoSharedItem   = errors.New("no such open task or goal in this team")
+	ErrInvalidProject = errors.New("project name must be 1 to 100 characters")
+	// ErrInvalidTeamName is wrapped by names that cannot be used for a team.
+	ErrInvalidTeamName = errors.New("invalid team name")
+)
+
+type Team struct {
+	ID        int
+	Name      string
+	Role      string // the role of the user the service is for
+	CreatedAt time.Time
+}
+
+// CanEdit reports whether the role allows creating and completing shared items.
+func (t Team) CanEdit() bool { return t.Role == RoleOwner || t.Role == RoleMember }
+
+// CanManage reports whether the role allows managing members and projects.
+func (t Team) CanManage() bool { return t.Role == RoleOwner }
+
+type TeamMember struct {
+	Login    string
+	Role     string
+	JoinedAt time.Time
+}
+
+type Project struct {
+	ID   int
+	Name string
+}
+
+type SharedTask struct {
+	Task
+	ID      int
+	Project string
+	DoneBy  string // login of the member who completed it, empty while open
+}
+
+type SharedGoal struct {
+	Goal
+	Project string
+	DoneBy  string // login of the member who completed it, empty while open
+}
+
+// TeamOverview is everything a team page shows.
+type TeamOverview struct {
+	Team      Team
+	Members   []TeamMember
+	Projects  []Project
+	OpenTasks []SharedTask
+	DoneTasks []SharedTask // the last teamListLimit
+	OpenGoals []SharedGoal
+	DoneGoals []SharedGoal // the last teamListLimit
+}
+
+type MemberStats struct {
+	Login     string
+	Role      string
+	Hours     float64
+	TasksDone int
+}
+
+// TeamStats adds up a year of work of every team member. Tasks count the
+// members' own tasks and the team's shared ones; hours come from all their
+// work sessions.
+type TeamStats struct {
+	Team      Team
+	Year      int
+	Members   []MemberStats // most hours first
+	Hours     float64
+	TasksDone int
+	Heatmap   []DaySessionsStat // every member's sessions together
+}
+
+func ValidateTeamName(name string) error {
+	if !loginPattern.MatchString(name) {
+		return fmt.Errorf("%w: use 2 to 32 lowercase letters, digits, '-' or '_', starting with a letter or digit", ErrInvalidTeamName)
+	}
+	return nil
+}
+
+func validRole(role string) bool {
+	return role == RoleOwner || role == RoleMember || role == RoleViewer
+}
+
+// team returns the team called name if the service's user is in it with one
+// of roles, or any role when none are given.
+func (s *DefaultAppService) team(name string, roles ...string) (repository.Team, error) {
+	t, err := repository.GetTeamForUser(s.db(), name, s.userID)
+	if err != nil {
+		log.Printf("GetTeamForUser exec error: %v", err)
+		return t, err
+	}
+	if t.Id == 0 {
+		return t, ErrNoTeam
+	}
+	if len(roles) == 0 {
+		return t, nil
+	}
+	for _, r := range roles {
+		if t.Role == r {
+			return t, nil
+		}
+	}
+	return t, ErrNotAllowed
+}
+
+func convertTeam(t repository.Team, loc *time.Location) Team {
+	return Team{ID: t.Id, Name: t.Name, Role: t.Role, CreatedAt: t.CreatedAt.In(loc)}
+}
+
+// ListTeams returns the teams the user is in, by name.
+func (s *DefaultAppService) ListTeams() ([]Team, error) {
+	rows, err := repository.ListTeamsForUser(s.db(), s.userID)
+	if err != nil {
+		log.Printf("ListTeams exec error: %v", err)
+		return nil, err
+	}
+	teams := make([]Team, len(rows))
+	for i, t := range rows {
+		teams[i] = convertTeam(t, s.loc)
+	}
+	return teams, nil
+}
+
+// CreateTeam starts a team with the user as its owner.
+func (s *DefaultAppService) CreateTeam(name string) error {
+	if err := ValidateTeamName(name); err != nil {
+		return err
+	}
+	return s.inTx(func(s *DefaultAppService) error {
+		taken, err := repository.TeamNameTaken(s.db(), name)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return fmt.Errorf("%w: %q", ErrTeamTaken, name)
+		}
+		if _, err := repository.CreateTeam(s.db(), name, s.userID); err != nil {
+			log.Printf("CreateTeam exec error: %v", err)
+			return err
+		}
+		_, err = s.audit("create", AuditTeam, name, nil, map[string]string{"name": name})
+		return err
+	})
+}
+
+// GetTeam returns the team page of a team the user is in.
+func (s *DefaultAppService) GetTeam(name string) (TeamOverview, error) {
+	t, err := s.team(name)
+	if err != nil {
+		return TeamOverview{}, err
+	}
+	overview := TeamOverview{Team: convertTeam(t, s.loc)}
+
+	members, err := repository.ListTeamMembers(s.db(), t.Id)
+	if err != nil {
+		return overview, err
+	}
+	for _, m := range members {
+		overview.Members = append(overview.Members, TeamMember{Login: m.Login, Role: m.Role, JoinedAt: m.JoinedAt.In(s.loc)})
+	}
+
+	projects, err := repository.ListProjects(s.db(), t.Id)
+	if err != nil {
+		return overview, err
+	}
+	for _, p := range projects {
+		overview.Projects = append(overview.Projects, Project{ID: p.Id, Name: p.Name})
+	}
+
+	if overview.OpenTasks, err = s.sharedTasks(t.Id, "todo", -1); err != nil {
+		return overview, err
+	}
+	if overview.DoneTasks, err = s.sharedTasks(t.Id, "done", teamListLimit); err != nil {
+		return overview, err
+	}
+	if overview.OpenGoals, err = s.sharedGoals(t.Id, "todo", -1); err != nil {
+		return overview, err
+	}
+	if overview.DoneGoals, err = s.sharedGoals(t.Id, "done", teamListLimit); err != nil {
+		return overview, err
+	}
+	return overview, nil
+}
+
+// sharedTasks lists a team's tasks with status; a negative limit means all.
+func (s *DefaultAppService) sharedTasks(teamID int, status string, limit int) ([]SharedTask, error) {
+	rows, err := repository.ListSharedTasks(s.db(), teamID, status, limit)
+	if err != nil {
+		log.Printf("ListSharedTasks exec error: %v", err)
+		return nil, err
+	}
+	tasks := make([]SharedTask, len(rows))
+	for i, r := range rows {
+		tasks[i] = SharedTask{
+			Task:    ConvertRepoTasks([]repository.Task{r.Task})[0],
+			ID:      r.Id,
+			Project: r.Project,
+			DoneBy:  r.DoneBy.String,
+		}
+	}
+	return tasks, nil
+}
+
+// sharedGoals lists a team's goals with status; a negative limit means all.
+func (s *DefaultAppService) sharedGoals(teamID int, status string, limit int) ([]SharedGoal, error) {
+	rows, err := repository.ListSharedGoals(s.db(), teamID, status, limit)
+	if err != nil {
+		log.Printf("ListSharedGoals exec error: %v", err)
+		return nil, err
+	}
+	goals := make([]SharedGoal, len(rows))
+	for i, r := range rows {
+		goals[i] = SharedGoal{
+			Goal:    ConvertRepoGoals([]repository.Goal{r.Goal}, s.loc)[0],
+			Project: r.Project,
+			DoneBy:  r.DoneBy.String,
+		}
+	}
+	return goals, nil
+}
+
+// SetTeamMember adds the account login to a team with role, or changes the
+// role of a member. Only owners can, and the last owner cannot step down.
+func (s *DefaultAppService) SetTeamMember(team, login, role string) error {
+	if !validRole(role) {
+		return ErrInvalidRole
+	}
+	t, err := s.team(team, RoleOwner)
+	if err != nil {
+		return err
+	}
+	user, err := s.GetUser(login)
+	if err != nil {
+		return err
+	}
+	return s.inTx(func(s *DefaultAppService) error {
+		if role != RoleOwner {
+			if err := s.keepAnOwner(t.Id, user.ID); err != nil {
+				return err
+			}
+		}
+		before, err := s.memberRole(t.Id, user.ID)
+		if err != nil {
+			return err
+		}
+		if err := repository.AddTeamMember(s.db(), t.Id, user.ID, role); err != nil {
+			log.Printf("SetTeamMember exec error: %v", err)
+			return err
+		}
+		_, err = s.audit("grant", AuditTeamMember, team+"/"+login, auditRole(before), auditRole(role))
+		return err
+	})
+}
+
+// RemoveTeamMember takes login out of a team. Owners can remove anyone and
+// everyone can leave, except the last owner.
+func (s *DefaultAppService) RemoveTeamMember(team, login string) error {
+	t, err := s.team(team)
+	if err != nil {
+		return err
+	}
+	user, err := s.GetUser(login)
+	if err != nil {
+		return err
+	}
+	if t.Role != RoleOwner && user.ID != s.userID {
+		return ErrNotAllowed
+	}
+	return s.inTx(func(s *DefaultAppService) error {
+		if err := s.keepAnOwner(t.Id, user.ID); err != nil {
+			return err
+		}
+		before, err := s.memberRole(t.Id, user.ID)
+		if err != nil {
+			return err
+		}
+		removed, err := repository.RemoveTeamMember(s.db(), t.Id, user.ID)
+		if err != nil {
+			log.Printf("RemoveTeamMember exec error: %v", err)
+			return err
+		}
+		if !removed {
+			return ErrNoUser
+		}
+		_, err = s.audit("remove", AuditTeamMember, team+"/"+login, auditRole(before), nil)
+		return err
+	})
+}
+
+// memberRole returns userID's role in the team, or "" if they are not in it.
+func (s *DefaultAppService) memberRole(teamID, userID int) (string, error) {
+	members, err := repository.ListTeamMembers(s.db(), teamID)
+	if err != nil {
+		return "", err
+	}
+	for _, m := range members {
+		if m.UserId == userID {
+			return m.Role, nil
+		}
+	}
+	return "", nil
+}
+
+// keepAnOwner returns ErrLastOwner if userID is the only owner of the team.
+func (s *DefaultAppService) keepAnOwner(teamID, userID int) error {
+	members, err := repository.ListTeamMembers(s.db(), teamID)
+	if err != nil {
+		return err
+	}
+	owners, isOwner := 0, false
+	for _, m := range members {
+		if m.Role == RoleOwner {
+			owners++
+			isOwner = isOwner || m.UserId == userID
+		}
+	}
+	if isOwner && owners == 1 {
+		return ErrLastOwner
+	}
+	return nil
+}
+
+func (s *DefaultAppService) CreateProject(team, name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" || len([]rune(name)) > 100 {
+		return ErrInvalidProject
+	}
+	t, err := s.team(team, RoleOwner)
+	if err != nil {
+		return err
+	}
+	return s.inTx(func(s *DefaultAppService) error {
+		if err := repository.CreateProject(s.db(), t.Id, name); err != nil {
+			log.Printf("CreateProject exec error: %v", err)
+			return err
+		}
+		_, err := s.audit("create", AuditProject, team+"/"+name, nil, map[string]string{"team": team, "name": name})
+		return err
+	})
+}
+
+// projectOf checks that projectID is one of the team's projects.
+func (s *DefaultAppService) projectOf(teamID, projectID int) error {
+	ok, err := repository.ProjectInTeam(s.db(), teamID, projectID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: unknown project", ErrNoSharedItem)
+	}
+	return nil
+}
+
+// AddSharedTask adds a task to one of the team's projects.
+func (s *DefaultAppService) AddSharedTask(team string, projectID int, task Task) error {
+	t, err := s.team(team, RoleOwner, RoleMember)
+	if err != nil {
+		return err
+	}
+	if err := s.projectOf(t.Id, projectID); err != nil {
+		return err
+	}
+	var estimate sql.NullInt64
+	if task.Estimate > 0 {
+		estimate = sql.NullInt64{Int64: int64(task.Estimate / time.Minute), Valid: true}
+	}
+	return s.inTx(func(s *DefaultAppService) error {
+		if err := repository.AddSharedTask(s.db(), projectID, s.userID, task.Name, task.Description, estimate); err != nil {
+			log.Printf("AddSharedTask exec error: %v", err)
+			return err
+		}
+		_, err := s.audit("create", AuditSharedTask, team, nil, map[string]any{
+			"project_id":       projectID,
+			"name":             task.Name,
+			"description":      task.Description,
+			"estimate_minutes": estimate.Int64,
+		})
+		return err
+	})
+}
+
+// CompleteSharedTask marks a team task done by the user. Unlike personal
+// tasks it does not need a running session; one is linked if there is.
+func (s *DefaultAppService) CompleteSharedTask(team string, id int) error {
+	t, err := s.team(team, RoleOwner, RoleMember)
+	if err != nil {
+		return err
+	}
+	return s.inTx(func(s *DefaultAppService) error {
+		done, err := repository.CompleteSharedTask(s.db(), t.Id, id, s.userID)
+		if err != nil {
+			log.Printf("CompleteSharedTask exec error: %v", err)
+			return err
+		}
+		if !done {
+			return ErrNoSharedItem
+		}
+		_, err = s.audit("complete", AuditSharedTask, fmt.Sprintf("%s/%d", team, id), map[string]string{"status": "todo"}, map[string]string{"status": "done"})
+		return err
+	})
+}
+
+// CreateSharedGoal adds a goal to one of the team's projects.
+func (s *DefaultAppService) CreateSharedGoal(team string, projectID int, goal Goal) error {
+	t, err := s.team(team, RoleOwner, RoleMember)
+	if err != nil {
+		return err
+	}
+	if err := s.projectOf(t.Id, projectID); err != nil {
+		return err
+	}
+	return s.inTx(func(s *DefaultAppService) error {
+		if err := repository.CreateSharedGoal(s.db(), projectID, s.userID, goal.Name, goal.Description, *goal.DueAt); err != nil {
+			log.Printf("CreateSharedGoal exec error: %v", err)
+			return err
+		}
+		_, err := s.audit("create", AuditSharedGoal, team, nil, map[string]any{
+			"project_id":  projectID,
+			"name":        goal.Name,
+			"description": goal.Description,
+			"due_at":      goal.DueAt,
+		})
+		return err
+	})
+}
+
+func (s *DefaultAppService) CompleteSharedGoal(team string, id int) error {
+	t, err := s.team(team, RoleOwner, RoleMember)
+	if err != nil {
+		return err
+	}
+	return s.inTx(func(s *DefaultAppService) error {
+		done, err := repository.CompleteSharedGoal(s.db(), t.Id, id, s.userID)
+		if err != nil {
+			log.Printf("CompleteSharedGoal exec error: %v", err)
+			return err
+		}
+		if !done {
+			return ErrNoSharedItem
+		}
+		_, err = s.audit("complete", AuditSharedGoal, fmt.Sprintf("%s/%d", team, id), map[string]string{"status": "todo"}, map[string]string{"status": "done"})
+		return err
+	})
+}
+
+// GetTeamStats adds up the members' work in year. The combined heatmap is
+// shaded by quantiles, since fixed thresholds set for one person do not fit
+// the sum of a team.
+func (s *DefaultAppService) GetTeamStats(team string, year int) (TeamStats, error) {
+	t, err := s.team(team)
+	if err != nil {
+		return TeamStats{}, err
+	}
+	stats := TeamStats{Team: convertTeam(t, s.loc), Year: year}
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, s.loc)
+	end := start.AddDate(1, 0, 0)
+
+	members, err := repository.ListTeamMembers(s.db(), t.Id)
+	if err != nil {
+		log.Printf("GetTeamStats ListTeamMembers error: %v", err)
+		return stats, err
+	}
+	sessions, err := repository.GetTeamWorkSessions(s.db(), t.Id, start, end)
+	if err != nil {
+		log.Printf("GetTeamStats GetTeamWorkSessions error: %v", err)
+		return stats, err
+	}
+	done, err := repository.CountTeamTasksDone(s.db(), t.Id, start, end)
+	if err != nil {
+		log.Printf("GetTeamStats CountTeamTasksDone error: %v", err)
+		return stats, err
+	}
+
+	var all []repository.WorkSession
+	for _, m := range members {
+		var worked time.Duration
+		for i, sess := range sessions[m.UserId] {
+			worked += sess.EndTime.Time.Sub(sess.StartTime)
+			// the heatmap puts a session on its day in the service's time zone
+			sessions[m.UserId][i].StartTime = sess.StartTime.In(s.loc)
+			sessions[m.UserId][i].EndTime.Time = sess.EndTime.Time.In(s.loc)
+		}
+		all = append(all, sessions[m.UserId]...)
+		stats.Members = append(stats.Members, MemberStats{
+			Login:     m.Login,
+			Role:      m.Role,
+			Hours:     worked.Hours(),
+			TasksDone: done[m.UserId],
+		})
+		stats.Hours += worked.Hours()
+		stats.TasksDone += done[m.UserId]
+	}
+	sort.SliceStable(stats.Members, func(i, j int) bool { return stats.Members[i].Hours > stats.Members[j].Hours })
+	stats.Heatmap = sessionHeatmap(all, year, HeatmapScale{Mode: ScaleQuantile})
+	return stats, nil
+}
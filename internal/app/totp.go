@@ -0,0 +1,245 @@
+package app
+
+import (
+	"abtprj/internal/repository"
+	"abtprj/internal/totp"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	TOTPIssuer        = "abtprj"
+	RecoveryCodeCount = 10
+)
+
+var (
+	ErrInvalidCode     = errors.New("invalid authentication code")
+	ErrTOTPEnabled     = errors.New("two-factor authentication is already enabled")
+	ErrTOTPNotEnrolled = errors.New("two-factor authentication setup was not started")
+)
+
+type TOTPStatus struct {
+	Enabled           bool
+	RecoveryCodesLeft int
+	Enrollment        *TOTPEnrollment // started but not yet confirmed
+}
+
+// TOTPEnrollment is what an authenticator app needs to add the account.
+type TOTPEnrollment struct {
+	Secret string
+	URI    string
+}
+
+// CompleteTOTPLogin finishes a login started by LoginAdmin with a TOTP code or an
+// unused recovery code. The pending session is replaced by a full one. Wrong codes
+// count towards the same limits as wrong passwords.
+func (s *DefaultAppService) CompleteTOTPLogin(token, code, ip string) (AdminSession, error) {
+	row, ok, err := repository.GetPendingAdminSession(s.db(), hashToken(token))
+	if err != nil {
+		log.Printf("CompleteTOTPLogin GetPendingAdminSession error: %v", err)
+		return AdminSession{}, err
+	}
+	if !ok {
+		return AdminSession{}, ErrNoSession
+	}
+	now := time.Now()
+	if err := s.checkThrottle(ip, row.Login, now); err != nil {
+		return AdminSession{}, err
+	}
+	if err := s.checkSecondFactor(row.AdminId, code); err != nil {
+		if errors.Is(err, ErrInvalidCode) {
+			s.Guard.Failed(FailedLogin{At: now, IP: ip, Login: row.Login, Reason: "wrong code"})
+		}
+		return AdminSession{}, err
+	}
+	s.Guard.Succeeded(ip, row.Login)
+	if err := repository.DeleteAdminSession(s.db(), hashToken(token)); err != nil {
+		return AdminSession{}, err
+	}
+	return s.openSession(row.AdminId, row.Login, row.MustChangePassword, false)
+}
+
+func (s *DefaultAppService) GetTOTPStatus(session AdminSession) (TOTPStatus, error) {
+	t, err := repository.GetAdminTOTP(s.db(), session.AdminID)
+	if err != nil {
+		return TOTPStatus{}, err
+	}
+	status := TOTPStatus{Enabled: t.Enabled}
+	switch {
+	case t.Enabled:
+		if status.RecoveryCodesLeft, err = repository.CountRecoveryCodes(s.db(), session.AdminID); err != nil {
+			return status, err
+		}
+	case t.Secret != "":
+		status.Enrollment = &TOTPEnrollment{Secret: t.Secret, URI: totp.URI(TOTPIssuer, session.Login, t.Secret)}
+	}
+	return status, nil
+}
+
+// BeginTOTPEnrollment generates a new secret for the admin. It is not used for
+// logins until EnableTOTP confirms the app produces matching codes.
+func (s *DefaultAppService) BeginTOTPEnrollment(session AdminSession) (TOTPEnrollment, error) {
+	t, err := repository.GetAdminTOTP(s.db(), session.AdminID)
+	if err != nil {
+		return TOTPEnrollment{}, err
+	}
+	if t.Enabled {
+		return TOTPEnrollment{}, ErrTOTPEnabled
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return TOTPEnrollment{}, err
+	}
+	if err := repository.SetAdminTOTPSecret(s.db(), session.AdminID, secret); err != nil {
+		log.Printf("BeginTOTPEnrollment SetAdminTOTPSecret error: %v", err)
+		return TOTPEnrollment{}, err
+	}
+	return TOTPEnrollment{Secret: secret, URI: totp.URI(TOTPIssuer, session.Login, secret)}, nil
+}
+
+// EnableTOTP turns two-factor authentication on once code matches the enrolled
+// secret, and returns fresh recovery codes. They are only stored hashed, so this
+// is the one time they can be shown.
+func (s *DefaultAppService) EnableTOTP(session AdminSession, code string) ([]string, error) {
+	t, err := repository.GetAdminTOTP(s.db(), session.AdminID)
+	if err != nil {
+		return nil, err
+	}
+	if t.Enabled {
+		return nil, ErrTOTPEnabled
+	}
+	if t.Secret == "" {
+		return nil, ErrTOTPNotEnrolled
+	}
+	counter, ok := totp.Verify(t.Secret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidCode
+	}
+
+	codes := make([]string, RecoveryCodeCount)
+	hashes := make([]string, RecoveryCodeCount)
+	for i := range codes {
+		if codes[i], err = newRecoveryCode(); err != nil {
+			return nil, err
+		}
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+	err = s.by(session, session.AdminID).inTx(func(s *DefaultAppService) error {
+		if err := repository.EnableAdminTOTP(s.db(), session.AdminID, counter, hashes); err != nil {
+			log.Printf("EnableTOTP EnableAdminTOTP error: %v", err)
+			return err
+		}
+		_, err := s.audit("enable-2fa", AuditAccount, session.Login, nil, nil)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableTOTP turns two-factor authentication off. It takes both the password and
+// a current code or recovery code, so a stolen session alone cannot do it.
+func (s *DefaultAppService) DisableTOTP(session AdminSession, password, code string) error {
+	hash, err := repository.GetAdminPasswordHash(s.db(), session.AdminID)
+	if err != nil {
+		return err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+		return ErrWrongPassword
+	}
+	if err := s.checkSecondFactor(session.AdminID, code); err != nil {
+		return err
+	}
+	return s.by(session, session.AdminID).inTx(func(s *DefaultAppService) error {
+		if err := repository.DisableAdminTOTP(s.db(), session.AdminID); err != nil {
+			return err
+		}
+		_, err := s.audit("disable-2fa", AuditAccount, session.Login, nil, nil)
+		return err
+	})
+}
+
+// ResetAdminTOTP turns off two-factor authentication for login, for an admin who
+// lost both the authenticator and the recovery codes.
+func (s *DefaultAppService) ResetAdminTOTP(login string) error {
+	admin, err := repository.GetAdminByLogin(s.db(), login)
+	if err != nil {
+		return err
+	}
+	if admin.Id == 0 {
+		return fmt.Errorf("no admin %q", login)
+	}
+	return s.fromCLI(admin.Id).inTx(func(s *DefaultAppService) error {
+		if err := repository.DisableAdminTOTP(s.db(), admin.Id); err != nil {
+			return err
+		}
+		_, err := s.audit("disable-2fa", AuditAccount, login, nil, nil)
+		return err
+	})
+}
+
+// checkSecondFactor accepts a TOTP code not used before or an unused recovery code,
+// which is then spent.
+func (s *DefaultAppService) checkSecondFactor(adminID int, code string) error {
+	t, err := repository.GetAdminTOTP(s.db(), adminID)
+	if err != nil {
+		return err
+	}
+	if !t.Enabled {
+		return ErrInvalidCode
+	}
+	if counter, ok := totp.Verify(t.Secret, code, time.Now()); ok {
+		fresh, err := repository.UseTOTPCounter(s.db(), adminID, counter)
+		if err != nil {
+			return err
+		}
+		if !fresh {
+			return ErrInvalidCode
+		}
+		return nil
+	}
+	used, err := repository.UseRecoveryCode(s.db(), adminID, hashRecoveryCode(code))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidCode
+	}
+	return nil
+}
+
+// recoveryAlphabet leaves out characters that are easy to misread.
+const recoveryAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// newRecoveryCode returns a random code such as "7KQ2M-XH4PA" (50 bits).
+func newRecoveryCode() (string, error) {
+	buf := make([]byte, 10)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for i, c := range buf {
+		if i == 5 {
+			b.WriteByte('-')
+		}
+		b.WriteByte(recoveryAlphabet[int(c)%len(recoveryAlphabet)])
+	}
+	return b.String(), nil
+}
+
+// hashRecoveryCode ignores case, spaces and dashes. Codes are random enough that
+// a plain SHA-256 is as good as a password hash here.
+func hashRecoveryCode(code string) string {
+	code = strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(code)))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,55 @@
+package app
+
+import (
+	"abtprj/internal/repository"
+	"abtprj/internal/totp"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestCheckSecondFactor_RefusesReplay accepts a code once and then refuses it,
+// and every code of an earlier step, as UseTOTPCounter records the last step
+// used. It needs a PostgreSQL database.
+func TestCheckSecondFactor_RefusesReplay(t *testing.T) {
+	db := testSchemaDB(t, "totp_replay")
+	if err := repository.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	var adminID int
+	if err := db.QueryRow("INSERT INTO admin (login, password_hash) VALUES ('alice', 'x') RETURNING id").Scan(&adminID); err != nil {
+		t.Fatal(err)
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := totp.Counter(time.Now())
+	if err := repository.SetAdminTOTPSecret(db, adminID, secret); err != nil {
+		t.Fatal(err)
+	}
+	if err := repository.EnableAdminTOTP(db, adminID, now-totp.Skew-1, nil); err != nil {
+		t.Fatal(err)
+	}
+	s := NewDefaultAppService(db)
+	code := func(counter int64) string {
+		c, err := totp.Code(secret, counter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	if err := s.checkSecondFactor(adminID, code(now)); err != nil {
+		t.Fatalf("first use of the current code: %v", err)
+	}
+	if err := s.checkSecondFactor(adminID, code(now)); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("second use of the current code: %v; want ErrInvalidCode", err)
+	}
+	if err := s.checkSecondFactor(adminID, code(now-1)); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("code of the step before, still within the skew: %v; want ErrInvalidCode", err)
+	}
+	if err := s.checkSecondFactor(adminID, code(now+1)); err != nil {
+		t.Errorf("code of the next step: %v; want it accepted once", err)
+	}
+}
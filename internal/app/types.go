@@ -6,10 +6,13 @@ import (
 )
 
 type Task struct {
+	ID          int // zero for a task not stored yet
 	Name        string
 	Description string
 	Status      string
 	DoneAt      *time.Time
+	Estimate    time.Duration // zero when the task was not estimated
+	Visibility  string        // "" follows the account's default
 }
 
 type WorkSession struct {
@@ -25,4 +28,6 @@ type Goal struct {
 	Status      string
 	DoneAt      *sql.NullTime
 	DueAt       *time.Time
+	State       string // one of the Goal* deadline states
+	Visibility  string // "" follows the account's default
 }
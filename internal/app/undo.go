@@ -0,0 +1,239 @@
+package app
+
+import (
+	"abtprj/internal/repository"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// UndoWindow is how long after a change it can still be undone.
+const UndoWindow = 5 * time.Minute
+
+var (
+	ErrInvalidUndo  = errors.New("nothing to undo: the change is unknown, already undone or too old")
+	ErrUndoConflict = errors.New("cannot undo: it has changed since")
+)
+
+// Undo is a change that Token reverts until ExpiresAt. The zero Undo is a
+// change that cannot be undone, such as one that changed nothing.
+type Undo struct {
+	Token     string
+	Summary   string // the change, as `Task "Taxes" completed`
+	ExpiresAt time.Time
+}
+
+// undoable issues the token that reverts the change e records. A change that
+// was not recorded cannot be undone, and neither can one whose token could not
+// be stored; it has happened all the same, so that is only logged.
+func (s *DefaultAppService) undoable(e repository.AuditEntry) Undo {
+	if e.Id == 0 {
+		return Undo{}
+	}
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		log.Printf("undoable rand error: %v", err)
+		return Undo{}
+	}
+	token := hex.EncodeToString(buf)
+	expiresAt := time.Now().Add(UndoWindow)
+	if err := repository.CreateUndoToken(s.db(), hashToken(token), s.userID, e.Id, expiresAt); err != nil {
+		log.Printf("undoable CreateUndoToken error: %v", err)
+		return Undo{}
+	}
+	return Undo{Token: token, Summary: undoSummary(e), ExpiresAt: expiresAt.In(s.loc)}
+}
+
+// GetUndo returns the change token can still undo, or ErrInvalidUndo.
+func (s *DefaultAppService) GetUndo(token string) (Undo, error) {
+	if token == "" {
+		return Undo{}, ErrInvalidUndo
+	}
+	e, expiresAt, ok, err := repository.GetUndoToken(s.db(), hashToken(token), s.userID)
+	if err != nil {
+		log.Printf("GetUndo exec error: %v", err)
+		return Undo{}, err
+	}
+	if !ok {
+		return Undo{}, ErrInvalidUndo
+	}
+	return Undo{Token: token, Summary: undoSummary(e), ExpiresAt: expiresAt.In(s.loc)}, nil
+}
+
+// UndoChange reverts the change token was issued for: a task or goal is open
+// again, a started session is gone and an ended one runs again. It returns
+// ErrUndoConflict, and changes nothing, if the item has changed since; a
+// token is good for one try.
+func (s *DefaultAppService) UndoChange(token string) error {
+	if token == "" {
+		return ErrInvalidUndo
+	}
+	e, _, ok, err := repository.GetUndoToken(s.db(), hashToken(token), s.userID)
+	if err != nil {
+		log.Printf("UndoChange GetUndoToken error: %v", err)
+		return err
+	}
+	if !ok {
+		return ErrInvalidUndo
+	}
+	if used, err := repository.UseUndoToken(s.db(), hashToken(token), s.userID); err != nil || !used {
+		if err != nil {
+			log.Printf("UndoChange UseUndoToken error: %v", err)
+			return err
+		}
+		return ErrInvalidUndo
+	}
+
+	return s.inTx(func(s *DefaultAppService) error {
+		switch e.Entity + " " + e.Action {
+		case AuditTask + " complete":
+			return s.undoTask(e)
+		case AuditGoal + " complete":
+			return s.undoGoal(e)
+		case AuditSession + " start":
+			return s.undoSessionStart(e)
+		case AuditSession + " end":
+			return s.undoSessionEnd(e)
+		}
+		return ErrInvalidUndo
+	})
+}
+
+// unchanged reports whether current is still what e recorded after the change.
+func unchanged(e repository.AuditEntry, current any) bool {
+	return e.After.Valid && auditJSON(current).String == e.After.String
+}
+
+func (s *DefaultAppService) undoTask(e repository.AuditEntry) error {
+	var before taskRecord
+	if err := json.Unmarshal([]byte(e.Before.String), &before); err != nil {
+		return fmt.Errorf("undo %d: %w", e.Id, err)
+	}
+	current, err := repository.GetTask(s.db(), s.userID, before.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrUndoConflict
+	}
+	if err != nil {
+		return err
+	}
+	if !unchanged(e, newTaskRecord(current)) {
+		return ErrUndoConflict
+	}
+	sessionID := sql.NullInt64{Int64: before.SessionID, Valid: before.SessionID != 0}
+	if err := repository.RestoreTask(s.db(), s.userID, before.ID, before.Status, toNullTime(before.DoneAt), sessionID); err != nil {
+		log.Printf("UndoChange RestoreTask error: %v", err)
+		return err
+	}
+	_, err = s.auditTask("undo", current)
+	return err
+}
+
+func (s *DefaultAppService) undoGoal(e repository.AuditEntry) error {
+	var before goalRecord
+	if err := json.Unmarshal([]byte(e.Before.String), &before); err != nil {
+		return fmt.Errorf("undo %d: %w", e.Id, err)
+	}
+	current, err := repository.GetGoal(s.db(), s.userID, before.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrUndoConflict
+	}
+	if err != nil {
+		return err
+	}
+	if !unchanged(e, newGoalRecord(current)) {
+		return ErrUndoConflict
+	}
+	if err := repository.RestoreGoal(s.db(), s.userID, before.ID, before.Status, toNullTime(before.DoneAt)); err != nil {
+		log.Printf("UndoChange RestoreGoal error: %v", err)
+		return err
+	}
+	_, err = s.auditGoal("undo", current)
+	return err
+}
+
+// undoSessionStart removes the session the change started, unless it has
+// ended or tasks were completed in it since.
+func (s *DefaultAppService) undoSessionStart(e repository.AuditEntry) error {
+	var started sessionRecord
+	if err := json.Unmarshal([]byte(e.After.String), &started); err != nil {
+		return fmt.Errorf("undo %d: %w", e.Id, err)
+	}
+	current, err := repository.GetWorkSession(s.db(), s.userID, started.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrUndoConflict
+	}
+	if err != nil {
+		return err
+	}
+	if !unchanged(e, newSessionRecord(current)) {
+		return ErrUndoConflict
+	}
+	deleted, err := repository.DeleteActiveWorkSession(s.db(), s.userID, started.ID)
+	if err != nil {
+		log.Printf("UndoChange DeleteActiveWorkSession error: %v", err)
+		return err
+	}
+	if !deleted {
+		return ErrUndoConflict
+	}
+	_, err = s.audit("undo", AuditSession, started.ID, newSessionRecord(current), nil)
+	return err
+}
+
+// undoSessionEnd makes the session the change ended run again, unless
+// another one has been started since.
+func (s *DefaultAppService) undoSessionEnd(e repository.AuditEntry) error {
+	var ended sessionRecord
+	if err := json.Unmarshal([]byte(e.After.String), &ended); err != nil {
+		return fmt.Errorf("undo %d: %w", e.Id, err)
+	}
+	current, err := repository.GetWorkSession(s.db(), s.userID, ended.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrUndoConflict
+	}
+	if err != nil {
+		return err
+	}
+	if !unchanged(e, newSessionRecord(current)) {
+		return ErrUndoConflict
+	}
+	reopened, err := repository.ReopenWorkSession(s.db(), s.userID, ended.ID)
+	if err != nil {
+		log.Printf("UndoChange ReopenWorkSession error: %v", err)
+		return err
+	}
+	if !reopened {
+		return ErrUndoConflict
+	}
+	_, err = s.auditSession("undo", current)
+	return err
+}
+
+// undoSummary describes the change e records for the undo prompt.
+func undoSummary(e repository.AuditEntry) string {
+	var named struct{ Name string }
+	json.Unmarshal([]byte(e.After.String), &named)
+	switch e.Entity + " " + e.Action {
+	case AuditTask + " complete":
+		return fmt.Sprintf("Task %q completed", named.Name)
+	case AuditGoal + " complete":
+		return fmt.Sprintf("Goal %q completed", named.Name)
+	case AuditSession + " start":
+		return "Work session started"
+	case AuditSession + " end":
+		return "Work session ended"
+	}
+	return e.Action + " " + e.Entity
+}
+
+func toNullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: *t, Valid: true}
+}
@@ -0,0 +1,211 @@
+package app
+
+import (
+	"abtprj/internal/repository"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// InviteTTL is how long an invite link can be used to register.
+const InviteTTL = 7 * 24 * time.Hour
+
+var (
+	ErrNoUser        = errors.New("no such user")
+	ErrInvalidInvite = errors.New("invite is unknown, used or expired")
+	ErrLoginTaken    = errors.New("login is already taken")
+	ErrInvalidToken  = errors.New("invalid token")
+	// ErrInvalidUsername is wrapped by logins that cannot be used for a new account.
+	ErrInvalidUsername = errors.New("invalid login")
+)
+
+// loginPattern keeps logins usable as the {username} of /u/{username}/.
+var loginPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{1,31}$`)
+
+// Invite is a registration link one user gave out. The token itself is only
+// shown when the invite is created.
+type Invite struct {
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedBy    string // login registered with it, empty while unused
+	UsedAt    *time.Time
+	Expired   bool
+}
+
+// ValidateLogin checks a login for a new account: 2 to 32 lowercase letters,
+// digits, '-' or '_', starting with a letter or digit.
+func ValidateLogin(login string) error {
+	if !loginPattern.MatchString(login) {
+		return fmt.Errorf("%w: use 2 to 32 lowercase letters, digits, '-' or '_', starting with a letter or digit", ErrInvalidUsername)
+	}
+	return nil
+}
+
+// ForUser returns a service whose tracker data (tasks, goals, sessions and
+// settings) is that of userID. The receiver is left unchanged.
+func (s *DefaultAppService) ForUser(userID int) AppService {
+	return s.WithUser(userID)
+}
+
+// WithUser is ForUser for callers that need the concrete service.
+func (s *DefaultAppService) WithUser(userID int) *DefaultAppService {
+	scoped := *s
+	scoped.userID = userID
+	return &scoped
+}
+
+// GetUser returns the account with login, or ErrNoUser.
+func (s *DefaultAppService) GetUser(login string) (Admin, error) {
+	a, err := repository.GetAdminByLogin(s.db(), login)
+	if err != nil {
+		log.Printf("GetUser exec error: %v", err)
+		return Admin{}, err
+	}
+	if a.Id == 0 {
+		return Admin{}, ErrNoUser
+	}
+	return Admin{ID: a.Id, Login: a.Login, CreatedAt: a.CreatedAt.In(s.loc)}, nil
+}
+
+// GetOwner returns the first account, whose pages are served at the site root,
+// or ErrNoUser on a fresh install.
+func (s *DefaultAppService) GetOwner() (Admin, error) {
+	a, err := repository.GetOwner(s.db())
+	if err != nil {
+		log.Printf("GetOwner exec error: %v", err)
+		return Admin{}, err
+	}
+	if a.Id == 0 {
+		return Admin{}, ErrNoUser
+	}
+	return Admin{ID: a.Id, Login: a.Login, CreatedAt: a.CreatedAt.In(s.loc)}, nil
+}
+
+// UserForAPIToken returns the account whose API token this is, with its id
+// and login only, or ErrInvalidToken.
+func (s *DefaultAppService) UserForAPIToken(token string) (Admin, error) {
+	if token == "" {
+		return Admin{}, ErrInvalidToken
+	}
+	a, ok, err := repository.FindSettingOwner(s.db(), apiTokenKey, token)
+	if err != nil {
+		log.Printf("UserForAPIToken exec error: %v", err)
+		return Admin{}, err
+	}
+	if !ok {
+		return Admin{}, ErrInvalidToken
+	}
+	return Admin{ID: a.Id, Login: a.Login}, nil
+}
+
+// CreateInvite issues a registration token valid for InviteTTL.
+func (s *DefaultAppService) CreateInvite(session AdminSession) (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+	expiresAt := time.Now().Add(InviteTTL)
+	err := s.by(session, session.AdminID).inTx(func(s *DefaultAppService) error {
+		if err := repository.CreateInvite(s.db(), hashToken(token), session.AdminID, expiresAt); err != nil {
+			log.Printf("CreateInvite exec error: %v", err)
+			return err
+		}
+		_, err := s.audit("create", AuditInvite, nil, nil, map[string]time.Time{"expires_at": expiresAt})
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// ListInvites returns the invites session's user issued, newest first.
+func (s *DefaultAppService) ListInvites(session AdminSession) ([]Invite, error) {
+	rows, err := repository.ListInvites(s.db(), session.AdminID)
+	if err != nil {
+		log.Printf("ListInvites exec error: %v", err)
+		return nil, err
+	}
+	now := time.Now()
+	invites := make([]Invite, len(rows))
+	for i, r := range rows {
+		invites[i] = Invite{
+			CreatedAt: r.CreatedAt.In(s.loc),
+			ExpiresAt: r.ExpiresAt.In(s.loc),
+			UsedBy:    r.UsedBy.String,
+			Expired:   !r.UsedAt.Valid && !r.ExpiresAt.After(now),
+		}
+		if r.UsedAt.Valid {
+			t := r.UsedAt.Time.In(s.loc)
+			invites[i].UsedAt = &t
+		}
+	}
+	return invites, nil
+}
+
+// CheckInvite returns ErrInvalidInvite unless token can still be used to register.
+func (s *DefaultAppService) CheckInvite(token string) error {
+	if token == "" {
+		return ErrInvalidInvite
+	}
+	valid, err := repository.InviteValid(s.db(), hashToken(token))
+	if err != nil {
+		log.Printf("CheckInvite exec error: %v", err)
+		return err
+	}
+	if !valid {
+		return ErrInvalidInvite
+	}
+	return nil
+}
+
+// Register creates an account with an invite, which can be used only once.
+func (s *DefaultAppService) Register(invite, login, password string) error {
+	if err := ValidateLogin(login); err != nil {
+		return err
+	}
+	if err := ValidatePassword(login, password); err != nil {
+		return err
+	}
+	if err := s.CheckInvite(invite); err != nil {
+		return err
+	}
+	existing, err := repository.GetAdminByLogin(s.db(), login)
+	if err != nil {
+		return err
+	}
+	if existing.Id != 0 {
+		return fmt.Errorf("%w: %q", ErrLoginTaken, login)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	return s.inTx(func(s *DefaultAppService) error {
+		ok, err := repository.RegisterWithInvite(s.db(), hashToken(invite), login, hash)
+		if err != nil {
+			log.Printf("Register exec error: %v", err)
+			return err
+		}
+		if !ok {
+			return ErrInvalidInvite
+		}
+		account, err := repository.GetAdminByLogin(s.db(), login)
+		if err != nil {
+			log.Printf("Register GetAdminByLogin error: %v", err)
+			return err
+		}
+		registered := s.WithUser(account.Id)
+		registered.actor.UserID, registered.actor.Login = account.Id, login
+		_, err = registered.audit("register", AuditAccount, login, nil, map[string]string{"login": login})
+		return err
+	})
+}
@@ -14,10 +14,13 @@ func ConvertRepoTasks(repoTasks []repository.Task) []Task {
 			doneAt = &t
 		}
 		out[i] = Task{
+			ID:          rt.Id,
 			Name:        rt.Name,
 			Description: rt.Description,
 			Status:      rt.Status,
 			DoneAt:      doneAt,
+			Estimate:    time.Duration(rt.Estimate.Int64) * time.Minute,
+			Visibility:  rt.Visibility.String,
 		}
 	}
 	return out
@@ -40,21 +43,99 @@ func ConvertRepoSessions(repoSessions []repository.WorkSession) []WorkSession {
 	return out
 }
 
-func ConvertRepoGoals(repoGoals []repository.Goal) []Goal {
+// ConvertRepoGoals converts goals and works out their deadline state, with
+// days counted in loc.
+func ConvertRepoGoals(repoGoals []repository.Goal, loc *time.Location) []Goal {
+	now := time.Now()
 	out := make([]Goal, len(repoGoals))
 	for i, goal := range repoGoals {
+		var dueAt *time.Time
+		if goal.DueAt.Valid {
+			t := goal.DueAt.Time
+			dueAt = &t
+		}
 		out[i] = Goal{
 			ID:          goal.Id,
 			Name:        goal.Name,
 			Description: goal.Description,
 			Status:      goal.Status,
 			DoneAt:      &goal.DoneAt,
-			DueAt:       &goal.DueAt.Time,
+			DueAt:       dueAt,
+			Visibility:  goal.Visibility.String,
 		}
+		out[i].State = GoalState(out[i], now, loc)
 	}
 	return out
 }
 
+// gridStart is the Monday the heatmap grid of year starts on, the one on or
+// before January 1.
+func gridStart(year int) time.Time {
+	jan1 := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	return jan1.AddDate(0, 0, -((int(jan1.Weekday()) + 6) % 7))
+}
+
+// gridDate is the date at index i of the heatmap grid of year, the inverse of
+// StatIndex.
+func gridDate(year, i int) string {
+	return gridStart(year).AddDate(0, 0, i).Format("2006-01-02")
+}
+
+// GridWeeks is the number of week columns in the heatmap grid of year: 53,
+// or 54 when a leap year starts on a Sunday.
+func GridWeeks(year int) int {
+	return StatIndex(time.Date(year, time.December, 31, 0, 0, 0, 0, time.UTC))/7 + 1
+}
+
+// StatIndex returns the position of day d in the slices produced by
+// GetDayTaskStats and GetDaySessionStats for d's year. The grid has a column
+// for every Monday-to-Sunday week that holds a day of the year, so its first
+// and last columns can start and end with days of the years around it.
+func StatIndex(d time.Time) int {
+	day := time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.UTC)
+	return int(day.Sub(gridStart(d.Year())) / (24 * time.Hour))
+}
+
+// workedOn returns the session time on day d from the stats of its year,
+// this year's or the one before.
+func workedOn(stats, prevYear []DaySessionsStat, year int, d time.Time) time.Duration {
+	if d.Year() != year {
+		stats = prevYear
+	}
+	if idx := StatIndex(d); idx < len(stats) {
+		return stats[idx].SessionDur
+	}
+	return 0
+}
+
+// WeekDuration sums the session time from Monday of d's week up to and
+// including d. stats are of d's year and prevYear of the year before, for a
+// week that starts in December.
+func WeekDuration(stats, prevYear []DaySessionsStat, d time.Time) time.Duration {
+	var total time.Duration
+	monday := d.AddDate(0, 0, -((int(d.Weekday()) + 6) % 7))
+	for day := monday; !day.After(d); day = day.AddDate(0, 0, 1) {
+		total += workedOn(stats, prevYear, d.Year(), day)
+	}
+	return total
+}
+
+// CurrentStreak counts consecutive days with recorded work ending at d,
+// going on into the year before through prevYear. A day without work yet
+// does not break the streak until it is over.
+func CurrentStreak(stats, prevYear []DaySessionsStat, d time.Time) int {
+	day := d
+	if workedOn(stats, prevYear, d.Year(), day) == 0 {
+		day = day.AddDate(0, 0, -1)
+	}
+	streak := 0
+	for day.Year() >= d.Year()-1 && workedOn(stats, prevYear, d.Year(), day) > 0 {
+		streak++
+		day = day.AddDate(0, 0, -1)
+	}
+	return streak
+}
+
 /*
 type Goal struct {
 	Id          int
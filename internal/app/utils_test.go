@@ -0,0 +1,88 @@
+package app
+
+import (
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 12, 0, 0, 0, time.UTC)
+}
+
+func TestStatIndex_YearBoundary(t *testing.T) {
+	tests := []struct {
+		day  time.Time
+		want int
+	}{
+		{date(2024, time.January, 1), 0},     // a Monday
+		{date(2024, time.December, 30), 364}, // ISO week 1 of 2025
+		{date(2024, time.December, 31), 365},
+		{date(2021, time.January, 1), 4}, // ISO week 53 of 2020
+		{date(2022, time.January, 1), 5}, // ISO week 52 of 2021
+		{date(2026, time.January, 1), 3},
+		{date(2026, time.December, 31), 367},
+		{date(2012, time.December, 31), 371}, // a leap year starting on a Sunday
+	}
+	for _, tt := range tests {
+		if got := StatIndex(tt.day); got != tt.want {
+			t.Errorf("StatIndex(%s) = %d; want %d", tt.day.Format("2006-01-02"), got, tt.want)
+		}
+	}
+}
+
+func TestGridWeeks(t *testing.T) {
+	for year, want := range map[int]int{2012: 54, 2021: 53, 2024: 53, 2026: 53} {
+		if got := GridWeeks(year); got != want {
+			t.Errorf("GridWeeks(%d) = %d; want %d", year, got, want)
+		}
+		if n := len(generateEmptySessionStats(year)); StatIndex(date(year, time.December, 31)) >= n {
+			t.Errorf("%d: December 31 falls outside the %d days of the grid", year, n)
+		}
+	}
+}
+
+func TestEmptyStats_DatedByGridPosition(t *testing.T) {
+	tasks, sessions := generateEmptyDayStats(2026), generateEmptySessionStats(2026)
+	for _, d := range []time.Time{date(2026, time.January, 1), date(2026, time.June, 15), date(2026, time.December, 31)} {
+		want := d.Format("2006-01-02")
+		if got := tasks[StatIndex(d)].Date; got != want {
+			t.Errorf("task cell of %s is dated %q", want, got)
+		}
+		if got := sessions[StatIndex(d)].Date; got != want {
+			t.Errorf("session cell of %s is dated %q", want, got)
+		}
+	}
+	// the first column starts with the last days of 2025
+	if got := tasks[0].Date; got != "2025-12-29" {
+		t.Errorf("first cell is dated %q; want Monday 2025-12-29", got)
+	}
+}
+
+func TestStreakAndWeek_YearBoundary(t *testing.T) {
+	stats := map[int][]DaySessionsStat{2025: generateEmptySessionStats(2025), 2026: generateEmptySessionStats(2026)}
+	for _, d := range []time.Time{date(2025, time.December, 30), date(2025, time.December, 31), date(2026, time.January, 1), date(2026, time.January, 2)} {
+		stats[d.Year()][StatIndex(d)].SessionDur = time.Hour
+	}
+
+	friday := date(2026, time.January, 2)
+	if got := CurrentStreak(stats[2026], stats[2025], friday); got != 4 {
+		t.Errorf("streak on %s = %d; want 4 reaching back into December", friday.Format("2006-01-02"), got)
+	}
+	if got := WeekDuration(stats[2026], stats[2025], friday); got != 4*time.Hour {
+		t.Errorf("week of %s = %v; want 4h from Monday December 29", friday.Format("2006-01-02"), got)
+	}
+
+	// the days of December in the first column of 2026 are last year's
+	if got := WeekDuration(stats[2026], nil, friday); got != 2*time.Hour {
+		t.Errorf("week without last year = %v; want 2h", got)
+	}
+
+	stats[2026][StatIndex(friday)].SessionDur = 0
+	if got := CurrentStreak(stats[2026], stats[2025], friday); got != 3 {
+		t.Errorf("streak before working today = %d; want 3", got)
+	}
+	stats[2026][StatIndex(date(2026, time.January, 1))].SessionDur = 0
+	if got := CurrentStreak(stats[2026], stats[2025], friday); got != 0 {
+		t.Errorf("streak after a day off = %d; want 0", got)
+	}
+}
@@ -0,0 +1,187 @@
+package app
+
+import (
+	"abtprj/internal/repository"
+	"database/sql"
+	"errors"
+	"log"
+)
+
+// Who sees a task or goal on the public pages. The account always sees all of
+// them; a redacted item still counts in the stats but shows no name.
+const (
+	VisibilityPublic   = "public"
+	VisibilityPrivate  = "private"
+	VisibilityRedacted = "redacted-title"
+
+	defaultVisibilityKey = "visibility.default"
+
+	redactedTaskName = "private task"
+	redactedGoalName = "private goal"
+)
+
+var (
+	ErrInvalidVisibility = errors.New("visibility must be public, private or redacted-title")
+	ErrNoItem            = errors.New("no such task or goal")
+)
+
+func validVisibility(v string) bool {
+	return v == VisibilityPublic || v == VisibilityPrivate || v == VisibilityRedacted
+}
+
+// nullVisibility stores "" as NULL, for items that follow the account's default.
+func nullVisibility(v string) (sql.NullString, error) {
+	if v == "" {
+		return sql.NullString{}, nil
+	}
+	if !validVisibility(v) {
+		return sql.NullString{}, ErrInvalidVisibility
+	}
+	return sql.NullString{String: v, Valid: true}, nil
+}
+
+// PublicView returns a service that shows the tracker as visitors of the
+// public pages see it: private tasks and goals are left out everywhere, stats
+// included, and redacted ones keep counting but lose their names.
+func (s *DefaultAppService) PublicView() AppService {
+	public := *s
+	public.public = true
+	return &public
+}
+
+// GetDefaultVisibility returns the visibility of items that have none of their
+// own; it is public until the account changes it.
+func (s *DefaultAppService) GetDefaultVisibility() (string, error) {
+	v, ok, err := repository.GetSetting(s.db(), s.userID, defaultVisibilityKey)
+	if err != nil {
+		return VisibilityPublic, err
+	}
+	if !ok || !validVisibility(v) {
+		return VisibilityPublic, nil
+	}
+	return v, nil
+}
+
+func (s *DefaultAppService) SetDefaultVisibility(v string) error {
+	if !validVisibility(v) {
+		return ErrInvalidVisibility
+	}
+	return s.inTx(func(s *DefaultAppService) error {
+		before, err := s.GetDefaultVisibility()
+		if err != nil {
+			return err
+		}
+		if err := repository.SetSetting(s.db(), s.userID, defaultVisibilityKey, v); err != nil {
+			return err
+		}
+		_, err = s.audit("update", AuditSetting, defaultVisibilityKey, before, v)
+		return err
+	})
+}
+
+// SetTaskVisibility changes who sees the task id; "" makes it follow the
+// account's default again.
+func (s *DefaultAppService) SetTaskVisibility(id int, visibility string) error {
+	v, err := nullVisibility(visibility)
+	if err != nil {
+		return err
+	}
+	return s.inTx(func(s *DefaultAppService) error {
+		before, err := repository.GetTask(s.db(), s.userID, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoItem
+		}
+		if err != nil {
+			log.Printf("SetTaskVisibility GetTask error: %v", err)
+			return err
+		}
+		if err := repository.SetTaskVisibility(s.db(), s.userID, id, v); err != nil {
+			log.Printf("SetTaskVisibility exec error: %v", err)
+			return err
+		}
+		_, err = s.auditTask("update", before)
+		return err
+	})
+}
+
+// SetGoalVisibility is SetTaskVisibility for goals.
+func (s *DefaultAppService) SetGoalVisibility(id int, visibility string) error {
+	v, err := nullVisibility(visibility)
+	if err != nil {
+		return err
+	}
+	return s.inTx(func(s *DefaultAppService) error {
+		before, err := repository.GetGoal(s.db(), s.userID, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNoItem
+		}
+		if err != nil {
+			log.Printf("SetGoalVisibility GetGoal error: %v", err)
+			return err
+		}
+		if err := repository.SetGoalVisibility(s.db(), s.userID, id, v); err != nil {
+			log.Printf("SetGoalVisibility exec error: %v", err)
+			return err
+		}
+		_, err = s.auditGoal("update", before)
+		return err
+	})
+}
+
+// visibilityRule returns what a public view does with an item stored with
+// visibility v. It is nil for other services, which show everything.
+func (s *DefaultAppService) visibilityRule() (func(v sql.NullString) string, error) {
+	if !s.public {
+		return nil, nil
+	}
+	def, err := s.GetDefaultVisibility()
+	if err != nil {
+		log.Printf("GetDefaultVisibility error: %v", err)
+		return nil, err
+	}
+	return func(v sql.NullString) string {
+		if v.Valid && validVisibility(v.String) {
+			return v.String
+		}
+		return def
+	}, nil
+}
+
+// visibleTasks drops the tasks a public view may not show and hides the names
+// of redacted ones. Other services get tasks back as they are.
+func (s *DefaultAppService) visibleTasks(tasks []repository.Task) ([]repository.Task, error) {
+	rule, err := s.visibilityRule()
+	if err != nil || rule == nil {
+		return tasks, err
+	}
+	out := tasks[:0:0]
+	for _, t := range tasks {
+		switch rule(t.Visibility) {
+		case VisibilityPrivate:
+			continue
+		case VisibilityRedacted:
+			t.Name, t.Description = redactedTaskName, ""
+		}
+		out = append(out, t)
+	}
+	return out, nil
+}
+
+// visibleGoals is visibleTasks for goals.
+func (s *DefaultAppService) visibleGoals(goals []repository.Goal) ([]repository.Goal, error) {
+	rule, err := s.visibilityRule()
+	if err != nil || rule == nil {
+		return goals, err
+	}
+	out := goals[:0:0]
+	for _, g := range goals {
+		switch rule(g.Visibility) {
+		case VisibilityPrivate:
+			continue
+		case VisibilityRedacted:
+			g.Name, g.Description = redactedGoalName, ""
+		}
+		out = append(out, g)
+	}
+	return out, nil
+}
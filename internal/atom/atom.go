@@ -0,0 +1,104 @@
+// Package atom writes RFC 4287 Atom feeds.
+package atom
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+type Entry struct {
+	ID        string // a URI that never changes for this entry
+	Title     string
+	Link      string
+	Summary   string
+	Updated   time.Time
+	Published time.Time
+	Category  string
+}
+
+type Feed struct {
+	ID      string
+	Title   string
+	Author  string
+	Link    string // the HTML page the feed mirrors
+	Self    string // the feed's own URL
+	Updated time.Time
+	Entries []Entry
+}
+
+type xmlLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type xmlText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type xmlCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type xmlEntry struct {
+	ID        string       `xml:"id"`
+	Title     xmlText      `xml:"title"`
+	Link      xmlLink      `xml:"link"`
+	Updated   string       `xml:"updated"`
+	Published string       `xml:"published,omitempty"`
+	Category  *xmlCategory `xml:"category"`
+	Summary   *xmlText     `xml:"summary"`
+}
+
+type xmlFeed struct {
+	XMLName xml.Name   `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string     `xml:"id"`
+	Title   string     `xml:"title"`
+	Updated string     `xml:"updated"`
+	Author  string     `xml:"author>name"`
+	Links   []xmlLink  `xml:"link"`
+	Entries []xmlEntry `xml:"entry"`
+}
+
+// Render serialises the feed. Titles and summaries are sent as plain text.
+func (f Feed) Render() ([]byte, error) {
+	out := xmlFeed{
+		ID:      f.ID,
+		Title:   f.Title,
+		Updated: stamp(f.Updated),
+		Author:  f.Author,
+		Links: []xmlLink{
+			{Rel: "alternate", Type: "text/html", Href: f.Link},
+			{Rel: "self", Type: "application/atom+xml", Href: f.Self},
+		},
+	}
+	for _, e := range f.Entries {
+		x := xmlEntry{
+			ID:      e.ID,
+			Title:   xmlText{Type: "text", Body: e.Title},
+			Link:    xmlLink{Rel: "alternate", Type: "text/html", Href: e.Link},
+			Updated: stamp(e.Updated),
+		}
+		if !e.Published.IsZero() {
+			x.Published = stamp(e.Published)
+		}
+		if e.Category != "" {
+			x.Category = &xmlCategory{Term: e.Category}
+		}
+		if e.Summary != "" {
+			x.Summary = &xmlText{Type: "text", Body: e.Summary}
+		}
+		out.Entries = append(out.Entries, x)
+	}
+
+	body, err := xml.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(body, '\n')...), nil
+}
+
+func stamp(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,125 @@
+// Package client talks to a running abtprj server over its /api/ endpoints.
+package client
+
+import (
+	"abtprj/internal/api"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type Client struct {
+	Server string // base URL, e.g. https://log.example.com
+	Token  string
+	HTTP   *http.Client
+}
+
+func New(cfg Config) *Client {
+	return &Client{
+		Server: strings.TrimRight(cfg.Server, "/"),
+		Token:  cfg.Token,
+		HTTP:   &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// Error is a non-2xx answer from the server.
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("server answered %d: %s", e.StatusCode, e.Message)
+}
+
+func (c *Client) Status() (api.Status, error) {
+	var st api.Status
+	return st, c.do(http.MethodGet, "/api/status", nil, &st)
+}
+
+func (c *Client) Start() (api.Status, error) {
+	var st api.Status
+	return st, c.do(http.MethodPost, "/api/session/start", nil, &st)
+}
+
+func (c *Client) Stop() (api.Status, error) {
+	var st api.Status
+	return st, c.do(http.MethodPost, "/api/session/stop", nil, &st)
+}
+
+func (c *Client) Today() (api.Today, error) {
+	var today api.Today
+	return today, c.do(http.MethodGet, "/api/today", nil, &today)
+}
+
+func (c *Client) Tasks() ([]api.Task, error) {
+	var tasks []api.Task
+	return tasks, c.do(http.MethodGet, "/api/tasks", nil, &tasks)
+}
+
+func (c *Client) AddTask(task api.NewTask) (api.Task, error) {
+	var created api.Task
+	return created, c.do(http.MethodPost, "/api/tasks", task, &created)
+}
+
+func (c *Client) CompleteTask(name string) (api.Task, error) {
+	var done api.Task
+	return done, c.do(http.MethodPost, "/api/tasks/done", api.TaskRef{Name: name}, &done)
+}
+
+// Goals lists open goals, or every goal when all is set.
+func (c *Client) Goals(all bool) ([]api.Goal, error) {
+	status := "todo"
+	if all {
+		status = "all"
+	}
+	var goals []api.Goal
+	return goals, c.do(http.MethodGet, "/api/goals?status="+url.QueryEscape(status), nil, &goals)
+}
+
+func (c *Client) do(method, path string, body, out any) error {
+	if c.Server == "" || c.Token == "" {
+		return fmt.Errorf("not logged in; run `abtprj login -server URL -token TOKEN` first")
+	}
+
+	var reqBody io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(raw)
+	}
+	req, err := http.NewRequest(method, c.Server+path, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr api.Error
+		if json.NewDecoder(resp.Body).Decode(&apiErr) != nil || apiErr.Error == "" {
+			apiErr.Error = http.StatusText(resp.StatusCode)
+		}
+		return &Error{StatusCode: resp.StatusCode, Message: apiErr.Error}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
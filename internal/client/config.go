@@ -0,0 +1,68 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Config is where the client finds the server. It is stored in the user's config
+// directory; ABTPRJ_SERVER and ABTPRJ_TOKEN override the stored values.
+type Config struct {
+	Server string `json:"server"`
+	Token  string `json:"token"`
+}
+
+// ConfigPath is the file the client configuration is kept in.
+func ConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "abtprj", "client.json"), nil
+}
+
+// LoadConfig reads the stored configuration and applies the environment. A missing
+// file is not an error.
+func LoadConfig() (Config, error) {
+	var cfg Config
+	path, err := ConfigPath()
+	if err != nil {
+		return cfg, err
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return cfg, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(raw, &cfg); err != nil {
+			return cfg, err
+		}
+	}
+
+	if v := os.Getenv("ABTPRJ_SERVER"); v != "" {
+		cfg.Server = v
+	}
+	if v := os.Getenv("ABTPRJ_TOKEN"); v != "" {
+		cfg.Token = v
+	}
+	return cfg, nil
+}
+
+// SaveConfig writes cfg readable by the current user only, since it holds the token.
+func SaveConfig(cfg Config) (string, error) {
+	path, err := ConfigPath()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return "", err
+	}
+	raw, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return path, os.WriteFile(path, append(raw, '\n'), 0o600)
+}
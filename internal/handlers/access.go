@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"abtprj/internal/app"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// adminPermissions is the permission each admin route needs on the account the
+// session works on, keyed by "METHOD path". app.PermNone routes only touch the
+// logged-in admin's own things, or check the instance owner themselves. Routes
+// that are not listed are not found.
+var adminPermissions = map[string]app.Permission{
+	"GET /admin/":                         app.PermView,
+	"GET /admin/get-work-status":          app.PermView,
+	"GET /admin/estimates":                app.PermView,
+	"GET /admin/export":                   app.PermView,
+	"GET /admin/journal":                  app.PermView,
+	"POST /admin/add-task":                app.PermEdit,
+	"POST /admin/complete-task":           app.PermEdit,
+	"POST /admin/create-goal":             app.PermEdit,
+	"POST /admin/complete-goal":           app.PermEdit,
+	"POST /admin/start-work-session":      app.PermEdit,
+	"POST /admin/end-work-session":        app.PermEdit,
+	"POST /admin/task-visibility":         app.PermEdit,
+	"POST /admin/goal-visibility":         app.PermEdit,
+	"POST /admin/undo":                    app.PermEdit,
+	"GET /admin/import":                   app.PermEdit,
+	"POST /admin/import/preview":          app.PermEdit,
+	"POST /admin/import/apply":            app.PermEdit,
+	"GET /admin/import-time":              app.PermEdit,
+	"POST /admin/import-time/preview":     app.PermEdit,
+	"POST /admin/import-time/apply":       app.PermEdit,
+	"POST /admin/update-heatmap-settings": app.PermManage,
+	"POST /admin/update-visibility":       app.PermManage,
+	"POST /admin/regenerate-feed-token":   app.PermManage,
+	"POST /admin/regenerate-api-token":    app.PermManage,
+	"GET /admin/access":                   app.PermManage,
+	"GET /admin/audit":                    app.PermManage,
+	"POST /admin/access":                  app.PermManage,
+	"POST /admin/access/revoke":           app.PermManage,
+	"POST /admin/switch-account":          app.PermNone,
+	"GET /admin/backup":                   app.PermNone,
+	"GET /admin/failed-logins":            app.PermNone,
+	"GET /admin/password":                 app.PermNone,
+	"POST /admin/password":                app.PermNone,
+	"GET /admin/2fa":                      app.PermNone,
+	"POST /admin/2fa/setup":               app.PermNone,
+	"POST /admin/2fa/enable":              app.PermNone,
+	"POST /admin/2fa/disable":             app.PermNone,
+	"GET /admin/invites":                  app.PermNone,
+	"POST /admin/invites":                 app.PermNone,
+	"POST /admin/logout":                  app.PermNone,
+}
+
+// adminPermission returns the permission the request needs. Team pages go by
+// the admin's role in the team instead.
+func adminPermission(r *http.Request) (app.Permission, bool) {
+	if strings.HasPrefix(r.URL.Path, "/admin/teams/") {
+		return app.PermNone, true
+	}
+	perm, ok := adminPermissions[r.Method+" "+r.URL.Path]
+	return perm, ok
+}
+
+// requirePermission runs after requireAdmin and lets the request through only
+// if the session's role on its account has the permission the route needs.
+func (h *Handler) requirePermission(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		perm, ok := adminPermission(r)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if session := currentAdmin(r); !session.Can(perm) {
+			log.Printf("requirePermission: %s as %s of %s cannot %s %s", session.Login, session.Role, session.AccountLogin, r.Method, r.URL.Path)
+			http.Error(w, "your role on this account does not allow this", http.StatusForbidden)
+			return
+		}
+		handler(w, r)
+	}
+}
+
+type AccessPageData struct {
+	Page
+	Access    []app.AccountAccess
+	Error     string
+	CSRFToken string
+}
+
+func (h *Handler) renderAccessPage(w http.ResponseWriter, r *http.Request, status int, data AccessPageData) {
+	access, err := h.AppService.ListAccountAccess(currentAdmin(r))
+	if err != nil {
+		log.Printf("renderAccessPage ListAccountAccess error: %v", err)
+		http.Error(w, "failed to load access", http.StatusInternalServerError)
+		return
+	}
+	data.Page = page(r)
+	data.Access = access
+	data.CSRFToken = currentAdmin(r).CSRFToken()
+	w.WriteHeader(status)
+	if err := h.Templates.ExecuteTemplate(w, "access.html", data); err != nil {
+		log.Printf("renderAccessPage template error: %v", err)
+	}
+}
+
+func (h *Handler) grantAccess(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "incorrect form values", http.StatusBadRequest)
+		return
+	}
+	login := strings.TrimSpace(r.FormValue("login"))
+	err := h.asAdmin(r).GrantAccess(currentAdmin(r), login, r.FormValue("role"))
+	switch {
+	case err == nil:
+		http.Redirect(w, r, "/admin/access", http.StatusSeeOther)
+	case errors.Is(err, app.ErrNoUser):
+		h.renderAccessPage(w, r, http.StatusNotFound, AccessPageData{Error: "There is no account " + login + "."})
+	case errors.Is(err, app.ErrInvalidRole):
+		h.renderAccessPage(w, r, http.StatusBadRequest, AccessPageData{Error: err.Error()})
+	case errors.Is(err, app.ErrNotAllowed):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		log.Printf("grantAccess GrantAccess error: %v", err)
+		http.Error(w, "failed to give access", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) revokeAccess(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "incorrect form values", http.StatusBadRequest)
+		return
+	}
+	err := h.asAdmin(r).RevokeAccess(currentAdmin(r), r.FormValue("login"))
+	switch {
+	case err == nil:
+		http.Redirect(w, r, "/admin/access", http.StatusSeeOther)
+	case errors.Is(err, app.ErrNoUser):
+		http.Error(w, "no such access", http.StatusNotFound)
+	case errors.Is(err, app.ErrNotAllowed):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		log.Printf("revokeAccess RevokeAccess error: %v", err)
+		http.Error(w, "failed to take access away", http.StatusInternalServerError)
+	}
+}
+
+// switchAccount makes the session work on another account the admin has
+// access to, or on their own.
+func (h *Handler) switchAccount(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "incorrect form values", http.StatusBadRequest)
+		return
+	}
+	err := h.AppService.SwitchAccount(currentAdmin(r), r.FormValue("account"))
+	switch {
+	case err == nil:
+		http.Redirect(w, r, "/admin/", http.StatusSeeOther)
+	case errors.Is(err, app.ErrNoUser), errors.Is(err, app.ErrNotAllowed):
+		http.Error(w, "you have no access to that account", http.StatusForbidden)
+	default:
+		log.Printf("switchAccount SwitchAccount error: %v", err)
+		http.Error(w, "failed to switch account", http.StatusInternalServerError)
+	}
+}
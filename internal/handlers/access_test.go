@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"abtprj/internal/app"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+const roleDenied = "your role on this account does not allow this"
+
+// actingSession is bob working on alice's tracker with role.
+func actingSession(role string) app.AdminSession {
+	return app.AdminSession{Token: "t", AdminID: 2, Login: "bob", AccountID: 1, AccountLogin: "alice", Role: role}
+}
+
+// serveAdmin sends an admin request through the full router, with the session
+// cookie and, for POSTs, the CSRF token.
+func serveAdmin(h *Handler, method, path string, form url.Values) *httptest.ResponseRecorder {
+	if form == nil {
+		form = url.Values{}
+	}
+	var req *http.Request
+	if method == http.MethodGet {
+		req = httptest.NewRequest(method, path, nil)
+	} else {
+		form.Set(csrfFieldName, app.AdminSession{Token: "t"}.CSRFToken())
+		req = httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "t"})
+	return serveMux(h, req)
+}
+
+func TestPermissions_EveryAdminRoute(t *testing.T) {
+	allowed := map[string]map[app.Permission]bool{
+		app.RoleViewer: {app.PermNone: true, app.PermView: true},
+		app.RoleEditor: {app.PermNone: true, app.PermView: true, app.PermEdit: true},
+		app.RoleOwner:  {app.PermNone: true, app.PermView: true, app.PermEdit: true, app.PermManage: true},
+	}
+	views := loadViews(t)
+
+	for route, perm := range adminPermissions {
+		method, path, _ := strings.Cut(route, " ")
+		for role, perms := range allowed {
+			svc := &mockService{adminSession: actingSession(role)}
+			h := &Handler{Templates: views, AppService: svc}
+
+			rr := serveAdmin(h, method, path, nil)
+
+			denied := rr.Code == http.StatusForbidden && strings.Contains(rr.Body.String(), roleDenied)
+			if denied == perms[perm] {
+				t.Errorf("%s as %s (needs %q): status %d %q", route, role, perm, rr.Code, strings.TrimSpace(rr.Body.String()))
+			}
+			if denied && (svc.addedTask != nil || svc.sessionStarted || svc.sessionEnded || svc.apiRegenerated ||
+				svc.tokenRegenerated || svc.updatedHeatmapSettings != nil || svc.accessChange != "") {
+				t.Errorf("%s as %s: handler ran although the role was refused", route, role)
+			}
+		}
+	}
+}
+
+func TestPermissions_UnlistedRoutesAreNotFound(t *testing.T) {
+	h := &Handler{AppService: &mockService{adminSession: app.AdminSession{Token: "t", AdminID: 1}}}
+
+	for _, route := range [][2]string{{http.MethodGet, "/admin/access/revoke"}, {http.MethodGet, "/admin/add-task"}, {http.MethodGet, "/admin/nope"}} {
+		if rr := serveAdmin(h, route[0], route[1], nil); rr.Code != http.StatusNotFound {
+			t.Errorf("%s %s: status %d; want %d", route[0], route[1], rr.Code, http.StatusNotFound)
+		}
+	}
+}
+
+func TestPermissions_TeamsGoByTeamRole(t *testing.T) {
+	svc := teamService(app.RoleMember)
+	svc.adminSession = actingSession(app.RoleViewer)
+	h := &Handler{AppService: svc}
+
+	rr := serveAdmin(h, http.MethodPost, "/admin/teams/eng/tasks/complete", url.Values{"id": {"9"}})
+
+	if rr.Code != http.StatusSeeOther {
+		t.Fatalf("status %d: %s", rr.Code, rr.Body.String())
+	}
+	if svc.userID != 2 {
+		t.Errorf("team change made as user %d; want the logged-in admin, 2", svc.userID)
+	}
+}
+
+func TestPermissions_ActingWorksOnTheOtherAccount(t *testing.T) {
+	svc := &mockService{adminSession: actingSession(app.RoleEditor)}
+	h := &Handler{AppService: svc}
+
+	rr := serveAdmin(h, http.MethodPost, "/admin/start-work-session", nil)
+
+	if rr.Code != http.StatusOK || !svc.sessionStarted {
+		t.Fatalf("status %d; want the session started", rr.Code)
+	}
+	if svc.userID != 1 {
+		t.Errorf("started a session for user %d; want alice's, 1", svc.userID)
+	}
+}
+
+func TestAdminPage_ShowsWhatTheRoleAllows(t *testing.T) {
+	for _, tt := range []struct {
+		role         string
+		edit, manage bool
+	}{
+		{app.RoleViewer, false, false},
+		{app.RoleEditor, true, false},
+		{app.RoleOwner, true, true},
+	} {
+		svc := &mockService{
+			adminSession: actingSession(tt.role),
+			apiToken:     "api-secret",
+			todoTasks:    []app.Task{{Name: "Write docs", Status: "todo"}},
+			accessible:   []app.AccountAccess{{Login: "alice", Role: tt.role}},
+		}
+		h := &Handler{Templates: loadViews(t), AppService: svc}
+
+		rr := serveAdmin(h, http.MethodGet, "/admin/", nil)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%s: status %d: %s", tt.role, rr.Code, rr.Body.String())
+		}
+		body := rr.Body.String()
+		if !strings.Contains(body, "Write docs") {
+			t.Errorf("%s: the private task list should be shown", tt.role)
+		}
+		if !strings.Contains(body, "working on the tracker of") || !strings.Contains(body, `action="/admin/switch-account"`) {
+			t.Errorf("%s: the page should say whose tracker it is and offer to switch", tt.role)
+		}
+		if got := strings.Contains(body, `action="/admin/add-task"`); got != tt.edit {
+			t.Errorf("%s: task form shown = %v; want %v", tt.role, got, tt.edit)
+		}
+		if got := strings.Contains(body, "api-secret"); got != tt.manage {
+			t.Errorf("%s: API token shown = %v; want %v", tt.role, got, tt.manage)
+		}
+	}
+}
+
+func TestAccess_GrantAndRevoke(t *testing.T) {
+	svc := &mockService{adminSession: app.AdminSession{Token: "t", AdminID: 1, Login: "alice"}, users: map[string]int{"bob": 2}}
+	h := &Handler{Templates: loadViews(t), AppService: svc}
+
+	rr := serveAdmin(h, http.MethodPost, "/admin/access", url.Values{"login": {"bob"}, "role": {"editor"}})
+	if rr.Code != http.StatusSeeOther || svc.accessChange != "grant bob editor on alice" {
+		t.Fatalf("grant: status %d, change %q", rr.Code, svc.accessChange)
+	}
+
+	rr = serveAdmin(h, http.MethodPost, "/admin/access", url.Values{"login": {"carol"}, "role": {"editor"}})
+	if rr.Code != http.StatusNotFound || !strings.Contains(rr.Body.String(), "no account carol") {
+		t.Errorf("unknown login: status %d", rr.Code)
+	}
+	rr = serveAdmin(h, http.MethodPost, "/admin/access", url.Values{"login": {"bob"}, "role": {"admin"}})
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("unknown role: status %d; want %d", rr.Code, http.StatusBadRequest)
+	}
+
+	rr = serveAdmin(h, http.MethodPost, "/admin/access/revoke", url.Values{"login": {"bob"}})
+	if rr.Code != http.StatusSeeOther || svc.accessChange != "revoke bob on alice" {
+		t.Errorf("revoke: status %d, change %q", rr.Code, svc.accessChange)
+	}
+}
+
+func TestAccess_SwitchAccount(t *testing.T) {
+	svc := &mockService{
+		adminSession: app.AdminSession{Token: "t", AdminID: 2, Login: "bob"},
+		accessible:   []app.AccountAccess{{Login: "alice", Role: app.RoleViewer}},
+	}
+	h := &Handler{AppService: svc}
+
+	rr := serveAdmin(h, http.MethodPost, "/admin/switch-account", url.Values{"account": {"alice"}})
+	if rr.Code != http.StatusSeeOther || svc.accessChange != "switch alice" {
+		t.Fatalf("status %d, change %q", rr.Code, svc.accessChange)
+	}
+
+	svc.accessChange = ""
+	rr = serveAdmin(h, http.MethodPost, "/admin/switch-account", url.Values{"account": {"carol"}})
+	if rr.Code != http.StatusForbidden || svc.accessChange != "" {
+		t.Errorf("switching to an account without access: status %d, change %q", rr.Code, svc.accessChange)
+	}
+}
@@ -2,25 +2,45 @@ package handlers
 
 import (
 	"abtprj/internal/app"
+	"abtprj/internal/importer"
 	_ "database/sql"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 )
 
 type AdminPageData struct {
 	TodoTasks       []app.Task
 	TodoGoals       []app.Goal
+	Deadlines       []app.Goal
 	CurrentSession  string
 	TotalSessionDur time.Duration
 	IsWorking       bool
+	HeatmapSettings app.HeatmapSettings
+	Visibility      string // the account's default for new tasks and goals
+	FeedToken       string
+	APIToken        string
+	Login           string
+	Owner           bool
+	CSRFToken       string
+
+	Page             // Account is the account being worked on
+	Role      string // the session's role on it
+	Acting    bool   // Account is not the admin's own
+	CanEdit   bool
+	CanManage bool
+	Accounts  []app.AccountAccess // other accounts the admin can switch to
+
+	Undo app.Undo // the change just made, while it can be undone
 }
 
 func (h *Handler) AdminHandler(w http.ResponseWriter, r *http.Request) {
 	switch {
 	case r.Method == http.MethodGet && r.URL.Path == "/admin/":
-		h.renderAdminPage(w)
+		h.renderAdminPage(w, r)
 	case r.Method == http.MethodPost && r.URL.Path == "/admin/add-task":
 		h.addTask(w, r)
 	case r.Method == http.MethodPost && r.URL.Path == "/admin/complete-task":
@@ -35,16 +55,80 @@ func (h *Handler) AdminHandler(w http.ResponseWriter, r *http.Request) {
 		h.startWorkSession(w, r)
 	case r.Method == http.MethodPost && r.URL.Path == "/admin/end-work-session":
 		h.endWorkSession(w, r)
-	case r.Method == http.MethodPost && r.URL.Path == "/admin/login":
-		h.handleLogin(w, r)
+	case r.Method == http.MethodPost && r.URL.Path == "/admin/update-heatmap-settings":
+		h.updateHeatmapSettings(w, r)
+	case r.Method == http.MethodPost && r.URL.Path == "/admin/update-visibility":
+		h.updateDefaultVisibility(w, r)
+	case r.Method == http.MethodPost && r.URL.Path == "/admin/task-visibility":
+		h.updateItemVisibility(w, r, h.service(r).SetTaskVisibility)
+	case r.Method == http.MethodPost && r.URL.Path == "/admin/goal-visibility":
+		h.updateItemVisibility(w, r, h.service(r).SetGoalVisibility)
+	case r.Method == http.MethodGet && r.URL.Path == "/admin/estimates":
+		h.renderEstimatesPage(w, r)
+	case r.Method == http.MethodGet && r.URL.Path == "/admin/export":
+		h.exportData(w, r)
+	case r.Method == http.MethodGet && r.URL.Path == "/admin/backup":
+		h.requireOwner(h.downloadBackup)(w, r)
+	case r.Method == http.MethodGet && r.URL.Path == "/admin/journal":
+		h.exportJournal(w, r)
+	case r.Method == http.MethodPost && r.URL.Path == "/admin/regenerate-feed-token":
+		h.regenerateFeedToken(w, r)
+	case r.Method == http.MethodPost && r.URL.Path == "/admin/regenerate-api-token":
+		h.regenerateAPIToken(w, r)
+	case r.Method == http.MethodGet && r.URL.Path == "/admin/import":
+		h.renderImportPage(w, r, http.StatusOK, ImportPageData{Format: importer.FormatTodoTxt})
+	case r.Method == http.MethodPost && r.URL.Path == "/admin/import/preview":
+		h.previewImport(w, r)
+	case r.Method == http.MethodPost && r.URL.Path == "/admin/import/apply":
+		h.applyImport(w, r)
+	case r.Method == http.MethodGet && r.URL.Path == "/admin/import-time":
+		h.renderTimeImportForm(w, r)
+	case r.Method == http.MethodPost && r.URL.Path == "/admin/import-time/preview":
+		h.handleTimeImport(w, r, false)
+	case r.Method == http.MethodPost && r.URL.Path == "/admin/import-time/apply":
+		h.handleTimeImport(w, r, true)
+	case r.Method == http.MethodGet && r.URL.Path == "/admin/password":
+		h.renderPasswordPage(w, r, http.StatusOK, PasswordPageData{})
+	case r.Method == http.MethodPost && r.URL.Path == "/admin/password":
+		h.changePassword(w, r)
+	case r.Method == http.MethodGet && r.URL.Path == "/admin/failed-logins":
+		h.requireOwner(h.renderFailedLogins)(w, r)
+	case r.Method == http.MethodGet && r.URL.Path == "/admin/2fa":
+		h.renderTwoFactorPage(w, r, http.StatusOK, TwoFactorPageData{})
+	case r.Method == http.MethodPost && r.URL.Path == "/admin/2fa/setup":
+		h.beginTOTPSetup(w, r)
+	case r.Method == http.MethodPost && r.URL.Path == "/admin/2fa/enable":
+		h.enableTOTP(w, r)
+	case r.Method == http.MethodPost && r.URL.Path == "/admin/2fa/disable":
+		h.disableTOTP(w, r)
+	case r.Method == http.MethodGet && r.URL.Path == "/admin/invites":
+		h.renderInvitesPage(w, r, InvitesPageData{})
+	case r.Method == http.MethodPost && r.URL.Path == "/admin/invites":
+		h.createInvite(w, r)
+	case r.Method == http.MethodGet && r.URL.Path == "/admin/access":
+		h.renderAccessPage(w, r, http.StatusOK, AccessPageData{})
+	case r.Method == http.MethodPost && r.URL.Path == "/admin/access":
+		h.grantAccess(w, r)
+	case r.Method == http.MethodPost && r.URL.Path == "/admin/access/revoke":
+		h.revokeAccess(w, r)
+	case r.Method == http.MethodGet && r.URL.Path == "/admin/audit":
+		h.renderAuditPage(w, r)
+	case r.Method == http.MethodPost && r.URL.Path == "/admin/undo":
+		h.undoChange(w, r)
+	case r.Method == http.MethodPost && r.URL.Path == "/admin/switch-account":
+		h.switchAccount(w, r)
+	case strings.HasPrefix(r.URL.Path, "/admin/teams/"):
+		h.teamsHandler(w, r)
+	case r.Method == http.MethodPost && r.URL.Path == "/admin/logout":
+		h.logout(w, r)
 
 	default:
 		http.NotFound(w, r)
 	}
 }
 
-func (h *Handler) renderAdminPage(w http.ResponseWriter) {
-	todoTasks, err := h.AppService.GetTodoTasks()
+func (h *Handler) renderAdminPage(w http.ResponseWriter, r *http.Request) {
+	todoTasks, err := h.service(r).GetTodoTasks()
 
 	if err != nil {
 		log.Printf("get tasks query error: %v", err)
@@ -52,15 +136,22 @@ func (h *Handler) renderAdminPage(w http.ResponseWriter) {
 		return
 	}
 
-	goals, err := h.AppService.GetTodoGoals()
+	goals, err := h.service(r).GetTodoGoals()
 	if err != nil {
 		log.Printf("get goals query error: %v", err)
 		http.Error(w, "repository error", http.StatusInternalServerError)
 		return
 	}
 
+	deadlines, err := h.service(r).GetUpcomingDeadlines()
+	if err != nil {
+		log.Printf("get deadlines query error: %v", err)
+		http.Error(w, "repository error", http.StatusInternalServerError)
+		return
+	}
+
 	today := time.Now().Format("2006-01-02")
-	sessions, err := h.AppService.GetWorkSessionsForDate(today)
+	sessions, err := h.service(r).GetWorkSessionsForDate(today)
 	if err != nil {
 		log.Printf("renderAdminPage GetWorkSessionsForDate error: %v", err)
 		http.Error(w, "failed to get sessions", http.StatusInternalServerError)
@@ -95,17 +186,67 @@ func (h *Handler) renderAdminPage(w http.ResponseWriter) {
 		totalDur += ongoingDur
 	}
 
-	isWorking, err := h.AppService.IsWorking()
+	isWorking, err := h.service(r).IsWorking()
 	if err != nil {
 		log.Printf("worklog query error: %v", err)
 	}
 
+	heatmapSettings, err := h.service(r).GetHeatmapSettings()
+	if err != nil {
+		log.Printf("renderAdminPage GetHeatmapSettings error: %v", err)
+	}
+
+	visibility, err := h.service(r).GetDefaultVisibility()
+	if err != nil {
+		log.Printf("renderAdminPage GetDefaultVisibility error: %v", err)
+	}
+
+	session := currentAdmin(r)
+
+	// the tokens give full access to the account, so only its owners see them
+	var feedToken, apiToken string
+	if session.Can(app.PermManage) {
+		if feedToken, err = h.service(r).GetFeedToken(); err != nil {
+			log.Printf("renderAdminPage GetFeedToken error: %v", err)
+		}
+		if apiToken, err = h.service(r).GetAPIToken(); err != nil {
+			log.Printf("renderAdminPage GetAPIToken error: %v", err)
+		}
+	}
+
+	accounts, err := h.AppService.ListAccessibleAccounts(session)
+	if err != nil {
+		log.Printf("renderAdminPage ListAccessibleAccounts error: %v", err)
+	}
+
+	var undo app.Undo
+	if token := takeUndoToken(w, r); token != "" && session.Can(app.PermEdit) {
+		if undo, err = h.service(r).GetUndo(token); err != nil && !errors.Is(err, app.ErrInvalidUndo) {
+			log.Printf("renderAdminPage GetUndo error: %v", err)
+		}
+	}
+
 	data := AdminPageData{
 		TodoTasks:       todoTasks,
 		TodoGoals:       goals,
+		Deadlines:       deadlines,
 		CurrentSession:  currentSession,
 		TotalSessionDur: totalDur.Truncate(time.Second),
 		IsWorking:       isWorking,
+		HeatmapSettings: heatmapSettings,
+		Visibility:      visibility,
+		FeedToken:       feedToken,
+		APIToken:        apiToken,
+		Login:           session.Login,
+		Owner:           session.Owner,
+		CSRFToken:       session.CSRFToken(),
+		Page:            page(r),
+		Role:            session.Role,
+		Acting:          session.ActingForOther(),
+		CanEdit:         session.Can(app.PermEdit),
+		CanManage:       session.Can(app.PermManage),
+		Accounts:        accounts,
+		Undo:            undo,
 	}
 
 	if err := h.Templates.ExecuteTemplate(w, "admin.html", data); err != nil {
@@ -135,8 +276,13 @@ func (h *Handler) createGoal(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	goal := app.Goal{Name: name, Description: desc, DueAt: &due}
-	if err := h.AppService.CreateGoal(goal); err != nil {
+	goal := app.Goal{Name: name, Description: desc, DueAt: &due, Visibility: r.FormValue("visibility")}
+	err = h.service(r).CreateGoal(goal)
+	if errors.Is(err, app.ErrInvalidVisibility) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
 		log.Printf("createGoal CreateGoal error: %v", err)
 		http.Error(w, "failed to create goal", http.StatusInternalServerError)
 		return
@@ -153,7 +299,19 @@ func (h *Handler) addTask(w http.ResponseWriter, r *http.Request) {
 	name := r.FormValue("name")
 	description := r.FormValue("description")
 
-	if err := h.AppService.AddTask(name, description); err != nil {
+	estimate, err := parseEstimate(r.FormValue("estimate"))
+	if err != nil {
+		http.Error(w, "invalid estimate", http.StatusBadRequest)
+		return
+	}
+
+	task := app.Task{Name: name, Description: description, Estimate: estimate, Visibility: r.FormValue("visibility")}
+	err = h.service(r).AddTask(task)
+	if errors.Is(err, app.ErrInvalidVisibility) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
 		log.Printf("addTask AddTask error: %v", err)
 		http.Error(w, "failed to add a task", http.StatusInternalServerError)
 		return
@@ -172,12 +330,13 @@ func (h *Handler) completeTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.AppService.CompleteTask(name); err != nil {
+	undo, err := h.service(r).CompleteTask(name)
+	if err != nil {
 		log.Printf("completeTask CompleteTask error: %v", err)
 		http.Error(w, "failed to complete a task", http.StatusInternalServerError)
 		return
 	}
-	http.Redirect(w, r, "/admin/", http.StatusSeeOther)
+	undoRedirect(w, r, undo)
 }
 
 func (h *Handler) completeGoal(w http.ResponseWriter, r *http.Request) {
@@ -197,17 +356,104 @@ func (h *Handler) completeGoal(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.AppService.CompleteGoal(goalID); err != nil {
+	undo, err := h.service(r).CompleteGoal(goalID)
+	if err != nil {
 		log.Printf("completeGoal CompleteGoal error: %v", err)
 		http.Error(w, "failed to complete a goal", http.StatusInternalServerError)
 		return
 	}
+	undoRedirect(w, r, undo)
+}
+
+func (h *Handler) updateHeatmapSettings(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "incorrect form values", http.StatusBadRequest)
+		return
+	}
+
+	taskThresholds, err := app.ParseThresholds(r.FormValue("task_thresholds"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	sessionThresholds, err := app.ParseThresholds(r.FormValue("session_thresholds"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	settings := app.HeatmapSettings{
+		Tasks:    app.HeatmapScale{Mode: r.FormValue("task_mode"), Thresholds: taskThresholds},
+		Sessions: app.HeatmapScale{Mode: r.FormValue("session_mode"), Thresholds: sessionThresholds},
+	}
+	if err := settings.Tasks.Validate(); err != nil {
+		http.Error(w, "tasks heatmap: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := settings.Sessions.Validate(); err != nil {
+		http.Error(w, "sessions heatmap: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service(r).UpdateHeatmapSettings(settings); err != nil {
+		log.Printf("updateHeatmapSettings UpdateHeatmapSettings error: %v", err)
+		http.Error(w, "failed to update heatmap settings", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/admin/", http.StatusSeeOther)
+}
+
+// updateDefaultVisibility sets how public the tasks and goals that have no
+// visibility of their own are.
+func (h *Handler) updateDefaultVisibility(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "incorrect form values", http.StatusBadRequest)
+		return
+	}
+	err := h.service(r).SetDefaultVisibility(r.FormValue("visibility"))
+	if errors.Is(err, app.ErrInvalidVisibility) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("updateDefaultVisibility SetDefaultVisibility error: %v", err)
+		http.Error(w, "failed to update visibility", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/admin/", http.StatusSeeOther)
+}
+
+// updateItemVisibility changes who sees one task or goal with set, one of the
+// service's Set*Visibility methods.
+func (h *Handler) updateItemVisibility(w http.ResponseWriter, r *http.Request, set func(id int, visibility string) error) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "incorrect form values", http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	err = set(id, r.FormValue("visibility"))
+	switch {
+	case errors.Is(err, app.ErrInvalidVisibility):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, app.ErrNoItem):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case err != nil:
+		log.Printf("updateItemVisibility error: %v", err)
+		http.Error(w, "failed to update visibility", http.StatusInternalServerError)
+		return
+	}
 	http.Redirect(w, r, "/admin/", http.StatusSeeOther)
 }
 
 func (h *Handler) getWorkingStatusForToday(w http.ResponseWriter, r *http.Request) {
 	today := time.Now().Format("2006-01-02")
-	sessions, err := h.AppService.GetWorkSessionsForDate(today)
+	sessions, err := h.service(r).GetWorkSessionsForDate(today)
 	if err != nil {
 		log.Printf("getWorkingStatusForToday GetWorkSessionsForDate error: %v", err)
 		http.Error(w, "failed to get working status", http.StatusInternalServerError)
@@ -227,46 +473,23 @@ func (h *Handler) getWorkingStatusForToday(w http.ResponseWriter, r *http.Reques
 }
 
 func (h *Handler) startWorkSession(w http.ResponseWriter, r *http.Request) {
-	if err := h.AppService.StartWorkSession(); err != nil {
+	undo, err := h.service(r).StartWorkSession()
+	if err != nil {
 		log.Printf("startWorkSession StartWorkSession error: %v", err)
 		http.Error(w, "failed to start a session", http.StatusInternalServerError)
 		return
 	}
+	setUndoToken(w, undo)
 	w.WriteHeader(http.StatusOK)
 }
 
 func (h *Handler) endWorkSession(w http.ResponseWriter, r *http.Request) {
-	if err := h.AppService.EndWorkSession(); err != nil {
+	undo, err := h.service(r).EndWorkSession()
+	if err != nil {
 		log.Printf("endWorkSession EndWorkSession error: %v", err)
 		http.Error(w, "failed to end a session", http.StatusInternalServerError)
 		return
 	}
+	setUndoToken(w, undo)
 	w.WriteHeader(http.StatusOK)
 }
-
-func (h *Handler) handleLogin(w http.ResponseWriter, r *http.Request) {
-	if err := r.ParseForm(); err != nil {
-		http.Error(w, "некорректные данные формы", http.StatusBadRequest)
-		return
-	}
-	login := r.FormValue("login")
-	password := r.FormValue("password")
-
-	if err := h.AppService.LoginAdmin(login, password); err != nil {
-		log.Printf("handleLogin LoginAdmin error: %v", err)
-		h.Templates.ExecuteTemplate(w, "login.html", map[string]string{
-			"Error": "Incorrect login or password",
-		})
-		return
-	}
-
-	http.SetCookie(w, &http.Cookie{
-		Name:     "admin_session",
-		Value:    "authenticated",
-		HttpOnly: true,
-		Path:     "/",
-		SameSite: http.SameSiteStrictMode,
-	})
-
-	http.Redirect(w, r, "/admin/", http.StatusSeeOther)
-}
@@ -2,11 +2,11 @@ package handlers
 
 import (
 	"abtprj/internal/app"
+	"html/template"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
-	"text/template"
 	"time"
 )
 
@@ -34,16 +34,16 @@ func TestAdminHandler_OK(t *testing.T) {
 	t1 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
 	mockTodos := []app.Task{
 		{
-			"testName1",
-			"testDescription1",
-			"TODO",
-			nil,
+			Name:        "testName1",
+			Description: "testDescription1",
+			Status:      "TODO",
+			DoneAt:      nil,
 		},
 		{
-			"testName2",
-			"testDescription2",
-			"DONE",
-			&t1,
+			Name:        "testName2",
+			Description: "testDescription2",
+			Status:      "DONE",
+			DoneAt:      &t1,
 		},
 	}
 
@@ -118,6 +118,147 @@ func TestAdminHandler_IsWorkingIndicator(t *testing.T) {
 	}
 }
 
+func TestAdminHandler_AddTask_WithEstimate(t *testing.T) {
+	svc := &mockService{}
+	h := &Handler{
+		Templates:  createAdminTemplate(),
+		AppService: svc,
+	}
+
+	form := strings.NewReader("name=refactor&description=cleanup&estimate=1h30m")
+	req := httptest.NewRequest(http.MethodPost, "/admin/add-task", form)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+
+	h.AdminHandler(rr, req)
+
+	if rr.Code != http.StatusSeeOther {
+		t.Fatalf("status code = %d; want %d", rr.Code, http.StatusSeeOther)
+	}
+	if svc.addedTask == nil {
+		t.Fatal("expected AddTask to be called")
+	}
+	if svc.addedTask.Name != "refactor" || svc.addedTask.Estimate != 90*time.Minute {
+		t.Errorf("added task = %+v; want refactor with 1h30m estimate", svc.addedTask)
+	}
+}
+
+func TestAdminHandler_AddTask_InvalidEstimate(t *testing.T) {
+	svc := &mockService{}
+	h := &Handler{
+		Templates:  createAdminTemplate(),
+		AppService: svc,
+	}
+
+	form := strings.NewReader("name=refactor&estimate=a+while")
+	req := httptest.NewRequest(http.MethodPost, "/admin/add-task", form)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+
+	h.AdminHandler(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("status code = %d; want %d", rr.Code, http.StatusBadRequest)
+	}
+	if svc.addedTask != nil {
+		t.Error("expected AddTask not to be called")
+	}
+}
+
+func TestAdminHandler_UpdateHeatmapSettings(t *testing.T) {
+	svc := &mockService{}
+	h := &Handler{
+		Templates:  createAdminTemplate(),
+		AppService: svc,
+	}
+
+	form := strings.NewReader("task_mode=fixed&task_thresholds=0,2,5,10&session_mode=quantile&session_thresholds=0,2,4,8")
+	req := httptest.NewRequest(http.MethodPost, "/admin/update-heatmap-settings", form)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+
+	h.AdminHandler(rr, req)
+
+	if rr.Code != http.StatusSeeOther {
+		t.Fatalf("status code = %d; want %d", rr.Code, http.StatusSeeOther)
+	}
+	if svc.updatedHeatmapSettings == nil {
+		t.Fatal("expected UpdateHeatmapSettings to be called")
+	}
+	got := svc.updatedHeatmapSettings
+	if got.Tasks.Mode != app.ScaleFixed || got.Tasks.ThresholdsString() != "0,2,5,10" {
+		t.Errorf("tasks scale = %+v; want fixed 0,2,5,10", got.Tasks)
+	}
+	if got.Sessions.Mode != app.ScaleQuantile {
+		t.Errorf("sessions mode = %q; want %q", got.Sessions.Mode, app.ScaleQuantile)
+	}
+}
+
+func TestAdminHandler_UpdateHeatmapSettings_Invalid(t *testing.T) {
+	cases := []struct {
+		name string
+		form string
+	}{
+		{"UnknownMode", "task_mode=log&task_thresholds=0,1,2,3&session_mode=fixed&session_thresholds=0,2,4,8"},
+		{"NotAscending", "task_mode=fixed&task_thresholds=0,3,2,1&session_mode=fixed&session_thresholds=0,2,4,8"},
+		{"WrongCount", "task_mode=fixed&task_thresholds=0,1,2,3&session_mode=fixed&session_thresholds=1,2"},
+		{"NotNumber", "task_mode=fixed&task_thresholds=0,a,2,3&session_mode=fixed&session_thresholds=0,2,4,8"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			svc := &mockService{}
+			h := &Handler{
+				Templates:  createAdminTemplate(),
+				AppService: svc,
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/admin/update-heatmap-settings", strings.NewReader(tc.form))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			rr := httptest.NewRecorder()
+
+			h.AdminHandler(rr, req)
+
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("status code = %d; want %d", rr.Code, http.StatusBadRequest)
+			}
+			if svc.updatedHeatmapSettings != nil {
+				t.Error("expected UpdateHeatmapSettings not to be called")
+			}
+		})
+	}
+}
+
+func TestAdminHandler_UpcomingDeadlines(t *testing.T) {
+	tmpl := template.Must(template.New("admin.html").Parse(`
+{{define "admin.html"}}
+{{range .Deadlines}}[{{.Name}}|{{.StateClass}}]{{end}}
+{{end}}
+`))
+
+	due1 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	due2 := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	svc := &mockService{
+		upcomingDeadlines: []app.Goal{
+			{Name: "first", DueAt: &due1, State: app.GoalOverdue},
+			{Name: "second", DueAt: &due2, State: app.GoalDueSoon},
+		},
+	}
+	h := &Handler{Templates: tmpl, AppService: svc}
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/", nil)
+	rr := httptest.NewRecorder()
+	h.AdminHandler(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status code = %d; want %d", rr.Code, http.StatusOK)
+	}
+	want := "[first|overdue][second|due-soon]"
+	if body := rr.Body.String(); !strings.Contains(body, want) {
+		t.Errorf("body = %q; want to contain %q", body, want)
+	}
+}
+
 func createAdminTemplate() *template.Template {
 	tmpl := template.Must(template.New("admin.html").Parse(`
 {{define "admin.html"}}
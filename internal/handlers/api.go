@@ -0,0 +1,354 @@
+package handlers
+
+import (
+	"abtprj/internal/api"
+	"abtprj/internal/app"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const maxAPIBody = 64 << 10
+
+// requireAPIToken lets a request through when it carries an API token as
+// "Authorization: Bearer <token>", acting as the user the token belongs to.
+func (h *Handler) requireAPIToken(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			token = ""
+		}
+		user, err := h.AppService.UserForAPIToken(token)
+		if errors.Is(err, app.ErrInvalidToken) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="abtprj"`)
+			writeAPIError(w, http.StatusUnauthorized, "invalid or missing API token")
+			return
+		}
+		if err != nil {
+			log.Printf("requireAPIToken UserForAPIToken error: %v", err)
+			writeAPIError(w, http.StatusInternalServerError, "failed to check token")
+			return
+		}
+		handler(w, h.withUser(r, user))
+	}
+}
+
+func (h *Handler) APIHandler(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/api/status":
+		h.apiStatus(w, r, http.StatusOK)
+	case r.Method == http.MethodPost && r.URL.Path == "/api/session/start":
+		h.apiStartSession(w, r)
+	case r.Method == http.MethodPost && r.URL.Path == "/api/session/stop":
+		h.apiStopSession(w, r)
+	case r.Method == http.MethodGet && r.URL.Path == "/api/today":
+		h.apiToday(w, r)
+	case r.Method == http.MethodGet && r.URL.Path == "/api/tasks":
+		h.apiTodoTasks(w, r)
+	case r.Method == http.MethodPost && r.URL.Path == "/api/tasks":
+		h.apiAddTask(w, r)
+	case r.Method == http.MethodPost && r.URL.Path == "/api/tasks/done":
+		h.apiCompleteTask(w, r)
+	case r.Method == http.MethodPost && r.URL.Path == "/api/tasks/visibility":
+		h.apiSetVisibility(w, r, h.service(r).SetTaskVisibility)
+	case r.Method == http.MethodPost && r.URL.Path == "/api/goals/visibility":
+		h.apiSetVisibility(w, r, h.service(r).SetGoalVisibility)
+	case r.Method == http.MethodGet && r.URL.Path == "/api/goals":
+		h.apiGoals(w, r)
+	case r.Method == http.MethodGet && r.URL.Path == "/api/audit":
+		h.apiAudit(w, r)
+	case r.Method == http.MethodPost && r.URL.Path == "/api/undo":
+		h.apiUndo(w, r)
+	default:
+		writeAPIError(w, http.StatusNotFound, "no such endpoint")
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("writeJSON error: %v", err)
+	}
+}
+
+func writeAPIError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, api.Error{Error: message})
+}
+
+func readJSON(r *http.Request, v any) error {
+	dec := json.NewDecoder(http.MaxBytesReader(nil, r.Body, maxAPIBody))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+func (h *Handler) apiStatus(w http.ResponseWriter, r *http.Request, status int) {
+	st, err := h.service(r).GetStatus()
+	if err != nil {
+		log.Printf("apiStatus GetStatus error: %v", err)
+		writeAPIError(w, http.StatusInternalServerError, "failed to load status")
+		return
+	}
+	writeJSON(w, status, api.Status{
+		Working:         st.Working,
+		SessionStart:    st.SessionStart,
+		WorkedTodayMins: int(st.WorkedToday / time.Minute),
+		TasksDoneToday:  st.TasksDoneToday,
+		OpenTasks:       st.OpenTasks,
+	})
+}
+
+func (h *Handler) apiStartSession(w http.ResponseWriter, r *http.Request) {
+	working, err := h.service(r).IsWorking()
+	if err != nil {
+		log.Printf("apiStartSession IsWorking error: %v", err)
+		writeAPIError(w, http.StatusInternalServerError, "failed to check session")
+		return
+	}
+	if working {
+		writeAPIError(w, http.StatusConflict, "a work session is already running")
+		return
+	}
+	undo, err := h.service(r).StartWorkSession()
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "failed to start work session")
+		return
+	}
+	setUndoToken(w, undo)
+	h.apiStatus(w, r, http.StatusOK)
+}
+
+func (h *Handler) apiStopSession(w http.ResponseWriter, r *http.Request) {
+	working, err := h.service(r).IsWorking()
+	if err != nil {
+		log.Printf("apiStopSession IsWorking error: %v", err)
+		writeAPIError(w, http.StatusInternalServerError, "failed to check session")
+		return
+	}
+	if !working {
+		writeAPIError(w, http.StatusConflict, "no work session is running")
+		return
+	}
+	undo, err := h.service(r).EndWorkSession()
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, "failed to end work session")
+		return
+	}
+	setUndoToken(w, undo)
+	h.apiStatus(w, r, http.StatusOK)
+}
+
+func (h *Handler) apiToday(w http.ResponseWriter, r *http.Request) {
+	loc := h.service(r).Location()
+	now := time.Now().In(loc)
+	date := now.Format("2006-01-02")
+
+	sessions, err := h.service(r).GetWorkSessionsForDate(date)
+	if err != nil {
+		log.Printf("apiToday GetWorkSessionsForDate error: %v", err)
+		writeAPIError(w, http.StatusInternalServerError, "failed to load sessions")
+		return
+	}
+	tasks, err := h.service(r).GetTasksForDate(date)
+	if err != nil {
+		log.Printf("apiToday GetTasksForDate error: %v", err)
+		writeAPIError(w, http.StatusInternalServerError, "failed to load tasks")
+		return
+	}
+
+	today := api.Today{Date: date, Sessions: []api.Session{}, Tasks: []api.Task{}}
+	for _, ws := range sessions {
+		end := now
+		if ws.EndTime != nil {
+			end = *ws.EndTime
+		}
+		minutes := int(end.Sub(ws.StartTime) / time.Minute)
+		today.WorkedMinutes += minutes
+		today.Sessions = append(today.Sessions, api.Session{Start: ws.StartTime, End: ws.EndTime, Minutes: minutes})
+	}
+	for _, t := range tasks {
+		today.Tasks = append(today.Tasks, apiTask(t))
+	}
+	writeJSON(w, http.StatusOK, today)
+}
+
+func (h *Handler) apiTodoTasks(w http.ResponseWriter, r *http.Request) {
+	tasks, err := h.service(r).GetTodoTasks()
+	if err != nil {
+		log.Printf("apiTodoTasks GetTodoTasks error: %v", err)
+		writeAPIError(w, http.StatusInternalServerError, "failed to load tasks")
+		return
+	}
+	out := []api.Task{}
+	for _, t := range tasks {
+		out = append(out, apiTask(t))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (h *Handler) apiAddTask(w http.ResponseWriter, r *http.Request) {
+	var body api.NewTask
+	if err := readJSON(r, &body); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return
+	}
+	body.Name = strings.TrimSpace(body.Name)
+	if body.Name == "" {
+		writeAPIError(w, http.StatusBadRequest, "task name is required")
+		return
+	}
+	if body.EstimateMinutes < 0 {
+		writeAPIError(w, http.StatusBadRequest, "estimate must not be negative")
+		return
+	}
+
+	task := app.Task{
+		Name:        body.Name,
+		Description: body.Description,
+		Status:      "todo",
+		Estimate:    time.Duration(body.EstimateMinutes) * time.Minute,
+		Visibility:  body.Visibility,
+	}
+	err := h.service(r).AddTask(task)
+	if errors.Is(err, app.ErrInvalidVisibility) {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		log.Printf("apiAddTask AddTask error: %v", err)
+		writeAPIError(w, http.StatusInternalServerError, "failed to add task")
+		return
+	}
+	writeJSON(w, http.StatusCreated, apiTask(task))
+}
+
+func (h *Handler) apiCompleteTask(w http.ResponseWriter, r *http.Request) {
+	var body api.TaskRef
+	if err := readJSON(r, &body); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return
+	}
+
+	tasks, err := h.service(r).GetTodoTasks()
+	if err != nil {
+		log.Printf("apiCompleteTask GetTodoTasks error: %v", err)
+		writeAPIError(w, http.StatusInternalServerError, "failed to load tasks")
+		return
+	}
+	var task *app.Task
+	for i := range tasks {
+		if tasks[i].Name == body.Name {
+			task = &tasks[i]
+			break
+		}
+	}
+	if task == nil {
+		writeAPIError(w, http.StatusNotFound, "no open task named "+body.Name)
+		return
+	}
+
+	// tasks are attributed to the running session, so one has to exist
+	working, err := h.service(r).IsWorking()
+	if err != nil {
+		log.Printf("apiCompleteTask IsWorking error: %v", err)
+		writeAPIError(w, http.StatusInternalServerError, "failed to check session")
+		return
+	}
+	if !working {
+		writeAPIError(w, http.StatusConflict, "start a work session before completing a task")
+		return
+	}
+	undo, err := h.service(r).CompleteTask(task.Name)
+	if err != nil {
+		log.Printf("apiCompleteTask CompleteTask error: %v", err)
+		writeAPIError(w, http.StatusInternalServerError, "failed to complete task")
+		return
+	}
+	setUndoToken(w, undo)
+
+	now := time.Now().In(h.service(r).Location())
+	task.Status, task.DoneAt = "done", &now
+	writeJSON(w, http.StatusOK, apiTask(*task))
+}
+
+// apiGoals lists open goals, or every goal with ?status=all.
+func (h *Handler) apiGoals(w http.ResponseWriter, r *http.Request) {
+	var goals []app.Goal
+	var err error
+	switch r.URL.Query().Get("status") {
+	case "", "todo":
+		goals, err = h.service(r).GetTodoGoals()
+	case "all":
+		goals, err = h.service(r).GetGoals()
+	default:
+		writeAPIError(w, http.StatusBadRequest, "status must be todo or all")
+		return
+	}
+	if err != nil {
+		log.Printf("apiGoals error: %v", err)
+		writeAPIError(w, http.StatusInternalServerError, "failed to load goals")
+		return
+	}
+
+	out := []api.Goal{}
+	for _, g := range goals {
+		out = append(out, api.Goal{
+			ID:          g.ID,
+			Name:        g.Name,
+			Description: g.Description,
+			Status:      g.Status,
+			State:       g.State,
+			DueAt:       g.DueAt,
+			Visibility:  g.Visibility,
+		})
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// apiSetVisibility changes who sees a task or goal with set, one of the
+// service's Set*Visibility methods.
+func (h *Handler) apiSetVisibility(w http.ResponseWriter, r *http.Request, set func(id int, visibility string) error) {
+	var body api.VisibilityChange
+	if err := readJSON(r, &body); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return
+	}
+	err := set(body.ID, body.Visibility)
+	switch {
+	case errors.Is(err, app.ErrInvalidVisibility):
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, app.ErrNoItem):
+		writeAPIError(w, http.StatusNotFound, err.Error())
+		return
+	case err != nil:
+		log.Printf("apiSetVisibility error: %v", err)
+		writeAPIError(w, http.StatusInternalServerError, "failed to update visibility")
+		return
+	}
+	writeJSON(w, http.StatusOK, body)
+}
+
+func apiTask(t app.Task) api.Task {
+	return api.Task{
+		ID:              t.ID,
+		Name:            t.Name,
+		Description:     t.Description,
+		Status:          t.Status,
+		DoneAt:          t.DoneAt,
+		EstimateMinutes: int(t.Estimate / time.Minute),
+		Visibility:      t.Visibility,
+	}
+}
+
+func (h *Handler) regenerateAPIToken(w http.ResponseWriter, r *http.Request) {
+	if _, err := h.service(r).RegenerateAPIToken(); err != nil {
+		log.Printf("regenerateAPIToken RegenerateAPIToken error: %v", err)
+		http.Error(w, "failed to regenerate API token", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/admin/", http.StatusSeeOther)
+}
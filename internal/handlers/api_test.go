@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"abtprj/internal/api"
+	"abtprj/internal/app"
+	"abtprj/internal/client"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func apiRequest(h *Handler, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer secret")
+	rr := httptest.NewRecorder()
+	h.requireAPIToken(h.APIHandler)(rr, req)
+	return rr
+}
+
+func TestAPI_RequiresToken(t *testing.T) {
+	h := &Handler{AppService: &mockService{apiToken: "secret"}}
+
+	for _, auth := range []string{"", "Bearer wrong", "secret", "Basic c2VjcmV0"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/status", nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rr := httptest.NewRecorder()
+		h.requireAPIToken(h.APIHandler)(rr, req)
+
+		if rr.Code != http.StatusUnauthorized {
+			t.Errorf("Authorization %q: status code = %d; want %d", auth, rr.Code, http.StatusUnauthorized)
+		}
+		if rr.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("Authorization %q: missing WWW-Authenticate", auth)
+		}
+	}
+}
+
+func TestAPI_StartStopConflicts(t *testing.T) {
+	svc := &mockService{apiToken: "secret", isWorking: true}
+	h := &Handler{AppService: svc}
+
+	if rr := apiRequest(h, http.MethodPost, "/api/session/start", ""); rr.Code != http.StatusConflict {
+		t.Errorf("start while working: status code = %d; want %d", rr.Code, http.StatusConflict)
+	}
+	if svc.sessionStarted {
+		t.Error("a second session must not be started")
+	}
+
+	svc.isWorking = false
+	if rr := apiRequest(h, http.MethodPost, "/api/session/stop", ""); rr.Code != http.StatusConflict {
+		t.Errorf("stop while idle: status code = %d; want %d", rr.Code, http.StatusConflict)
+	}
+	if svc.sessionEnded {
+		t.Error("no session should be ended")
+	}
+}
+
+func TestAPI_AddTask(t *testing.T) {
+	svc := &mockService{apiToken: "secret"}
+	h := &Handler{AppService: svc}
+
+	rr := apiRequest(h, http.MethodPost, "/api/tasks", `{"name":"  Fix login ","description":"cookies","estimate_minutes":45}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("status code = %d; want %d: %s", rr.Code, http.StatusCreated, rr.Body.String())
+	}
+	if svc.addedTask == nil || svc.addedTask.Name != "Fix login" || svc.addedTask.Estimate != 45*time.Minute {
+		t.Errorf("added task = %+v", svc.addedTask)
+	}
+
+	for _, body := range []string{`{"name":""}`, `{"name":"x","estimate_minutes":-5}`, `{"title":"x"}`, `not json`} {
+		if rr := apiRequest(h, http.MethodPost, "/api/tasks", body); rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: status code = %d; want %d", body, rr.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestAPI_CompleteTask(t *testing.T) {
+	svc := &mockService{apiToken: "secret", todoTasks: []app.Task{{Name: "Fix login", Status: "todo"}}}
+	h := &Handler{AppService: svc}
+
+	if rr := apiRequest(h, http.MethodPost, "/api/tasks/done", `{"name":"Unknown"}`); rr.Code != http.StatusNotFound {
+		t.Errorf("unknown task: status code = %d; want %d", rr.Code, http.StatusNotFound)
+	}
+	if rr := apiRequest(h, http.MethodPost, "/api/tasks/done", `{"name":"Fix login"}`); rr.Code != http.StatusConflict {
+		t.Errorf("without a session: status code = %d; want %d", rr.Code, http.StatusConflict)
+	}
+	if svc.completedTask != "" {
+		t.Fatalf("task completed without a session")
+	}
+
+	svc.isWorking = true
+	rr := apiRequest(h, http.MethodPost, "/api/tasks/done", `{"name":"Fix login"}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status code = %d; want %d: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	var task api.Task
+	if err := json.Unmarshal(rr.Body.Bytes(), &task); err != nil || task.Status != "done" || task.DoneAt == nil {
+		t.Errorf("response = %s, err %v", rr.Body.String(), err)
+	}
+	if svc.completedTask != "Fix login" {
+		t.Errorf("completed %q; want Fix login", svc.completedTask)
+	}
+}
+
+func TestAPI_GoalsBadStatus(t *testing.T) {
+	h := &Handler{AppService: &mockService{apiToken: "secret"}}
+
+	if rr := apiRequest(h, http.MethodGet, "/api/goals?status=late", ""); rr.Code != http.StatusBadRequest {
+		t.Errorf("status code = %d; want %d", rr.Code, http.StatusBadRequest)
+	}
+	if rr := apiRequest(h, http.MethodGet, "/api/nothing", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("status code = %d; want %d", rr.Code, http.StatusNotFound)
+	}
+}
+
+// TestAPI_Client runs the command-line client against the real routes.
+func TestAPI_Client(t *testing.T) {
+	start := time.Date(2025, 3, 3, 9, 0, 0, 0, time.UTC)
+	end := start.Add(90 * time.Minute)
+	due := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+	svc := &mockService{
+		apiToken:        "secret",
+		status:          app.TrackerStatus{Working: false, WorkedToday: 90 * time.Minute, TasksDoneToday: 1, OpenTasks: 2},
+		sessionsForDate: []app.WorkSession{{StartTime: start, EndTime: &end}},
+		tasksForDate:    []app.Task{{Name: "Write report", Status: "done", DoneAt: &end}},
+		todoGoals:       []app.Goal{{ID: 4, Name: "Ship v1", Status: "todo", DueAt: &due, State: app.GoalUpcoming}},
+	}
+	h := &Handler{AppService: svc}
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	c := client.New(client.Config{Server: srv.URL + "/", Token: "secret"})
+
+	st, err := c.Start()
+	if err != nil || !svc.sessionStarted {
+		t.Fatalf("Start: %v, started %v", err, svc.sessionStarted)
+	}
+	if st.WorkedTodayMins != 90 || st.OpenTasks != 2 {
+		t.Errorf("status = %+v", st)
+	}
+
+	today, err := c.Today()
+	if err != nil {
+		t.Fatalf("Today: %v", err)
+	}
+	if today.WorkedMinutes != 90 || len(today.Sessions) != 1 || len(today.Tasks) != 1 || today.Tasks[0].Name != "Write report" {
+		t.Errorf("today = %+v", today)
+	}
+
+	goals, err := c.Goals(false)
+	if err != nil || len(goals) != 1 || goals[0].ID != 4 || !goals[0].DueAt.Equal(due) {
+		t.Errorf("goals = %+v, err %v", goals, err)
+	}
+
+	_, err = c.CompleteTask("Missing")
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound || !strings.Contains(apiErr.Message, "Missing") {
+		t.Errorf("CompleteTask error = %v; want a 404 naming the task", err)
+	}
+
+	bad := client.New(client.Config{Server: srv.URL, Token: "wrong"})
+	if _, err := bad.Status(); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Status with a wrong token: %v; want 401", err)
+	}
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"abtprj/internal/app"
+	"abtprj/internal/atom"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	feedEntries   = 50
+	feedCacheTime = 5 * time.Minute
+)
+
+// worklogFeed serves the recently completed tasks and goals as an Atom feed.
+func (h *Handler) worklogFeed(w http.ResponseWriter, r *http.Request) {
+	items, err := h.service(r).GetRecentlyCompleted(feedEntries)
+	if err != nil {
+		log.Printf("worklogFeed GetRecentlyCompleted error: %v", err)
+		http.Error(w, "failed to load worklog", http.StatusInternalServerError)
+		return
+	}
+
+	origin := h.baseURL(r)
+	base := origin + basePath(r)
+	var host string
+	if u, err := url.Parse(origin); err == nil {
+		host = u.Hostname()
+	}
+	loc := h.service(r).Location()
+	account := page(r).Account
+	feed := atom.Feed{
+		ID:     base + "/worklog/",
+		Title:  account + "'s worklog",
+		Author: account,
+		Link:   base + "/worklog/",
+		Self:   base + "/worklog/feed.atom",
+	}
+	for _, it := range items {
+		title := it.Name
+		if it.Kind == app.CompletedGoal {
+			title = "Goal reached: " + it.Name
+		}
+		feed.Entries = append(feed.Entries, atom.Entry{
+			// tag URIs (RFC 4151) keep entries distinct across instances
+			ID:        "tag:" + host + ",2024:" + it.Kind + "/" + strconv.Itoa(it.ID),
+			Title:     title,
+			Link:      base + "/worklog/?date=" + it.DoneAt.In(loc).Format("2006-01-02"),
+			Summary:   it.Description,
+			Updated:   it.DoneAt,
+			Published: it.CreatedAt,
+			Category:  it.Kind,
+		})
+	}
+	if len(items) > 0 {
+		feed.Updated = items[0].DoneAt
+	} else {
+		feed.Updated = time.Unix(0, 0)
+	}
+
+	body, err := feed.Render()
+	if err != nil {
+		log.Printf("worklogFeed Render error: %v", err)
+		http.Error(w, "failed to render feed", http.StatusInternalServerError)
+		return
+	}
+	writeCached(w, r, "application/atom+xml; charset=utf-8", feedCacheTime, body)
+}
+
+// baseURL is the scheme and host links to this instance start with: the
+// configured PublicURL, or else the ones the request reached us on. Then
+// X-Forwarded-Proto is only believed from a reverse proxy on the same host,
+// as in clientIP.
+func (h *Handler) baseURL(r *http.Request) string {
+	if h.PublicURL != "" {
+		return h.PublicURL
+	}
+	scheme := "http"
+	if r.TLS != nil || behindLocalProxy(r) && r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"abtprj/internal/app"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testAtomFeed struct {
+	ID      string `xml:"id"`
+	Title   string `xml:"title"`
+	Author  string `xml:"author>name"`
+	Updated string `xml:"updated"`
+	Entries []struct {
+		ID      string `xml:"id"`
+		Title   string `xml:"title"`
+		Summary string `xml:"summary"`
+		Updated string `xml:"updated"`
+		Link    struct {
+			Href string `xml:"href,attr"`
+		} `xml:"link"`
+		Category struct {
+			Term string `xml:"term,attr"`
+		} `xml:"category"`
+	} `xml:"entry"`
+}
+
+func TestWorkLogHandler_Feed(t *testing.T) {
+	moscow, _ := time.LoadLocation("Europe/Moscow")
+	svc := &mockService{
+		loc: moscow,
+		completed: []app.CompletedItem{
+			{Kind: app.CompletedTask, ID: 7, Name: "Fix <login>", Description: "cookies & sessions",
+				DoneAt: time.Date(2025, 3, 1, 22, 30, 0, 0, time.UTC), CreatedAt: time.Date(2025, 2, 27, 9, 0, 0, 0, time.UTC)},
+			{Kind: app.CompletedGoal, ID: 3, Name: "Ship v1", DoneAt: time.Date(2025, 2, 28, 12, 0, 0, 0, time.UTC)},
+		},
+	}
+	h := &Handler{AppService: svc}
+
+	req := httptest.NewRequest(http.MethodGet, "http://log.example.com:8080/worklog/feed.atom", nil)
+	rr := httptest.NewRecorder()
+	h.WorkLogHandler(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status code = %d; want %d", rr.Code, http.StatusOK)
+	}
+	if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/atom+xml") {
+		t.Errorf("Content-Type = %q; want application/atom+xml", ct)
+	}
+	if svc.completedLimit != feedEntries {
+		t.Errorf("limit = %d; want %d", svc.completedLimit, feedEntries)
+	}
+
+	var feed testAtomFeed
+	if err := xml.Unmarshal(rr.Body.Bytes(), &feed); err != nil {
+		t.Fatalf("feed is not valid XML: %v\n%s", err, rr.Body.String())
+	}
+	if feed.ID != "http://log.example.com:8080/worklog/" || feed.Updated != "2025-03-01T22:30:00Z" {
+		t.Errorf("feed id = %q, updated = %q", feed.ID, feed.Updated)
+	}
+	if len(feed.Entries) != 2 {
+		t.Fatalf("got %d entries; want 2", len(feed.Entries))
+	}
+
+	task := feed.Entries[0]
+	if task.ID != "tag:log.example.com,2024:task/7" || task.Title != "Fix <login>" || task.Summary != "cookies & sessions" || task.Category.Term != "task" {
+		t.Errorf("task entry = %+v", task)
+	}
+	// completed at 01:30 on 2 March in Moscow
+	if task.Link.Href != "http://log.example.com:8080/worklog/?date=2025-03-02" {
+		t.Errorf("task link = %q", task.Link.Href)
+	}
+	if goal := feed.Entries[1]; goal.Title != "Goal reached: Ship v1" || goal.ID != "tag:log.example.com,2024:goal/3" {
+		t.Errorf("goal entry = %+v", goal)
+	}
+}
+
+func TestWorkLogHandler_FeedNamesTheAccount(t *testing.T) {
+	svc := &mockService{users: map[string]int{"alice": 7}, feedToken: "secret"}
+	h := &Handler{AppService: svc}
+
+	rr := serveMux(h, httptest.NewRequest(http.MethodGet, "/u/alice/worklog/feed.atom", nil))
+	var feed testAtomFeed
+	if err := xml.Unmarshal(rr.Body.Bytes(), &feed); err != nil {
+		t.Fatalf("feed is not valid XML: %v\n%s", err, rr.Body.String())
+	}
+	if feed.Title != "alice's worklog" || feed.Author != "alice" {
+		t.Errorf("feed title = %q, author = %q; want alice's", feed.Title, feed.Author)
+	}
+
+	rr = serveMux(h, httptest.NewRequest(http.MethodGet, "/u/alice/calendar/goals.ics?token=secret", nil))
+	if body := rr.Body.String(); !strings.Contains(body, "X-WR-CALNAME:alice goals\r\n") {
+		t.Errorf("calendar is not named after alice:\n%s", body)
+	}
+}
+
+func TestWorkLogHandler_FeedLinks(t *testing.T) {
+	tests := []struct {
+		name      string
+		publicURL string
+		remote    string
+		want      string
+	}{
+		{"public URL wins over the request", "https://log.example.com", "192.0.2.1:1234", "https://log.example.com/worklog/"},
+		{"forwarded proto from a remote client is ignored", "", "192.0.2.1:1234", "http://attacker.example/worklog/"},
+		{"forwarded proto from a local proxy", "", "127.0.0.1:1234", "https://attacker.example/worklog/"},
+	}
+	for _, tt := range tests {
+		svc := &mockService{completed: []app.CompletedItem{{Kind: app.CompletedTask, ID: 7, Name: "Fix", DoneAt: time.Now()}}}
+		h := &Handler{AppService: svc, PublicURL: tt.publicURL}
+
+		req := httptest.NewRequest(http.MethodGet, "http://attacker.example/worklog/feed.atom", nil)
+		req.RemoteAddr = tt.remote
+		req.Header.Set("X-Forwarded-Proto", "https")
+		rr := httptest.NewRecorder()
+		h.WorkLogHandler(rr, req)
+
+		var feed testAtomFeed
+		if err := xml.Unmarshal(rr.Body.Bytes(), &feed); err != nil {
+			t.Fatalf("%s: feed is not valid XML: %v", tt.name, err)
+		}
+		if feed.ID != tt.want {
+			t.Errorf("%s: feed id = %q; want %q", tt.name, feed.ID, tt.want)
+		}
+		if tt.publicURL != "" && feed.Entries[0].ID != "tag:log.example.com,2024:task/7" {
+			t.Errorf("%s: entry id = %q; want the public host in it", tt.name, feed.Entries[0].ID)
+		}
+	}
+}
+
+func TestWorkLogHandler_FeedNotModified(t *testing.T) {
+	h := &Handler{AppService: &mockService{}}
+
+	req := httptest.NewRequest(http.MethodGet, "/worklog/feed.atom", nil)
+	rr := httptest.NewRecorder()
+	h.WorkLogHandler(rr, req)
+
+	var feed testAtomFeed
+	if err := xml.Unmarshal(rr.Body.Bytes(), &feed); err != nil || len(feed.Entries) != 0 {
+		t.Fatalf("empty feed = %q, err %v", rr.Body.String(), err)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/worklog/feed.atom", nil)
+	req.Header.Set("If-None-Match", rr.Header().Get("ETag"))
+	rr = httptest.NewRecorder()
+	h.WorkLogHandler(rr, req)
+
+	if rr.Code != http.StatusNotModified {
+		t.Errorf("status code = %d; want %d", rr.Code, http.StatusNotModified)
+	}
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"abtprj/internal/api"
+	"abtprj/internal/app"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// auditPageSize is how many entries the audit page shows at a time.
+const auditPageSize = 50
+
+type AuditPageData struct {
+	Page
+	Query    url.Values // the filters, to fill the form in again
+	Entries  []app.AuditEntry
+	Older    string // link to the next page, empty on the last
+	Actions  []string
+	Entities []string
+}
+
+// parseAuditFilter reads the audit log filters of a page or API request:
+// action, entity, actor, from and to as dates in loc, both included, before
+// as the id to page back from and limit.
+func parseAuditFilter(q url.Values, loc *time.Location) (app.AuditFilter, error) {
+	f := app.AuditFilter{Action: q.Get("action"), Entity: q.Get("entity"), Actor: q.Get("actor")}
+	var err error
+	if s := q.Get("from"); s != "" {
+		if f.From, err = time.ParseInLocation("2006-01-02", s, loc); err != nil {
+			return f, errors.New("invalid from date")
+		}
+	}
+	if s := q.Get("to"); s != "" {
+		if f.To, err = time.ParseInLocation("2006-01-02", s, loc); err != nil {
+			return f, errors.New("invalid to date")
+		}
+		f.To = f.To.AddDate(0, 0, 1)
+	}
+	if !f.From.IsZero() && !f.To.IsZero() && f.To.Before(f.From) {
+		return f, errors.New("to date is before from date")
+	}
+	if s := q.Get("before"); s != "" {
+		if f.BeforeId, err = strconv.ParseInt(s, 10, 64); err != nil || f.BeforeId <= 0 {
+			return f, errors.New("invalid before id")
+		}
+	}
+	if s := q.Get("limit"); s != "" {
+		if f.Limit, err = strconv.Atoi(s); err != nil || f.Limit <= 0 {
+			return f, errors.New("invalid limit")
+		}
+	}
+	return f, nil
+}
+
+func (h *Handler) renderAuditPage(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter, err := parseAuditFilter(q, h.service(r).Location())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if filter.Limit == 0 {
+		filter.Limit = auditPageSize
+	}
+	entries, err := h.service(r).ListAudit(filter)
+	if err != nil {
+		log.Printf("renderAuditPage ListAudit error: %v", err)
+		http.Error(w, "failed to load audit log", http.StatusInternalServerError)
+		return
+	}
+
+	data := AuditPageData{
+		Page:     page(r),
+		Query:    q,
+		Entries:  entries,
+		Actions:  app.AuditActions,
+		Entities: app.AuditEntities,
+	}
+	if len(entries) > 0 && len(entries) == filter.Limit {
+		older := url.Values{}
+		for k, v := range q {
+			older[k] = v
+		}
+		older.Set("before", strconv.FormatInt(entries[len(entries)-1].ID, 10))
+		data.Older = "/admin/audit?" + older.Encode()
+	}
+	if err := h.Templates.ExecuteTemplate(w, "audit.html", data); err != nil {
+		log.Printf("template exec error: %v", err)
+	}
+}
+
+// apiAudit lists the audit log of the token's account, newest first, with the
+// filters of the admin page.
+func (h *Handler) apiAudit(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAuditFilter(r.URL.Query(), h.service(r).Location())
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	entries, err := h.service(r).ListAudit(filter)
+	if err != nil {
+		log.Printf("apiAudit ListAudit error: %v", err)
+		writeAPIError(w, http.StatusInternalServerError, "failed to load audit log")
+		return
+	}
+
+	out := []api.AuditEntry{}
+	for _, e := range entries {
+		out = append(out, api.AuditEntry{
+			ID:       e.ID,
+			At:       e.At,
+			Actor:    e.Actor,
+			IP:       e.IP,
+			Action:   e.Action,
+			Entity:   e.Entity,
+			EntityID: e.EntityID,
+			Before:   rawJSON(e.Before),
+			After:    rawJSON(e.After),
+		})
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func rawJSON(s string) json.RawMessage {
+	if s == "" {
+		return nil
+	}
+	return json.RawMessage(s)
+}
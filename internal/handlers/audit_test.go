@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"abtprj/internal/api"
+	"abtprj/internal/app"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAudit_ChangesAreMadeAsTheAdmin(t *testing.T) {
+	svc := &mockService{adminSession: app.AdminSession{Token: "t", AdminID: 1, Login: "alice", AccountID: 1}}
+	h := &Handler{AppService: svc}
+
+	rr := serveAdmin(h, http.MethodPost, "/admin/add-task", url.Values{"name": {"Taxes"}})
+
+	if rr.Code != http.StatusSeeOther {
+		t.Fatalf("status %d", rr.Code)
+	}
+	if svc.actor.UserID != 1 || svc.actor.Login != "alice" || svc.actor.IP == "" {
+		t.Errorf("actor = %+v; want alice with her address", svc.actor)
+	}
+}
+
+func TestAudit_APIChangesAreMadeAsTheTokenOwner(t *testing.T) {
+	svc := &mockService{apiToken: "secret", apiUser: 3, apiLogin: "carol"}
+	h := &Handler{AppService: svc}
+
+	if rr := apiRequest(h, http.MethodPost, "/api/tasks", `{"name":"Taxes"}`); rr.Code != http.StatusCreated {
+		t.Fatalf("status %d: %s", rr.Code, rr.Body)
+	}
+	if svc.actor.UserID != 3 || svc.actor.Login != "carol" {
+		t.Errorf("actor = %+v; want the token's account 3, carol", svc.actor)
+	}
+}
+
+func TestAudit_Page(t *testing.T) {
+	at := time.Date(2025, 3, 2, 10, 0, 0, 0, time.UTC)
+	svc := &mockService{
+		adminSession: app.AdminSession{Token: "t", AdminID: 1, Login: "alice", AccountID: 1, AccountLogin: "alice", Role: app.RoleOwner},
+		audit: []app.AuditEntry{
+			{ID: 7, At: at, Actor: "bob", IP: "10.0.0.2", Action: "complete", Entity: app.AuditTask, EntityID: "4",
+				Before: `{"id":4,"name":"Taxes","status":"todo"}`, After: `{"id":4,"name":"Taxes","status":"done"}`},
+			{ID: 6, At: at, Action: "regenerate", Entity: app.AuditToken, EntityID: "api.token"},
+		},
+	}
+	h := &Handler{Templates: loadViews(t), AppService: svc}
+
+	rr := serveAdmin(h, http.MethodGet, "/admin/audit?action=complete&entity=task&actor=bob&from=2025-03-01&to=2025-03-02&before=9", nil)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rr.Code, rr.Body)
+	}
+	f := svc.auditFilter
+	if f.Action != "complete" || f.Entity != app.AuditTask || f.Actor != "bob" || f.BeforeId != 9 || f.Limit != auditPageSize {
+		t.Errorf("filter = %+v", f)
+	}
+	if !f.From.Equal(time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)) || !f.To.Equal(time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("range = %v to %v; want both days included", f.From, f.To)
+	}
+	body := rr.Body.String()
+	for _, want := range []string{"10.0.0.2", "&#34;status&#34;:&#34;done&#34;", "command line", `<option value="complete" selected>`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("page is missing %q", want)
+		}
+	}
+	if strings.Contains(body, "Older changes") {
+		t.Error("a page that is not full has no older page")
+	}
+
+	if rr := serveAdmin(h, http.MethodGet, "/admin/audit?from=March", nil); rr.Code != http.StatusBadRequest {
+		t.Errorf("invalid date: status %d; want %d", rr.Code, http.StatusBadRequest)
+	}
+}
+
+func TestAudit_PageLinksToOlderEntries(t *testing.T) {
+	svc := &mockService{adminSession: app.AdminSession{Token: "t", AdminID: 1, Login: "alice", AccountID: 1, Role: app.RoleOwner}}
+	for id := auditPageSize; id > 0; id-- {
+		svc.audit = append(svc.audit, app.AuditEntry{ID: int64(id + 100), Action: "create", Entity: app.AuditTask})
+	}
+	h := &Handler{Templates: loadViews(t), AppService: svc}
+
+	body := serveAdmin(h, http.MethodGet, "/admin/audit?entity=task", nil).Body.String()
+
+	if !strings.Contains(body, `href="/admin/audit?before=101&amp;entity=task"`) {
+		t.Error("a full page should link to the entries before its last one, keeping the filters")
+	}
+}
+
+func TestAudit_API(t *testing.T) {
+	svc := &mockService{
+		apiToken: "secret",
+		audit: []app.AuditEntry{
+			{ID: 2, Actor: "alice", Action: "update", Entity: app.AuditSetting, EntityID: "visibility.default", Before: `"public"`, After: `"private"`},
+			{ID: 1, Actor: "alice", Action: "regenerate", Entity: app.AuditToken},
+		},
+	}
+	h := &Handler{AppService: svc}
+
+	rr := apiRequest(h, http.MethodGet, "/api/audit?entity=setting&limit=10", "")
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rr.Code, rr.Body)
+	}
+	if svc.auditFilter.Entity != app.AuditSetting || svc.auditFilter.Limit != 10 {
+		t.Errorf("filter = %+v", svc.auditFilter)
+	}
+	var entries []api.AuditEntry
+	if err := json.Unmarshal(rr.Body.Bytes(), &entries); err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || string(entries[0].Before) != `"public"` || string(entries[0].After) != `"private"` {
+		t.Errorf("entries = %+v", entries)
+	}
+	if !strings.Contains(rr.Body.String(), `"id":1,`) || strings.Contains(rr.Body.String(), `"before":null`) {
+		t.Errorf("an entry without values should leave them out: %s", rr.Body)
+	}
+
+	if rr := apiRequest(h, http.MethodGet, "/api/audit?limit=-1", ""); rr.Code != http.StatusBadRequest {
+		t.Errorf("invalid limit: status %d; want %d", rr.Code, http.StatusBadRequest)
+	}
+}
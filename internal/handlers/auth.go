@@ -1,19 +1,75 @@
 package handlers
 
 import (
+	"abtprj/internal/app"
+	"context"
+	"errors"
+	"fmt"
 	"log"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
 const sessionCookieName = "admin_session"
 
+type adminSessionKey struct{}
+
+// currentAdmin returns the session requireAdmin attached to the request.
+func currentAdmin(r *http.Request) app.AdminSession {
+	session, _ := r.Context().Value(adminSessionKey{}).(app.AdminSession)
+	return session
+}
+
+// requireAdmin lets the request through only with a live session cookie, and
+// anything but a GET only with the session's CSRF token. An admin who has to
+// change their password is kept on the password form until they do. The handler
+// works on the data of the account the session acts for, which is the admin's
+// own unless they switched to one that gave them access.
 func (h *Handler) requireAdmin(handler http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		cookie, err := r.Cookie(sessionCookieName)
-		if err != nil || cookie.Value != "authenticated" {
+		if err != nil {
 			http.Redirect(w, r, "/login", http.StatusFound)
 			return
 		}
+		session, err := h.AppService.GetAdminSession(cookie.Value)
+		if errors.Is(err, app.ErrNoSession) {
+			http.Redirect(w, r, "/login", http.StatusFound)
+			return
+		}
+		if err != nil {
+			log.Printf("requireAdmin GetAdminSession error: %v", err)
+			http.Error(w, "failed to check session", http.StatusInternalServerError)
+			return
+		}
+		if !safeMethod(r.Method) && !validCSRF(w, r, session.CSRFToken()) {
+			log.Printf("requireAdmin: %s %s from %s without a valid CSRF token", r.Method, r.URL.Path, clientIP(r))
+			http.Error(w, "invalid or missing CSRF token", http.StatusForbidden)
+			return
+		}
+		if session.MustChangePassword && r.URL.Path != "/admin/password" && r.URL.Path != "/admin/logout" {
+			http.Redirect(w, r, "/admin/password", http.StatusFound)
+			return
+		}
+		ctx := context.WithValue(r.Context(), adminSessionKey{}, session)
+		actor := app.Actor{UserID: session.AdminID, Login: session.Login, IP: clientIP(r)}
+		ctx = context.WithValue(ctx, serviceKey{}, h.AppService.ForUser(session.AccountID).As(actor))
+		handler(w, r.WithContext(ctx))
+	}
+}
+
+// requireOwner keeps pages that cover every account, such as the backup and the
+// failed login list, to the instance owner.
+func (h *Handler) requireOwner(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !currentAdmin(r).Owner {
+			http.Error(w, "only the instance owner can do this", http.StatusForbidden)
+			return
+		}
 		handler(w, r)
 	}
 }
@@ -32,8 +88,15 @@ func (h *Handler) HandleLogin(w http.ResponseWriter, r *http.Request) {
 	login := r.FormValue("login")
 	password := r.FormValue("password")
 
-	if err := h.AppService.LoginAdmin(login, password); err != nil {
+	session, err := h.AppService.LoginAdmin(login, password, clientIP(r))
+	var throttled *app.ThrottleError
+	if errors.As(err, &throttled) {
+		h.renderThrottled(w, "login.html", throttled)
+		return
+	}
+	if err != nil {
 		log.Printf("login error: %v", err)
+		w.WriteHeader(http.StatusUnauthorized)
 		err := h.Templates.ExecuteTemplate(w, "login.html", map[string]string{
 			"Error": "Invalid login or password",
 		})
@@ -44,13 +107,135 @@ func (h *Handler) HandleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Set session cookie and redirect
+	setSessionCookie(w, session)
+	if session.TOTPPending {
+		http.Redirect(w, r, "/login/totp", http.StatusSeeOther)
+		return
+	}
+	redirectAfterLogin(w, r, session)
+}
+
+func (h *Handler) TOTPLoginPage(w http.ResponseWriter, r *http.Request) {
+	if err := h.Templates.ExecuteTemplate(w, "login_totp.html", nil); err != nil {
+		http.Error(w, "Failed to render login page", http.StatusInternalServerError)
+	}
+}
+
+// HandleTOTPLogin takes the second factor for a login whose password was accepted.
+func (h *Handler) HandleTOTPLogin(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	session, err := h.AppService.CompleteTOTPLogin(cookie.Value, r.FormValue("code"), clientIP(r))
+	var throttled *app.ThrottleError
+	switch {
+	case errors.As(err, &throttled):
+		h.renderThrottled(w, "login_totp.html", throttled)
+		return
+	case errors.Is(err, app.ErrNoSession):
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	case errors.Is(err, app.ErrInvalidCode):
+		w.WriteHeader(http.StatusUnauthorized)
+		if err := h.Templates.ExecuteTemplate(w, "login_totp.html", map[string]string{
+			"Error": "Invalid code",
+		}); err != nil {
+			log.Printf("HandleTOTPLogin template error: %v", err)
+		}
+		return
+	case err != nil:
+		log.Printf("HandleTOTPLogin CompleteTOTPLogin error: %v", err)
+		http.Error(w, "failed to log in", http.StatusInternalServerError)
+		return
+	}
+
+	setSessionCookie(w, session)
+	redirectAfterLogin(w, r, session)
+}
+
+// renderThrottled shows the login form again with 429 and a Retry-After header.
+func (h *Handler) renderThrottled(w http.ResponseWriter, page string, throttled *app.ThrottleError) {
+	seconds := int(math.Ceil(throttled.RetryAfter.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	w.WriteHeader(http.StatusTooManyRequests)
+	err := h.Templates.ExecuteTemplate(w, page, map[string]string{
+		"Error": fmt.Sprintf("Too many failed attempts. Try again in %s.", throttled.RetryAfter.Round(time.Second)),
+	})
+	if err != nil {
+		log.Printf("renderThrottled template error: %v", err)
+	}
+}
+
+// clientIP is the address a login attempt came from. X-Forwarded-For is only
+// believed from a reverse proxy on the same host, and only its last hop, since
+// anything before that is up to the client.
+func clientIP(r *http.Request) string {
+	host := remoteHost(r)
+	if behindLocalProxy(r) {
+		if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+			hops := strings.Split(fwd, ",")
+			if last := strings.TrimSpace(hops[len(hops)-1]); net.ParseIP(last) != nil {
+				return last
+			}
+		}
+	}
+	return host
+}
+
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// behindLocalProxy reports whether r came from a reverse proxy on the same
+// host, the only one whose X-Forwarded-* headers are believed.
+func behindLocalProxy(r *http.Request) bool {
+	ip := net.ParseIP(remoteHost(r))
+	return ip != nil && ip.IsLoopback()
+}
+
+func setSessionCookie(w http.ResponseWriter, session app.AdminSession) {
 	http.SetCookie(w, &http.Cookie{
 		Name:     sessionCookieName,
-		Value:    "authenticated",
+		Value:    session.Token,
+		Expires:  session.ExpiresAt,
 		HttpOnly: true,
 		Path:     "/",
 		SameSite: http.SameSiteStrictMode,
 	})
+}
+
+func redirectAfterLogin(w http.ResponseWriter, r *http.Request, session app.AdminSession) {
+	if session.MustChangePassword {
+		http.Redirect(w, r, "/admin/password", http.StatusSeeOther)
+		return
+	}
 	http.Redirect(w, r, "/admin/", http.StatusSeeOther)
 }
+
+func (h *Handler) logout(w http.ResponseWriter, r *http.Request) {
+	if err := h.AppService.LogoutAdmin(currentAdmin(r).Token); err != nil {
+		log.Printf("logout LogoutAdmin error: %v", err)
+		http.Error(w, "failed to log out", http.StatusInternalServerError)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    "",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Path:     "/",
+		SameSite: http.SameSiteStrictMode,
+	})
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
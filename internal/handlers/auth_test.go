@@ -1,15 +1,16 @@
 package handlers
 
 import (
+	"abtprj/internal/app"
+	"html/template"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
-	"text/template"
 )
 
 func TestRequireAdmin_NoCookie_Redirects(t *testing.T) {
-	h := &Handler{}
+	h := &Handler{AppService: &mockService{}}
 	innerCalled := false
 	inner := func(w http.ResponseWriter, r *http.Request) {
 		innerCalled = true
@@ -35,7 +36,7 @@ func TestRequireAdmin_NoCookie_Redirects(t *testing.T) {
 }
 
 func TestRequireAdmin_WrongCookie_Redirects(t *testing.T) {
-	h := &Handler{}
+	h := &Handler{AppService: &mockService{adminSession: app.AdminSession{Token: "right"}}}
 	innerCalled := false
 	inner := func(w http.ResponseWriter, r *http.Request) {
 		innerCalled = true
@@ -63,17 +64,20 @@ func TestRequireAdmin_WrongCookie_Redirects(t *testing.T) {
 }
 
 func TestRequireAdmin_ValidCookie_Allows(t *testing.T) {
-	h := &Handler{}
+	h := &Handler{AppService: &mockService{adminSession: app.AdminSession{Token: "right", Login: "admin"}}}
 	innerCalled := false
 	inner := func(w http.ResponseWriter, r *http.Request) {
 		innerCalled = true
+		if login := currentAdmin(r).Login; login != "admin" {
+			t.Errorf("expected session of admin in context, got %q", login)
+		}
 		w.Write([]byte("OK"))
 	}
 
 	wrapped := h.requireAdmin(inner)
 
 	req := httptest.NewRequest(http.MethodGet, "/admin/secret", nil)
-	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "authenticated"})
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "right"})
 	rr := httptest.NewRecorder()
 
 	wrapped(rr, req)
@@ -129,12 +133,91 @@ func TestLoginPage_Success_SetsCookieAndRedirects(t *testing.T) {
 	cookies := rr.Result().Cookies()
 	found := false
 	for _, cookie := range cookies {
-		if cookie.Name == sessionCookieName && cookie.Value == "authenticated" {
+		if cookie.Name == sessionCookieName && cookie.Value == "session-token" {
 			found = true
 		}
 	}
 	if !found {
-		t.Error("expected cookie &q=authenticated, but it wasn't set", sessionCookieName)
+		t.Errorf("expected cookie %q with the session token, but it wasn't set", sessionCookieName)
+	}
+}
+
+func TestHandleLogin_WrongPassword(t *testing.T) {
+	h := &Handler{Templates: createLoginTemplate(), AppService: &mockService{loginErr: app.ErrInvalidLogin}}
+
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader("login=admin&password=nope"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+
+	h.HandleLogin(rr, req)
+
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status: %d, got: %d", http.StatusUnauthorized, rr.Code)
+	}
+	if len(rr.Result().Cookies()) != 0 {
+		t.Error("expected no cookie after a failed login")
+	}
+}
+
+func TestHandleLogin_MustChangePassword_RedirectsToForm(t *testing.T) {
+	svc := &mockService{adminSession: app.AdminSession{MustChangePassword: true}}
+	h := &Handler{Templates: createLoginTemplate(), AppService: svc}
+
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader("login=admin&password=admin"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+
+	h.HandleLogin(rr, req)
+
+	if loc := rr.Header().Get("Location"); loc != "/admin/password" {
+		t.Errorf("expected redirect to /admin/password, got: %s", loc)
+	}
+}
+
+func TestRequireAdmin_MustChangePassword(t *testing.T) {
+	svc := &mockService{adminSession: app.AdminSession{Token: "t", MustChangePassword: true}}
+	h := &Handler{AppService: svc}
+	wrapped := h.requireAdmin(func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("OK")) })
+
+	for path, wantInner := range map[string]bool{
+		"/admin/":         false,
+		"/admin/export":   false,
+		"/admin/password": true,
+		"/admin/logout":   true,
+	} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "t"})
+		rr := httptest.NewRecorder()
+		wrapped(rr, req)
+
+		if got := rr.Body.String() == "OK"; got != wantInner {
+			t.Errorf("%s: inner handler called = %v, want %v", path, got, wantInner)
+		}
+		if !wantInner && rr.Header().Get("Location") != "/admin/password" {
+			t.Errorf("%s: expected redirect to /admin/password, got %q", path, rr.Header().Get("Location"))
+		}
+	}
+}
+
+func TestLogout_ClearsSession(t *testing.T) {
+	svc := &mockService{adminSession: app.AdminSession{Token: "t"}}
+	h := &Handler{AppService: svc}
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/logout", nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "t"})
+	req.Header.Set(csrfHeaderName, svc.adminSession.CSRFToken())
+	rr := httptest.NewRecorder()
+	h.requireAdmin(h.AdminHandler)(rr, req)
+
+	if svc.loggedOut != "t" {
+		t.Errorf("expected session t to be logged out, got %q", svc.loggedOut)
+	}
+	if loc := rr.Header().Get("Location"); loc != "/login" {
+		t.Errorf("expected redirect to /login, got: %s", loc)
+	}
+	cookies := rr.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].MaxAge >= 0 {
+		t.Errorf("expected the session cookie to be cleared, got %v", cookies)
 	}
 }
 
@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"time"
+)
+
+func (h *Handler) downloadBackup(w http.ResponseWriter, r *http.Request) {
+	// Built in memory first so a failed dump is a 500 rather than a truncated download.
+	var buf bytes.Buffer
+	if err := h.service(r).Backup(&buf); err != nil {
+		log.Printf("downloadBackup Backup error: %v", err)
+		http.Error(w, "failed to create backup", http.StatusInternalServerError)
+		return
+	}
+
+	filename := "abtprj-backup-" + time.Now().Format("2006-01-02-150405") + ".tar.gz"
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		log.Printf("downloadBackup write error: %v", err)
+	}
+}
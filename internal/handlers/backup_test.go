@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"abtprj/internal/app"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func backupRequest(h *Handler) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/admin/backup", nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "t"})
+	rr := httptest.NewRecorder()
+	h.requireAdmin(h.AdminHandler)(rr, req)
+	return rr
+}
+
+func TestAdminHandler_Backup(t *testing.T) {
+	h := &Handler{AppService: &mockService{adminSession: app.AdminSession{Token: "t", Owner: true}}}
+
+	rr := backupRequest(h)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status code = %d; want %d", rr.Code, http.StatusOK)
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "application/gzip" {
+		t.Errorf("Content-Type = %q; want application/gzip", ct)
+	}
+	if cd := rr.Header().Get("Content-Disposition"); !strings.Contains(cd, "attachment") || !strings.HasSuffix(cd, `.tar.gz"`) {
+		t.Errorf("Content-Disposition = %q; want a .tar.gz attachment", cd)
+	}
+	if body := rr.Body.String(); body != "BACKUP" {
+		t.Errorf("body = %q; want BACKUP", body)
+	}
+}
+
+func TestAdminHandler_Backup_Error(t *testing.T) {
+	h := &Handler{AppService: &mockService{
+		adminSession: app.AdminSession{Token: "t", Owner: true},
+		backupErr:    errors.New("db down"),
+	}}
+
+	rr := backupRequest(h)
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("status code = %d; want %d", rr.Code, http.StatusInternalServerError)
+	}
+	if cd := rr.Header().Get("Content-Disposition"); cd != "" {
+		t.Errorf("Content-Disposition = %q; want none on failure", cd)
+	}
+}
+
+func TestAdminHandler_Backup_OwnerOnly(t *testing.T) {
+	h := &Handler{AppService: &mockService{adminSession: app.AdminSession{Token: "t", Login: "bob"}}}
+
+	rr := backupRequest(h)
+
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("status code = %d; want %d", rr.Code, http.StatusForbidden)
+	}
+	if body := rr.Body.String(); strings.Contains(body, "BACKUP") {
+		t.Errorf("body = %q; a backup of every account must not reach other users", body)
+	}
+}
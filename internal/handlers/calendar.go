@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"abtprj/internal/app"
+	"log"
+	"net/http"
+	"time"
+)
+
+type CalendarPageData struct {
+	app.MonthCalendar
+	Page
+}
+
+func (h *Handler) CalendarHandler(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/calendar/":
+		h.renderCalendarPage(w, r)
+	case r.Method == http.MethodGet && r.URL.Path == "/calendar/goals.ics":
+		h.goalsFeed(w, r)
+	case r.Method == http.MethodGet && r.URL.Path == "/calendar/sessions.ics":
+		h.sessionsFeed(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (h *Handler) renderCalendarPage(w http.ResponseWriter, r *http.Request) {
+	month := time.Now().In(h.service(r).Location())
+	if raw := r.URL.Query().Get("month"); raw != "" {
+		parsed, err := time.Parse("2006-01", raw)
+		if err != nil {
+			http.Error(w, "invalid month, expected YYYY-MM", http.StatusBadRequest)
+			return
+		}
+		month = parsed
+	}
+
+	cal, err := h.service(r).GetMonthCalendar(month.Year(), month.Month())
+	if err != nil {
+		log.Printf("renderCalendarPage GetMonthCalendar error: %v", err)
+		http.Error(w, "failed to load calendar", http.StatusInternalServerError)
+		return
+	}
+
+	data := CalendarPageData{MonthCalendar: cal, Page: page(r)}
+	if err := h.Templates.ExecuteTemplate(w, "calendar.html", data); err != nil {
+		log.Printf("template exec error: %v", err)
+	}
+}
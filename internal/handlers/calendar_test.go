@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"abtprj/internal/app"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func createCalendarTemplate() *template.Template {
+	return template.Must(template.New("calendar.html").Parse(`
+{{define "calendar.html"}}
+TITLE: {{.Title}} PREV: {{.Prev}} NEXT: {{.Next}}
+{{- range .Weeks}}{{range .}}
+{{.Date}}|{{.Hours}}|{{.TasksDone}}|{{.GoalsDue}}
+{{- end}}{{end}}
+{{end}}
+`))
+}
+
+func TestCalendarHandler_NotFound(t *testing.T) {
+	h := &Handler{Templates: createCalendarTemplate(), AppService: &mockService{}}
+
+	req := httptest.NewRequest(http.MethodGet, "/calendar", nil)
+	rr := httptest.NewRecorder()
+	h.CalendarHandler(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("status code = %d; want %d", rr.Code, http.StatusNotFound)
+	}
+}
+
+func TestCalendarHandler_OK(t *testing.T) {
+	svc := &mockService{
+		calendar: app.MonthCalendar{
+			Title: "March 2025",
+			Prev:  "2025-02",
+			Next:  "2025-04",
+			Weeks: [][]app.CalendarDay{{
+				{Date: "2025-03-03", Day: 3, InMonth: true, Worked: 150 * time.Minute, TasksDone: 2, GoalsDue: 1},
+				{Date: "2025-03-04", Day: 4, InMonth: true},
+			}},
+		},
+	}
+	h := &Handler{Templates: createCalendarTemplate(), AppService: svc}
+
+	req := httptest.NewRequest(http.MethodGet, "/calendar/?month=2025-03", nil)
+	rr := httptest.NewRecorder()
+	h.CalendarHandler(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status code = %d; want %d", rr.Code, http.StatusOK)
+	}
+	if svc.calendarYear != 2025 || svc.calendarMonth != time.March {
+		t.Errorf("requested %d-%d; want 2025-3", svc.calendarYear, svc.calendarMonth)
+	}
+	body := rr.Body.String()
+	for _, want := range []string{"TITLE: March 2025 PREV: 2025-02 NEXT: 2025-04", "2025-03-03|2.5h|2|1", "2025-03-04||0|0"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body = %q; want to contain %q", body, want)
+		}
+	}
+}
+
+func TestCalendarHandler_DefaultsToCurrentMonth(t *testing.T) {
+	// UTC+14, so its month can differ from the server's
+	kiritimati, _ := time.LoadLocation("Pacific/Kiritimati")
+	svc := &mockService{loc: kiritimati}
+	h := &Handler{Templates: createCalendarTemplate(), AppService: svc}
+
+	req := httptest.NewRequest(http.MethodGet, "/calendar/", nil)
+	rr := httptest.NewRecorder()
+	h.CalendarHandler(rr, req)
+
+	now := time.Now().In(kiritimati)
+	if svc.calendarYear != now.Year() || svc.calendarMonth != now.Month() {
+		t.Errorf("requested %d-%d; want %d-%d", svc.calendarYear, svc.calendarMonth, now.Year(), now.Month())
+	}
+}
+
+func TestCalendarHandler_InvalidMonth(t *testing.T) {
+	h := &Handler{Templates: createCalendarTemplate(), AppService: &mockService{}}
+
+	req := httptest.NewRequest(http.MethodGet, "/calendar/?month=2025-13", nil)
+	rr := httptest.NewRecorder()
+	h.CalendarHandler(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("status code = %d; want %d", rr.Code, http.StatusBadRequest)
+	}
+}
+
+func TestRegisterRoutes_Calendar(t *testing.T) {
+	svc := &mockService{feedToken: "secret"}
+	h := &Handler{Templates: createCalendarTemplate(), AppService: svc}
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	for path, want := range map[string]int{
+		"/calendar/":                       http.StatusOK,
+		"/calendar/goals.ics?token=secret": http.StatusOK,
+		"/calendar/sessions.ics":           http.StatusForbidden,
+	} {
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		if rr.Code != want {
+			t.Errorf("GET %s: status code = %d; want %d", path, rr.Code, want)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"abtprj/internal/app"
+	"bytes"
+	"html/template"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func csrfHandler(svc *mockService) *Handler {
+	return &Handler{
+		Templates: template.Must(template.New("admin.html").Parse(
+			`{{define "admin.html"}}<meta name="csrf-token" content="{{.CSRFToken}}">{{end}}` +
+				`{{define "import.html"}}imported={{.Imported}} error={{.Error}}{{end}}`)),
+		AppService: svc,
+	}
+}
+
+func TestCSRF_RejectsStateChangingRequests(t *testing.T) {
+	session := app.AdminSession{Token: "t"}
+	tests := []struct {
+		name  string
+		token string
+		in    string // "header" or "form"
+	}{
+		{"missing", "", "form"},
+		{"wrong form field", "forged", "form"},
+		{"wrong header", "forged", "header"},
+		{"token of another session", app.AdminSession{Token: "other"}.CSRFToken(), "form"},
+	}
+	paths := []string{"/admin/add-task", "/admin/complete-task", "/admin/create-goal",
+		"/admin/start-work-session", "/admin/end-work-session", "/admin/regenerate-api-token", "/admin/logout"}
+
+	for _, tt := range tests {
+		for _, path := range paths {
+			svc := &mockService{adminSession: session}
+			h := csrfHandler(svc)
+
+			form := url.Values{"name": {"Task"}}
+			if tt.in == "form" && tt.token != "" {
+				form.Set(csrfFieldName, tt.token)
+			}
+			req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if tt.in == "header" {
+				req.Header.Set(csrfHeaderName, tt.token)
+			}
+			req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "t"})
+			rr := httptest.NewRecorder()
+			h.requireAdmin(h.AdminHandler)(rr, req)
+
+			if rr.Code != http.StatusForbidden {
+				t.Errorf("%s %s: expected status %d, got %d", tt.name, path, http.StatusForbidden, rr.Code)
+			}
+			if svc.addedTask != nil || svc.sessionStarted || svc.sessionEnded || svc.apiRegenerated || svc.loggedOut != "" {
+				t.Errorf("%s %s: handler ran despite the missing token", tt.name, path)
+			}
+		}
+	}
+}
+
+func TestCSRF_AcceptsValidToken(t *testing.T) {
+	session := app.AdminSession{Token: "t"}
+
+	// form field, as sent by the admin page forms
+	svc := &mockService{adminSession: session}
+	h := csrfHandler(svc)
+	form := url.Values{"name": {"Task"}, csrfFieldName: {session.CSRFToken()}}
+	req := httptest.NewRequest(http.MethodPost, "/admin/add-task", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "t"})
+	rr := httptest.NewRecorder()
+	h.requireAdmin(h.AdminHandler)(rr, req)
+	if svc.addedTask == nil || svc.addedTask.Name != "Task" {
+		t.Errorf("expected the task to be added, got status %d", rr.Code)
+	}
+
+	// header, as sent by admin.js
+	req = httptest.NewRequest(http.MethodPost, "/admin/start-work-session", nil)
+	req.Header.Set(csrfHeaderName, session.CSRFToken())
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "t"})
+	rr = httptest.NewRecorder()
+	h.requireAdmin(h.AdminHandler)(rr, req)
+	if rr.Code != http.StatusOK || !svc.sessionStarted {
+		t.Errorf("expected the session to start, got status %d", rr.Code)
+	}
+}
+
+func TestCSRF_MultipartUpload(t *testing.T) {
+	session := app.AdminSession{Token: "t"}
+	svc := &mockService{adminSession: session}
+	h := csrfHandler(svc)
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField(csrfFieldName, session.CSRFToken())
+	mw.WriteField("format", "todotxt")
+	fw, _ := mw.CreateFormFile("file", "todo.txt")
+	fw.Write([]byte("Write tests\n"))
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/import/apply", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "t"})
+	rr := httptest.NewRecorder()
+	h.requireAdmin(h.AdminHandler)(rr, req)
+
+	if rr.Code != http.StatusOK || len(svc.importedTasks) != 1 {
+		t.Errorf("expected one imported task, got status %d, %d tasks: %s", rr.Code, len(svc.importedTasks), rr.Body.String())
+	}
+}
+
+func TestCSRF_GetNeedsNoToken(t *testing.T) {
+	session := app.AdminSession{Token: "t"}
+	h := csrfHandler(&mockService{adminSession: session})
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/", nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "t"})
+	rr := httptest.NewRecorder()
+	h.requireAdmin(h.AdminHandler)(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+	if !strings.Contains(rr.Body.String(), session.CSRFToken()) {
+		t.Errorf("expected the page to carry the CSRF token, got %q", rr.Body.String())
+	}
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"abtprj/internal/app"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type EstimatesPageData struct {
+	Page
+	From   string
+	To     string
+	Report app.EstimateReport
+}
+
+func (h *Handler) renderEstimatesPage(w http.ResponseWriter, r *http.Request) {
+	today := time.Now().Format("2006-01-02")
+	fromStr := r.URL.Query().Get("from")
+	if fromStr == "" {
+		fromStr = time.Now().AddDate(0, 0, -84).Format("2006-01-02")
+	}
+	toStr := r.URL.Query().Get("to")
+	if toStr == "" {
+		toStr = today
+	}
+
+	from, err := time.Parse("2006-01-02", fromStr)
+	if err != nil {
+		http.Error(w, "invalid from date", http.StatusBadRequest)
+		return
+	}
+	to, err := time.Parse("2006-01-02", toStr)
+	if err != nil {
+		http.Error(w, "invalid to date", http.StatusBadRequest)
+		return
+	}
+	if to.Before(from) {
+		http.Error(w, "to date is before from date", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.service(r).GetEstimateReport(from, to.Add(24*time.Hour))
+	if err != nil {
+		log.Printf("renderEstimatesPage GetEstimateReport error: %v", err)
+		http.Error(w, "failed to build estimate report", http.StatusInternalServerError)
+		return
+	}
+
+	data := EstimatesPageData{Page: page(r), From: fromStr, To: toStr, Report: report}
+	if err := h.Templates.ExecuteTemplate(w, "estimates.html", data); err != nil {
+		log.Printf("template exec error: %v", err)
+	}
+}
+
+// parseEstimate accepts a Go duration such as "1h30m" or a plain number of minutes.
+// An empty string means no estimate.
+func parseEstimate(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	if minutes, err := strconv.Atoi(s); err == nil {
+		if minutes < 0 {
+			return 0, errors.New("estimate must not be negative")
+		}
+		return time.Duration(minutes) * time.Minute, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, errors.New("estimate must not be negative")
+	}
+	return d.Truncate(time.Minute), nil
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"abtprj/internal/app"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAdminHandler_EstimatesPage(t *testing.T) {
+	tmpl := template.Must(template.New("estimates.html").Parse(`
+{{define "estimates.html"}}
+RANGE: {{.From}}..{{.To}}
+OVERALL: {{.Report.AccuracyPercent}}%
+{{- range .Report.Tasks}}
+{{.Name}}|{{.Estimate}}|{{.Actual}}|{{.Overrun}}|{{.AccuracyPercent}}
+{{- end}}
+{{end}}
+`))
+
+	done := time.Date(2025, 3, 3, 12, 0, 0, 0, time.UTC)
+	svc := &mockService{
+		estimateReport: app.EstimateReport{
+			Tasks: []app.TaskEstimate{
+				{Name: "write docs", DoneAt: done, Estimate: time.Hour, Actual: 90 * time.Minute, Overrun: 30 * time.Minute, Accuracy: 2.0 / 3},
+			},
+			Accuracy: 2.0 / 3,
+		},
+	}
+	h := &Handler{Templates: tmpl, AppService: svc}
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/estimates?from=2025-03-01&to=2025-03-31", nil)
+	rr := httptest.NewRecorder()
+	h.AdminHandler(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status code = %d; want %d", rr.Code, http.StatusOK)
+	}
+	body := rr.Body.String()
+	for _, want := range []string{"RANGE: 2025-03-01..2025-03-31", "OVERALL: 67%", "write docs|1h0m0s|1h30m0s|30m0s|67"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body = %q; want to contain %q", body, want)
+		}
+	}
+
+	wantFrom := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	wantTo := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	if !svc.estimateFrom.Equal(wantFrom) || !svc.estimateTo.Equal(wantTo) {
+		t.Errorf("report range = %v..%v; want %v..%v", svc.estimateFrom, svc.estimateTo, wantFrom, wantTo)
+	}
+}
+
+func TestAdminHandler_EstimatesPage_BadRange(t *testing.T) {
+	h := &Handler{AppService: &mockService{}}
+
+	for _, path := range []string{
+		"/admin/estimates?from=yesterday",
+		"/admin/estimates?to=2025-13-01",
+		"/admin/estimates?from=2025-03-10&to=2025-03-01",
+	} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		rr := httptest.NewRecorder()
+		h.AdminHandler(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: status code = %d; want %d", path, rr.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestParseEstimate(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"45", 45 * time.Minute, false},
+		{"1h30m", 90 * time.Minute, false},
+		{" 2h ", 2 * time.Hour, false},
+		{"-5", 0, true},
+		{"soon", 0, true},
+	}
+
+	for _, tc := range cases {
+		got, err := parseEstimate(tc.in)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("parseEstimate(%q) error = %v; wantErr %v", tc.in, err, tc.wantErr)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("parseEstimate(%q) = %v; want %v", tc.in, got, tc.want)
+		}
+	}
+}
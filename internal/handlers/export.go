@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"abtprj/internal/app"
+	"log"
+	"net/http"
+	"time"
+)
+
+func (h *Handler) exportData(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	kind := q.Get("kind")
+	format := q.Get("format")
+	if format == "" {
+		format = app.ExportCSV
+	}
+
+	filter, err := app.ParseExportFilter(q.Get("from"), q.Get("to"), q.Get("status"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := app.ValidateExport(kind, format, filter); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	contentType := "text/csv; charset=utf-8"
+	if format == app.ExportJSON {
+		contentType = "application/json"
+	}
+	filename := kind + "-" + time.Now().Format("2006-01-02") + "." + format
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+
+	// Rows are streamed as they are read, so a failure half way can only be logged.
+	if err := h.service(r).Export(w, kind, format, filter); err != nil {
+		log.Printf("exportData Export error: %v", err)
+	}
+}
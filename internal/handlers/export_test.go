@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"abtprj/internal/app"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAdminHandler_Export(t *testing.T) {
+	svc := &mockService{}
+	h := &Handler{AppService: svc}
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/export?kind=tasks&format=json&from=2025-01-01&to=2025-01-31&status=done", nil)
+	rr := httptest.NewRecorder()
+	h.AdminHandler(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status code = %d; want %d", rr.Code, http.StatusOK)
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q; want application/json", ct)
+	}
+	if cd := rr.Header().Get("Content-Disposition"); !strings.Contains(cd, "attachment") || !strings.Contains(cd, ".json") {
+		t.Errorf("Content-Disposition = %q; want a .json attachment", cd)
+	}
+	if body := rr.Body.String(); body != "EXPORT tasks json" {
+		t.Errorf("body = %q; want %q", body, "EXPORT tasks json")
+	}
+
+	want := app.ExportFilter{
+		From:   time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:     time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC),
+		Status: "done",
+	}
+	if svc.exportFilter != want {
+		t.Errorf("filter = %+v; want %+v", svc.exportFilter, want)
+	}
+}
+
+func TestAdminHandler_Export_DefaultsToCSV(t *testing.T) {
+	svc := &mockService{}
+	h := &Handler{AppService: svc}
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/export?kind=sessions", nil)
+	rr := httptest.NewRecorder()
+	h.AdminHandler(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status code = %d; want %d", rr.Code, http.StatusOK)
+	}
+	if svc.exportFormat != app.ExportCSV {
+		t.Errorf("format = %q; want %q", svc.exportFormat, app.ExportCSV)
+	}
+	if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Errorf("Content-Type = %q; want text/csv", ct)
+	}
+}
+
+func TestAdminHandler_Export_BadRequest(t *testing.T) {
+	for _, query := range []string{
+		"kind=users",
+		"kind=tasks&format=xml",
+		"kind=tasks&from=01/02/2025",
+		"kind=tasks&from=2025-02-01&to=2025-01-01",
+		"kind=sessions&status=done",
+	} {
+		svc := &mockService{}
+		h := &Handler{AppService: svc}
+
+		req := httptest.NewRequest(http.MethodGet, "/admin/export?"+query, nil)
+		rr := httptest.NewRecorder()
+		h.AdminHandler(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: status code = %d; want %d", query, rr.Code, http.StatusBadRequest)
+		}
+		if svc.exportKind != "" {
+			t.Errorf("%s: expected Export not to be called", query)
+		}
+	}
+}
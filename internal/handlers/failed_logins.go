@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"abtprj/internal/app"
+	"log"
+	"net/http"
+)
+
+// failedLoginsShown is how many failed logins the admin view lists.
+const failedLoginsShown = 100
+
+type FailedLoginsPageData struct {
+	Page
+	Failures []app.FailedLogin
+}
+
+func (h *Handler) renderFailedLogins(w http.ResponseWriter, r *http.Request) {
+	failures, err := h.AppService.RecentFailedLogins(failedLoginsShown)
+	if err != nil {
+		log.Printf("renderFailedLogins RecentFailedLogins error: %v", err)
+		http.Error(w, "failed to load failed logins", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := h.Templates.ExecuteTemplate(w, "failed_logins.html", FailedLoginsPageData{Page: page(r), Failures: failures}); err != nil {
+		log.Printf("renderFailedLogins template error: %v", err)
+	}
+}
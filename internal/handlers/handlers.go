@@ -3,24 +3,53 @@ package handlers
 import (
 	"abtprj/internal/app"
 	"database/sql"
+	"io"
 	"net/http"
-	"text/template"
 )
 
+// Templates renders a page by name. *html/template.Template and views.Set both do.
+type Templates interface {
+	ExecuteTemplate(w io.Writer, name string, data any) error
+}
+
 type Handler struct {
 	DB         *sql.DB
-	Templates  *template.Template
+	Templates  Templates
 	AppService app.AppService
+	// PublicURL is the scheme and host links in feeds and invites start with,
+	// such as "https://log.example.com". Empty takes them from each request.
+	PublicURL string
 }
 
-func NewHandler(db *sql.DB, templates *template.Template, appService app.AppService) *Handler {
+func NewHandler(db *sql.DB, templates Templates, appService app.AppService) *Handler {
 	return &Handler{DB: db, Templates: templates, AppService: appService}
 }
 
+// RegisterRoutes mounts the public pages twice: for the instance owner at the
+// root and for every user under /u/{username}/.
 func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
-	mux.HandleFunc("/", h.MainHandler)
-	mux.HandleFunc("/worklog/", h.WorkLogHandler)
-	mux.HandleFunc("/stats/", h.StatsHandler)
-	mux.HandleFunc("/admin/", h.requireAdmin(h.AdminHandler))
-	mux.HandleFunc("/login", h.HandleLogin)
+	public := http.NewServeMux()
+	public.HandleFunc("/", h.MainHandler)
+	public.HandleFunc("/worklog/", h.WorkLogHandler)
+	public.HandleFunc("/stats/", h.StatsHandler)
+	public.HandleFunc("/calendar/", h.CalendarHandler)
+	mux.HandleFunc("/", h.ownerPages(public))
+	mux.HandleFunc("/u/", h.userPages(public))
+	mux.HandleFunc("/register", h.RegisterHandler)
+	mux.HandleFunc("/admin/", h.requireAdmin(h.requirePermission(h.AdminHandler)))
+	mux.HandleFunc("/api/", h.requireAPIToken(h.APIHandler))
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			h.HandleLogin(w, r)
+			return
+		}
+		h.LoginPage(w, r)
+	})
+	mux.HandleFunc("/login/totp", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			h.HandleTOTPLogin(w, r)
+			return
+		}
+		h.TOTPLoginPage(w, r)
+	})
 }
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"abtprj/internal/app"
+	"abtprj/internal/ical"
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// sessionFeedWindow is how far back the sessions feed reaches.
+const sessionFeedWindow = 365 * 24 * time.Hour
+
+// checkFeedToken answers 403 unless the request carries the current feed token.
+// The token stands for the account, so the feeds leave nothing out.
+func (h *Handler) checkFeedToken(w http.ResponseWriter, r *http.Request) bool {
+	want, err := h.accountService(r).GetFeedToken()
+	if err != nil {
+		log.Printf("checkFeedToken GetFeedToken error: %v", err)
+		http.Error(w, "failed to check token", http.StatusInternalServerError)
+		return false
+	}
+	got := r.URL.Query().Get("token")
+	if want == "" || subtle.ConstantTimeCompare([]byte(got), []byte(want)) != 1 {
+		http.Error(w, "invalid feed token", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+func (h *Handler) goalsFeed(w http.ResponseWriter, r *http.Request) {
+	if !h.checkFeedToken(w, r) {
+		return
+	}
+
+	goals, err := h.accountService(r).GetGoals()
+	if err != nil {
+		log.Printf("goalsFeed GetGoals error: %v", err)
+		http.Error(w, "failed to load goals", http.StatusInternalServerError)
+		return
+	}
+
+	cal := ical.Calendar{Name: page(r).Account + " goals", Location: h.service(r).Location()}
+	for _, g := range goals {
+		if g.DueAt == nil {
+			continue
+		}
+		due := g.DueAt.UTC()
+		day := time.Date(due.Year(), due.Month(), due.Day(), 0, 0, 0, 0, time.UTC)
+
+		summary := "🎯 " + g.Name
+		switch g.State {
+		case app.GoalOverdue:
+			summary += " (overdue)"
+		case app.GoalDoneOnTime, app.GoalDoneLate:
+			summary += " ✓"
+		}
+		description := g.Description
+		if g.State != "" {
+			description += "\n\nStatus: " + g.State
+		}
+
+		cal.Events = append(cal.Events, ical.Event{
+			UID:         "goal-" + strconv.Itoa(g.ID) + "@abtprj",
+			Summary:     summary,
+			Description: description,
+			Start:       day,
+			End:         day.AddDate(0, 0, 1),
+			AllDay:      true,
+			Status:      "CONFIRMED",
+			Categories:  []string{"goal", g.State},
+		})
+	}
+
+	writeICS(w, cal)
+}
+
+func (h *Handler) sessionsFeed(w http.ResponseWriter, r *http.Request) {
+	if !h.checkFeedToken(w, r) {
+		return
+	}
+
+	now := time.Now()
+	sessions, err := h.service(r).GetWorkSessionsBetween(now.Add(-sessionFeedWindow), now)
+	if err != nil {
+		log.Printf("sessionsFeed GetWorkSessionsBetween error: %v", err)
+		http.Error(w, "failed to load sessions", http.StatusInternalServerError)
+		return
+	}
+
+	cal := ical.Calendar{Name: page(r).Account + " work sessions", Location: h.service(r).Location()}
+	for _, sess := range sessions {
+		if sess.EndTime == nil {
+			continue
+		}
+		cal.Events = append(cal.Events, ical.Event{
+			UID:         "session-" + strconv.Itoa(sess.ID) + "@abtprj",
+			Summary:     "Work session",
+			Description: "Worked " + sess.EndTime.Sub(sess.StartTime).Truncate(time.Minute).String(),
+			Start:       sess.StartTime,
+			End:         *sess.EndTime,
+			Status:      "CONFIRMED",
+			Categories:  []string{"work"},
+		})
+	}
+
+	writeICS(w, cal)
+}
+
+func writeICS(w http.ResponseWriter, cal ical.Calendar) {
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Cache-Control", "private, max-age=900")
+	w.Write(cal.Render(time.Now()))
+}
+
+func (h *Handler) regenerateFeedToken(w http.ResponseWriter, r *http.Request) {
+	if _, err := h.service(r).RegenerateFeedToken(); err != nil {
+		log.Printf("regenerateFeedToken RegenerateFeedToken error: %v", err)
+		http.Error(w, "failed to regenerate feed token", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/admin/", http.StatusSeeOther)
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"abtprj/internal/app"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCalendarHandler_Feeds_RequireToken(t *testing.T) {
+	h := &Handler{AppService: &mockService{feedToken: "secret"}}
+
+	for _, path := range []string{
+		"/calendar/goals.ics",
+		"/calendar/goals.ics?token=wrong",
+		"/calendar/sessions.ics?token=",
+	} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		rr := httptest.NewRecorder()
+		h.CalendarHandler(rr, req)
+
+		if rr.Code != http.StatusForbidden {
+			t.Errorf("%s: status code = %d; want %d", path, rr.Code, http.StatusForbidden)
+		}
+	}
+}
+
+func TestCalendarHandler_GoalsFeed(t *testing.T) {
+	due := time.Date(2025, 3, 14, 0, 0, 0, 0, time.UTC)
+	done := time.Date(2025, 3, 13, 18, 0, 0, 0, time.UTC)
+	svc := &mockService{
+		feedToken: "secret",
+		goals: []app.Goal{
+			{ID: 7, Name: "Ship v1", Description: "all, of it; now", Status: "todo", DueAt: &due, State: app.GoalOverdue},
+			{ID: 8, Name: "Write tests", Status: "done", DueAt: &due, DoneAt: &sql.NullTime{Time: done, Valid: true}, State: app.GoalDoneOnTime},
+			{ID: 9, Name: "No deadline", Status: "todo"},
+		},
+	}
+	h := &Handler{AppService: svc}
+
+	req := httptest.NewRequest(http.MethodGet, "/calendar/goals.ics?token=secret", nil)
+	rr := httptest.NewRecorder()
+	h.CalendarHandler(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status code = %d; want %d", rr.Code, http.StatusOK)
+	}
+	if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/calendar") {
+		t.Errorf("Content-Type = %q; want text/calendar", ct)
+	}
+
+	body := rr.Body.String()
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"UID:goal-7@abtprj\r\n",
+		"DTSTART;VALUE=DATE:20250314\r\n",
+		"DTEND;VALUE=DATE:20250315\r\n",
+		"SUMMARY:🎯 Ship v1 (overdue)\r\n",
+		`DESCRIPTION:all\, of it\; now\n\nStatus: overdue`,
+		"SUMMARY:🎯 Write tests ✓\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body does not contain %q:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "No deadline") {
+		t.Error("goals without a due date should not be in the feed")
+	}
+	for _, line := range strings.Split(body, "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line longer than 75 octets: %q", line)
+		}
+	}
+}
+
+func TestCalendarHandler_SessionsFeed_WithTimezone(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+
+	// spans the switch to summer time on 2025-03-30
+	start1 := time.Date(2025, 3, 28, 9, 0, 0, 0, loc)
+	end1 := time.Date(2025, 3, 28, 11, 30, 0, 0, loc)
+	start2 := time.Date(2025, 4, 1, 9, 0, 0, 0, loc)
+	end2 := time.Date(2025, 4, 1, 10, 0, 0, 0, loc)
+	svc := &mockService{
+		feedToken: "secret",
+		loc:       loc,
+		sessionsBetween: []app.WorkSession{
+			{ID: 1, StartTime: start1, EndTime: &end1},
+			{ID: 2, StartTime: start2, EndTime: &end2},
+			{ID: 3, StartTime: end2},
+		},
+	}
+	h := &Handler{AppService: svc}
+
+	req := httptest.NewRequest(http.MethodGet, "/calendar/sessions.ics?token=secret", nil)
+	rr := httptest.NewRecorder()
+	h.CalendarHandler(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status code = %d; want %d", rr.Code, http.StatusOK)
+	}
+
+	body := rr.Body.String()
+	for _, want := range []string{
+		"BEGIN:VTIMEZONE\r\nTZID:Europe/Berlin\r\n",
+		"BEGIN:DAYLIGHT\r\nDTSTART:20250330T020000\r\nTZOFFSETFROM:+0100\r\nTZOFFSETTO:+0200\r\n",
+		"DTSTART;TZID=Europe/Berlin:20250328T090000\r\n",
+		"DTEND;TZID=Europe/Berlin:20250328T113000\r\n",
+		"DTSTART;TZID=Europe/Berlin:20250401T090000\r\n",
+		"DESCRIPTION:Worked 2h30m0s\r\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body does not contain %q:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "session-3@") {
+		t.Error("ongoing sessions should not be in the feed")
+	}
+}
+
+func TestAdminHandler_RegenerateFeedToken(t *testing.T) {
+	svc := &mockService{feedToken: "old"}
+	h := &Handler{AppService: svc}
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/regenerate-feed-token", nil)
+	rr := httptest.NewRecorder()
+	h.AdminHandler(rr, req)
+
+	if rr.Code != http.StatusSeeOther {
+		t.Errorf("status code = %d; want %d", rr.Code, http.StatusSeeOther)
+	}
+	if !svc.tokenRegenerated {
+		t.Error("expected RegenerateFeedToken to be called")
+	}
+}
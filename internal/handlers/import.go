@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"abtprj/internal/app"
+	"abtprj/internal/importer"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+)
+
+const maxImportSize = 10 << 20
+
+type ImportPageData struct {
+	Page
+	Format    string
+	Content   string
+	Mapping   importer.CSVMapping
+	Plan      *app.TaskImportPlan
+	Imported  bool
+	Error     string
+	CSRFToken string
+}
+
+func (h *Handler) renderImportPage(w http.ResponseWriter, r *http.Request, status int, data ImportPageData) {
+	data.Page = page(r)
+	data.CSRFToken = currentAdmin(r).CSRFToken()
+	w.WriteHeader(status)
+	if err := h.Templates.ExecuteTemplate(w, "import.html", data); err != nil {
+		log.Printf("template exec error: %v", err)
+	}
+}
+
+// readImportForm collects the format, CSV mapping and file contents from either an
+// uploaded file or the pasted text field.
+func readImportForm(r *http.Request) (ImportPageData, error) {
+	content, err := readUpload(r)
+	if err != nil {
+		return ImportPageData{}, err
+	}
+	return ImportPageData{
+		Format:  r.FormValue("format"),
+		Content: content,
+		Mapping: importer.CSVMapping{
+			Name:        r.FormValue("name_column"),
+			Description: r.FormValue("description_column"),
+			Status:      r.FormValue("status_column"),
+			DoneAt:      r.FormValue("done_column"),
+		},
+	}, nil
+}
+
+// readUpload parses an import form and returns the uploaded file, or the pasted
+// content field when no file was sent.
+func readUpload(r *http.Request) (string, error) {
+	r.Body = http.MaxBytesReader(nil, r.Body, maxImportSize)
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := r.ParseMultipartForm(maxImportSize); err != nil {
+			return "", err
+		}
+	} else if err := r.ParseForm(); err != nil {
+		return "", err
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		return r.FormValue("content"), nil
+	}
+	defer file.Close()
+	raw, err := io.ReadAll(file)
+	if err != nil {
+		return "", err
+	}
+	return string(raw), nil
+}
+
+func (h *Handler) previewImport(w http.ResponseWriter, r *http.Request) {
+	h.handleImport(w, r, false)
+}
+
+func (h *Handler) applyImport(w http.ResponseWriter, r *http.Request) {
+	h.handleImport(w, r, true)
+}
+
+func (h *Handler) handleImport(w http.ResponseWriter, r *http.Request, apply bool) {
+	data, err := readImportForm(r)
+	if err != nil {
+		http.Error(w, "incorrect form values", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(data.Content) == "" {
+		data.Error = "Nothing to import: choose a file or paste its contents."
+		h.renderImportPage(w, r, http.StatusBadRequest, data)
+		return
+	}
+
+	tasks, err := importer.ParseTasks(strings.NewReader(data.Content), data.Format, data.Mapping)
+	if err != nil {
+		data.Error = err.Error()
+		h.renderImportPage(w, r, http.StatusBadRequest, data)
+		return
+	}
+
+	var plan app.TaskImportPlan
+	if apply {
+		plan, err = h.service(r).ImportTasks(tasks)
+	} else {
+		plan, err = h.service(r).PlanTaskImport(tasks)
+	}
+	if err != nil {
+		log.Printf("handleImport error: %v", err)
+		http.Error(w, "failed to import tasks", http.StatusInternalServerError)
+		return
+	}
+
+	data.Plan = &plan
+	data.Imported = apply
+	h.renderImportPage(w, r, http.StatusOK, data)
+}
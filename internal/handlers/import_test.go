@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"bytes"
+	"html/template"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func createImportTemplate() *template.Template {
+	return template.Must(template.New("import.html").Parse(`
+{{define "import.html"}}
+{{- if .Error}}ERROR: {{.Error}}{{end}}
+{{- with .Plan}}{{range .Items}}
+{{.Line}}|{{.Name}}|{{.Description}}|{{.Done}}|{{if .DoneAt}}{{.DoneAt.Format "2006-01-02T15:04"}}{{end}}|{{if .CreatedAt}}{{.CreatedAt.Format "2006-01-02"}}{{end}}
+{{- end}}{{end}}
+{{- if .Imported}}
+IMPORTED{{end}}
+{{end}}
+`))
+}
+
+func postImportForm(h *Handler, path string, form url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+	h.AdminHandler(rr, req)
+	return rr
+}
+
+func TestAdminHandler_ImportPreview_TodoTxt(t *testing.T) {
+	svc := &mockService{}
+	h := &Handler{Templates: createImportTemplate(), AppService: svc}
+
+	content := "x 2024-02-01 2024-01-20 Call the bank +finance\n\n(A) 2024-01-22 Write report @work\nPlain task\n"
+	rr := postImportForm(h, "/admin/import/preview", url.Values{"format": {"todotxt"}, "content": {content}})
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status code = %d; want %d: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	body := rr.Body.String()
+	for _, want := range []string{
+		"1|Call the bank &#43;finance||true|2024-02-01T00:00|2024-01-20",
+		"3|Write report @work|Priority A|false||2024-01-22",
+		"4|Plain task||false||",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body = %q; want to contain %q", body, want)
+		}
+	}
+	if svc.importedTasks != nil || strings.Contains(body, "IMPORTED") {
+		t.Error("preview must not import anything")
+	}
+}
+
+func TestAdminHandler_ImportPreview_Taskwarrior(t *testing.T) {
+	h := &Handler{Templates: createImportTemplate(), AppService: &mockService{}}
+
+	content := `[
+{"description":"Fix login","status":"completed","entry":"20240105T080000Z","end":"20240106T173000Z","project":"web","tags":["bug"]},
+{"description":"Removed","status":"deleted","entry":"20240105T080000Z"},
+{"description":"Plan sprint","status":"pending","entry":"20240107T090000Z","annotations":[{"description":"with the team"}]}
+]`
+	rr := postImportForm(h, "/admin/import/preview", url.Values{"format": {"taskwarrior"}, "content": {content}})
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status code = %d; want %d: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	body := rr.Body.String()
+	for _, want := range []string{
+		"1|Fix login|Project: web\nTags: bug|true|2024-01-06T17:30|2024-01-05",
+		"3|Plan sprint|with the team|false||2024-01-07",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body = %q; want to contain %q", body, want)
+		}
+	}
+	if strings.Contains(body, "Removed") {
+		t.Error("deleted Taskwarrior tasks should be skipped")
+	}
+}
+
+func TestAdminHandler_ImportApply_CSVUpload(t *testing.T) {
+	svc := &mockService{}
+	h := &Handler{Templates: createImportTemplate(), AppService: svc}
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for k, v := range map[string]string{
+		"format":             "csv",
+		"name_column":        "Title",
+		"description_column": "Notes",
+		"done_column":        "Finished",
+	} {
+		mw.WriteField(k, v)
+	}
+	fw, _ := mw.CreateFormFile("file", "tasks.csv")
+	fw.Write([]byte("Title,Notes,Finished\nBuy milk,2 litres,2024-03-01 10:15\n\"Call mom, again\",,\n"))
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/import/apply", &buf)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	rr := httptest.NewRecorder()
+	h.AdminHandler(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status code = %d; want %d: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	if len(svc.importedTasks) != 2 {
+		t.Fatalf("imported %d tasks; want 2", len(svc.importedTasks))
+	}
+	body := rr.Body.String()
+	for _, want := range []string{
+		"2|Buy milk|2 litres|true|2024-03-01T10:15|",
+		"3|Call mom, again||false||",
+		"IMPORTED",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body = %q; want to contain %q", body, want)
+		}
+	}
+}
+
+func TestAdminHandler_Import_BadInput(t *testing.T) {
+	cases := []struct {
+		name string
+		form url.Values
+		want string
+	}{
+		{"Empty", url.Values{"format": {"todotxt"}}, "Nothing to import"},
+		{"UnknownFormat", url.Values{"format": {"xml"}, "content": {"a"}}, "unknown import format"},
+		{"MissingColumn", url.Values{"format": {"csv"}, "content": {"a,b\n1,2\n"}, "name_column": {"Title"}}, `column &#34;Title&#34; not found`},
+		{"BadJSON", url.Values{"format": {"taskwarrior"}, "content": {"{"}}, "invalid Taskwarrior export"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			svc := &mockService{}
+			h := &Handler{Templates: createImportTemplate(), AppService: svc}
+
+			rr := postImportForm(h, "/admin/import/apply", tc.form)
+
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("status code = %d; want %d", rr.Code, http.StatusBadRequest)
+			}
+			if !strings.Contains(rr.Body.String(), tc.want) {
+				t.Errorf("body = %q; want to contain %q", rr.Body.String(), tc.want)
+			}
+			if svc.planCalled {
+				t.Error("expected nothing to be planned or imported")
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"abtprj/internal/app"
+	"abtprj/internal/importer"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type TimeImportPageData struct {
+	Page
+	Source    string
+	Timezone  string
+	Overlap   string
+	Content   string
+	Summary   *app.TimeImportSummary
+	Imported  bool
+	Error     string
+	CSRFToken string
+}
+
+func (h *Handler) renderTimeImportPage(w http.ResponseWriter, r *http.Request, status int, data TimeImportPageData) {
+	data.Page = page(r)
+	data.CSRFToken = currentAdmin(r).CSRFToken()
+	w.WriteHeader(status)
+	if err := h.Templates.ExecuteTemplate(w, "import_time.html", data); err != nil {
+		log.Printf("template exec error: %v", err)
+	}
+}
+
+func (h *Handler) renderTimeImportForm(w http.ResponseWriter, r *http.Request) {
+	h.renderTimeImportPage(w, r, http.StatusOK, TimeImportPageData{
+		Source:   importer.SourceToggl,
+		Timezone: h.service(r).Location().String(),
+		Overlap:  app.OverlapSkip,
+	})
+}
+
+// handleTimeImport previews or applies an import of Toggl or Clockify time entries.
+// The export's times are read in the chosen timezone and stored in UTC.
+func (h *Handler) handleTimeImport(w http.ResponseWriter, r *http.Request, apply bool) {
+	content, err := readUpload(r)
+	if err != nil {
+		http.Error(w, "incorrect form values", http.StatusBadRequest)
+		return
+	}
+	data := TimeImportPageData{
+		Source:   r.FormValue("source"),
+		Timezone: strings.TrimSpace(r.FormValue("timezone")),
+		Overlap:  r.FormValue("overlap"),
+		Content:  content,
+	}
+	if strings.TrimSpace(data.Content) == "" {
+		data.Error = "Nothing to import: choose a file or paste its contents."
+		h.renderTimeImportPage(w, r, http.StatusBadRequest, data)
+		return
+	}
+	if data.Overlap != app.OverlapSkip && data.Overlap != app.OverlapMerge {
+		data.Error = "Choose whether overlapping entries are skipped or merged."
+		h.renderTimeImportPage(w, r, http.StatusBadRequest, data)
+		return
+	}
+
+	loc := h.service(r).Location()
+	if data.Timezone != "" {
+		if loc, err = time.LoadLocation(data.Timezone); err != nil {
+			data.Error = "Unknown timezone " + data.Timezone
+			h.renderTimeImportPage(w, r, http.StatusBadRequest, data)
+			return
+		}
+	}
+
+	entries, rowErrs, err := importer.ParseTimeEntries(strings.NewReader(data.Content), data.Source, loc)
+	if err != nil {
+		data.Error = err.Error()
+		h.renderTimeImportPage(w, r, http.StatusBadRequest, data)
+		return
+	}
+
+	summary, err := h.service(r).ImportTimeEntries(entries, data.Overlap, !apply)
+	if err != nil {
+		log.Printf("handleTimeImport error: %v", err)
+		http.Error(w, "failed to import time entries", http.StatusInternalServerError)
+		return
+	}
+	summary.Invalid = rowErrs
+
+	data.Summary = &summary
+	data.Imported = apply
+	h.renderTimeImportPage(w, r, http.StatusOK, data)
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func createTimeImportTemplate() *template.Template {
+	return template.Must(template.New("import_time.html").Parse(`
+{{define "import_time.html"}}
+{{- if .Error}}ERROR: {{.Error}}{{end}}
+{{- with .Summary}}{{range .Rows}}
+{{.Line}}|{{.Description}}|{{.Project}}|{{.Start.UTC.Format "2006-01-02T15:04"}}|{{.End.UTC.Format "2006-01-02T15:04"}}|{{.Outcome}}
+{{- end}}{{range .Invalid}}
+{{.Line}}|invalid|{{.Reason}}
+{{- end}}{{end}}
+{{- if .Imported}}
+IMPORTED{{end}}
+{{end}}
+`))
+}
+
+func TestAdminHandler_TimeImportPreview_Toggl(t *testing.T) {
+	svc := &mockService{}
+	h := &Handler{Templates: createTimeImportTemplate(), AppService: svc}
+
+	content := "User,Email,Client,Project,Task,Description,Billable,Start date,Start time,End date,End time,Duration\n" +
+		"me,me@x,,Site,,Fix login,No,2024-03-01,09:00:00,2024-03-01,10:30:00,01:30:00\n" +
+		"me,me@x,,Site,,Broken,No,2024-03-01,11:00:00,2024-03-01,10:00:00,00:00:00\n"
+	rr := postImportForm(h, "/admin/import-time/preview", url.Values{
+		"source": {"toggl"}, "timezone": {"Europe/Berlin"}, "overlap": {"skip"}, "content": {content},
+	})
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status code = %d; want %d: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	body := rr.Body.String()
+	for _, want := range []string{
+		"2|Fix login|Site|2024-03-01T08:00|2024-03-01T09:30|imported",
+		"3|invalid|ends before it starts",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body = %q; want to contain %q", body, want)
+		}
+	}
+	if !svc.timeDryRun || svc.timeOverlap != "skip" {
+		t.Errorf("ImportTimeEntries called with dryRun=%v overlap=%q; want a skipping dry run", svc.timeDryRun, svc.timeOverlap)
+	}
+	if strings.Contains(body, "IMPORTED") {
+		t.Error("preview must not report an import")
+	}
+}
+
+func TestAdminHandler_TimeImportPreview_AmbiguousDate(t *testing.T) {
+	svc := &mockService{}
+	h := &Handler{Templates: createTimeImportTemplate(), AppService: svc}
+
+	content := "Project,Description,Start Date,Start Time,End Date,End Time\n" +
+		"Site,Day first,13/03/2024,09:00,13/03/2024,10:00\n" +
+		"Site,Month first,03/14/2024,09:00,03/14/2024,10:00\n" +
+		"Site,Either way,03/04/2024,09:00,03/04/2024,10:00\n"
+	rr := postImportForm(h, "/admin/import-time/preview", url.Values{
+		"source": {"clockify"}, "timezone": {"UTC"}, "overlap": {"skip"}, "content": {content},
+	})
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status code = %d; want %d: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	body := rr.Body.String()
+	for _, want := range []string{
+		"2|Day first|Site|2024-03-13T09:00|2024-03-13T10:00|imported",
+		"3|Month first|Site|2024-03-14T09:00|2024-03-14T10:00|imported",
+		"4|invalid|start: ambiguous date",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body = %q; want to contain %q", body, want)
+		}
+	}
+	if len(svc.timeEntries) != 2 {
+		t.Errorf("ImportTimeEntries got %d entries; want the ambiguous row left out", len(svc.timeEntries))
+	}
+}
+
+func TestAdminHandler_TimeImportApply_Clockify(t *testing.T) {
+	moscow, _ := time.LoadLocation("Europe/Moscow")
+	svc := &mockService{loc: moscow}
+	h := &Handler{Templates: createTimeImportTemplate(), AppService: svc}
+
+	content := "\"Project\",\"Client\",\"Description\",\"Task\",\"User\",\"Start Date\",\"Start Time\",\"End Date\",\"End Time\"\n" +
+		"\"Site\",\"\",\"Night deploy\",\"\",\"me\",\"03/13/2024\",\"11:30 PM\",\"03/14/2024\",\"01:15 AM\"\n"
+	rr := postImportForm(h, "/admin/import-time/apply", url.Values{
+		"source": {"clockify"}, "overlap": {"merge"}, "content": {content},
+	})
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status code = %d; want %d: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	if svc.timeDryRun || svc.timeOverlap != "merge" || len(svc.timeEntries) != 1 {
+		t.Fatalf("ImportTimeEntries called with %d entries, dryRun=%v overlap=%q", len(svc.timeEntries), svc.timeDryRun, svc.timeOverlap)
+	}
+	// no timezone given: the service location is used
+	body := rr.Body.String()
+	for _, want := range []string{"2|Night deploy|Site|2024-03-13T20:30|2024-03-13T22:15|imported", "IMPORTED"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body = %q; want to contain %q", body, want)
+		}
+	}
+}
+
+func TestAdminHandler_TimeImport_BadInput(t *testing.T) {
+	header := "Description,Start date,Start time,End date,End time\n"
+	cases := []struct {
+		name string
+		form url.Values
+		want string
+	}{
+		{"Empty", url.Values{"source": {"toggl"}, "overlap": {"skip"}}, "Nothing to import"},
+		{"UnknownSource", url.Values{"source": {"harvest"}, "overlap": {"skip"}, "content": {header}}, "unknown time tracker"},
+		{"UnknownOverlap", url.Values{"source": {"toggl"}, "overlap": {"ignore"}, "content": {header}}, "skipped or merged"},
+		{"UnknownTimezone", url.Values{"source": {"toggl"}, "overlap": {"skip"}, "timezone": {"Mars/Olympus"}, "content": {header}}, "Unknown timezone"},
+		{"MissingColumn", url.Values{"source": {"toggl"}, "overlap": {"skip"}, "content": {"Description,Start date,Start time,End date\n"}}, `column &#34;End time&#34; not found`},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			svc := &mockService{}
+			h := &Handler{Templates: createTimeImportTemplate(), AppService: svc}
+
+			rr := postImportForm(h, "/admin/import-time/apply", tc.form)
+
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("status code = %d; want %d", rr.Code, http.StatusBadRequest)
+			}
+			if !strings.Contains(rr.Body.String(), tc.want) {
+				t.Errorf("body = %q; want to contain %q", rr.Body.String(), tc.want)
+			}
+			if svc.timeImportDone {
+				t.Error("expected nothing to be imported")
+			}
+		})
+	}
+}
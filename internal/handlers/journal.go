@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"abtprj/internal/app"
+	"bytes"
+	"log"
+	"net/http"
+	"time"
+)
+
+// exportJournal serves GET /admin/journal?from=YYYY-MM-DD&to=YYYY-MM-DD as Markdown.
+// Without dates it covers the current week.
+func (h *Handler) exportJournal(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	from, to, err := app.ParseJournalRange(q.Get("from"), q.Get("to"), h.service(r).Location(), time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := h.service(r).WriteJournal(&buf, from, to); err != nil {
+		log.Printf("exportJournal WriteJournal error: %v", err)
+		http.Error(w, "failed to build journal", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	if q.Get("download") != "" {
+		filename := "journal-" + from.Format("2006-01-02") + "-" + to.Format("2006-01-02") + ".md"
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	}
+	w.Write(buf.Bytes())
+}
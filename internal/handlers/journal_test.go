@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAdminHandler_Journal(t *testing.T) {
+	moscow, _ := time.LoadLocation("Europe/Moscow")
+	svc := &mockService{loc: moscow}
+	h := &Handler{AppService: svc}
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/journal?from=2025-03-03&to=2025-03-09&download=1", nil)
+	rr := httptest.NewRecorder()
+	h.AdminHandler(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status code = %d; want %d: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "text/markdown; charset=utf-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if cd := rr.Header().Get("Content-Disposition"); !strings.Contains(cd, "journal-2025-03-03-2025-03-09.md") {
+		t.Errorf("Content-Disposition = %q", cd)
+	}
+	if rr.Body.String() != "# Journal" {
+		t.Errorf("body = %q", rr.Body.String())
+	}
+	if !svc.journalFrom.Equal(time.Date(2025, 3, 3, 0, 0, 0, 0, moscow)) || !svc.journalTo.Equal(time.Date(2025, 3, 9, 0, 0, 0, 0, moscow)) {
+		t.Errorf("range = %v – %v", svc.journalFrom, svc.journalTo)
+	}
+}
+
+func TestAdminHandler_JournalDefaultsToThisWeek(t *testing.T) {
+	svc := &mockService{}
+	h := &Handler{AppService: svc}
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/journal", nil)
+	rr := httptest.NewRecorder()
+	h.AdminHandler(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status code = %d; want %d", rr.Code, http.StatusOK)
+	}
+	if cd := rr.Header().Get("Content-Disposition"); cd != "" {
+		t.Errorf("Content-Disposition = %q; want the journal shown inline", cd)
+	}
+	if svc.journalFrom.Weekday() != time.Monday {
+		t.Errorf("from = %v; want a Monday", svc.journalFrom)
+	}
+	if days := svc.journalTo.Sub(svc.journalFrom); days < 0 || days > 6*24*time.Hour {
+		t.Errorf("range = %v – %v; want within the current week", svc.journalFrom, svc.journalTo)
+	}
+}
+
+func TestAdminHandler_JournalBadRange(t *testing.T) {
+	for _, query := range []string{"from=yesterday", "from=2025-03-09&to=2025-03-01", "from=2020-01-01&to=2025-01-01"} {
+		svc := &mockService{}
+		h := &Handler{AppService: svc}
+
+		req := httptest.NewRequest(http.MethodGet, "/admin/journal?"+query, nil)
+		rr := httptest.NewRecorder()
+		h.AdminHandler(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: status code = %d; want %d", query, rr.Code, http.StatusBadRequest)
+		}
+		if !svc.journalFrom.IsZero() {
+			t.Errorf("%s: journal should not be written", query)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"abtprj/internal/app"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandleLogin_Throttled(t *testing.T) {
+	svc := &mockService{loginErr: &app.ThrottleError{RetryAfter: 90500 * time.Millisecond}}
+	h := &Handler{
+		Templates:  template.Must(template.New("login.html").Parse(`{{define "login.html"}}{{.Error}}{{end}}`)),
+		AppService: svc,
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader("login=admin&password=guess"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.RemoteAddr = "203.0.113.7:51234"
+	rr := httptest.NewRecorder()
+	h.HandleLogin(rr, req)
+
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status %d, got %d", http.StatusTooManyRequests, rr.Code)
+	}
+	if got := rr.Header().Get("Retry-After"); got != "91" {
+		t.Errorf("expected Retry-After 91, got %q", got)
+	}
+	if !strings.Contains(rr.Body.String(), "Try again in 1m31s") {
+		t.Errorf("unexpected body %q", rr.Body.String())
+	}
+	if svc.loginIP != "203.0.113.7" {
+		t.Errorf("expected attempt from 203.0.113.7, got %q", svc.loginIP)
+	}
+}
+
+func TestHandleTOTPLogin_Throttled(t *testing.T) {
+	svc := &mockService{loginErr: &app.ThrottleError{RetryAfter: time.Minute}}
+	h := &Handler{Templates: createTwoFactorTemplate(), AppService: svc}
+
+	req := httptest.NewRequest(http.MethodPost, "/login/totp", strings.NewReader("code=123456"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "pending"})
+	rr := httptest.NewRecorder()
+	h.HandleTOTPLogin(rr, req)
+
+	if rr.Code != http.StatusTooManyRequests || rr.Header().Get("Retry-After") != "60" {
+		t.Errorf("expected 429 with Retry-After 60, got %d %q", rr.Code, rr.Header().Get("Retry-After"))
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		remote, forwarded, want string
+	}{
+		{"203.0.113.7:1234", "", "203.0.113.7"},
+		{"203.0.113.7:1234", "198.51.100.1", "203.0.113.7"},
+		{"127.0.0.1:1234", "198.51.100.1", "198.51.100.1"},
+		{"127.0.0.1:1234", "10.0.0.1, 198.51.100.1", "198.51.100.1"},
+		{"127.0.0.1:1234", "not an ip", "127.0.0.1"},
+		{"[::1]:1234", "2001:db8::1", "2001:db8::1"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/login", nil)
+		req.RemoteAddr = tt.remote
+		if tt.forwarded != "" {
+			req.Header.Set("X-Forwarded-For", tt.forwarded)
+		}
+		if got := clientIP(req); got != tt.want {
+			t.Errorf("clientIP(%q, %q) = %q, want %q", tt.remote, tt.forwarded, got, tt.want)
+		}
+	}
+}
+
+func TestFailedLoginsPage(t *testing.T) {
+	at := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	svc := &mockService{
+		adminSession: app.AdminSession{Token: "t", Owner: true},
+		failedLogins: []app.FailedLogin{{At: at, IP: "203.0.113.7", Login: "admin", Reason: "wrong password"}},
+	}
+	h := &Handler{
+		Templates: template.Must(template.New("failed_logins.html").Parse(
+			`{{define "failed_logins.html"}}{{range .Failures}}{{.At.Format "15:04"}} {{.IP}} {{.Login}} {{.Reason}}{{end}}{{end}}`)),
+		AppService: svc,
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/failed-logins", nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "t"})
+	rr := httptest.NewRecorder()
+	h.requireAdmin(h.AdminHandler)(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+	if got := rr.Body.String(); got != "10:00 203.0.113.7 admin wrong password" {
+		t.Errorf("unexpected body %q", got)
+	}
+}
@@ -2,28 +2,484 @@ package handlers
 
 import (
 	"abtprj/internal/app"
+	"abtprj/internal/importer"
+	"fmt"
+	"io"
+	"slices"
+	"time"
 )
 
 type mockService struct {
 	taskStats    []app.DayTasksStat
 	sessionStats []app.DaySessionsStat
 
+	sessionStatsByYear map[int][]app.DaySessionsStat // instead of sessionStats, when set
+
 	isWorking       bool
 	sessionsForDate []app.WorkSession
 	tasksForDate    []app.Task
 	todoTasks       []app.Task
 	goals           []app.Goal
 	todoGoals       []app.Goal
+
+	heatmapSettings        app.HeatmapSettings
+	updatedHeatmapSettings *app.HeatmapSettings
+
+	publicView        bool   // PublicView was asked for
+	defaultVisibility string // set by SetDefaultVisibility
+	visibilitySet     string // "task 3 private", by SetTaskVisibility or SetGoalVisibility
+
+	deadlineStats     app.GoalDeadlineStats
+	upcomingDeadlines []app.Goal
+
+	addedTask      *app.Task
+	createdGoal    *app.Goal
+	estimateReport app.EstimateReport
+	estimateFrom   time.Time
+	estimateTo     time.Time
+
+	calendar      app.MonthCalendar
+	calendarYear  int
+	calendarMonth time.Month
+
+	exportKind   string
+	exportFormat string
+	exportFilter app.ExportFilter
+
+	feedToken        string
+	sessionsBetween  []app.WorkSession
+	tokenRegenerated bool
+	loc              *time.Location
+
+	importPlan    app.TaskImportPlan
+	importedTasks []importer.Task
+	planCalled    bool
+
+	timeEntries    []importer.TimeEntry
+	timeOverlap    string
+	timeDryRun     bool
+	timeImportDone bool
+
+	backupErr error
+
+	completed      []app.CompletedItem
+	completedLimit int
+
+	journalFrom time.Time
+	journalTo   time.Time
+
+	apiToken       string
+	apiRegenerated bool
+	status         app.TrackerStatus
+	sessionStarted bool
+	sessionEnded   bool
+	completedTask  string
+
+	adminSession    app.AdminSession // returned by LoginAdmin and for its token
+	loginErr        error
+	loginIP         string
+	failedLogins    []app.FailedLogin
+	loggedOut       string
+	changeCurrent   string
+	changeNew       string
+	changePassword  error
+	passwordChanged bool
+
+	totpSession     app.AdminSession // returned by CompleteTOTPLogin
+	totpCode        string           // the code CompleteTOTPLogin, EnableTOTP and DisableTOTP accept
+	totpStatus      app.TOTPStatus
+	totpEnrolled    bool
+	totpEnabled     bool
+	totpDisabled    bool
+	disablePassword string
+
+	userID     int            // the last ForUser argument
+	users      map[string]int // login to id, for GetUser
+	owner      app.Admin
+	apiUser    int    // owner of apiToken
+	apiLogin   string // and their login
+	invite     string // the one invite token that is still valid
+	invites    []app.Invite
+	registered string // login Register created
+
+	teams      map[string]app.TeamOverview // by name, with the user's role in Team.Role
+	teamStats  app.TeamStats
+	statsYear  int
+	teamAction string // the last team change that went through
+
+	access       []app.AccountAccess // who has access to the session's account
+	accessible   []app.AccountAccess // accounts the session's admin can switch to
+	accessChange string              // the last grant, revoke or switch
+
+	actor       app.Actor        // the last As argument
+	audit       []app.AuditEntry // returned by ListAudit
+	auditFilter app.AuditFilter
+
+	undo     app.Undo // returned by the changes that can be undone and GetUndo
+	undoErr  error    // returned by GetUndo and UndoChange
+	undoneBy string   // the last UndoChange token
+}
+
+func (m *mockService) ForUser(userID int) app.AppService {
+	m.userID = userID
+	return m
+}
+
+func (m *mockService) PublicView() app.AppService {
+	m.publicView = true
+	return m
+}
+
+func (m *mockService) As(actor app.Actor) app.AppService {
+	m.actor = actor
+	return m
+}
+
+func (m *mockService) ListAudit(filter app.AuditFilter) ([]app.AuditEntry, error) {
+	m.auditFilter = filter
+	return m.audit, nil
+}
+
+func (m *mockService) GetUndo(token string) (app.Undo, error) {
+	if m.undoErr != nil {
+		return app.Undo{}, m.undoErr
+	}
+	return m.undo, nil
+}
+
+func (m *mockService) UndoChange(token string) error {
+	m.undoneBy = token
+	return m.undoErr
+}
+
+func (m *mockService) GetUser(login string) (app.Admin, error) {
+	id, ok := m.users[login]
+	if !ok {
+		return app.Admin{}, app.ErrNoUser
+	}
+	return app.Admin{ID: id, Login: login}, nil
+}
+
+func (m *mockService) GetOwner() (app.Admin, error) {
+	if m.owner.ID == 0 {
+		return app.Admin{}, app.ErrNoUser
+	}
+	return m.owner, nil
+}
+
+func (m *mockService) UserForAPIToken(token string) (app.Admin, error) {
+	if token == "" || token != m.apiToken {
+		return app.Admin{}, app.ErrInvalidToken
+	}
+	return app.Admin{ID: m.apiUser, Login: m.apiLogin}, nil
+}
+
+func (m *mockService) CreateInvite(session app.AdminSession) (string, error) {
+	m.invite = "new-invite"
+	return m.invite, nil
+}
+
+func (m *mockService) ListInvites(session app.AdminSession) ([]app.Invite, error) {
+	return m.invites, nil
+}
+
+func (m *mockService) CheckInvite(token string) error {
+	if token == "" || token != m.invite {
+		return app.ErrInvalidInvite
+	}
+	return nil
+}
+
+func (m *mockService) Register(invite, login, password string) error {
+	if err := app.ValidateLogin(login); err != nil {
+		return err
+	}
+	if err := app.ValidatePassword(login, password); err != nil {
+		return err
+	}
+	if err := m.CheckInvite(invite); err != nil {
+		return err
+	}
+	if _, ok := m.users[login]; ok {
+		return app.ErrLoginTaken
+	}
+	m.invite = ""
+	m.registered = login
+	return nil
+}
+
+func (m *mockService) ListAccountAccess(session app.AdminSession) ([]app.AccountAccess, error) {
+	if !session.Can(app.PermManage) {
+		return nil, app.ErrNotAllowed
+	}
+	return m.access, nil
+}
+
+func (m *mockService) ListAccessibleAccounts(session app.AdminSession) ([]app.AccountAccess, error) {
+	return m.accessible, nil
+}
+
+func (m *mockService) GrantAccess(session app.AdminSession, login, role string) error {
+	if !session.Can(app.PermManage) {
+		return app.ErrNotAllowed
+	}
+	if role != app.RoleViewer && role != app.RoleEditor && role != app.RoleOwner {
+		return app.ErrInvalidRole
+	}
+	if _, ok := m.users[login]; !ok {
+		return app.ErrNoUser
+	}
+	m.accessChange = fmt.Sprintf("grant %s %s on %s", login, role, session.AccountLogin)
+	return nil
+}
+
+func (m *mockService) RevokeAccess(session app.AdminSession, login string) error {
+	if !session.Can(app.PermManage) {
+		return app.ErrNotAllowed
+	}
+	m.accessChange = fmt.Sprintf("revoke %s on %s", login, session.AccountLogin)
+	return nil
+}
+
+func (m *mockService) SwitchAccount(session app.AdminSession, login string) error {
+	if login != "" && login != session.Login {
+		found := false
+		for _, a := range m.accessible {
+			found = found || a.Login == login
+		}
+		if !found {
+			return app.ErrNotAllowed
+		}
+	}
+	m.accessChange = "switch " + login
+	return nil
+}
+
+func (m *mockService) AccountRole(adminID, accountID int) (string, error) {
+	if adminID == accountID {
+		return app.RoleOwner, nil
+	}
+	for _, a := range m.accessible {
+		if m.users[a.Login] == accountID {
+			return a.Role, nil
+		}
+	}
+	return "", nil
+}
+
+// team checks the user's role in a mock team the way DefaultAppService does.
+func (m *mockService) team(name string, roles ...string) (app.TeamOverview, error) {
+	t, ok := m.teams[name]
+	if !ok {
+		return t, app.ErrNoTeam
+	}
+	if len(roles) == 0 {
+		return t, nil
+	}
+	for _, r := range roles {
+		if t.Team.Role == r {
+			return t, nil
+		}
+	}
+	return t, app.ErrNotAllowed
+}
+
+func (m *mockService) ListTeams() ([]app.Team, error) {
+	var teams []app.Team
+	for _, t := range m.teams {
+		teams = append(teams, t.Team)
+	}
+	return teams, nil
+}
+
+func (m *mockService) CreateTeam(name string) error {
+	if err := app.ValidateTeamName(name); err != nil {
+		return err
+	}
+	if _, ok := m.teams[name]; ok {
+		return app.ErrTeamTaken
+	}
+	m.teamAction = "create " + name
+	return nil
+}
+
+func (m *mockService) GetTeam(name string) (app.TeamOverview, error) { return m.team(name) }
+
+func (m *mockService) GetTeamStats(team string, year int) (app.TeamStats, error) {
+	if _, err := m.team(team); err != nil {
+		return app.TeamStats{}, err
+	}
+	m.statsYear = year
+	return m.teamStats, nil
+}
+
+func (m *mockService) SetTeamMember(team, login, role string) error {
+	if _, err := m.team(team, app.RoleOwner); err != nil {
+		return err
+	}
+	m.teamAction = fmt.Sprintf("member %s %s %s", team, login, role)
+	return nil
+}
+
+func (m *mockService) RemoveTeamMember(team, login string) error {
+	t, err := m.team(team)
+	if err != nil {
+		return err
+	}
+	if !t.Team.CanManage() && login != m.adminSession.Login {
+		return app.ErrNotAllowed
+	}
+	m.teamAction = fmt.Sprintf("remove %s %s", team, login)
+	return nil
+}
+
+func (m *mockService) CreateProject(team, name string) error {
+	if _, err := m.team(team, app.RoleOwner); err != nil {
+		return err
+	}
+	m.teamAction = fmt.Sprintf("project %s %s", team, name)
+	return nil
+}
+
+func (m *mockService) AddSharedTask(team string, projectID int, task app.Task) error {
+	if _, err := m.team(team, app.RoleOwner, app.RoleMember); err != nil {
+		return err
+	}
+	m.teamAction = fmt.Sprintf("task %s %d %s %v", team, projectID, task.Name, task.Estimate)
+	return nil
+}
+
+func (m *mockService) CompleteSharedTask(team string, id int) error {
+	if _, err := m.team(team, app.RoleOwner, app.RoleMember); err != nil {
+		return err
+	}
+	m.teamAction = fmt.Sprintf("complete task %s %d", team, id)
+	return nil
+}
+
+func (m *mockService) CreateSharedGoal(team string, projectID int, goal app.Goal) error {
+	if _, err := m.team(team, app.RoleOwner, app.RoleMember); err != nil {
+		return err
+	}
+	m.teamAction = fmt.Sprintf("goal %s %d %s %s", team, projectID, goal.Name, goal.DueAt.Format("2006-01-02"))
+	return nil
+}
+
+func (m *mockService) CompleteSharedGoal(team string, id int) error {
+	if _, err := m.team(team, app.RoleOwner, app.RoleMember); err != nil {
+		return err
+	}
+	m.teamAction = fmt.Sprintf("complete goal %s %d", team, id)
+	return nil
 }
 
-func (m *mockService) LoginAdmin(login, password string) error { return nil }
-func (m *mockService) AddTask(name, description string) error  { return nil }
-func (m *mockService) CompleteTask(name string) error          { return nil }
-func (m *mockService) CreateGoal(goal app.Goal) error          { return nil }
-func (m *mockService) CompleteGoal(id int) error               { return nil }
-func (m *mockService) StartWorkSession() error                 { return nil }
-func (m *mockService) EndWorkSession() error                   { return nil }
-func (m *mockService) CheckIfAdminExists() (bool, error)       { return false, nil }
+func (m *mockService) CreateGoal(goal app.Goal) error {
+	if !validVisibility(goal.Visibility) {
+		return app.ErrInvalidVisibility
+	}
+	m.createdGoal = &goal
+	return nil
+}
+
+func (m *mockService) CompleteGoal(id int) (app.Undo, error) { return m.undo, nil }
+func (m *mockService) CheckIfAdminExists() (bool, error)     { return false, nil }
+
+func (m *mockService) LoginAdmin(login, password, ip string) (app.AdminSession, error) {
+	m.loginIP = ip
+	if m.loginErr != nil {
+		return app.AdminSession{}, m.loginErr
+	}
+	if m.adminSession.Token == "" {
+		m.adminSession.Token = "session-token"
+	}
+	m.adminSession.Login = login
+	return m.adminSession, nil
+}
+
+func (m *mockService) GetAdminSession(token string) (app.AdminSession, error) {
+	if token == "" || token != m.adminSession.Token || m.adminSession.TOTPPending {
+		return app.AdminSession{}, app.ErrNoSession
+	}
+	session := m.adminSession
+	if session.Role == "" {
+		// like a session that never switched account
+		session.AccountID, session.AccountLogin, session.Role = session.AdminID, session.Login, app.RoleOwner
+	}
+	return session, nil
+}
+
+func (m *mockService) LogoutAdmin(token string) error {
+	m.loggedOut = token
+	return nil
+}
+
+func (m *mockService) RecentFailedLogins(limit int) ([]app.FailedLogin, error) {
+	return m.failedLogins, nil
+}
+
+func (m *mockService) CompleteTOTPLogin(token, code, ip string) (app.AdminSession, error) {
+	if m.loginErr != nil {
+		return app.AdminSession{}, m.loginErr
+	}
+	if token != m.adminSession.Token || !m.adminSession.TOTPPending {
+		return app.AdminSession{}, app.ErrNoSession
+	}
+	if code != m.totpCode {
+		return app.AdminSession{}, app.ErrInvalidCode
+	}
+	return m.totpSession, nil
+}
+
+func (m *mockService) GetTOTPStatus(session app.AdminSession) (app.TOTPStatus, error) {
+	return m.totpStatus, nil
+}
+
+func (m *mockService) BeginTOTPEnrollment(session app.AdminSession) (app.TOTPEnrollment, error) {
+	m.totpEnrolled = true
+	m.totpStatus.Enrollment = &app.TOTPEnrollment{Secret: "SECRET", URI: "otpauth://totp/abtprj:" + session.Login}
+	return *m.totpStatus.Enrollment, nil
+}
+
+func (m *mockService) EnableTOTP(session app.AdminSession, code string) ([]string, error) {
+	if code != m.totpCode {
+		return nil, app.ErrInvalidCode
+	}
+	m.totpEnabled = true
+	return []string{"AAAAA-BBBBB", "CCCCC-DDDDD"}, nil
+}
+
+func (m *mockService) DisableTOTP(session app.AdminSession, password, code string) error {
+	m.disablePassword = password
+	if code != m.totpCode {
+		return app.ErrInvalidCode
+	}
+	m.totpDisabled = true
+	return nil
+}
+
+func (m *mockService) ChangeAdminPassword(session app.AdminSession, current, newPassword string) error {
+	m.changeCurrent, m.changeNew = current, newPassword
+	if m.changePassword != nil {
+		return m.changePassword
+	}
+	m.passwordChanged = true
+	return nil
+}
+
+func (m *mockService) CompleteTask(name string) (app.Undo, error) {
+	m.completedTask = name
+	return m.undo, nil
+}
+
+func (m *mockService) StartWorkSession() (app.Undo, error) {
+	m.sessionStarted = true
+	return m.undo, nil
+}
+
+func (m *mockService) EndWorkSession() (app.Undo, error) {
+	m.sessionEnded = true
+	return m.undo, nil
+}
 
 func (m *mockService) GetGoals() ([]app.Goal, error) {
 	return m.goals, nil
@@ -38,6 +494,11 @@ func (m *mockService) IsWorking() (bool, error) {
 }
 
 func (m *mockService) GetTasksForDate(date string) ([]app.Task, error) {
+	if m.publicView {
+		return slices.DeleteFunc(slices.Clone(m.tasksForDate), func(t app.Task) bool {
+			return t.Visibility == app.VisibilityPrivate
+		}), nil
+	}
 	return m.tasksForDate, nil
 }
 
@@ -54,5 +515,172 @@ func (m *mockService) GetDayTaskStats(year int) ([]app.DayTasksStat, error) {
 }
 
 func (m *mockService) GetDaySessionStats(year int) ([]app.DaySessionsStat, error) {
+	if m.sessionStatsByYear != nil {
+		return m.sessionStatsByYear[year], nil
+	}
 	return m.sessionStats, nil
 }
+
+func (m *mockService) GetHeatmapSettings() (app.HeatmapSettings, error) {
+	return m.heatmapSettings, nil
+}
+
+func (m *mockService) UpdateHeatmapSettings(settings app.HeatmapSettings) error {
+	m.updatedHeatmapSettings = &settings
+	return nil
+}
+
+func (m *mockService) GetDefaultVisibility() (string, error) {
+	if m.defaultVisibility == "" {
+		return app.VisibilityPublic, nil
+	}
+	return m.defaultVisibility, nil
+}
+
+func (m *mockService) SetDefaultVisibility(visibility string) error {
+	if visibility == "" || !validVisibility(visibility) {
+		return app.ErrInvalidVisibility
+	}
+	m.defaultVisibility = visibility
+	return nil
+}
+
+func (m *mockService) SetTaskVisibility(id int, visibility string) error {
+	if !slices.ContainsFunc(m.todoTasks, func(t app.Task) bool { return t.ID == id }) {
+		return app.ErrNoItem
+	}
+	return m.setVisibility("task", id, visibility)
+}
+
+func (m *mockService) SetGoalVisibility(id int, visibility string) error {
+	if !slices.ContainsFunc(m.todoGoals, func(g app.Goal) bool { return g.ID == id }) {
+		return app.ErrNoItem
+	}
+	return m.setVisibility("goal", id, visibility)
+}
+
+func (m *mockService) setVisibility(kind string, id int, visibility string) error {
+	if visibility != "" && !validVisibility(visibility) {
+		return app.ErrInvalidVisibility
+	}
+	m.visibilitySet = fmt.Sprintf("%s %d %s", kind, id, visibility)
+	return nil
+}
+
+func (m *mockService) GetGoalDeadlineStats() (app.GoalDeadlineStats, error) {
+	return m.deadlineStats, nil
+}
+
+func (m *mockService) GetUpcomingDeadlines() ([]app.Goal, error) {
+	return m.upcomingDeadlines, nil
+}
+
+func (m *mockService) AddTask(task app.Task) error {
+	if !validVisibility(task.Visibility) {
+		return app.ErrInvalidVisibility
+	}
+	m.addedTask = &task
+	return nil
+}
+
+func (m *mockService) GetEstimateReport(from, to time.Time) (app.EstimateReport, error) {
+	m.estimateFrom, m.estimateTo = from, to
+	return m.estimateReport, nil
+}
+
+func (m *mockService) GetMonthCalendar(year int, month time.Month) (app.MonthCalendar, error) {
+	m.calendarYear, m.calendarMonth = year, month
+	return m.calendar, nil
+}
+
+func (m *mockService) Export(w io.Writer, kind, format string, filter app.ExportFilter) error {
+	m.exportKind, m.exportFormat, m.exportFilter = kind, format, filter
+	_, err := io.WriteString(w, "EXPORT "+kind+" "+format)
+	return err
+}
+
+func (m *mockService) Backup(w io.Writer) error {
+	if m.backupErr != nil {
+		return m.backupErr
+	}
+	_, err := io.WriteString(w, "BACKUP")
+	return err
+}
+
+func (m *mockService) GetFeedToken() (string, error) {
+	return m.feedToken, nil
+}
+
+func (m *mockService) RegenerateFeedToken() (string, error) {
+	m.tokenRegenerated = true
+	m.feedToken = "regenerated"
+	return m.feedToken, nil
+}
+
+func (m *mockService) GetWorkSessionsBetween(from, to time.Time) ([]app.WorkSession, error) {
+	return m.sessionsBetween, nil
+}
+
+func (m *mockService) Location() *time.Location {
+	if m.loc == nil {
+		return time.UTC
+	}
+	return m.loc
+}
+
+func (m *mockService) PlanTaskImport(tasks []importer.Task) (app.TaskImportPlan, error) {
+	m.planCalled = true
+	m.importPlan.Items = nil
+	for _, t := range tasks {
+		m.importPlan.Items = append(m.importPlan.Items, app.TaskImportItem{Task: t})
+	}
+	return m.importPlan, nil
+}
+
+func (m *mockService) ImportTasks(tasks []importer.Task) (app.TaskImportPlan, error) {
+	m.importedTasks = tasks
+	return m.PlanTaskImport(tasks)
+}
+
+func (m *mockService) ImportTimeEntries(entries []importer.TimeEntry, overlap string, dryRun bool) (app.TimeImportSummary, error) {
+	m.timeEntries, m.timeOverlap, m.timeDryRun, m.timeImportDone = entries, overlap, dryRun, true
+	summary := app.TimeImportSummary{Imported: len(entries)}
+	for _, e := range entries {
+		summary.Rows = append(summary.Rows, app.TimeImportRow{TimeEntry: e, Outcome: "imported"})
+	}
+	return summary, nil
+}
+
+func (m *mockService) GetRecentlyCompleted(limit int) ([]app.CompletedItem, error) {
+	m.completedLimit = limit
+	return m.completed, nil
+}
+
+func (m *mockService) WriteJournal(w io.Writer, from, to time.Time) error {
+	m.journalFrom, m.journalTo = from, to
+	_, err := io.WriteString(w, "# Journal")
+	return err
+}
+
+func (m *mockService) GetStatus() (app.TrackerStatus, error) {
+	return m.status, nil
+}
+
+func (m *mockService) GetAPIToken() (string, error) {
+	return m.apiToken, nil
+}
+
+func (m *mockService) RegenerateAPIToken() (string, error) {
+	m.apiRegenerated = true
+	m.apiToken = "regenerated"
+	return m.apiToken, nil
+}
+
+// validVisibility is what AddTask and CreateGoal accept; "" is the default.
+func validVisibility(v string) bool {
+	switch v {
+	case "", app.VisibilityPublic, app.VisibilityPrivate, app.VisibilityRedacted:
+		return true
+	}
+	return false
+}
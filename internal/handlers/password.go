@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"abtprj/internal/app"
+	"errors"
+	"log"
+	"net/http"
+)
+
+type PasswordPageData struct {
+	Page
+	Login     string
+	Forced    bool
+	Error     string
+	Success   bool
+	MinLength int
+	CSRFToken string
+}
+
+func (h *Handler) renderPasswordPage(w http.ResponseWriter, r *http.Request, status int, data PasswordPageData) {
+	session := currentAdmin(r)
+	data.Page = page(r)
+	data.Login = session.Login
+	data.Forced = session.MustChangePassword
+	data.MinLength = app.MinPasswordLength
+	data.CSRFToken = session.CSRFToken()
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := h.Templates.ExecuteTemplate(w, "password.html", data); err != nil {
+		log.Printf("renderPasswordPage template error: %v", err)
+	}
+}
+
+func (h *Handler) changePassword(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form data", http.StatusBadRequest)
+		return
+	}
+	current := r.FormValue("current_password")
+	newPassword := r.FormValue("new_password")
+	if newPassword != r.FormValue("confirm_password") {
+		h.renderPasswordPage(w, r, http.StatusBadRequest, PasswordPageData{Error: "The new passwords do not match."})
+		return
+	}
+
+	session := currentAdmin(r)
+	err := h.asAdmin(r).ChangeAdminPassword(session, current, newPassword)
+	switch {
+	case errors.Is(err, app.ErrWrongPassword):
+		h.renderPasswordPage(w, r, http.StatusBadRequest, PasswordPageData{Error: "The current password is wrong."})
+		return
+	case errors.Is(err, app.ErrWeakPassword):
+		h.renderPasswordPage(w, r, http.StatusBadRequest, PasswordPageData{Error: err.Error()})
+		return
+	case err != nil:
+		log.Printf("changePassword ChangeAdminPassword error: %v", err)
+		http.Error(w, "failed to change password", http.StatusInternalServerError)
+		return
+	}
+
+	if session.MustChangePassword {
+		http.Redirect(w, r, "/admin/", http.StatusSeeOther)
+		return
+	}
+	h.renderPasswordPage(w, r, http.StatusOK, PasswordPageData{Success: true})
+}
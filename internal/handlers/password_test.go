@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"abtprj/internal/app"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func createPasswordTemplate() *template.Template {
+	return template.Must(template.New("password.html").Parse(
+		`{{define "password.html"}}forced={{.Forced}} success={{.Success}} error={{.Error}}{{end}}`))
+}
+
+func postPassword(h *Handler, current, newPassword, confirm string) *httptest.ResponseRecorder {
+	form := url.Values{
+		"current_password": {current}, "new_password": {newPassword}, "confirm_password": {confirm},
+		csrfFieldName: {app.AdminSession{Token: "t"}.CSRFToken()},
+	}
+	req := httptest.NewRequest(http.MethodPost, "/admin/password", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "t"})
+	rr := httptest.NewRecorder()
+	h.requireAdmin(h.AdminHandler)(rr, req)
+	return rr
+}
+
+func TestPasswordPage_Forced(t *testing.T) {
+	svc := &mockService{adminSession: app.AdminSession{Token: "t", MustChangePassword: true}}
+	h := &Handler{Templates: createPasswordTemplate(), AppService: svc}
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/password", nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "t"})
+	rr := httptest.NewRecorder()
+	h.requireAdmin(h.AdminHandler)(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+	if !strings.Contains(rr.Body.String(), "forced=true") {
+		t.Errorf("expected forced form, got %q", rr.Body.String())
+	}
+}
+
+func TestChangePassword_Success(t *testing.T) {
+	svc := &mockService{adminSession: app.AdminSession{Token: "t"}}
+	h := &Handler{Templates: createPasswordTemplate(), AppService: svc}
+
+	rr := postPassword(h, "old secret", "new long secret", "new long secret")
+
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "success=true") {
+		t.Fatalf("expected success page, got %d %q", rr.Code, rr.Body.String())
+	}
+	if svc.changeCurrent != "old secret" || svc.changeNew != "new long secret" {
+		t.Errorf("unexpected passwords passed: %q, %q", svc.changeCurrent, svc.changeNew)
+	}
+}
+
+func TestChangePassword_ForcedRedirectsToAdmin(t *testing.T) {
+	svc := &mockService{adminSession: app.AdminSession{Token: "t", MustChangePassword: true}}
+	h := &Handler{Templates: createPasswordTemplate(), AppService: svc}
+
+	rr := postPassword(h, "admin", "new long secret", "new long secret")
+
+	if rr.Code != http.StatusSeeOther || rr.Header().Get("Location") != "/admin/" {
+		t.Errorf("expected redirect to /admin/, got %d %q", rr.Code, rr.Header().Get("Location"))
+	}
+}
+
+func TestChangePassword_Errors(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		confirm   string
+		wantError string
+	}{
+		{"mismatch", nil, "other", "do not match"},
+		{"wrong current", app.ErrWrongPassword, "", "current password is wrong"},
+		{"weak", fmt.Errorf("%w: use at least 8 characters", app.ErrWeakPassword), "", "at least 8 characters"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &mockService{adminSession: app.AdminSession{Token: "t"}, changePassword: tt.err}
+			h := &Handler{Templates: createPasswordTemplate(), AppService: svc}
+			confirm := tt.confirm
+			if confirm == "" {
+				confirm = "short"
+			}
+
+			rr := postPassword(h, "old", "short", confirm)
+
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("expected status %d, got %d", http.StatusBadRequest, rr.Code)
+			}
+			if !strings.Contains(rr.Body.String(), tt.wantError) {
+				t.Errorf("expected error %q, got %q", tt.wantError, rr.Body.String())
+			}
+			if svc.passwordChanged {
+				t.Error("password should not have changed")
+			}
+		})
+	}
+}
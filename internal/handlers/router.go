@@ -4,13 +4,17 @@ import (
 	"net/http"
 )
 
+type IndexPageData struct {
+	Page
+}
+
 func (h *Handler) MainHandler(w http.ResponseWriter, r *http.Request) {
 	if r.URL.Path != "/" {
 		http.NotFound(w, r)
 		return
 	}
 
-	if err := h.Templates.ExecuteTemplate(w, "index.html", nil); err != nil {
+	if err := h.Templates.ExecuteTemplate(w, "index.html", IndexPageData{Page: page(r)}); err != nil {
 		http.Error(w, "failed to render index.html", http.StatusInternalServerError)
 	}
 }
@@ -10,35 +10,56 @@ import (
 func (h *Handler) StatsHandler(w http.ResponseWriter, r *http.Request) {
 	switch {
 	case r.Method == http.MethodGet && r.URL.Path == "/stats/":
-		h.renderStatsPage(w)
+		h.renderStatsPage(w, r)
+	case r.Method == http.MethodGet && r.URL.Path == "/stats/tasks.svg":
+		h.renderHeatmapSVG(w, r, "tasks")
+	case r.Method == http.MethodGet && r.URL.Path == "/stats/sessions.svg":
+		h.renderHeatmapSVG(w, r, "sessions")
+	case r.Method == http.MethodGet && r.URL.Path == "/stats/badges/hours-week.svg":
+		h.renderBadgeSVG(w, r, "hours-week")
+	case r.Method == http.MethodGet && r.URL.Path == "/stats/badges/streak.svg":
+		h.renderBadgeSVG(w, r, "streak")
 	default:
 		http.NotFound(w, r)
 	}
 }
 
-func (h *Handler) renderStatsPage(w http.ResponseWriter) {
-	year := time.Now().Year()
+func (h *Handler) renderStatsPage(w http.ResponseWriter, r *http.Request) {
+	year := time.Now().In(h.service(r).Location()).Year()
 
-	taskStats, err := h.AppService.GetDayTaskStats(year)
+	taskStats, err := h.service(r).GetDayTaskStats(year)
 	if err != nil {
 		log.Printf("could not get task stats: %v", err)
 		http.Error(w, "failed to load stats", http.StatusInternalServerError)
 		return
 	}
 
-	sessionStats, err := h.AppService.GetDaySessionStats(year)
+	sessionStats, err := h.service(r).GetDaySessionStats(year)
 	if err != nil {
 		log.Printf("could not get session stats: %v", err)
 		http.Error(w, "failed to load stats", http.StatusInternalServerError)
 		return
 	}
 
+	deadlineStats, err := h.service(r).GetGoalDeadlineStats()
+	if err != nil {
+		log.Printf("could not get goal deadline stats: %v", err)
+		http.Error(w, "failed to load stats", http.StatusInternalServerError)
+		return
+	}
+
 	data := struct {
 		TaskContributions    []app.DayTasksStat
 		SessionContributions []app.DaySessionsStat
+		GoalDeadlines        app.GoalDeadlineStats
+		OnTimePercent        int
+		Page
 	}{
 		taskStats,
 		sessionStats,
+		deadlineStats,
+		app.Percent(deadlineStats.OnTimeRate),
+		page(r),
 	}
 
 	if err := h.Templates.ExecuteTemplate(w, "stats.html", data); err != nil {
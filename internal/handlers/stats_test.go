@@ -2,11 +2,11 @@ package handlers
 
 import (
 	"abtprj/internal/app"
+	"html/template"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
-	"text/template"
 	"time"
 )
 
@@ -116,3 +116,38 @@ SESSIONS:
 		}
 	}
 }
+
+func TestStatsHandler_GoalDeadlines(t *testing.T) {
+	tmpl := template.Must(template.New("stats.html").Parse(`
+{{define "stats.html"}}
+RATE: {{.OnTimePercent}}% OVERDUE: {{.GoalDeadlines.Overdue}}
+{{- range .TaskContributions}}{{if .HasOverdueGoal}} OVERDUE-CELL:{{.Date}}{{end}}{{end}}
+{{end}}
+`))
+
+	svc := &mockService{
+		taskStats: []app.DayTasksStat{
+			{Date: "2025-01-01", Goals: []app.Goal{{Name: "late", State: app.GoalOverdue}}},
+			{Date: "2025-01-02", Goals: []app.Goal{{Name: "fine", State: app.GoalUpcoming}}},
+		},
+		deadlineStats: app.GoalDeadlineStats{DoneOnTime: 2, DoneLate: 1, Overdue: 1, Open: 3, OnTimeRate: 2.0 / 3},
+	}
+	h := &Handler{Templates: tmpl, AppService: svc}
+
+	req := httptest.NewRequest(http.MethodGet, "/stats/", nil)
+	rr := httptest.NewRecorder()
+	h.StatsHandler(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status code = %d; want %d", rr.Code, http.StatusOK)
+	}
+	body := rr.Body.String()
+	for _, want := range []string{"RATE: 67%", "OVERDUE: 1", "OVERDUE-CELL:2025-01-01"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body = %q; want to contain %q", body, want)
+		}
+	}
+	if strings.Contains(body, "OVERDUE-CELL:2025-01-02") {
+		t.Errorf("body = %q; did not expect 2025-01-02 to be highlighted", body)
+	}
+}
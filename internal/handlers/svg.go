@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"abtprj/internal/app"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	svgCell      = 10
+	svgGap       = 3
+	svgLeftPad   = 28
+	svgTopPad    = 16
+	svgCacheTime = 15 * time.Minute
+)
+
+type svgTheme struct {
+	Background string
+	Text       string
+	Levels     [5]string
+}
+
+var svgThemes = map[string]svgTheme{
+	"dark": {
+		Background: "#121212",
+		Text:       "#dddddd",
+		Levels:     [5]string{"#929292", "#c6e48b", "#7bc96f", "#239a3b", "#196127"},
+	},
+	"light": {
+		Background: "#ffffff",
+		Text:       "#57606a",
+		Levels:     [5]string{"#ebedf0", "#9be9a8", "#40c463", "#30a14e", "#216e39"},
+	},
+}
+
+// heatmapCell is the renderer's view of one DayTasksStat or DaySessionsStat.
+type heatmapCell struct {
+	Row, Col int
+	Level    int
+	Title    string
+}
+
+func (h *Handler) renderHeatmapSVG(w http.ResponseWriter, r *http.Request, kind string) {
+	themeName := r.URL.Query().Get("theme")
+	if themeName == "" {
+		themeName = "dark"
+	}
+	theme, ok := svgThemes[themeName]
+	if !ok {
+		http.Error(w, "unknown theme", http.StatusBadRequest)
+		return
+	}
+
+	// the current year, week and day are the account's, not the server's
+	now := time.Now().In(h.service(r).Location())
+	year := now.Year()
+	if raw := r.URL.Query().Get("year"); raw != "" {
+		y, err := strconv.Atoi(raw)
+		if err != nil || y < 2000 || y > 9999 {
+			http.Error(w, "invalid year", http.StatusBadRequest)
+			return
+		}
+		year = y
+	}
+
+	weeks := app.GridWeeks(year)
+	if raw := r.URL.Query().Get("weeks"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > weeks {
+			http.Error(w, fmt.Sprintf("weeks must be between 1 and %d", weeks), http.StatusBadRequest)
+			return
+		}
+		weeks = n
+	}
+
+	var cells []heatmapCell
+	switch kind {
+	case "tasks":
+		stats, err := h.service(r).GetDayTaskStats(year)
+		if err != nil {
+			log.Printf("renderHeatmapSVG GetDayTaskStats error: %v", err)
+			http.Error(w, "failed to load stats", http.StatusInternalServerError)
+			return
+		}
+		for _, s := range stats {
+			cells = append(cells, heatmapCell{s.Row, s.Col, s.Level, fmt.Sprintf("%d tasks on %s", s.Count, s.Date)})
+		}
+	case "sessions":
+		stats, err := h.service(r).GetDaySessionStats(year)
+		if err != nil {
+			log.Printf("renderHeatmapSVG GetDaySessionStats error: %v", err)
+			http.Error(w, "failed to load stats", http.StatusInternalServerError)
+			return
+		}
+		for _, s := range stats {
+			cells = append(cells, heatmapCell{s.Row, s.Col, s.Level, fmt.Sprintf("%s worked on %s", s.SessionDur.Truncate(time.Minute), s.Date)})
+		}
+	}
+
+	// Show the trailing weeks up to the current one for this year, the end of the year otherwise.
+	lastCol := app.GridWeeks(year) + 1
+	if year == now.Year() {
+		lastCol = app.StatIndex(now)/7 + 2
+	}
+	firstCol := lastCol - weeks + 1
+	if firstCol < 2 {
+		firstCol = 2
+	}
+
+	writeSVG(w, r, heatmapSVG(cells, theme, year, firstCol, lastCol))
+}
+
+func heatmapSVG(cells []heatmapCell, theme svgTheme, year, firstCol, lastCol int) []byte {
+	step := svgCell + svgGap
+	width := svgLeftPad + (lastCol-firstCol+1)*step
+	height := svgTopPad + 7*step
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="Verdana,sans-serif" font-size="9">`, width, height, width, height)
+	fmt.Fprintf(&b, `<rect width="100%%" height="100%%" fill="%s"/>`, theme.Background)
+
+	for m := time.January; m <= time.December; m++ {
+		col := app.StatIndex(time.Date(year, m, 1, 0, 0, 0, 0, time.UTC))/7 + 2
+		if col < firstCol || col > lastCol {
+			continue
+		}
+		fmt.Fprintf(&b, `<text x="%d" y="10" fill="%s">%s</text>`, svgLeftPad+(col-firstCol)*step, theme.Text, m.String()[:3])
+	}
+	for row, label := range []string{"Mon", "", "Wed", "", "Fri", "", "Sun"} {
+		if label == "" {
+			continue
+		}
+		fmt.Fprintf(&b, `<text x="0" y="%d" fill="%s">%s</text>`, svgTopPad+row*step+svgCell-1, theme.Text, label)
+	}
+
+	for _, c := range cells {
+		if c.Col < firstCol || c.Col > lastCol {
+			continue
+		}
+		level := c.Level
+		if level < 0 || level > 4 {
+			level = 0
+		}
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" rx="2" fill="%s"><title>%s</title></rect>`,
+			svgLeftPad+(c.Col-firstCol)*step, svgTopPad+(c.Row-2)*step, svgCell, svgCell, theme.Levels[level], c.Title)
+	}
+	b.WriteString(`</svg>`)
+	return b.Bytes()
+}
+
+func (h *Handler) renderBadgeSVG(w http.ResponseWriter, r *http.Request, kind string) {
+	now := time.Now().In(h.service(r).Location())
+	stats, err := h.service(r).GetDaySessionStats(now.Year())
+	if err != nil {
+		log.Printf("renderBadgeSVG GetDaySessionStats error: %v", err)
+		http.Error(w, "failed to load stats", http.StatusInternalServerError)
+		return
+	}
+
+	// the week and the streak can reach back into last year
+	prevYear, err := h.service(r).GetDaySessionStats(now.Year() - 1)
+	if err != nil {
+		log.Printf("renderBadgeSVG GetDaySessionStats error: %v", err)
+		http.Error(w, "failed to load stats", http.StatusInternalServerError)
+		return
+	}
+
+	var label, value string
+	switch kind {
+	case "hours-week":
+		label = "hours this week"
+		value = strconv.FormatFloat(app.WeekDuration(stats, prevYear, now).Hours(), 'f', 1, 64) + "h"
+	case "streak":
+		label = "current streak"
+		days := app.CurrentStreak(stats, prevYear, now)
+		value = strconv.Itoa(days) + " days"
+		if days == 1 {
+			value = "1 day"
+		}
+	}
+
+	writeSVG(w, r, badgeSVG(label, value, r.URL.Query().Get("color")))
+}
+
+// badgeSVG renders a shields.io style flat badge.
+func badgeSVG(label, value, color string) []byte {
+	if !isHexColor(color) {
+		color = "4c1"
+	}
+	textWidth := func(s string) int { return len(s)*6 + 10 }
+	lw, vw := textWidth(label), textWidth(value)
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="20" role="img" aria-label="%s: %s">`, lw+vw, label, value)
+	fmt.Fprintf(&b, `<title>%s: %s</title>`, label, value)
+	fmt.Fprintf(&b, `<linearGradient id="s" x2="0" y2="100%%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>`)
+	fmt.Fprintf(&b, `<clipPath id="r"><rect width="%d" height="20" rx="3" fill="#fff"/></clipPath>`, lw+vw)
+	fmt.Fprintf(&b, `<g clip-path="url(#r)"><rect width="%d" height="20" fill="#555"/><rect x="%d" width="%d" height="20" fill="#%s"/><rect width="%d" height="20" fill="url(#s)"/></g>`, lw, lw, vw, color, lw+vw)
+	fmt.Fprintf(&b, `<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">`)
+	fmt.Fprintf(&b, `<text x="%d" y="14">%s</text><text x="%d" y="14">%s</text></g>`, lw/2, label, lw+vw/2, value)
+	b.WriteString(`</svg>`)
+	return b.Bytes()
+}
+
+func isHexColor(s string) bool {
+	if len(s) != 3 && len(s) != 6 {
+		return false
+	}
+	for _, c := range s {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+// writeSVG sends body with caching headers and answers conditional requests.
+func writeSVG(w http.ResponseWriter, r *http.Request, body []byte) {
+	writeCached(w, r, "image/svg+xml; charset=utf-8", svgCacheTime, body)
+}
+
+// writeCached sends body as a publicly cacheable response with a content-derived
+// ETag, answering a matching If-None-Match with 304. An account looking at its
+// own pages sees private items, so only its browser may keep that copy.
+func writeCached(w http.ResponseWriter, r *http.Request, contentType string, maxAge time.Duration, body []byte) {
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+
+	w.Header().Set("Content-Type", contentType)
+	cache := "public"
+	if fullView(r) {
+		cache = "private"
+	}
+	w.Header().Set("Cache-Control", cache+", max-age="+strconv.Itoa(int(maxAge.Seconds())))
+	w.Header().Set("ETag", etag)
+
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Write(body)
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"abtprj/internal/app"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStatsHandler_HeatmapSVG(t *testing.T) {
+	svc := &mockService{
+		taskStats: []app.DayTasksStat{
+			{Date: "2025-01-01", Count: 3, Level: 3, Row: 4, Col: 2},
+		},
+		sessionStats: []app.DaySessionsStat{
+			{Date: "2025-01-01", SessionDur: 5 * time.Hour, Level: 3, Row: 4, Col: 2},
+		},
+	}
+	h := &Handler{AppService: svc}
+
+	cases := []struct {
+		path string
+		want string
+	}{
+		{"/stats/tasks.svg?year=2025", "3 tasks on 2025-01-01"},
+		{"/stats/sessions.svg?year=2025&theme=light", "5h0m0s worked on 2025-01-01"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			rr := httptest.NewRecorder()
+
+			h.StatsHandler(rr, req)
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("status code = %d; want %d", rr.Code, http.StatusOK)
+			}
+			if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, "image/svg+xml") {
+				t.Errorf("Content-Type = %q; want image/svg+xml", ct)
+			}
+			if rr.Header().Get("ETag") == "" || rr.Header().Get("Cache-Control") == "" {
+				t.Error("expected ETag and Cache-Control headers")
+			}
+			body := rr.Body.String()
+			if !strings.HasPrefix(body, "<svg") || !strings.Contains(body, tc.want) {
+				t.Errorf("body = %q; want svg containing %q", body, tc.want)
+			}
+		})
+	}
+}
+
+func TestStatsHandler_HeatmapSVG_BadParams(t *testing.T) {
+	h := &Handler{AppService: &mockService{}}
+
+	for _, path := range []string{
+		"/stats/tasks.svg?theme=neon",
+		"/stats/tasks.svg?year=abc",
+		"/stats/sessions.svg?weeks=0",
+		"/stats/sessions.svg?year=2026&weeks=54",
+	} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		rr := httptest.NewRecorder()
+
+		h.StatsHandler(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: status code = %d; want %d", path, rr.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestStatsHandler_HeatmapSVG_NotModified(t *testing.T) {
+	h := &Handler{AppService: &mockService{}}
+
+	req := httptest.NewRequest(http.MethodGet, "/stats/tasks.svg?year=2025", nil)
+	rr := httptest.NewRecorder()
+	h.StatsHandler(rr, req)
+	etag := rr.Header().Get("ETag")
+
+	req = httptest.NewRequest(http.MethodGet, "/stats/tasks.svg?year=2025", nil)
+	req.Header.Set("If-None-Match", etag)
+	rr = httptest.NewRecorder()
+	h.StatsHandler(rr, req)
+
+	if rr.Code != http.StatusNotModified {
+		t.Errorf("status code = %d; want %d", rr.Code, http.StatusNotModified)
+	}
+	if rr.Body.Len() != 0 {
+		t.Errorf("expected empty body, got %q", rr.Body.String())
+	}
+}
+
+func TestStatsHandler_Badges(t *testing.T) {
+	now := time.Now()
+	byYear := map[int][]app.DaySessionsStat{
+		now.Year():     make([]app.DaySessionsStat, app.GridWeeks(now.Year())*7),
+		now.Year() - 1: make([]app.DaySessionsStat, app.GridWeeks(now.Year()-1)*7),
+	}
+	worked := func(d time.Time, dur time.Duration) {
+		byYear[d.Year()][app.StatIndex(d)].SessionDur = dur
+	}
+	worked(now, 90*time.Minute)
+	worked(now.AddDate(0, 0, -1), time.Hour) // can be last year's
+
+	h := &Handler{AppService: &mockService{sessionStatsByYear: byYear}}
+
+	wantStreak := "2 days"
+	wantHours := "2.5h"
+	if now.Weekday() == time.Monday {
+		wantHours = "1.5h"
+	}
+
+	cases := []struct {
+		path string
+		want string
+	}{
+		{"/stats/badges/hours-week.svg", "hours this week: " + wantHours},
+		{"/stats/badges/streak.svg", "current streak: " + wantStreak},
+	}
+
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+		rr := httptest.NewRecorder()
+
+		h.StatsHandler(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%s: status code = %d; want %d", tc.path, rr.Code, http.StatusOK)
+		}
+		if body := rr.Body.String(); !strings.Contains(body, tc.want) {
+			t.Errorf("%s: body = %q; want to contain %q", tc.path, body, tc.want)
+		}
+	}
+}
@@ -0,0 +1,318 @@
+package handlers
+
+import (
+	"abtprj/internal/app"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type TeamsPageData struct {
+	Page
+	Teams     []app.Team
+	Error     string
+	CSRFToken string
+}
+
+type TeamPageData struct {
+	Page
+	app.TeamOverview
+	Login     string
+	CSRFToken string
+}
+
+type TeamStatsPageData struct {
+	Page
+	app.TeamStats
+	PrevYear, NextYear int
+}
+
+// teamsHandler serves /admin/teams/ and the pages and actions of one team
+// under /admin/teams/{name}/. What a user may do is decided by their role in
+// the team, in the AppService, and teams are always the logged-in admin's own,
+// whichever account the session works on.
+func (h *Handler) teamsHandler(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.Path, "/admin/teams/")
+	if rest == "" {
+		switch r.Method {
+		case http.MethodGet:
+			h.renderTeamsPage(w, r, http.StatusOK, TeamsPageData{})
+		case http.MethodPost:
+			h.createTeam(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+		return
+	}
+
+	team, action, found := strings.Cut(rest, "/")
+	if !found {
+		http.Redirect(w, r, teamPath(team), http.StatusMovedPermanently)
+		return
+	}
+	switch {
+	case r.Method == http.MethodGet && action == "":
+		h.renderTeamPage(w, r, team)
+	case r.Method == http.MethodGet && action == "stats":
+		h.renderTeamStatsPage(w, r, team)
+	case r.Method == http.MethodPost && action == "members":
+		h.setTeamMember(w, r, team)
+	case r.Method == http.MethodPost && action == "members/remove":
+		h.removeTeamMember(w, r, team)
+	case r.Method == http.MethodPost && action == "projects":
+		h.createProject(w, r, team)
+	case r.Method == http.MethodPost && action == "tasks":
+		h.addSharedTask(w, r, team)
+	case r.Method == http.MethodPost && action == "tasks/complete":
+		h.completeSharedTask(w, r, team)
+	case r.Method == http.MethodPost && action == "goals":
+		h.createSharedGoal(w, r, team)
+	case r.Method == http.MethodPost && action == "goals/complete":
+		h.completeSharedGoal(w, r, team)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func teamPath(team string) string {
+	return "/admin/teams/" + url.PathEscape(team) + "/"
+}
+
+// teamError answers a failed team request with the status its error calls for.
+func teamError(w http.ResponseWriter, op string, err error) {
+	switch {
+	case errors.Is(err, app.ErrNoTeam), errors.Is(err, app.ErrNoUser), errors.Is(err, app.ErrNoSharedItem):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, app.ErrNotAllowed):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, app.ErrLastOwner), errors.Is(err, app.ErrTeamTaken):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, app.ErrInvalidRole), errors.Is(err, app.ErrInvalidTeamName), errors.Is(err, app.ErrInvalidProject):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		log.Printf("%s error: %v", op, err)
+		http.Error(w, "failed to "+op, http.StatusInternalServerError)
+	}
+}
+
+// ownPage is the Page of the admin's own teams, whichever account the session
+// works on.
+func ownPage(r *http.Request) Page {
+	return Page{Account: currentAdmin(r).Login}
+}
+
+func (h *Handler) renderTeamsPage(w http.ResponseWriter, r *http.Request, status int, data TeamsPageData) {
+	teams, err := h.ownService(r).ListTeams()
+	if err != nil {
+		log.Printf("renderTeamsPage ListTeams error: %v", err)
+		http.Error(w, "failed to load teams", http.StatusInternalServerError)
+		return
+	}
+	data.Page = ownPage(r)
+	data.Teams = teams
+	data.CSRFToken = currentAdmin(r).CSRFToken()
+	w.WriteHeader(status)
+	if err := h.Templates.ExecuteTemplate(w, "teams.html", data); err != nil {
+		log.Printf("renderTeamsPage template error: %v", err)
+	}
+}
+
+func (h *Handler) createTeam(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "incorrect form values", http.StatusBadRequest)
+		return
+	}
+	name := strings.TrimSpace(r.FormValue("name"))
+	err := h.ownService(r).CreateTeam(name)
+	switch {
+	case err == nil:
+		http.Redirect(w, r, teamPath(name), http.StatusSeeOther)
+	case errors.Is(err, app.ErrInvalidTeamName):
+		h.renderTeamsPage(w, r, http.StatusBadRequest, TeamsPageData{Error: err.Error()})
+	case errors.Is(err, app.ErrTeamTaken):
+		h.renderTeamsPage(w, r, http.StatusConflict, TeamsPageData{Error: "That team name is already taken."})
+	default:
+		log.Printf("createTeam CreateTeam error: %v", err)
+		http.Error(w, "failed to create team", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) renderTeamPage(w http.ResponseWriter, r *http.Request, team string) {
+	overview, err := h.ownService(r).GetTeam(team)
+	if err != nil {
+		teamError(w, "load team", err)
+		return
+	}
+	data := TeamPageData{
+		Page:         ownPage(r),
+		TeamOverview: overview,
+		Login:        currentAdmin(r).Login,
+		CSRFToken:    currentAdmin(r).CSRFToken(),
+	}
+	if err := h.Templates.ExecuteTemplate(w, "team.html", data); err != nil {
+		log.Printf("renderTeamPage template error: %v", err)
+	}
+}
+
+func (h *Handler) renderTeamStatsPage(w http.ResponseWriter, r *http.Request, team string) {
+	year := time.Now().Year()
+	if v := r.URL.Query().Get("year"); v != "" {
+		y, err := strconv.Atoi(v)
+		if err != nil || y < 1970 || y > 9999 {
+			http.Error(w, "invalid year", http.StatusBadRequest)
+			return
+		}
+		year = y
+	}
+
+	stats, err := h.ownService(r).GetTeamStats(team, year)
+	if err != nil {
+		teamError(w, "load team stats", err)
+		return
+	}
+	data := TeamStatsPageData{Page: ownPage(r), TeamStats: stats, PrevYear: year - 1, NextYear: year + 1}
+	if err := h.Templates.ExecuteTemplate(w, "team_stats.html", data); err != nil {
+		log.Printf("renderTeamStatsPage template error: %v", err)
+	}
+}
+
+func (h *Handler) setTeamMember(w http.ResponseWriter, r *http.Request, team string) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "incorrect form values", http.StatusBadRequest)
+		return
+	}
+	login := strings.TrimSpace(r.FormValue("login"))
+	if err := h.ownService(r).SetTeamMember(team, login, r.FormValue("role")); err != nil {
+		teamError(w, "set team member", err)
+		return
+	}
+	http.Redirect(w, r, teamPath(team), http.StatusSeeOther)
+}
+
+// removeTeamMember takes a member out of the team; users leaving a team are
+// sent back to their list of teams.
+func (h *Handler) removeTeamMember(w http.ResponseWriter, r *http.Request, team string) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "incorrect form values", http.StatusBadRequest)
+		return
+	}
+	login := r.FormValue("login")
+	if err := h.ownService(r).RemoveTeamMember(team, login); err != nil {
+		teamError(w, "remove team member", err)
+		return
+	}
+	if login == currentAdmin(r).Login {
+		http.Redirect(w, r, "/admin/teams/", http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, teamPath(team), http.StatusSeeOther)
+}
+
+func (h *Handler) createProject(w http.ResponseWriter, r *http.Request, team string) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "incorrect form values", http.StatusBadRequest)
+		return
+	}
+	if err := h.ownService(r).CreateProject(team, r.FormValue("name")); err != nil {
+		teamError(w, "create project", err)
+		return
+	}
+	http.Redirect(w, r, teamPath(team), http.StatusSeeOther)
+}
+
+func (h *Handler) addSharedTask(w http.ResponseWriter, r *http.Request, team string) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "incorrect form values", http.StatusBadRequest)
+		return
+	}
+	projectID, err := strconv.Atoi(r.FormValue("project"))
+	if err != nil {
+		http.Error(w, "invalid project", http.StatusBadRequest)
+		return
+	}
+	name := strings.TrimSpace(r.FormValue("name"))
+	if name == "" {
+		http.Error(w, "task name is not present", http.StatusBadRequest)
+		return
+	}
+	estimate, err := parseEstimate(r.FormValue("estimate"))
+	if err != nil {
+		http.Error(w, "invalid estimate", http.StatusBadRequest)
+		return
+	}
+
+	task := app.Task{Name: name, Description: r.FormValue("description"), Estimate: estimate}
+	if err := h.ownService(r).AddSharedTask(team, projectID, task); err != nil {
+		teamError(w, "add a task", err)
+		return
+	}
+	http.Redirect(w, r, teamPath(team), http.StatusSeeOther)
+}
+
+func (h *Handler) completeSharedTask(w http.ResponseWriter, r *http.Request, team string) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "incorrect form values", http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		http.Error(w, "invalid task id", http.StatusBadRequest)
+		return
+	}
+	if err := h.ownService(r).CompleteSharedTask(team, id); err != nil {
+		teamError(w, "complete a task", err)
+		return
+	}
+	http.Redirect(w, r, teamPath(team), http.StatusSeeOther)
+}
+
+func (h *Handler) createSharedGoal(w http.ResponseWriter, r *http.Request, team string) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "incorrect form values", http.StatusBadRequest)
+		return
+	}
+	projectID, err := strconv.Atoi(r.FormValue("project"))
+	if err != nil {
+		http.Error(w, "invalid project", http.StatusBadRequest)
+		return
+	}
+	name := strings.TrimSpace(r.FormValue("goal_name"))
+	dueStr := r.FormValue("goal_due")
+	if name == "" || dueStr == "" {
+		http.Error(w, "missing goal name or due date", http.StatusBadRequest)
+		return
+	}
+	due, err := time.Parse("2006-01-02", dueStr)
+	if err != nil {
+		http.Error(w, "invalid due date", http.StatusBadRequest)
+		return
+	}
+
+	goal := app.Goal{Name: name, Description: r.FormValue("goal_description"), DueAt: &due}
+	if err := h.ownService(r).CreateSharedGoal(team, projectID, goal); err != nil {
+		teamError(w, "create goal", err)
+		return
+	}
+	http.Redirect(w, r, teamPath(team), http.StatusSeeOther)
+}
+
+func (h *Handler) completeSharedGoal(w http.ResponseWriter, r *http.Request, team string) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "incorrect form values", http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		http.Error(w, "invalid goal id", http.StatusBadRequest)
+		return
+	}
+	if err := h.ownService(r).CompleteSharedGoal(team, id); err != nil {
+		teamError(w, "complete a goal", err)
+		return
+	}
+	http.Redirect(w, r, teamPath(team), http.StatusSeeOther)
+}
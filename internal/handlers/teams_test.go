@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"abtprj/internal/app"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func teamService(role string) *mockService {
+	due := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	return &mockService{
+		adminSession: app.AdminSession{Token: "t", AdminID: 2, Login: "bob"},
+		teams: map[string]app.TeamOverview{"eng": {
+			Team:      app.Team{ID: 1, Name: "eng", Role: role},
+			Members:   []app.TeamMember{{Login: "alice", Role: app.RoleOwner}, {Login: "bob", Role: role}},
+			Projects:  []app.Project{{ID: 4, Name: "site"}},
+			OpenTasks: []app.SharedTask{{ID: 9, Task: app.Task{Name: "Ship it"}, Project: "site"}},
+			OpenGoals: []app.SharedGoal{{Goal: app.Goal{ID: 5, Name: "Launch", DueAt: &due}, Project: "site"}},
+		}},
+	}
+}
+
+func getAdmin(h *Handler, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "t"})
+	rr := httptest.NewRecorder()
+	h.requireAdmin(h.AdminHandler)(rr, req)
+	return rr
+}
+
+func TestTeams_RolesControlSharedItems(t *testing.T) {
+	actions := []struct {
+		path string
+		form url.Values
+		who  []string // roles allowed to do it
+		want string   // teamAction when allowed
+	}{
+		{"/admin/teams/eng/tasks", url.Values{"project": {"4"}, "name": {"Ship it"}, "estimate": {"30"}},
+			[]string{app.RoleOwner, app.RoleMember}, "task eng 4 Ship it 30m0s"},
+		{"/admin/teams/eng/tasks/complete", url.Values{"id": {"9"}},
+			[]string{app.RoleOwner, app.RoleMember}, "complete task eng 9"},
+		{"/admin/teams/eng/goals", url.Values{"project": {"4"}, "goal_name": {"Launch"}, "goal_due": {"2025-03-01"}},
+			[]string{app.RoleOwner, app.RoleMember}, "goal eng 4 Launch 2025-03-01"},
+		{"/admin/teams/eng/goals/complete", url.Values{"id": {"5"}},
+			[]string{app.RoleOwner, app.RoleMember}, "complete goal eng 5"},
+		{"/admin/teams/eng/projects", url.Values{"name": {"api"}},
+			[]string{app.RoleOwner}, "project eng api"},
+		{"/admin/teams/eng/members", url.Values{"login": {"carol"}, "role": {"viewer"}},
+			[]string{app.RoleOwner}, "member eng carol viewer"},
+		{"/admin/teams/eng/members/remove", url.Values{"login": {"alice"}},
+			[]string{app.RoleOwner}, "remove eng alice"},
+	}
+
+	for _, a := range actions {
+		for _, role := range []string{app.RoleOwner, app.RoleMember, app.RoleViewer} {
+			svc := teamService(role)
+			h := &Handler{AppService: svc}
+
+			rr := postAdminForm(h, a.path, a.form)
+
+			allowed := false
+			for _, r := range a.who {
+				allowed = allowed || r == role
+			}
+			if !allowed {
+				if rr.Code != http.StatusForbidden || svc.teamAction != "" {
+					t.Errorf("%s as %s: status %d, action %q; want 403 and nothing done", a.path, role, rr.Code, svc.teamAction)
+				}
+				continue
+			}
+			if rr.Code != http.StatusSeeOther || rr.Header().Get("Location") != "/admin/teams/eng/" {
+				t.Errorf("%s as %s: status %d to %q; want a redirect to the team", a.path, role, rr.Code, rr.Header().Get("Location"))
+			}
+			if svc.teamAction != a.want {
+				t.Errorf("%s as %s: action %q; want %q", a.path, role, svc.teamAction, a.want)
+			}
+		}
+	}
+}
+
+func TestTeams_EveryoneCanLeave(t *testing.T) {
+	svc := teamService(app.RoleViewer)
+	h := &Handler{AppService: svc}
+
+	rr := postAdminForm(h, "/admin/teams/eng/members/remove", url.Values{"login": {"bob"}})
+
+	if rr.Code != http.StatusSeeOther || rr.Header().Get("Location") != "/admin/teams/" {
+		t.Fatalf("status %d to %q; want a redirect to the teams list", rr.Code, rr.Header().Get("Location"))
+	}
+	if svc.teamAction != "remove eng bob" {
+		t.Errorf("action = %q", svc.teamAction)
+	}
+}
+
+func TestTeams_OtherTeamsAreNotFound(t *testing.T) {
+	h := &Handler{Templates: loadViews(t), AppService: teamService(app.RoleOwner)}
+
+	for _, path := range []string{"/admin/teams/ops/", "/admin/teams/ops/stats"} {
+		if rr := getAdmin(h, path); rr.Code != http.StatusNotFound {
+			t.Errorf("GET %s: status %d; want %d", path, rr.Code, http.StatusNotFound)
+		}
+	}
+	if rr := postAdminForm(h, "/admin/teams/ops/tasks/complete", url.Values{"id": {"1"}}); rr.Code != http.StatusNotFound {
+		t.Errorf("completing a task of another team: status %d; want %d", rr.Code, http.StatusNotFound)
+	}
+}
+
+func TestTeams_PageShowsActionsForTheRole(t *testing.T) {
+	for _, tt := range []struct {
+		role           string
+		complete, form bool
+	}{
+		{app.RoleOwner, true, true},
+		{app.RoleMember, true, false},
+		{app.RoleViewer, false, false},
+	} {
+		h := &Handler{Templates: loadViews(t), AppService: teamService(tt.role)}
+
+		rr := getAdmin(h, "/admin/teams/eng/")
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%s: status %d: %s", tt.role, rr.Code, rr.Body.String())
+		}
+		body := rr.Body.String()
+		if !strings.Contains(body, "Ship it") || !strings.Contains(body, "Launch") {
+			t.Errorf("%s: shared task and goal should be listed", tt.role)
+		}
+		if got := strings.Contains(body, `action="/admin/teams/eng/tasks/complete"`); got != tt.complete {
+			t.Errorf("%s: complete button shown = %v; want %v", tt.role, got, tt.complete)
+		}
+		if got := strings.Contains(body, `action="/admin/teams/eng/members"`); got != tt.form {
+			t.Errorf("%s: member form shown = %v; want %v", tt.role, got, tt.form)
+		}
+	}
+}
+
+func TestTeams_Create(t *testing.T) {
+	svc := teamService(app.RoleOwner)
+	h := &Handler{Templates: loadViews(t), AppService: svc}
+
+	rr := postAdminForm(h, "/admin/teams/", url.Values{"name": {"design"}})
+	if rr.Code != http.StatusSeeOther || rr.Header().Get("Location") != "/admin/teams/design/" {
+		t.Fatalf("status %d to %q; want a redirect to the new team", rr.Code, rr.Header().Get("Location"))
+	}
+
+	rr = postAdminForm(h, "/admin/teams/", url.Values{"name": {"eng"}})
+	if rr.Code != http.StatusConflict || !strings.Contains(rr.Body.String(), "already taken") {
+		t.Errorf("taken name: status %d; want %d with a message", rr.Code, http.StatusConflict)
+	}
+
+	rr = postAdminForm(h, "/admin/teams/", url.Values{"name": {"Bad Name"}})
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("invalid name: status %d; want %d", rr.Code, http.StatusBadRequest)
+	}
+}
+
+func TestTeams_Stats(t *testing.T) {
+	svc := teamService(app.RoleViewer)
+	svc.teamStats = app.TeamStats{
+		Team:      app.Team{Name: "eng", Role: app.RoleViewer},
+		Year:      2024,
+		Members:   []app.MemberStats{{Login: "alice", Hours: 12.5, TasksDone: 3}, {Login: "bob", Hours: 4, TasksDone: 1}},
+		Hours:     16.5,
+		TasksDone: 4,
+		Heatmap:   []app.DaySessionsStat{{Date: "2024-01-01", SessionDur: 2 * time.Hour, Level: 4, Row: 2, Col: 2}},
+	}
+	h := &Handler{Templates: loadViews(t), AppService: svc}
+
+	rr := getAdmin(h, "/admin/teams/eng/stats?year=2024")
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rr.Code, rr.Body.String())
+	}
+	if svc.statsYear != 2024 {
+		t.Errorf("stats for %d; want 2024", svc.statsYear)
+	}
+	body := rr.Body.String()
+	for _, want := range []string{"<td>alice</td>", "12.5", "16.5", `data-date="2024-01-01"`, "level-4"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("stats page is missing %q", want)
+		}
+	}
+
+	if rr := getAdmin(h, "/admin/teams/eng/stats?year=abc"); rr.Code != http.StatusBadRequest {
+		t.Errorf("bad year: status %d; want %d", rr.Code, http.StatusBadRequest)
+	}
+}
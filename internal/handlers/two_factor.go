@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"abtprj/internal/app"
+	"abtprj/internal/qr"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// qrModule is the width of a QR code module in SVG units; the page sets the
+// displayed size.
+const qrModule = 4
+
+type TwoFactorPageData struct {
+	Page
+	Status        app.TOTPStatus
+	OTPAuthURL    template.URL  // Status.Enrollment.URI, marked safe for href
+	QRCode        template.HTML // Status.Enrollment.URI as an inline SVG QR code
+	RecoveryCodes []string      // only right after enabling
+	Error         string
+	CSRFToken     string
+}
+
+func (h *Handler) renderTwoFactorPage(w http.ResponseWriter, r *http.Request, status int, data TwoFactorPageData) {
+	totpStatus, err := h.AppService.GetTOTPStatus(currentAdmin(r))
+	if err != nil {
+		log.Printf("renderTwoFactorPage GetTOTPStatus error: %v", err)
+		http.Error(w, "failed to load two-factor settings", http.StatusInternalServerError)
+		return
+	}
+	data.Status = totpStatus
+	if e := totpStatus.Enrollment; e != nil && strings.HasPrefix(e.URI, "otpauth://") {
+		data.OTPAuthURL = template.URL(e.URI)
+		// without a QR code, the link and the key still work
+		code, err := qr.Encode([]byte(e.URI))
+		if err != nil {
+			log.Printf("renderTwoFactorPage qr.Encode error: %v", err)
+		} else {
+			data.QRCode = template.HTML(code.SVG(qrModule))
+		}
+	}
+	data.Page = page(r)
+	data.CSRFToken = currentAdmin(r).CSRFToken()
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := h.Templates.ExecuteTemplate(w, "two_factor.html", data); err != nil {
+		log.Printf("renderTwoFactorPage template error: %v", err)
+	}
+}
+
+func (h *Handler) beginTOTPSetup(w http.ResponseWriter, r *http.Request) {
+	_, err := h.AppService.BeginTOTPEnrollment(currentAdmin(r))
+	if err != nil && !errors.Is(err, app.ErrTOTPEnabled) {
+		log.Printf("beginTOTPSetup BeginTOTPEnrollment error: %v", err)
+		http.Error(w, "failed to start two-factor setup", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/admin/2fa", http.StatusSeeOther)
+}
+
+func (h *Handler) enableTOTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form data", http.StatusBadRequest)
+		return
+	}
+	codes, err := h.asAdmin(r).EnableTOTP(currentAdmin(r), r.FormValue("code"))
+	switch {
+	case errors.Is(err, app.ErrInvalidCode), errors.Is(err, app.ErrTOTPNotEnrolled), errors.Is(err, app.ErrTOTPEnabled):
+		h.renderTwoFactorPage(w, r, http.StatusBadRequest, TwoFactorPageData{Error: err.Error()})
+		return
+	case err != nil:
+		log.Printf("enableTOTP EnableTOTP error: %v", err)
+		http.Error(w, "failed to enable two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+	h.renderTwoFactorPage(w, r, http.StatusOK, TwoFactorPageData{RecoveryCodes: codes})
+}
+
+func (h *Handler) disableTOTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form data", http.StatusBadRequest)
+		return
+	}
+	err := h.asAdmin(r).DisableTOTP(currentAdmin(r), r.FormValue("password"), r.FormValue("code"))
+	switch {
+	case errors.Is(err, app.ErrWrongPassword), errors.Is(err, app.ErrInvalidCode):
+		h.renderTwoFactorPage(w, r, http.StatusBadRequest, TwoFactorPageData{Error: err.Error()})
+		return
+	case err != nil:
+		log.Printf("disableTOTP DisableTOTP error: %v", err)
+		http.Error(w, "failed to disable two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/admin/2fa", http.StatusSeeOther)
+}
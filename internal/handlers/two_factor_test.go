@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"abtprj/internal/app"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func createTwoFactorTemplate() *template.Template {
+	return template.Must(template.New("two_factor.html").Parse(`{{define "two_factor.html"}}` +
+		`enabled={{.Status.Enabled}} enrolling={{if .Status.Enrollment}}{{.Status.Enrollment.Secret}}{{end}} ` +
+		`codes={{range .RecoveryCodes}}{{.}},{{end}} error={{.Error}}{{end}}` +
+		`{{define "login_totp.html"}}TOTP PAGE {{.}}{{end}}`))
+}
+
+// postAdminForm posts form with the session "t" and its CSRF token.
+func postAdminForm(h *Handler, path string, form url.Values) *httptest.ResponseRecorder {
+	if form == nil {
+		form = url.Values{}
+	}
+	form.Set(csrfFieldName, app.AdminSession{Token: "t"}.CSRFToken())
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "t"})
+	rr := httptest.NewRecorder()
+	h.requireAdmin(h.AdminHandler)(rr, req)
+	return rr
+}
+
+func TestHandleLogin_TOTPPending_RedirectsToCode(t *testing.T) {
+	svc := &mockService{adminSession: app.AdminSession{Token: "pending", TOTPPending: true}}
+	h := &Handler{Templates: createLoginTemplate(), AppService: svc}
+
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader("login=admin&password=secret"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+	h.HandleLogin(rr, req)
+
+	if loc := rr.Header().Get("Location"); loc != "/login/totp" {
+		t.Errorf("expected redirect to /login/totp, got: %s", loc)
+	}
+	cookies := rr.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Value != "pending" {
+		t.Errorf("expected the pending session cookie, got %v", cookies)
+	}
+}
+
+func TestRequireAdmin_RejectsPendingSession(t *testing.T) {
+	svc := &mockService{adminSession: app.AdminSession{Token: "pending", TOTPPending: true}}
+	h := &Handler{AppService: svc}
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/", nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "pending"})
+	rr := httptest.NewRecorder()
+	h.requireAdmin(func(w http.ResponseWriter, r *http.Request) { t.Error("inner handler called") })(rr, req)
+
+	if loc := rr.Header().Get("Location"); loc != "/login" {
+		t.Errorf("expected redirect to /login, got: %s", loc)
+	}
+}
+
+func TestHandleTOTPLogin(t *testing.T) {
+	tests := []struct {
+		name       string
+		code       string
+		wantStatus int
+		wantLoc    string
+		wantCookie string
+	}{
+		{"valid code", "123456", http.StatusSeeOther, "/admin/", "full"},
+		{"wrong code", "000000", http.StatusUnauthorized, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &mockService{
+				adminSession: app.AdminSession{Token: "pending", TOTPPending: true},
+				totpSession:  app.AdminSession{Token: "full"},
+				totpCode:     "123456",
+			}
+			h := &Handler{Templates: createTwoFactorTemplate(), AppService: svc}
+
+			req := httptest.NewRequest(http.MethodPost, "/login/totp", strings.NewReader("code="+tt.code))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "pending"})
+			rr := httptest.NewRecorder()
+			h.HandleTOTPLogin(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rr.Code)
+			}
+			if loc := rr.Header().Get("Location"); loc != tt.wantLoc {
+				t.Errorf("expected redirect to %q, got %q", tt.wantLoc, loc)
+			}
+			var cookie string
+			for _, c := range rr.Result().Cookies() {
+				cookie = c.Value
+			}
+			if cookie != tt.wantCookie {
+				t.Errorf("expected cookie %q, got %q", tt.wantCookie, cookie)
+			}
+		})
+	}
+}
+
+func TestHandleTOTPLogin_NoPendingSession(t *testing.T) {
+	h := &Handler{Templates: createTwoFactorTemplate(), AppService: &mockService{}}
+
+	req := httptest.NewRequest(http.MethodPost, "/login/totp", strings.NewReader("code=123456"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+	h.HandleTOTPLogin(rr, req)
+
+	if loc := rr.Header().Get("Location"); loc != "/login" {
+		t.Errorf("expected redirect to /login, got %q", loc)
+	}
+}
+
+func TestTwoFactor_SetupAndEnable(t *testing.T) {
+	svc := &mockService{adminSession: app.AdminSession{Token: "t", Login: "admin"}, totpCode: "123456"}
+	h := &Handler{Templates: createTwoFactorTemplate(), AppService: svc}
+
+	rr := postAdminForm(h, "/admin/2fa/setup", nil)
+	if rr.Code != http.StatusSeeOther || !svc.totpEnrolled {
+		t.Fatalf("expected enrollment and a redirect, got %d", rr.Code)
+	}
+
+	rr = postAdminForm(h, "/admin/2fa/enable", url.Values{"code": {"999999"}})
+	if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), "enrolling=SECRET") {
+		t.Errorf("expected the enrollment again with an error, got %d %q", rr.Code, rr.Body.String())
+	}
+
+	rr = postAdminForm(h, "/admin/2fa/enable", url.Values{"code": {"123456"}})
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "codes=AAAAA-BBBBB,CCCCC-DDDDD,") {
+		t.Errorf("expected recovery codes, got %d %q", rr.Code, rr.Body.String())
+	}
+	if !svc.totpEnabled {
+		t.Error("expected TOTP to be enabled")
+	}
+}
+
+func TestTwoFactor_Disable(t *testing.T) {
+	svc := &mockService{
+		adminSession: app.AdminSession{Token: "t"},
+		totpStatus:   app.TOTPStatus{Enabled: true, RecoveryCodesLeft: 10},
+		totpCode:     "123456",
+	}
+	h := &Handler{Templates: createTwoFactorTemplate(), AppService: svc}
+
+	rr := postAdminForm(h, "/admin/2fa/disable", url.Values{"password": {"pw"}, "code": {"000000"}})
+	if rr.Code != http.StatusBadRequest || svc.totpDisabled {
+		t.Errorf("expected refusal with a wrong code, got %d", rr.Code)
+	}
+
+	rr = postAdminForm(h, "/admin/2fa/disable", url.Values{"password": {"pw"}, "code": {"123456"}})
+	if rr.Code != http.StatusSeeOther || !svc.totpDisabled || svc.disablePassword != "pw" {
+		t.Errorf("expected TOTP disabled with the password passed on, got %d", rr.Code)
+	}
+}
+
+func TestTwoFactorPage_ShowsAQRCode(t *testing.T) {
+	svc := &mockService{adminSession: app.AdminSession{Token: "t", AdminID: 1, Login: "alice"}}
+	svc.totpStatus.Enrollment = &app.TOTPEnrollment{Secret: "SECRET", URI: "otpauth://totp/abtprj:alice?secret=SECRET"}
+	h := &Handler{Templates: loadViews(t), AppService: svc}
+
+	body := getAdmin(h, "/admin/2fa").Body.String()
+
+	if !strings.Contains(body, `<div class="qr-code"`) || !strings.Contains(body, `<svg xmlns="http://www.w3.org/2000/svg"`) {
+		t.Error("enrollment page should show the otpauth URI as a QR code")
+	}
+	if !strings.Contains(body, `href="otpauth://totp/abtprj:alice?secret=SECRET"`) {
+		t.Error("enrollment page should keep the otpauth link")
+	}
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"abtprj/internal/api"
+	"abtprj/internal/app"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+)
+
+// setUndoToken hands the token that reverts the change to fetch and API
+// callers. Changes that cannot be undone send none.
+func setUndoToken(w http.ResponseWriter, undo app.Undo) {
+	if undo.Token != "" {
+		w.Header().Set("Undo-Token", undo.Token)
+	}
+}
+
+// undoCookieName holds the token of the change a form just made until the
+// admin page has offered to undo it, so the token never shows in a URL.
+const undoCookieName = "undo"
+
+// undoRedirect sends a form that made the change back to the admin page, which
+// shows the toast that offers to undo it.
+func undoRedirect(w http.ResponseWriter, r *http.Request, undo app.Undo) {
+	if undo.Token != "" {
+		http.SetCookie(w, &http.Cookie{
+			Name:     undoCookieName,
+			Value:    undo.Token,
+			MaxAge:   int(app.UndoWindow / time.Second),
+			HttpOnly: true,
+			Path:     "/admin/",
+			SameSite: http.SameSiteStrictMode,
+		})
+	}
+	http.Redirect(w, r, "/admin/", http.StatusSeeOther)
+}
+
+// takeUndoToken returns the token undoRedirect left, once: the cookie is
+// cleared, so reloading the page does not offer the undo again.
+func takeUndoToken(w http.ResponseWriter, r *http.Request) string {
+	c, err := r.Cookie(undoCookieName)
+	if err != nil || c.Value == "" {
+		return ""
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     undoCookieName,
+		Value:    "",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Path:     "/admin/",
+		SameSite: http.SameSiteStrictMode,
+	})
+	return c.Value
+}
+
+// undoStatus is the status an UndoChange error is reported with.
+func undoStatus(err error) int {
+	switch {
+	case errors.Is(err, app.ErrInvalidUndo):
+		return http.StatusGone
+	case errors.Is(err, app.ErrUndoConflict):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+func (h *Handler) undoChange(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "incorrect form values", http.StatusBadRequest)
+		return
+	}
+	if err := h.service(r).UndoChange(r.FormValue("token")); err != nil {
+		status := undoStatus(err)
+		if status == http.StatusInternalServerError {
+			log.Printf("undoChange UndoChange error: %v", err)
+			http.Error(w, "failed to undo", status)
+			return
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	http.Redirect(w, r, "/admin/", http.StatusSeeOther)
+}
+
+func (h *Handler) apiUndo(w http.ResponseWriter, r *http.Request) {
+	var body api.UndoRef
+	if err := readJSON(r, &body); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return
+	}
+	if body.Token == "" {
+		writeAPIError(w, http.StatusBadRequest, "token is required")
+		return
+	}
+	if err := h.service(r).UndoChange(body.Token); err != nil {
+		status := undoStatus(err)
+		if status == http.StatusInternalServerError {
+			log.Printf("apiUndo UndoChange error: %v", err)
+			writeAPIError(w, status, "failed to undo")
+			return
+		}
+		writeAPIError(w, status, err.Error())
+		return
+	}
+	h.apiStatus(w, r, http.StatusOK)
+}
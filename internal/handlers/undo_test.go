@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"abtprj/internal/app"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestUndo_CompletingShowsTheToast(t *testing.T) {
+	svc := &mockService{
+		adminSession: actingSession(app.RoleEditor),
+		undo:         app.Undo{Token: "abc123", Summary: `Task "Taxes" completed`},
+	}
+	h := &Handler{Templates: loadViews(t), AppService: svc}
+
+	rr := serveAdmin(h, http.MethodPost, "/admin/complete-task", url.Values{"name": {"Taxes"}})
+
+	if rr.Code != http.StatusSeeOther || rr.Header().Get("Location") != "/admin/" {
+		t.Fatalf("status %d to %q; want a redirect to the admin page", rr.Code, rr.Header().Get("Location"))
+	}
+	var flash *http.Cookie
+	for _, c := range rr.Result().Cookies() {
+		if c.Name == undoCookieName {
+			flash = c
+		}
+	}
+	if flash == nil || flash.Value != "abc123" || !flash.HttpOnly {
+		t.Fatalf("undo cookie = %+v; want the token in an HttpOnly cookie", flash)
+	}
+
+	rr = adminPageWith(h, flash)
+	body := rr.Body.String()
+	for _, want := range []string{`action="/admin/undo"`, `value="abc123"`, "Task &#34;Taxes&#34; completed"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("page is missing %q", want)
+		}
+	}
+	if c := rr.Result().Cookies(); len(c) != 1 || c[0].Name != undoCookieName || c[0].MaxAge >= 0 {
+		t.Errorf("cookies = %+v; want the undo cookie cleared once shown", c)
+	}
+
+	svc.undoErr = app.ErrInvalidUndo
+	body = adminPageWith(h, flash).Body.String()
+	if !strings.Contains(body, `id="undo-toast" class="undo-toast" hidden`) {
+		t.Error("an expired token should not offer an undo")
+	}
+}
+
+// adminPageWith serves the admin page to a signed in browser sending cookie.
+func adminPageWith(h *Handler, cookie *http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/admin/", nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "t"})
+	req.AddCookie(&http.Cookie{Name: cookie.Name, Value: cookie.Value})
+	return serveMux(h, req)
+}
+
+func TestUndo_NothingToUndoRedirectsPlainly(t *testing.T) {
+	svc := &mockService{adminSession: actingSession(app.RoleEditor)}
+	h := &Handler{AppService: svc}
+
+	rr := serveAdmin(h, http.MethodPost, "/admin/complete-goal", url.Values{"id": {"4"}})
+
+	if rr.Header().Get("Location") != "/admin/" {
+		t.Errorf("redirect to %q; want /admin/", rr.Header().Get("Location"))
+	}
+}
+
+func TestUndo_SessionToggleSendsToken(t *testing.T) {
+	svc := &mockService{adminSession: actingSession(app.RoleEditor), undo: app.Undo{Token: "abc123"}}
+	h := &Handler{AppService: svc}
+
+	rr := serveAdmin(h, http.MethodPost, "/admin/start-work-session", nil)
+
+	if rr.Code != http.StatusOK || rr.Header().Get("Undo-Token") != "abc123" {
+		t.Errorf("status %d, Undo-Token %q", rr.Code, rr.Header().Get("Undo-Token"))
+	}
+}
+
+func TestUndo_Admin(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{nil, http.StatusSeeOther},
+		{app.ErrInvalidUndo, http.StatusGone},
+		{app.ErrUndoConflict, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		svc := &mockService{adminSession: actingSession(app.RoleEditor), undoErr: tt.err}
+		h := &Handler{AppService: svc}
+
+		rr := serveAdmin(h, http.MethodPost, "/admin/undo", url.Values{"token": {"abc123"}})
+
+		if rr.Code != tt.want {
+			t.Errorf("%v: status %d; want %d", tt.err, rr.Code, tt.want)
+		}
+		if svc.undoneBy != "abc123" {
+			t.Errorf("%v: undone by %q", tt.err, svc.undoneBy)
+		}
+	}
+
+	svc := &mockService{adminSession: actingSession(app.RoleViewer)}
+	h := &Handler{AppService: svc}
+	if rr := serveAdmin(h, http.MethodPost, "/admin/undo", url.Values{"token": {"abc123"}}); rr.Code != http.StatusForbidden {
+		t.Errorf("viewer: status %d; want %d", rr.Code, http.StatusForbidden)
+	}
+}
+
+func TestUndo_API(t *testing.T) {
+	svc := &mockService{apiToken: "secret", isWorking: true, undo: app.Undo{Token: "abc123"},
+		todoTasks: []app.Task{{Name: "Taxes", Status: "todo"}}}
+	h := &Handler{AppService: svc}
+
+	if rr := apiRequest(h, http.MethodPost, "/api/tasks/done", `{"name":"Taxes"}`); rr.Header().Get("Undo-Token") != "abc123" {
+		t.Errorf("completing a task: Undo-Token %q", rr.Header().Get("Undo-Token"))
+	}
+
+	rr := apiRequest(h, http.MethodPost, "/api/undo", `{"token":"abc123"}`)
+	if rr.Code != http.StatusOK || svc.undoneBy != "abc123" {
+		t.Errorf("status %d, undone by %q", rr.Code, svc.undoneBy)
+	}
+
+	svc.undoErr = app.ErrUndoConflict
+	if rr := apiRequest(h, http.MethodPost, "/api/undo", `{"token":"abc123"}`); rr.Code != http.StatusConflict {
+		t.Errorf("conflict: status %d; want %d", rr.Code, http.StatusConflict)
+	}
+	if rr := apiRequest(h, http.MethodPost, "/api/undo", `{}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("no token: status %d; want %d", rr.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,292 @@
+package handlers
+
+import (
+	"abtprj/internal/app"
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type (
+	serviceKey     struct{}
+	basePathKey    struct{}
+	pageAccountKey struct{}
+	fullViewKey    struct{}
+)
+
+// service returns the AppService scoped to the user the request is for: the
+// logged-in admin, the API token's owner or the user whose public page it is.
+// Without one, as when a handler is called directly, it is h.AppService.
+func (h *Handler) service(r *http.Request) app.AppService {
+	if svc, ok := r.Context().Value(serviceKey{}).(app.AppService); ok {
+		return svc
+	}
+	return h.AppService
+}
+
+// ownService is the AppService for the logged-in admin's own data, whichever
+// account the session works on.
+func (h *Handler) ownService(r *http.Request) app.AppService {
+	return h.AppService.ForUser(currentAdmin(r).AdminID).As(requestActor(r))
+}
+
+// asAdmin is h.AppService recording its changes as made by the request's admin,
+// for the calls that take the session rather than working on one account.
+func (h *Handler) asAdmin(r *http.Request) app.AppService {
+	return h.AppService.As(requestActor(r))
+}
+
+// requestActor is who the audit log names for the request's changes: the
+// logged-in admin, or just the address when nobody is.
+func requestActor(r *http.Request) app.Actor {
+	session := currentAdmin(r)
+	return app.Actor{UserID: session.AdminID, Login: session.Login, IP: clientIP(r)}
+}
+
+// basePath is the prefix of the public pages being served: "" for the instance
+// owner's pages at the root and "/u/{username}" for everyone else's.
+func basePath(r *http.Request) string {
+	base, _ := r.Context().Value(basePathKey{}).(string)
+	return base
+}
+
+// Page is what the layout shows of the account a page belongs to.
+type Page struct {
+	Account string // login in the title and header
+	Base    string // prefix of the account's public pages
+}
+
+// appName names the pages that belong to no account.
+const appName = "abtprj"
+
+// page is the Page of the request: the account of the public page being served,
+// or on an admin page the account the session works on. An instance without
+// accounts shows its pages under appName.
+func page(r *http.Request) Page {
+	p := Page{Account: currentAdmin(r).AccountLogin}
+	if account, ok := r.Context().Value(pageAccountKey{}).(app.Admin); ok {
+		p = Page{Account: account.Login, Base: basePath(r)}
+	}
+	if p.Account == "" {
+		p.Account = appName
+	}
+	return p
+}
+
+// withUser scopes the request to user's data, with the changes it makes
+// recorded as theirs.
+func (h *Handler) withUser(r *http.Request, user app.Admin) *http.Request {
+	svc := h.AppService.ForUser(user.ID).As(app.Actor{UserID: user.ID, Login: user.Login, IP: clientIP(r)})
+	return r.WithContext(context.WithValue(r.Context(), serviceKey{}, svc))
+}
+
+// withVisitor scopes a public page of account to what its visitor may see:
+// everything when they are logged in as that account or one it gave access,
+// otherwise only what the account made public.
+func (h *Handler) withVisitor(r *http.Request, account app.Admin) *http.Request {
+	svc := h.AppService.ForUser(account.ID)
+	ctx := context.WithValue(r.Context(), pageAccountKey{}, account)
+	if h.seesEverything(r, account.ID) {
+		ctx = context.WithValue(ctx, fullViewKey{}, true)
+	} else {
+		svc = svc.PublicView()
+	}
+	return r.WithContext(context.WithValue(ctx, serviceKey{}, svc))
+}
+
+// seesEverything reports whether the request carries a live session of userID
+// or of an account userID gave access to, with any role.
+func (h *Handler) seesEverything(r *http.Request, userID int) bool {
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil || userID == 0 {
+		return false
+	}
+	session, err := h.AppService.GetAdminSession(cookie.Value)
+	if err != nil {
+		if !errors.Is(err, app.ErrNoSession) {
+			log.Printf("seesEverything GetAdminSession error: %v", err)
+		}
+		return false
+	}
+	role, err := h.AppService.AccountRole(session.AdminID, userID)
+	if err != nil {
+		log.Printf("seesEverything AccountRole error: %v", err)
+		return false
+	}
+	return role != ""
+}
+
+// fullView reports whether a public page is shown with nothing hidden, to its
+// account or one with access to it, so it must not be cached for anyone else.
+func fullView(r *http.Request) bool {
+	full, _ := r.Context().Value(fullViewKey{}).(bool)
+	return full
+}
+
+// accountService is the public page's account with nothing hidden, for feeds
+// that prove ownership with a token instead of a login.
+func (h *Handler) accountService(r *http.Request) app.AppService {
+	if account, ok := r.Context().Value(pageAccountKey{}).(app.Admin); ok {
+		return h.AppService.ForUser(account.ID)
+	}
+	return h.service(r)
+}
+
+// ownerPages serves the public pages at the root with the first account's data.
+// An instance without accounts shows empty pages.
+func (h *Handler) ownerPages(pages http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		owner, err := h.AppService.GetOwner()
+		if err != nil && !errors.Is(err, app.ErrNoUser) {
+			log.Printf("ownerPages GetOwner error: %v", err)
+			http.Error(w, "failed to load user", http.StatusInternalServerError)
+			return
+		}
+		pages.ServeHTTP(w, h.withVisitor(r, owner))
+	}
+}
+
+// userPages serves /u/{username}/... with the public page for the rest of the
+// path, showing that user's data.
+func (h *Handler) userPages(pages http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		login, rest, found := strings.Cut(strings.TrimPrefix(r.URL.Path, "/u/"), "/")
+		if login == "" {
+			http.NotFound(w, r)
+			return
+		}
+		if !found {
+			http.Redirect(w, r, "/u/"+url.PathEscape(login)+"/", http.StatusMovedPermanently)
+			return
+		}
+		user, err := h.AppService.GetUser(login)
+		if errors.Is(err, app.ErrNoUser) {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			log.Printf("userPages GetUser error: %v", err)
+			http.Error(w, "failed to load user", http.StatusInternalServerError)
+			return
+		}
+
+		r = h.withVisitor(r, user)
+		r = r.WithContext(context.WithValue(r.Context(), basePathKey{}, "/u/"+url.PathEscape(user.Login)))
+		u := *r.URL
+		u.Path = "/" + rest
+		u.RawPath = ""
+		r.URL = &u
+		pages.ServeHTTP(w, r)
+	}
+}
+
+type InvitesPageData struct {
+	Page
+	Invites   []app.Invite
+	NewLink   string
+	CSRFToken string
+}
+
+func (h *Handler) renderInvitesPage(w http.ResponseWriter, r *http.Request, data InvitesPageData) {
+	invites, err := h.AppService.ListInvites(currentAdmin(r))
+	if err != nil {
+		log.Printf("renderInvitesPage ListInvites error: %v", err)
+		http.Error(w, "failed to load invites", http.StatusInternalServerError)
+		return
+	}
+	data.Page = page(r)
+	data.Invites = invites
+	data.CSRFToken = currentAdmin(r).CSRFToken()
+	if err := h.Templates.ExecuteTemplate(w, "invites.html", data); err != nil {
+		log.Printf("renderInvitesPage template error: %v", err)
+	}
+}
+
+// createInvite shows a new registration link once; only its hash is kept.
+func (h *Handler) createInvite(w http.ResponseWriter, r *http.Request) {
+	token, err := h.asAdmin(r).CreateInvite(currentAdmin(r))
+	if err != nil {
+		log.Printf("createInvite CreateInvite error: %v", err)
+		http.Error(w, "failed to create invite", http.StatusInternalServerError)
+		return
+	}
+	h.renderInvitesPage(w, r, InvitesPageData{
+		NewLink: h.baseURL(r) + "/register?invite=" + url.QueryEscape(token),
+	})
+}
+
+type RegisterPageData struct {
+	Page
+	Invite    string
+	Login     string
+	Error     string
+	MinLength int
+}
+
+func (h *Handler) RegisterHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		invite := r.URL.Query().Get("invite")
+		if err := h.AppService.CheckInvite(invite); err != nil {
+			h.renderRegisterError(w, err, RegisterPageData{})
+			return
+		}
+		h.renderRegisterPage(w, http.StatusOK, RegisterPageData{Invite: invite})
+	case http.MethodPost:
+		h.register(w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) register(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+	data := RegisterPageData{Invite: r.FormValue("invite"), Login: strings.TrimSpace(r.FormValue("login"))}
+	password := r.FormValue("password")
+	if password != r.FormValue("confirm") {
+		data.Error = "The passwords do not match."
+		h.renderRegisterPage(w, http.StatusBadRequest, data)
+		return
+	}
+
+	if err := h.asAdmin(r).Register(data.Invite, data.Login, password); err != nil {
+		h.renderRegisterError(w, err, data)
+		return
+	}
+	log.Printf("registered account %q from %s", data.Login, clientIP(r))
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+func (h *Handler) renderRegisterError(w http.ResponseWriter, err error, data RegisterPageData) {
+	switch {
+	case errors.Is(err, app.ErrInvalidInvite):
+		data.Invite = ""
+		data.Error = "This invite link is invalid, already used or expired. Ask for a new one."
+		h.renderRegisterPage(w, http.StatusForbidden, data)
+	case errors.Is(err, app.ErrLoginTaken):
+		data.Error = "That login is already taken."
+		h.renderRegisterPage(w, http.StatusConflict, data)
+	case errors.Is(err, app.ErrWeakPassword), errors.Is(err, app.ErrInvalidUsername):
+		data.Error = err.Error()
+		h.renderRegisterPage(w, http.StatusBadRequest, data)
+	default:
+		log.Printf("register error: %v", err)
+		http.Error(w, "failed to register", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) renderRegisterPage(w http.ResponseWriter, status int, data RegisterPageData) {
+	data.Page = Page{Account: appName}
+	data.MinLength = app.MinPasswordLength
+	w.WriteHeader(status)
+	if err := h.Templates.ExecuteTemplate(w, "register.html", data); err != nil {
+		log.Printf("renderRegisterPage template error: %v", err)
+	}
+}
@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"abtprj/internal/app"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func serveMux(h *Handler, req *http.Request) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestUserPages_ShowThatUsersData(t *testing.T) {
+	svc := &mockService{users: map[string]int{"alice": 7}, owner: app.Admin{ID: 1, Login: "owner"}}
+	h := &Handler{Templates: loadViews(t), AppService: svc}
+
+	rr := serveMux(h, httptest.NewRequest(http.MethodGet, "/u/alice/worklog/?date=2025-01-02", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d; want %d", rr.Code, http.StatusOK)
+	}
+	if svc.userID != 7 {
+		t.Errorf("served data of user %d; want 7", svc.userID)
+	}
+	body := rr.Body.String()
+	for _, want := range []string{`href="/u/alice/stats/"`, `href="/u/alice/worklog/feed.atom"`, `<title>alice's Worklog</title>`, `<h1>alice</h1>`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("page is missing %s", want)
+		}
+	}
+}
+
+func TestUserPages_RootIsTheOwner(t *testing.T) {
+	svc := &mockService{users: map[string]int{"alice": 7}, owner: app.Admin{ID: 1, Login: "owner"}}
+	h := &Handler{Templates: loadViews(t), AppService: svc}
+
+	rr := serveMux(h, httptest.NewRequest(http.MethodGet, "/stats/", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d; want %d", rr.Code, http.StatusOK)
+	}
+	if svc.userID != 1 {
+		t.Errorf("served data of user %d; want the owner's", svc.userID)
+	}
+	if !strings.Contains(rr.Body.String(), `href="/stats/tasks.svg"`) {
+		t.Error("owner pages should link without a /u/ prefix")
+	}
+	if !strings.Contains(rr.Body.String(), `<title>owner's Stats</title>`) {
+		t.Error("owner pages should be named after the owner")
+	}
+}
+
+func TestUserPages_Routing(t *testing.T) {
+	h := &Handler{Templates: loadViews(t), AppService: &mockService{users: map[string]int{"alice": 7}}}
+
+	cases := []struct {
+		path     string
+		status   int
+		location string
+	}{
+		{"/u/nobody/", http.StatusNotFound, ""},
+		{"/u/", http.StatusNotFound, ""},
+		{"/u/alice", http.StatusMovedPermanently, "/u/alice/"},
+		{"/u/alice/nothing-here", http.StatusNotFound, ""},
+		{"/u/alice/", http.StatusOK, ""},
+	}
+	for _, tc := range cases {
+		rr := serveMux(h, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if rr.Code != tc.status {
+			t.Errorf("%s: status = %d; want %d", tc.path, rr.Code, tc.status)
+		}
+		if loc := rr.Header().Get("Location"); loc != tc.location {
+			t.Errorf("%s: Location = %q; want %q", tc.path, loc, tc.location)
+		}
+	}
+
+	rr := serveMux(h, httptest.NewRequest(http.MethodGet, "/u/alice/worklog/", nil))
+	if loc := rr.Header().Get("Location"); !strings.HasPrefix(loc, "/u/alice/worklog/?date=") {
+		t.Errorf("worklog without a date redirected to %q; want to stay under /u/alice/", loc)
+	}
+}
+
+func TestRequireAdmin_ActsAsTheSessionUser(t *testing.T) {
+	svc := &mockService{adminSession: app.AdminSession{Token: "t", AdminID: 3}}
+	h := &Handler{AppService: svc}
+
+	rr := postAdminForm(h, "/admin/start-work-session", nil)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d; want %d", rr.Code, http.StatusOK)
+	}
+	if svc.userID != 3 {
+		t.Errorf("started a session for user %d; want 3", svc.userID)
+	}
+}
+
+func TestAPI_ActsAsTheTokenOwner(t *testing.T) {
+	svc := &mockService{apiToken: "secret", apiUser: 5}
+	h := &Handler{AppService: svc}
+
+	rr := apiRequest(h, http.MethodPost, "/api/session/start", "")
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d; want %d", rr.Code, http.StatusOK)
+	}
+	if svc.userID != 5 {
+		t.Errorf("started a session for user %d; want 5", svc.userID)
+	}
+}
+
+func TestInvites_CreateShowsTheLinkOnce(t *testing.T) {
+	svc := &mockService{adminSession: app.AdminSession{Token: "t", AdminID: 1}}
+	h := &Handler{Templates: loadViews(t), AppService: svc}
+
+	rr := postAdminForm(h, "/admin/invites", nil)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d; want %d", rr.Code, http.StatusOK)
+	}
+	if !strings.Contains(rr.Body.String(), "/register?invite=new-invite") {
+		t.Errorf("the new invite link is not shown:\n%s", rr.Body.String())
+	}
+}
+
+func TestInvites_LinkUsesThePublicURL(t *testing.T) {
+	svc := &mockService{adminSession: app.AdminSession{Token: "t", AdminID: 1}}
+	h := &Handler{Templates: loadViews(t), AppService: svc, PublicURL: "https://log.example.com"}
+
+	rr := postAdminForm(h, "/admin/invites", nil)
+
+	if !strings.Contains(rr.Body.String(), "https://log.example.com/register?invite=new-invite") {
+		t.Errorf("the invite link does not start with the public URL:\n%s", rr.Body.String())
+	}
+}
+
+func postRegister(h *Handler, form url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/register", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return serveMux(h, req)
+}
+
+func TestRegister(t *testing.T) {
+	form := func(invite, login, password, confirm string) url.Values {
+		return url.Values{"invite": {invite}, "login": {login}, "password": {password}, "confirm": {confirm}}
+	}
+	cases := []struct {
+		name   string
+		form   url.Values
+		status int
+	}{
+		{"bad invite", form("wrong", "bob", "correct horse", "correct horse"), http.StatusForbidden},
+		{"passwords differ", form("inv", "bob", "correct horse", "correct hose"), http.StatusBadRequest},
+		{"bad login", form("inv", "Bob Smith", "correct horse", "correct horse"), http.StatusBadRequest},
+		{"weak password", form("inv", "bob", "password", "password"), http.StatusBadRequest},
+		{"login taken", form("inv", "alice", "correct horse", "correct horse"), http.StatusConflict},
+	}
+	for _, tc := range cases {
+		svc := &mockService{invite: "inv", users: map[string]int{"alice": 7}}
+		h := &Handler{Templates: loadViews(t), AppService: svc}
+		rr := postRegister(h, tc.form)
+		if rr.Code != tc.status {
+			t.Errorf("%s: status = %d; want %d", tc.name, rr.Code, tc.status)
+		}
+		if svc.registered != "" {
+			t.Errorf("%s: account %q created", tc.name, svc.registered)
+		}
+	}
+
+	svc := &mockService{invite: "inv"}
+	h := &Handler{Templates: loadViews(t), AppService: svc}
+	rr := postRegister(h, form("inv", "bob", "correct horse", "correct horse"))
+	if rr.Code != http.StatusSeeOther || rr.Header().Get("Location") != "/login" {
+		t.Fatalf("status = %d, Location = %q; want a redirect to /login", rr.Code, rr.Header().Get("Location"))
+	}
+	if svc.registered != "bob" {
+		t.Errorf("registered %q; want bob", svc.registered)
+	}
+
+	rr = postRegister(h, form("inv", "carol", "correct horse", "correct horse"))
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("reused invite: status = %d; want %d", rr.Code, http.StatusForbidden)
+	}
+}
+
+func TestRegister_FormNeedsAValidInvite(t *testing.T) {
+	h := &Handler{Templates: loadViews(t), AppService: &mockService{invite: "inv"}}
+
+	rr := serveMux(h, httptest.NewRequest(http.MethodGet, "/register?invite=nope", nil))
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("status = %d; want %d", rr.Code, http.StatusForbidden)
+	}
+	if strings.Contains(rr.Body.String(), `name="password"`) {
+		t.Error("the form is shown for an invalid invite")
+	}
+
+	rr = serveMux(h, httptest.NewRequest(http.MethodGet, "/register?invite=inv", nil))
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `value="inv"`) {
+		t.Errorf("status = %d; want the form carrying the invite", rr.Code)
+	}
+}
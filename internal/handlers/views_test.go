@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"abtprj/internal/app"
+	"abtprj/internal/views"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const xssPayload = `<script>alert("x")</script>`
+
+func loadViews(t *testing.T) views.Set {
+	t.Helper()
+	set, err := views.Load("../../templates")
+	if err != nil {
+		t.Fatalf("loading templates: %v", err)
+	}
+	return set
+}
+
+func TestViews_AllPagesUseTheLayout(t *testing.T) {
+	set := loadViews(t)
+	for _, page := range []string{"index.html", "worklog.html", "stats.html", "calendar.html", "admin.html",
+		"estimates.html", "import.html", "import_time.html", "failed_logins.html",
+		"login.html", "login_totp.html", "password.html", "two_factor.html", "invites.html", "register.html",
+		"teams.html", "team.html", "team_stats.html", "access.html", "audit.html"} {
+		if _, ok := set[page]; !ok {
+			t.Errorf("page %s not loaded", page)
+		}
+	}
+
+	var body strings.Builder
+	if err := set.ExecuteTemplate(&body, "index.html", nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"<!DOCTYPE html>", `<link rel="stylesheet" href="/static/style.css">`, `<a href="/stats/">Stats</a>`} {
+		if !strings.Contains(body.String(), want) {
+			t.Errorf("index.html is missing %q", want)
+		}
+	}
+	if err := set.ExecuteTemplate(&body, "missing.html", nil); err == nil {
+		t.Error("expected an error for an unknown page")
+	}
+}
+
+func TestViews_EscapeUserContent(t *testing.T) {
+	doneAt := time.Date(2025, 1, 2, 10, 0, 0, 0, time.UTC)
+	svc := &mockService{
+		adminSession: app.AdminSession{Token: "t"},
+		tasksForDate: []app.Task{{Name: xssPayload, Description: xssPayload, Status: "done", DoneAt: &doneAt}},
+		todoTasks:    []app.Task{{Name: xssPayload, Description: xssPayload, Status: "todo"}},
+		todoGoals:    []app.Goal{{Name: xssPayload, Description: xssPayload}},
+	}
+	h := &Handler{Templates: loadViews(t), AppService: svc}
+
+	for _, path := range []string{"/worklog/?date=2025-01-02", "/admin/"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "t"})
+		rr := httptest.NewRecorder()
+		if path == "/admin/" {
+			h.requireAdmin(h.AdminHandler)(rr, req)
+		} else {
+			h.WorkLogHandler(rr, req)
+		}
+
+		body := rr.Body.String()
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%s: status %d: %s", path, rr.Code, body)
+		}
+		if strings.Contains(body, xssPayload) {
+			t.Errorf("%s: task name rendered unescaped", path)
+		}
+		if !strings.Contains(body, "&lt;script&gt;") {
+			t.Errorf("%s: expected the escaped task name in the page", path)
+		}
+	}
+}
+
+func TestViews_PagesRender(t *testing.T) {
+	svc := &mockService{
+		adminSession: app.AdminSession{Token: "t", Login: "admin", Owner: true},
+		users:        map[string]int{"admin": 1},
+		invite:       "inv",
+		invites:      []app.Invite{{CreatedAt: time.Now(), ExpiresAt: time.Now(), UsedBy: xssPayload, UsedAt: new(time.Time)}},
+		totpStatus:   app.TOTPStatus{Enrollment: &app.TOTPEnrollment{Secret: "ABC", URI: "otpauth://totp/abtprj:admin?secret=ABC"}},
+		failedLogins: []app.FailedLogin{{At: time.Now(), IP: "203.0.113.7", Login: xssPayload, Reason: "wrong password"}},
+	}
+	h := &Handler{Templates: loadViews(t), AppService: svc}
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+
+	for _, path := range []string{"/", "/stats/", "/calendar/?month=2025-01", "/login", "/admin/estimates",
+		"/admin/import", "/admin/import-time", "/admin/failed-logins", "/admin/password", "/admin/2fa",
+		"/admin/invites", "/register?invite=inv", "/u/admin/", "/u/admin/stats/", "/u/admin/calendar/?month=2025-01"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: "t"})
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+
+		body := rr.Body.String()
+		if rr.Code != http.StatusOK {
+			t.Errorf("%s: status %d", path, rr.Code)
+		}
+		if !strings.HasSuffix(strings.TrimSpace(body), "</html>") {
+			t.Errorf("%s: page cut short, template error? %q", path, body[max(0, len(body)-200):])
+		}
+		if strings.Contains(body, "ZgotmplZ") {
+			t.Errorf("%s: a value was rejected by the escaper", path)
+		}
+		if strings.Contains(body, xssPayload) {
+			t.Errorf("%s: unescaped user content", path)
+		}
+	}
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("OK")) })
+	rr := httptest.NewRecorder()
+	SecurityHeaders(inner).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	csp := rr.Header().Get("Content-Security-Policy")
+	for _, want := range []string{"default-src 'self'", "script-src 'self'", "frame-ancestors 'none'"} {
+		if !strings.Contains(csp, want) {
+			t.Errorf("Content-Security-Policy %q lacks %q", csp, want)
+		}
+	}
+	if strings.Contains(csp, "script-src 'self' 'unsafe-inline'") {
+		t.Error("inline scripts must not be allowed")
+	}
+	if rr.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Error("expected X-Content-Type-Options: nosniff")
+	}
+}
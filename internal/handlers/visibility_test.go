@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"abtprj/internal/app"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+var publicPages = []string{"/worklog/?date=2025-01-02", "/stats/", "/calendar/", "/worklog/feed.atom", "/stats/tasks.svg"}
+
+// visitPublic requests path as a visitor with the session token, if any.
+func visitPublic(h *Handler, path, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if token != "" {
+		req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: token})
+	}
+	return serveMux(h, req)
+}
+
+func TestVisibility_VisitorsSeeThePublicView(t *testing.T) {
+	for _, path := range publicPages {
+		for _, prefix := range []string{"", "/u/alice"} {
+			svc := &mockService{users: map[string]int{"alice": 1}, owner: app.Admin{ID: 1, Login: "alice"}}
+			h := &Handler{Templates: loadViews(t), AppService: svc}
+
+			rr := visitPublic(h, prefix+path, "")
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("%s: status %d", prefix+path, rr.Code)
+			}
+			if !svc.publicView {
+				t.Errorf("%s: rendered without hiding private items", prefix+path)
+			}
+		}
+	}
+}
+
+func TestVisibility_AccountSeesEverything(t *testing.T) {
+	for _, path := range publicPages {
+		svc := &mockService{
+			users:        map[string]int{"alice": 1},
+			owner:        app.Admin{ID: 1, Login: "alice"},
+			adminSession: app.AdminSession{Token: "t", AdminID: 1, Login: "alice"},
+		}
+		h := &Handler{Templates: loadViews(t), AppService: svc}
+
+		rr := visitPublic(h, "/u/alice"+path, "t")
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%s: status %d", path, rr.Code)
+		}
+		if svc.publicView {
+			t.Errorf("%s: the account's own visit should see private items", path)
+		}
+		if cc := rr.Header().Get("Cache-Control"); strings.Contains(cc, "public") {
+			t.Errorf("%s: Cache-Control %q lets shared caches keep private items", path, cc)
+		}
+	}
+}
+
+func TestVisibility_AccountsWithAccessSeeEverything(t *testing.T) {
+	done := time.Date(2025, 1, 2, 18, 0, 0, 0, time.UTC)
+	svc := &mockService{
+		users:        map[string]int{"alice": 1, "bob": 2},
+		adminSession: app.AdminSession{Token: "t", AdminID: 2, Login: "bob"},
+		accessible:   []app.AccountAccess{{Login: "alice", Role: app.RoleViewer}},
+		tasksForDate: []app.Task{{ID: 4, Name: "Taxes", Status: "done", DoneAt: &done, Visibility: app.VisibilityPrivate}},
+	}
+	h := &Handler{Templates: loadViews(t), AppService: svc}
+
+	rr := visitPublic(h, "/u/alice/worklog/?date=2025-01-02", "t")
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status %d", rr.Code)
+	}
+	if svc.publicView || !strings.Contains(rr.Body.String(), "Taxes") {
+		t.Error("bob, a viewer of alice's account, should see her private task")
+	}
+	if cc := rr.Header().Get("Cache-Control"); strings.Contains(cc, "public") {
+		t.Errorf("Cache-Control %q lets shared caches keep private items", cc)
+	}
+}
+
+func TestVisibility_OtherAccountsSeeThePublicView(t *testing.T) {
+	svc := &mockService{
+		users:        map[string]int{"alice": 1, "bob": 2},
+		adminSession: app.AdminSession{Token: "t", AdminID: 2, Login: "bob"},
+	}
+	h := &Handler{Templates: loadViews(t), AppService: svc}
+
+	rr := visitPublic(h, "/u/alice/stats/", "t")
+
+	if rr.Code != http.StatusOK || !svc.publicView {
+		t.Errorf("status %d, public view %v; bob should see what everyone sees", rr.Code, svc.publicView)
+	}
+}
+
+func TestVisibility_NewTasksAndGoals(t *testing.T) {
+	svc := &mockService{adminSession: app.AdminSession{Token: "t", AdminID: 1, Login: "alice"}}
+	h := &Handler{AppService: svc}
+
+	rr := serveAdmin(h, http.MethodPost, "/admin/add-task", url.Values{"name": {"Taxes"}, "visibility": {app.VisibilityPrivate}})
+	if rr.Code != http.StatusSeeOther || svc.addedTask == nil || svc.addedTask.Visibility != app.VisibilityPrivate {
+		t.Errorf("add task: status %d, task %+v", rr.Code, svc.addedTask)
+	}
+	rr = serveAdmin(h, http.MethodPost, "/admin/create-goal", url.Values{"goal_name": {"Move"}, "goal_due": {"2025-06-01"}, "visibility": {app.VisibilityRedacted}})
+	if rr.Code != http.StatusSeeOther || svc.createdGoal == nil || svc.createdGoal.Visibility != app.VisibilityRedacted {
+		t.Errorf("create goal: status %d, goal %+v", rr.Code, svc.createdGoal)
+	}
+
+	rr = serveAdmin(h, http.MethodPost, "/admin/add-task", url.Values{"name": {"Taxes"}, "visibility": {"secret"}})
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("unknown visibility: status %d; want %d", rr.Code, http.StatusBadRequest)
+	}
+}
+
+func TestVisibility_Default(t *testing.T) {
+	svc := &mockService{adminSession: app.AdminSession{Token: "t", AdminID: 1, Login: "alice"}}
+	h := &Handler{Templates: loadViews(t), AppService: svc}
+
+	rr := serveAdmin(h, http.MethodPost, "/admin/update-visibility", url.Values{"visibility": {app.VisibilityRedacted}})
+	if rr.Code != http.StatusSeeOther || svc.defaultVisibility != app.VisibilityRedacted {
+		t.Fatalf("status %d, default %q", rr.Code, svc.defaultVisibility)
+	}
+	if rr := serveAdmin(h, http.MethodPost, "/admin/update-visibility", url.Values{"visibility": {""}}); rr.Code != http.StatusBadRequest {
+		t.Errorf("empty visibility: status %d; want %d", rr.Code, http.StatusBadRequest)
+	}
+
+	body := getAdmin(h, "/admin/").Body.String()
+	if !strings.Contains(body, "Default (redacted-title)") || !strings.Contains(body, `<option value="redacted-title" selected>`) {
+		t.Error("admin page should show the saved default")
+	}
+}
+
+func TestVisibility_ChangeTaskOrGoal(t *testing.T) {
+	svc := &mockService{
+		adminSession: actingSession(app.RoleEditor),
+		todoTasks:    []app.Task{{ID: 3, Name: "Taxes"}},
+		todoGoals:    []app.Goal{{ID: 4, Name: "Move"}},
+	}
+	h := &Handler{Templates: loadViews(t), AppService: svc}
+
+	body := getAdmin(h, "/admin/").Body.String()
+	for _, want := range []string{`action="/admin/task-visibility"`, `action="/admin/goal-visibility"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("admin page is missing %q", want)
+		}
+	}
+
+	rr := serveAdmin(h, http.MethodPost, "/admin/task-visibility", url.Values{"id": {"3"}, "visibility": {app.VisibilityPrivate}})
+	if rr.Code != http.StatusSeeOther || svc.visibilitySet != "task 3 private" {
+		t.Errorf("task: status %d, set %q", rr.Code, svc.visibilitySet)
+	}
+	rr = serveAdmin(h, http.MethodPost, "/admin/goal-visibility", url.Values{"id": {"4"}, "visibility": {""}})
+	if rr.Code != http.StatusSeeOther || svc.visibilitySet != "goal 4 " {
+		t.Errorf("goal back to the default: status %d, set %q", rr.Code, svc.visibilitySet)
+	}
+
+	tests := []struct {
+		form url.Values
+		want int
+	}{
+		{url.Values{"id": {"3"}, "visibility": {"secret"}}, http.StatusBadRequest},
+		{url.Values{"id": {"x"}, "visibility": {app.VisibilityPublic}}, http.StatusBadRequest},
+		{url.Values{"id": {"9"}, "visibility": {app.VisibilityPublic}}, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if rr := serveAdmin(h, http.MethodPost, "/admin/task-visibility", tt.form); rr.Code != tt.want {
+			t.Errorf("%v: status %d; want %d", tt.form, rr.Code, tt.want)
+		}
+	}
+}
+
+func TestVisibility_ChangeOverAPI(t *testing.T) {
+	svc := &mockService{apiToken: "secret", todoTasks: []app.Task{{ID: 3}}, todoGoals: []app.Goal{{ID: 4}}}
+	h := &Handler{AppService: svc}
+
+	rr := apiRequest(h, http.MethodPost, "/api/tasks/visibility", `{"id":3,"visibility":"redacted-title"}`)
+	if rr.Code != http.StatusOK || svc.visibilitySet != "task 3 redacted-title" {
+		t.Errorf("task: status %d, set %q", rr.Code, svc.visibilitySet)
+	}
+	rr = apiRequest(h, http.MethodPost, "/api/goals/visibility", `{"id":4,"visibility":"public"}`)
+	if rr.Code != http.StatusOK || svc.visibilitySet != "goal 4 public" {
+		t.Errorf("goal: status %d, set %q", rr.Code, svc.visibilitySet)
+	}
+	if rr := apiRequest(h, http.MethodPost, "/api/goals/visibility", `{"id":9,"visibility":"public"}`); rr.Code != http.StatusNotFound {
+		t.Errorf("unknown goal: status %d; want %d", rr.Code, http.StatusNotFound)
+	}
+	if rr := apiRequest(h, http.MethodPost, "/api/tasks/visibility", `{"id":3,"visibility":"secret"}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("unknown visibility: status %d; want %d", rr.Code, http.StatusBadRequest)
+	}
+}
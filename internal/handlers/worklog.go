@@ -13,12 +13,15 @@ type WorklogPageData struct {
 	TotalSessionDur time.Duration
 	IsWorking       bool
 	AllSessions     []string
+	Page
 }
 
 func (h *Handler) WorkLogHandler(w http.ResponseWriter, r *http.Request) {
 	switch {
 	case r.Method == http.MethodGet && r.URL.Path == "/worklog/":
 		h.renderWorklogPage(w, r)
+	case r.Method == http.MethodGet && r.URL.Path == "/worklog/feed.atom":
+		h.worklogFeed(w, r)
 	default:
 		http.NotFound(w, r)
 	}
@@ -34,19 +37,19 @@ func (h *Handler) renderWorklogPage(w http.ResponseWriter, r *http.Request) {
 	if raw == "" {
 		today := time.Now().Format("2006-01-02")
 		// redirect to /worklog/?date=YYYY-MM-DD
-		http.Redirect(w, r, "/worklog/?date="+today, http.StatusSeeOther)
+		http.Redirect(w, r, basePath(r)+"/worklog/?date="+today, http.StatusSeeOther)
 		return
 	}
 	date = raw
 
-	dones, err := h.AppService.GetTasksForDate(date)
+	dones, err := h.service(r).GetTasksForDate(date)
 	if err != nil {
 		log.Printf("worklog query error: %v", err)
 		http.Error(w, "repository query error", http.StatusInternalServerError)
 		return
 	}
 
-	workSessions, err := h.AppService.GetWorkSessionsForDate(date)
+	workSessions, err := h.service(r).GetWorkSessionsForDate(date)
 	if err != nil {
 		log.Printf("working status query error: %v", err)
 		http.Error(w, "get working status error", http.StatusInternalServerError)
@@ -82,7 +85,7 @@ func (h *Handler) renderWorklogPage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var isWorking bool
-	isWorking, err = h.AppService.IsWorking()
+	isWorking, err = h.service(r).IsWorking()
 	if err != nil {
 		log.Printf("worklog query error: %v", err)
 	}
@@ -111,6 +114,7 @@ func (h *Handler) renderWorklogPage(w http.ResponseWriter, r *http.Request) {
 		TotalSessionDur: totalDur,
 		IsWorking:       isWorking,
 		AllSessions:     sessionStrings,
+		Page:            page(r),
 	}
 
 	if err := h.Templates.ExecuteTemplate(w, "worklog.html", data); err != nil {
@@ -2,11 +2,11 @@ package handlers
 
 import (
 	"abtprj/internal/app"
+	"html/template"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
-	"text/template"
 	"time"
 )
 
@@ -67,15 +67,17 @@ func TestWorkLogHandler_OK(t *testing.T) {
 
 	t1 := time.Date(2025, time.January, 23, 0, 5, 0, 0, time.UTC)
 	tasksForDate := []app.Task{
-		{"testTask1",
-			"testDescription1",
-			"DONE",
-			&t1,
+		{
+			Name:        "testTask1",
+			Description: "testDescription1",
+			Status:      "DONE",
+			DoneAt:      &t1,
 		},
-		{"testTask2",
-			"testDescription2",
-			"TODO",
-			&t1,
+		{
+			Name:        "testTask2",
+			Description: "testDescription2",
+			Status:      "TODO",
+			DoneAt:      &t1,
 		},
 	}
 
@@ -0,0 +1,192 @@
+// Package ical writes minimal RFC 5545 calendars.
+package ical
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	Start       time.Time
+	End         time.Time
+	AllDay      bool   // Start and End are dates, End exclusive
+	Status      string // CONFIRMED, TENTATIVE or CANCELLED; empty to omit
+	Categories  []string
+}
+
+type Calendar struct {
+	Name     string
+	Location *time.Location // timed events are written in this zone
+	Events   []Event
+}
+
+// Render serialises the calendar, including a VTIMEZONE for Location
+// covering the span of its timed events.
+func (c Calendar) Render(now time.Time) []byte {
+	loc := c.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	var b bytes.Buffer
+	line := func(s string) { b.WriteString(fold(s)) }
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//abtprj//abtprj//EN")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	if c.Name != "" {
+		line("X-WR-CALNAME:" + escape(c.Name))
+	}
+	if loc != time.UTC {
+		line("X-WR-TIMEZONE:" + loc.String())
+		if from, to, ok := c.timedSpan(); ok {
+			for _, l := range vtimezone(loc, from, to) {
+				line(l)
+			}
+		}
+	}
+
+	stamp := now.UTC().Format("20060102T150405Z")
+	for _, e := range c.Events {
+		line("BEGIN:VEVENT")
+		line("UID:" + e.UID)
+		line("DTSTAMP:" + stamp)
+		if e.AllDay {
+			line("DTSTART;VALUE=DATE:" + e.Start.Format("20060102"))
+			line("DTEND;VALUE=DATE:" + e.End.Format("20060102"))
+		} else if loc == time.UTC {
+			line("DTSTART:" + e.Start.UTC().Format("20060102T150405Z"))
+			line("DTEND:" + e.End.UTC().Format("20060102T150405Z"))
+		} else {
+			line("DTSTART;TZID=" + loc.String() + ":" + e.Start.In(loc).Format("20060102T150405"))
+			line("DTEND;TZID=" + loc.String() + ":" + e.End.In(loc).Format("20060102T150405"))
+		}
+		line("SUMMARY:" + escape(e.Summary))
+		if e.Description != "" {
+			line("DESCRIPTION:" + escape(e.Description))
+		}
+		if e.Status != "" {
+			line("STATUS:" + e.Status)
+		}
+		var cats []string
+		for _, cat := range e.Categories {
+			if cat != "" {
+				cats = append(cats, escape(cat))
+			}
+		}
+		if len(cats) > 0 {
+			line("CATEGORIES:" + strings.Join(cats, ","))
+		}
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return b.Bytes()
+}
+
+func (c Calendar) timedSpan() (from, to time.Time, ok bool) {
+	for _, e := range c.Events {
+		if e.AllDay {
+			continue
+		}
+		if !ok || e.Start.Before(from) {
+			from = e.Start
+		}
+		if !ok || e.End.After(to) {
+			to = e.End
+		}
+		ok = true
+	}
+	return from, to, ok
+}
+
+// vtimezone describes loc between from and to. Every offset change in that span is
+// written as its own STANDARD or DAYLIGHT observance, so no recurrence rules are needed.
+func vtimezone(loc *time.Location, from, to time.Time) []string {
+	lines := []string{"BEGIN:VTIMEZONE", "TZID:" + loc.String()}
+
+	observance := func(at time.Time, dtstart string, offsetFrom int) {
+		name, offset := at.In(loc).Zone()
+		kind := "STANDARD"
+		if at.In(loc).IsDST() {
+			kind = "DAYLIGHT"
+		}
+		lines = append(lines,
+			"BEGIN:"+kind,
+			"DTSTART:"+dtstart,
+			"TZOFFSETFROM:"+formatOffset(offsetFrom),
+			"TZOFFSETTO:"+formatOffset(offset),
+			"TZNAME:"+name,
+			"END:"+kind,
+		)
+	}
+
+	// the observance in effect at the first event, declared as valid since the epoch
+	start := from.AddDate(0, 0, -1)
+	_, offset := start.In(loc).Zone()
+	observance(start, "19700101T000000", offset)
+
+	// then every transition, found by stepping a day at a time and bisecting
+	for day := start; day.Before(to); day = day.Add(24 * time.Hour) {
+		next := day.Add(24 * time.Hour)
+		_, nextOffset := next.In(loc).Zone()
+		if nextOffset == offset {
+			continue
+		}
+		lo, hi := day, next
+		for hi.Sub(lo) > time.Second {
+			mid := lo.Add(hi.Sub(lo) / 2)
+			if _, o := mid.In(loc).Zone(); o == offset {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		// onsets are written in the wall time of the offset being left
+		at := hi.Truncate(time.Second)
+		observance(at, at.In(time.FixedZone("", offset)).Format("20060102T150405"), offset)
+		offset = nextOffset
+	}
+
+	return append(lines, "END:VTIMEZONE")
+}
+
+func formatOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+	return fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds%3600/60)
+}
+
+// escape quotes TEXT values as required by RFC 5545 section 3.3.11.
+func escape(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+	return r.Replace(s)
+}
+
+// fold splits content lines longer than 75 octets and terminates them with CRLF,
+// taking care not to split a UTF-8 sequence.
+func fold(s string) string {
+	const limit = 75
+	var b strings.Builder
+	width := 0
+	for _, r := range s {
+		n := len(string(r))
+		if width+n > limit {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += n
+	}
+	b.WriteString("\r\n")
+	return b.String()
+}
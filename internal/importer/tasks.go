@@ -0,0 +1,318 @@
+// Package importer parses data exported from other tools into abtprj records.
+package importer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FormatTodoTxt     = "todotxt"
+	FormatTaskwarrior = "taskwarrior"
+	FormatCSV         = "csv"
+)
+
+// Task is one task read from an import file.
+type Task struct {
+	Line        int // 1-based line or record number in the source, for error messages
+	Name        string
+	Description string
+	Done        bool
+	DoneAt      *time.Time
+	CreatedAt   *time.Time
+}
+
+// CSVMapping names the header of each column a CSV import reads.
+// Only Name is required.
+type CSVMapping struct {
+	Name        string
+	Description string
+	Status      string
+	DoneAt      string
+}
+
+// ParseTasks reads tasks in the given format. mapping is only used for CSV.
+func ParseTasks(r io.Reader, format string, mapping CSVMapping) ([]Task, error) {
+	switch format {
+	case FormatTodoTxt:
+		return ParseTodoTxt(r)
+	case FormatTaskwarrior:
+		return ParseTaskwarrior(r)
+	case FormatCSV:
+		return ParseTaskCSV(r, mapping)
+	default:
+		return nil, fmt.Errorf("unknown import format %q", format)
+	}
+}
+
+var (
+	todoDate     = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	todoPriority = regexp.MustCompile(`^\([A-Z]\)$`)
+)
+
+// ParseTodoTxt reads the todo.txt format (https://github.com/todotxt/todo.txt):
+//
+//	(A) 2024-01-20 Call the bank +finance @phone
+//	x 2024-02-01 2024-01-20 Call the bank +finance @phone
+//
+// The completion mark, the priority and then the completion and creation dates
+// are taken off the front of the line; the rest, including +project and @context
+// tags, becomes the name.
+func ParseTodoTxt(r io.Reader) ([]Task, error) {
+	var tasks []Task
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; sc.Scan(); n++ {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		t := Task{Line: n}
+		if fields[0] == "x" {
+			t.Done = true
+			fields = fields[1:]
+		}
+		if len(fields) > 0 && todoPriority.MatchString(fields[0]) {
+			t.Description = "Priority " + fields[0][1:2]
+			fields = fields[1:]
+		}
+		var dates []time.Time
+		for len(fields) > 0 && len(dates) < 2 && todoDate.MatchString(fields[0]) {
+			d, err := time.Parse("2006-01-02", fields[0])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid date %q", n, fields[0])
+			}
+			dates = append(dates, d)
+			fields = fields[1:]
+		}
+		switch {
+		case t.Done && len(dates) == 2:
+			t.DoneAt, t.CreatedAt = &dates[0], &dates[1]
+		case t.Done && len(dates) == 1:
+			t.DoneAt = &dates[0]
+		case len(dates) >= 1:
+			t.CreatedAt = &dates[0]
+		}
+
+		t.Name = strings.Join(fields, " ")
+		if t.Name == "" {
+			return nil, fmt.Errorf("line %d: task has no text", n)
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks, sc.Err()
+}
+
+type taskwarriorTask struct {
+	Description string   `json:"description"`
+	Status      string   `json:"status"`
+	Entry       string   `json:"entry"`
+	End         string   `json:"end"`
+	Project     string   `json:"project"`
+	Tags        []string `json:"tags"`
+	Annotations []struct {
+		Description string `json:"description"`
+	} `json:"annotations"`
+}
+
+// ParseTaskwarrior reads the output of `task export`. Deleted tasks are skipped.
+func ParseTaskwarrior(r io.Reader) ([]Task, error) {
+	var raw []taskwarriorTask
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("invalid Taskwarrior export: %w", err)
+	}
+
+	var tasks []Task
+	for i, tw := range raw {
+		if tw.Status == "deleted" {
+			continue
+		}
+		t := Task{Line: i + 1, Name: strings.TrimSpace(tw.Description)}
+		if t.Name == "" {
+			return nil, fmt.Errorf("task %d: missing description", i+1)
+		}
+
+		var notes []string
+		if tw.Project != "" {
+			notes = append(notes, "Project: "+tw.Project)
+		}
+		if len(tw.Tags) > 0 {
+			notes = append(notes, "Tags: "+strings.Join(tw.Tags, ", "))
+		}
+		for _, a := range tw.Annotations {
+			notes = append(notes, a.Description)
+		}
+		t.Description = strings.Join(notes, "\n")
+
+		if tw.Entry != "" {
+			created, err := parseTaskwarriorDate(tw.Entry)
+			if err != nil {
+				return nil, fmt.Errorf("task %d: %w", i+1, err)
+			}
+			t.CreatedAt = &created
+		}
+		if tw.Status == "completed" {
+			t.Done = true
+			if tw.End != "" {
+				end, err := parseTaskwarriorDate(tw.End)
+				if err != nil {
+					return nil, fmt.Errorf("task %d: %w", i+1, err)
+				}
+				t.DoneAt = &end
+			}
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks, nil
+}
+
+func parseTaskwarriorDate(s string) (time.Time, error) {
+	t, err := time.Parse("20060102T150405Z", s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q", s)
+	}
+	return t, nil
+}
+
+// ParseTaskCSV reads a CSV file with a header row, taking columns by the names in mapping.
+// A row is done when its status column reads done, completed, x, yes, true or 1, or
+// when it has a done date.
+func ParseTaskCSV(r io.Reader, mapping CSVMapping) ([]Task, error) {
+	if mapping.Name == "" {
+		return nil, errors.New("CSV import needs the name of the task name column")
+	}
+
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading CSV header: %w", err)
+	}
+	cols, err := headerIndex(header, map[string]string{
+		"name":        mapping.Name,
+		"description": mapping.Description,
+		"status":      mapping.Status,
+		"done_at":     mapping.DoneAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var tasks []Task
+	for n := 2; ; n++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		field := func(key string) string {
+			i, ok := cols[key]
+			if !ok || i >= len(rec) {
+				return ""
+			}
+			return strings.TrimSpace(rec[i])
+		}
+
+		t := Task{Line: n, Name: field("name"), Description: field("description")}
+		if t.Name == "" {
+			continue
+		}
+		switch strings.ToLower(field("status")) {
+		case "done", "completed", "complete", "x", "yes", "true", "1":
+			t.Done = true
+		}
+		if raw := field("done_at"); raw != "" {
+			doneAt, err := ParseTime(raw, time.UTC)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n, err)
+			}
+			t.Done = true
+			t.DoneAt = &doneAt
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks, nil
+}
+
+// headerIndex maps each wanted key to the index of its column. Keys with an empty
+// column name are left out; a named column missing from the header is an error.
+func headerIndex(header []string, wanted map[string]string) (map[string]int, error) {
+	pos := make(map[string]int, len(header))
+	for i, h := range header {
+		pos[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))] = i
+	}
+	out := make(map[string]int)
+	for key, col := range wanted {
+		if col == "" {
+			continue
+		}
+		i, ok := pos[strings.ToLower(strings.TrimSpace(col))]
+		if !ok {
+			return nil, fmt.Errorf("column %q not found in CSV header", col)
+		}
+		out[key] = i
+	}
+	return out, nil
+}
+
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+var slashDate = regexp.MustCompile(`^(\d{1,2})/(\d{1,2})/\d{4}(?: \d{2}:\d{2}(:\d{2})?)?$`)
+
+// ParseTime accepts the date and time layouts common in spreadsheet exports.
+// Values without an offset are read in loc. Dates with slashes are read day
+// first or month first, whichever fits; when both do it is an error.
+func ParseTime(s string, loc *time.Location) (time.Time, error) {
+	if m := slashDate.FindStringSubmatch(s); m != nil {
+		return parseSlashDate(s, m, loc)
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognised date %q", s)
+}
+
+// parseSlashDate reads s, matched by slashDate as m. A part above 12 can only be
+// the day, and a date whose day and month are equal reads the same either way.
+func parseSlashDate(s string, m []string, loc *time.Location) (time.Time, error) {
+	first, _ := strconv.Atoi(m[1])
+	second, _ := strconv.Atoi(m[2])
+	layout := "2/1/2006"
+	switch {
+	case first > 12 || first == second:
+	case second > 12:
+		layout = "1/2/2006"
+	default:
+		return time.Time{}, fmt.Errorf("ambiguous date %q: it could be day or month first; use YYYY-MM-DD", s)
+	}
+	if len(s) > len("01/02/2006") {
+		layout += " 15:04"
+		if m[3] != "" {
+			layout += ":05"
+		}
+	}
+	t, err := time.ParseInLocation(layout, s, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unrecognised date %q", s)
+	}
+	return t, nil
+}
@@ -0,0 +1,159 @@
+package importer
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	SourceToggl    = "toggl"
+	SourceClockify = "clockify"
+)
+
+// TimeEntry is one tracked interval read from another time tracker.
+type TimeEntry struct {
+	Line        int
+	Description string
+	Project     string
+	Start       time.Time
+	End         time.Time
+}
+
+// RowError explains why a row of an import file was left out.
+type RowError struct {
+	Line   int
+	Reason string
+}
+
+type timeEntryColumns struct {
+	description, project string
+	startDate, startTime string
+	endDate, endTime     string
+}
+
+var timeEntrySources = map[string]timeEntryColumns{
+	// Toggl Track "Detailed report" CSV
+	SourceToggl: {
+		description: "Description", project: "Project",
+		startDate: "Start date", startTime: "Start time",
+		endDate: "End date", endTime: "End time",
+	},
+	// Clockify "Detailed report" CSV
+	SourceClockify: {
+		description: "Description", project: "Project",
+		startDate: "Start Date", startTime: "Start Time",
+		endDate: "End Date", endTime: "End Time",
+	},
+}
+
+// entryDateLayouts are the dates both tools write besides slash dates, which
+// follow the user's settings and go through parseSlashDate.
+var entryDateLayouts = []string{"2006-01-02", "02.01.2006"}
+
+var clockLayouts = []string{"15:04:05", "15:04", "3:04:05 PM", "3:04 PM", "03:04:05 PM", "03:04 PM"}
+
+// ParseTimeEntries reads a Toggl or Clockify detailed CSV export. Both tools write
+// local times without an offset, so loc must be the time zone the export was made in.
+// Rows that cannot be read are returned as RowErrors instead of failing the import.
+func ParseTimeEntries(r io.Reader, source string, loc *time.Location) ([]TimeEntry, []RowError, error) {
+	cols, ok := timeEntrySources[source]
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown time tracker %q, expected toggl or clockify", source)
+	}
+
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading CSV header: %w", err)
+	}
+	idx, err := headerIndex(header, map[string]string{
+		"description": cols.description,
+		"start_date":  cols.startDate,
+		"start_time":  cols.startTime,
+		"end_date":    cols.endDate,
+		"end_time":    cols.endTime,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w; is this a %s detailed report?", err, source)
+	}
+	if projIdx, err := headerIndex(header, map[string]string{"project": cols.project}); err == nil {
+		idx["project"] = projIdx["project"]
+	}
+
+	var entries []TimeEntry
+	var rowErrs []RowError
+	for n := 2; ; n++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		field := func(key string) string {
+			i, ok := idx[key]
+			if !ok || i >= len(rec) {
+				return ""
+			}
+			return strings.TrimSpace(rec[i])
+		}
+
+		start, err := parseDateClock(field("start_date"), field("start_time"), loc)
+		if err != nil {
+			rowErrs = append(rowErrs, RowError{n, "start: " + err.Error()})
+			continue
+		}
+		end, err := parseDateClock(field("end_date"), field("end_time"), loc)
+		if err != nil {
+			rowErrs = append(rowErrs, RowError{n, "end: " + err.Error()})
+			continue
+		}
+		if !end.After(start) {
+			rowErrs = append(rowErrs, RowError{n, "ends before it starts"})
+			continue
+		}
+
+		entries = append(entries, TimeEntry{
+			Line:        n,
+			Description: field("description"),
+			Project:     field("project"),
+			Start:       start,
+			End:         end,
+		})
+	}
+	return entries, rowErrs, nil
+}
+
+func parseDateClock(date, clock string, loc *time.Location) (time.Time, error) {
+	if date == "" || clock == "" {
+		return time.Time{}, fmt.Errorf("missing date or time")
+	}
+	day, err := parseEntryDate(date, loc)
+	if err != nil {
+		return time.Time{}, err
+	}
+	for _, cl := range clockLayouts {
+		if c, err := time.Parse(cl, clock); err == nil {
+			return time.Date(day.Year(), day.Month(), day.Day(), c.Hour(), c.Minute(), c.Second(), 0, loc), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognised time %q", clock)
+}
+
+// parseEntryDate reads a date column. A slash date that fits both day and month
+// first is an error rather than a guess.
+func parseEntryDate(date string, loc *time.Location) (time.Time, error) {
+	if m := slashDate.FindStringSubmatch(date); m != nil {
+		return parseSlashDate(date, m, loc)
+	}
+	for _, layout := range entryDateLayouts {
+		if t, err := time.ParseInLocation(layout, date, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognised date %q", date)
+}
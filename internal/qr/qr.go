@@ -0,0 +1,457 @@
+// Package qr encodes short byte strings, such as otpauth:// URIs, as QR codes
+// (ISO/IEC 18004) and draws them as SVG. It supports what enrolling an
+// authenticator app needs: byte mode, error correction level M and versions 1
+// to 10, which hold up to 213 bytes.
+package qr
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrTooLong is returned for data that does not fit in a version 10 symbol.
+var ErrTooLong = errors.New("qr: data too long")
+
+// Code is an encoded QR symbol: Size by Size modules, without the quiet zone.
+type Code struct {
+	Size    int
+	modules [][]bool // [y][x], true for dark
+}
+
+// Dark reports whether the module in column x of row y is dark.
+func (c *Code) Dark(x, y int) bool {
+	return c.modules[y][x]
+}
+
+// version describes a symbol version at error correction level M.
+type version struct {
+	ecPerBlock int
+	groups     [2]struct{ blocks, data int } // data codewords per block
+	alignment  []int
+}
+
+var versions = [...]version{
+	1:  {10, [2]struct{ blocks, data int }{{1, 16}}, nil},
+	2:  {16, [2]struct{ blocks, data int }{{1, 28}}, []int{6, 18}},
+	3:  {26, [2]struct{ blocks, data int }{{1, 44}}, []int{6, 22}},
+	4:  {18, [2]struct{ blocks, data int }{{2, 32}}, []int{6, 26}},
+	5:  {24, [2]struct{ blocks, data int }{{2, 43}}, []int{6, 30}},
+	6:  {16, [2]struct{ blocks, data int }{{4, 27}}, []int{6, 34}},
+	7:  {18, [2]struct{ blocks, data int }{{4, 31}}, []int{6, 22, 38}},
+	8:  {22, [2]struct{ blocks, data int }{{2, 38}, {2, 39}}, []int{6, 24, 42}},
+	9:  {22, [2]struct{ blocks, data int }{{3, 36}, {2, 37}}, []int{6, 26, 46}},
+	10: {26, [2]struct{ blocks, data int }{{4, 43}, {1, 44}}, []int{6, 28, 50}},
+}
+
+func (v version) dataCodewords() int {
+	return v.groups[0].blocks*v.groups[0].data + v.groups[1].blocks*v.groups[1].data
+}
+
+// Encode returns the smallest symbol that holds data, with the mask that
+// scores best against the standard's penalty rules.
+func Encode(data []byte) (*Code, error) {
+	ver := 0
+	for v := 1; v < len(versions); v++ {
+		if 4+countBits(v)+8*len(data) <= 8*versions[v].dataCodewords() {
+			ver = v
+			break
+		}
+	}
+	if ver == 0 {
+		return nil, fmt.Errorf("%w: %d bytes", ErrTooLong, len(data))
+	}
+
+	codewords := interleave(versions[ver], dataCodewords(ver, data))
+	c := newSymbol(ver)
+	c.placeData(codewords)
+
+	best, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		c.applyMask(mask)
+		c.drawFormat(mask)
+		if p := c.penalty(); bestPenalty < 0 || p < bestPenalty {
+			best, bestPenalty = mask, p
+		}
+		c.applyMask(mask) // masking twice undoes it
+	}
+	c.applyMask(best)
+	c.drawFormat(best)
+	return &c.Code, nil
+}
+
+// countBits is the width of the byte mode character count in version v.
+func countBits(v int) int {
+	if v < 10 {
+		return 8
+	}
+	return 16
+}
+
+// dataCodewords is data in byte mode, terminated and padded to the capacity of version v.
+func dataCodewords(v int, data []byte) []byte {
+	var bits bitBuffer
+	bits.append(0b0100, 4)
+	bits.append(len(data), countBits(v))
+	for _, b := range data {
+		bits.append(int(b), 8)
+	}
+	capacity := 8 * versions[v].dataCodewords()
+	bits.append(0, min(4, capacity-len(bits)))
+	bits.append(0, (8-len(bits)%8)%8)
+
+	out := bits.bytes()
+	for pad := byte(0xEC); len(out) < versions[v].dataCodewords(); pad ^= 0xEC ^ 0x11 {
+		out = append(out, pad)
+	}
+	return out
+}
+
+type bitBuffer []bool
+
+func (b *bitBuffer) append(value, n int) {
+	for i := n - 1; i >= 0; i-- {
+		*b = append(*b, value>>i&1 == 1)
+	}
+}
+
+func (b bitBuffer) bytes() []byte {
+	out := make([]byte, len(b)/8)
+	for i, bit := range b {
+		if bit {
+			out[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+	return out
+}
+
+// interleave splits data into the blocks of v, adds error correction to each
+// and interleaves them as they are placed in the symbol.
+func interleave(v version, data []byte) []byte {
+	var blocks, ecs [][]byte
+	divisor := rsDivisor(v.ecPerBlock)
+	for _, g := range v.groups {
+		for range g.blocks {
+			block := data[:g.data]
+			data = data[g.data:]
+			blocks = append(blocks, block)
+			ecs = append(ecs, rsRemainder(block, divisor))
+		}
+	}
+	var out []byte
+	for i := 0; i < len(blocks[len(blocks)-1]); i++ {
+		for _, block := range blocks {
+			if i < len(block) {
+				out = append(out, block[i])
+			}
+		}
+	}
+	for i := 0; i < v.ecPerBlock; i++ {
+		for _, ec := range ecs {
+			out = append(out, ec[i])
+		}
+	}
+	return out
+}
+
+// gfMul multiplies in GF(256) modulo the QR polynomial x^8+x^4+x^3+x^2+1.
+func gfMul(x, y byte) byte {
+	var z byte
+	for i := 7; i >= 0; i-- {
+		carry := z >> 7
+		z = z<<1 ^ carry*0x1D
+		z ^= (y >> i & 1) * x
+	}
+	return z
+}
+
+// rsDivisor is the Reed-Solomon generator polynomial of the given degree,
+// highest power first and without its leading 1.
+func rsDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for range degree {
+		for j := range result {
+			result[j] = gfMul(result[j], root)
+			if j+1 < len(result) {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMul(root, 0x02)
+	}
+	return result
+}
+
+// rsRemainder is the error correction codewords of data.
+func rsRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, d := range divisor {
+			result[i] ^= gfMul(d, factor)
+		}
+	}
+	return result
+}
+
+// symbol is a Code being built, which knows the modules data may not go in.
+type symbol struct {
+	Code
+	version  int
+	function [][]bool
+}
+
+func newSymbol(ver int) *symbol {
+	size := 17 + 4*ver
+	c := &symbol{Code: Code{Size: size}, version: ver}
+	c.modules = make([][]bool, size)
+	c.function = make([][]bool, size)
+	for y := range size {
+		c.modules[y] = make([]bool, size)
+		c.function[y] = make([]bool, size)
+	}
+
+	for i := range size {
+		c.set(6, i, i%2 == 0)
+		c.set(i, 6, i%2 == 0)
+	}
+	for _, p := range [][2]int{{3, 3}, {size - 4, 3}, {3, size - 4}} {
+		for dy := -4; dy <= 4; dy++ {
+			for dx := -4; dx <= 4; dx++ {
+				x, y := p[0]+dx, p[1]+dy
+				if x < 0 || x >= size || y < 0 || y >= size {
+					continue
+				}
+				d := max(abs(dx), abs(dy))
+				c.set(x, y, d != 2 && d != 4)
+			}
+		}
+	}
+	align := versions[ver].alignment
+	last := len(align) - 1
+	for i, ay := range align {
+		for j, ax := range align {
+			if i == 0 && j == 0 || i == 0 && j == last || i == last && j == 0 {
+				continue // taken by a finder pattern
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					c.set(ax+dx, ay+dy, max(abs(dx), abs(dy)) != 1)
+				}
+			}
+		}
+	}
+	c.drawFormat(0) // reserves the format modules until the mask is known
+	if ver >= 7 {
+		bits := versionBits(ver)
+		for i := range 18 {
+			a, b := size-11+i%3, i/3
+			c.set(a, b, bits>>i&1 == 1)
+			c.set(b, a, bits>>i&1 == 1)
+		}
+	}
+	return c
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// set draws a function module.
+func (c *symbol) set(x, y int, dark bool) {
+	c.modules[y][x] = dark
+	c.function[y][x] = true
+}
+
+// formatBits is the 15-bit format information for level M and mask.
+func formatBits(mask int) int {
+	data := 0b00<<3 | mask // 00 is level M
+	rem := data
+	for range 10 {
+		rem = rem<<1 ^ (rem>>9)*0x537
+	}
+	return (data<<10 | rem) ^ 0x5412
+}
+
+// versionBits is the 18-bit version information of versions 7 and up.
+func versionBits(ver int) int {
+	rem := ver
+	for range 12 {
+		rem = rem<<1 ^ (rem>>11)*0x1F25
+	}
+	return ver<<12 | rem
+}
+
+func (c *symbol) drawFormat(mask int) {
+	bits := formatBits(mask)
+	bit := func(i int) bool { return bits>>i&1 == 1 }
+	size := c.Size
+
+	for i := 0; i <= 5; i++ {
+		c.set(8, i, bit(i))
+	}
+	c.set(8, 7, bit(6))
+	c.set(8, 8, bit(7))
+	c.set(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		c.set(14-i, 8, bit(i))
+	}
+
+	for i := 0; i < 8; i++ {
+		c.set(size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		c.set(8, size-15+i, bit(i))
+	}
+	c.set(8, size-8, true) // the dark module
+}
+
+// placeData fills the non-function modules with codewords, in the zigzag of
+// two-module columns the standard prescribes.
+func (c *symbol) placeData(codewords []byte) {
+	i := 0
+	for right := c.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5 // skip the vertical timing pattern
+		}
+		upward := (right+1)&2 == 0
+		for vert := range c.Size {
+			y := vert
+			if upward {
+				y = c.Size - 1 - vert
+			}
+			for j := range 2 {
+				x := right - j
+				if c.function[y][x] || i >= len(codewords)*8 {
+					continue
+				}
+				c.modules[y][x] = codewords[i/8]>>(7-i%8)&1 == 1
+				i++
+			}
+		}
+	}
+}
+
+// applyMask flips the data modules that mask selects.
+func (c *symbol) applyMask(mask int) {
+	for y := range c.Size {
+		for x := range c.Size {
+			if c.function[y][x] {
+				continue
+			}
+			var flip bool
+			switch mask {
+			case 0:
+				flip = (x+y)%2 == 0
+			case 1:
+				flip = y%2 == 0
+			case 2:
+				flip = x%3 == 0
+			case 3:
+				flip = (x+y)%3 == 0
+			case 4:
+				flip = (x/3+y/2)%2 == 0
+			case 5:
+				flip = x*y%2+x*y%3 == 0
+			case 6:
+				flip = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				flip = ((x+y)%2+x*y%3)%2 == 0
+			}
+			c.modules[y][x] = c.modules[y][x] != flip
+		}
+	}
+}
+
+// penalty scores the symbol by the four rules masks are chosen by; lower is
+// easier to scan.
+func (c *symbol) penalty() int {
+	size, total := c.Size, 0
+	line := make([]bool, size)
+	for _, vertical := range []bool{false, true} {
+		for a := range size {
+			for b := range size {
+				if vertical {
+					line[b] = c.modules[b][a]
+				} else {
+					line[b] = c.modules[a][b]
+				}
+			}
+			total += linePenalty(line)
+		}
+	}
+
+	dark := 0
+	for y := range size {
+		for x := range size {
+			if c.modules[y][x] {
+				dark++
+			}
+			if x+1 < size && y+1 < size {
+				m := c.modules[y][x]
+				if c.modules[y][x+1] == m && c.modules[y+1][x] == m && c.modules[y+1][x+1] == m {
+					total += 3
+				}
+			}
+		}
+	}
+	cells := size * size
+	k := (abs(dark*20-cells*10)+cells-1)/cells - 1
+	return total + max(k, 0)*10
+}
+
+// finderLike are the 1:1:3:1:1 runs with four light modules on one side that
+// rule 3 penalises.
+var finderLike = [2][11]bool{
+	{true, false, true, true, true, false, true, false, false, false, false},
+	{false, false, false, false, true, false, true, true, true, false, true},
+}
+
+// linePenalty scores one row or column by rules 1 and 3.
+func linePenalty(line []bool) int {
+	total := 0
+	run := 1
+	for i := 1; i <= len(line); i++ {
+		if i < len(line) && line[i] == line[i-1] {
+			run++
+			continue
+		}
+		if run >= 5 {
+			total += 3 + run - 5
+		}
+		run = 1
+	}
+	for i := 0; i+11 <= len(line); i++ {
+		for _, p := range finderLike {
+			if [11]bool(line[i:i+11]) == p {
+				total += 40
+			}
+		}
+	}
+	return total
+}
+
+// SVG draws the code with a four-module quiet zone, each module scale units
+// wide. The image scales to its container; the caller sets its display size.
+func (c *Code) SVG(scale int) string {
+	const quiet = 4
+	side := (c.Size + 2*quiet) * scale
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, side, side)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="#ffffff"/><path fill="#000000" d="`, side, side)
+	for y := range c.Size {
+		for x := range c.Size {
+			if c.modules[y][x] {
+				fmt.Fprintf(&b, "M%d %dh%dv%dh-%dz", (x+quiet)*scale, (y+quiet)*scale, scale, scale, scale)
+			}
+		}
+	}
+	b.WriteString(`"/></svg>`)
+	return b.String()
+}
@@ -0,0 +1,201 @@
+package qr
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRSRemainder(t *testing.T) {
+	// "HELLO WORLD" at 1-M, from the worked example of the standard's annex
+	data := []byte{32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17}
+	want := []byte{196, 35, 39, 119, 235, 215, 231, 226, 93, 23}
+	if got := rsRemainder(data, rsDivisor(10)); !bytes.Equal(got, want) {
+		t.Errorf("rsRemainder = %v; want %v", got, want)
+	}
+}
+
+func TestFormatAndVersionBits(t *testing.T) {
+	format := []string{
+		"101010000010010", "101000100100101", "101111001111100", "101101101001011",
+		"100010111111001", "100000011001110", "100111110010111", "100101010100000",
+	}
+	for mask, want := range format {
+		if got := fmt.Sprintf("%015b", formatBits(mask)); got != want {
+			t.Errorf("formatBits(%d) = %s; want %s", mask, got, want)
+		}
+	}
+	for ver, want := range map[int]string{7: "000111110010010100", 8: "001000010110111100", 10: "001010010011010011"} {
+		if got := fmt.Sprintf("%018b", versionBits(ver)); got != want {
+			t.Errorf("versionBits(%d) = %s; want %s", ver, got, want)
+		}
+	}
+}
+
+func TestEncode_ReadsBack(t *testing.T) {
+	uri := "otpauth://totp/abtprj%3Aalice?algorithm=SHA1&digits=6&issuer=abtprj&period=30&secret=JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"
+	inputs := []string{"a", strings.Repeat("x", 14), strings.Repeat("y", 15), uri, strings.Repeat("z", 213)}
+	for _, in := range inputs {
+		code, err := Encode([]byte(in))
+		if err != nil {
+			t.Fatalf("Encode(%d bytes): %v", len(in), err)
+		}
+		got, err := readBack(code)
+		if err != nil {
+			t.Errorf("%d bytes: %v", len(in), err)
+			continue
+		}
+		if got != in {
+			t.Errorf("%d bytes read back as %q", len(in), got)
+		}
+	}
+}
+
+func TestEncode_Versions(t *testing.T) {
+	for _, tt := range []struct{ n, size int }{{14, 21}, {15, 25}, {213, 57}} {
+		code, err := Encode(bytes.Repeat([]byte("a"), tt.n))
+		if err != nil || code.Size != tt.size {
+			t.Errorf("%d bytes: size %v, %v; want %d", tt.n, code, err, tt.size)
+		}
+	}
+	if _, err := Encode(make([]byte, 214)); !errors.Is(err, ErrTooLong) {
+		t.Errorf("214 bytes: %v; want ErrTooLong", err)
+	}
+}
+
+func TestSVG(t *testing.T) {
+	code, err := Encode([]byte("abtprj"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	svg := code.SVG(4)
+	if !strings.HasPrefix(svg, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 116 116"`) {
+		t.Errorf("SVG starts %.80q; want a 29-module square at scale 4", svg)
+	}
+	// the top left module of the finder pattern, inside the quiet zone
+	if !strings.Contains(svg, "M16 16h4v4h-4z") {
+		t.Error("SVG does not draw the first finder module")
+	}
+}
+
+// readBack decodes code as a reader would: it finds the mask from the format
+// information, unmasks the data, checks the error correction of every block
+// and returns the byte mode payload.
+func readBack(code *Code) (string, error) {
+	ver := (code.Size - 17) / 4
+	layout := newSymbol(ver)
+
+	var format int
+	for i := 0; i <= 5; i++ {
+		format |= b2i(code.Dark(8, i)) << i
+	}
+	format |= b2i(code.Dark(8, 7))<<6 | b2i(code.Dark(8, 8))<<7 | b2i(code.Dark(7, 8))<<8
+	for i := 9; i < 15; i++ {
+		format |= b2i(code.Dark(14-i, 8)) << i
+	}
+	mask := -1
+	for m := range 8 {
+		if formatBits(m) == format {
+			mask = m
+		}
+	}
+	if mask < 0 {
+		return "", fmt.Errorf("format information %015b is not level M", format)
+	}
+
+	plain := &symbol{Code: Code{Size: code.Size, modules: make([][]bool, code.Size)}, version: ver, function: layout.function}
+	for y := range code.Size {
+		plain.modules[y] = append([]bool(nil), code.modules[y]...)
+	}
+	plain.applyMask(mask)
+
+	v := versions[ver]
+	var bits bitBuffer
+	for right := code.Size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vert := range code.Size {
+			y := vert
+			if upward {
+				y = code.Size - 1 - vert
+			}
+			for j := range 2 {
+				if x := right - j; !layout.function[y][x] {
+					bits = append(bits, plain.modules[y][x])
+				}
+			}
+		}
+	}
+	codewords := bits.bytes()
+
+	var blocks [][]byte
+	for _, g := range v.groups {
+		for range g.blocks {
+			blocks = append(blocks, make([]byte, 0, g.data+v.ecPerBlock))
+		}
+	}
+	next := 0
+	for i := 0; i < v.groups[len(v.groups)-1].data || i < v.groups[0].data; i++ {
+		for b := range blocks {
+			if i < blockData(v, b) {
+				blocks[b] = append(blocks[b], codewords[next])
+				next++
+			}
+		}
+	}
+	var data []byte
+	for b := range blocks {
+		ec := make([]byte, v.ecPerBlock)
+		for i := range ec {
+			ec[i] = codewords[next+i*len(blocks)+b]
+		}
+		if want := rsRemainder(blocks[b], rsDivisor(v.ecPerBlock)); !bytes.Equal(ec, want) {
+			return "", fmt.Errorf("block %d: error correction %v; want %v", b, ec, want)
+		}
+		data = append(data, blocks[b]...)
+	}
+
+	if data[0]>>4 != 0b0100 {
+		return "", fmt.Errorf("mode %04b; want byte mode", data[0]>>4)
+	}
+	var n int
+	var payload []byte
+	if countBits(ver) == 8 {
+		n = int(data[0]&0x0f)<<4 | int(data[1]>>4)
+		payload = shiftNibble(data[1:])
+	} else {
+		n = int(data[0]&0x0f)<<12 | int(data[1])<<4 | int(data[2]>>4)
+		payload = shiftNibble(data[2:])
+	}
+	if n > len(payload) {
+		return "", fmt.Errorf("count %d exceeds the data", n)
+	}
+	return string(payload[:n]), nil
+}
+
+func blockData(v version, b int) int {
+	if b < v.groups[0].blocks {
+		return v.groups[0].data
+	}
+	return v.groups[1].data
+}
+
+// shiftNibble reads bytes that start four bits into data.
+func shiftNibble(data []byte) []byte {
+	out := make([]byte, len(data)-1)
+	for i := range out {
+		out[i] = data[i]<<4 | data[i+1]>>4
+	}
+	return out
+}
+
+func b2i(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,131 @@
+// Package ratelimit slows down repeated failures per key with exponential
+// backoff and a temporary lockout.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter counts failures per key. Memory keeps them in process; an
+// implementation backed by a shared store can take its place.
+type Limiter interface {
+	// Wait returns how long key has to wait before its next attempt, zero if none.
+	Wait(key string, now time.Time) time.Duration
+	// Attempt returns how long key has to wait, counting nothing, or zero after
+	// counting the attempt as a failure. Checking and counting are one step, so
+	// attempts made at the same time cannot all get past the limit; Reset clears
+	// them once an attempt succeeds.
+	Attempt(key string, now time.Time) time.Duration
+	// Reset forgets the failures of key.
+	Reset(key string)
+}
+
+type Policy struct {
+	Free      int           // failures allowed before any delay
+	BaseDelay time.Duration // delay after the first failure past Free, doubled for each one after
+	MaxDelay  time.Duration
+	LockAfter int // failures after which the key is locked for LockFor
+	LockFor   time.Duration
+	Forget    time.Duration // failures older than this no longer count
+}
+
+// Delay is the wait after the given number of consecutive failures.
+func (p Policy) Delay(failures int) time.Duration {
+	if p.LockAfter > 0 && failures >= p.LockAfter {
+		return p.LockFor
+	}
+	if failures <= p.Free {
+		return 0
+	}
+	d := p.BaseDelay
+	for i := p.Free + 1; i < failures && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	if d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	return d
+}
+
+type entry struct {
+	failures int
+	last     time.Time
+	until    time.Time
+}
+
+// maxEntries bounds memory use; stale entries are swept once it is reached.
+const maxEntries = 10000
+
+type Memory struct {
+	policy  Policy
+	mu      sync.Mutex
+	entries map[string]*entry
+}
+
+func NewMemory(policy Policy) *Memory {
+	return &Memory{policy: policy, entries: make(map[string]*entry)}
+}
+
+func (m *Memory) Wait(key string, now time.Time) time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e := m.get(key, now)
+	if e == nil || !now.Before(e.until) {
+		return 0
+	}
+	return e.until.Sub(now)
+}
+
+func (m *Memory) Attempt(key string, now time.Time) time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e := m.get(key, now)
+	if e != nil && now.Before(e.until) {
+		return e.until.Sub(now)
+	}
+	if e == nil {
+		if len(m.entries) >= maxEntries {
+			m.sweep(now)
+		}
+		e = &entry{}
+		m.entries[key] = e
+	}
+	e.failures++
+	e.last = now
+	e.until = now.Add(m.policy.Delay(e.failures))
+	return 0
+}
+
+func (m *Memory) Reset(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, key)
+}
+
+// get returns the live entry for key, dropping it once it is forgotten.
+func (m *Memory) get(key string, now time.Time) *entry {
+	e, ok := m.entries[key]
+	if !ok {
+		return nil
+	}
+	if m.stale(e, now) {
+		delete(m.entries, key)
+		return nil
+	}
+	return e
+}
+
+func (m *Memory) stale(e *entry, now time.Time) bool {
+	return now.After(e.until) && now.Sub(e.last) > m.policy.Forget
+}
+
+func (m *Memory) sweep(now time.Time) {
+	for k, e := range m.entries {
+		if m.stale(e, now) {
+			delete(m.entries, k)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package ratelimit
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+var testPolicy = Policy{
+	Free: 2, BaseDelay: time.Second, MaxDelay: 10 * time.Second,
+	LockAfter: 8, LockFor: time.Hour, Forget: time.Hour,
+}
+
+func TestPolicyDelay(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{1, 0},
+		{2, 0},
+		{3, time.Second},
+		{4, 2 * time.Second},
+		{5, 4 * time.Second},
+		{6, 8 * time.Second},
+		{7, 10 * time.Second}, // capped at MaxDelay
+		{8, time.Hour},        // locked
+		{20, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := testPolicy.Delay(tt.failures); got != tt.want {
+			t.Errorf("Delay(%d) = %s; want %s", tt.failures, got, tt.want)
+		}
+	}
+
+	noLock := testPolicy
+	noLock.LockAfter = 0
+	if got := noLock.Delay(100); got != noLock.MaxDelay {
+		t.Errorf("Delay(100) without a lockout = %s; want MaxDelay", got)
+	}
+}
+
+func TestMemory_Attempt(t *testing.T) {
+	m := NewMemory(testPolicy)
+	now := time.Date(2025, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	// the free attempts go through back to back
+	for i := 0; i < testPolicy.Free; i++ {
+		if wait := m.Attempt("k", now); wait != 0 {
+			t.Fatalf("attempt %d waits %s; want none", i+1, wait)
+		}
+	}
+	// the third is let through and makes the next one wait
+	if wait := m.Attempt("k", now); wait != 0 {
+		t.Fatalf("third attempt waits %s; want none", wait)
+	}
+	if wait := m.Wait("k", now); wait != time.Second {
+		t.Errorf("Wait = %s; want 1s", wait)
+	}
+	// refused attempts are not counted
+	for i := 0; i < 5; i++ {
+		if wait := m.Attempt("k", now.Add(500*time.Millisecond)); wait != 500*time.Millisecond {
+			t.Fatalf("attempt during the wait: %s; want 500ms", wait)
+		}
+	}
+	if wait := m.Attempt("k", now.Add(time.Second)); wait != 0 {
+		t.Fatalf("attempt after the wait: %s; want none", wait)
+	}
+	if wait := m.Wait("k", now.Add(time.Second)); wait != 2*time.Second {
+		t.Errorf("Wait after the fourth attempt = %s; want 2s", wait)
+	}
+
+	if wait := m.Wait("other", now); wait != 0 {
+		t.Errorf("another key waits %s; want none", wait)
+	}
+
+	m.Reset("k")
+	if wait := m.Wait("k", now.Add(time.Second)); wait != 0 {
+		t.Errorf("Wait after Reset = %s; want none", wait)
+	}
+}
+
+func TestMemory_Forget(t *testing.T) {
+	m := NewMemory(testPolicy)
+	now := time.Date(2025, time.March, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 4; i++ {
+		m.Attempt("k", now)
+		now = now.Add(10 * time.Second)
+	}
+	later := now.Add(testPolicy.Forget + time.Minute)
+	if wait := m.Attempt("k", later); wait != 0 {
+		t.Fatalf("attempt after Forget waits %s; want none", wait)
+	}
+	// counting started over: two more are still free
+	if wait := m.Wait("k", later); wait != 0 {
+		t.Errorf("Wait = %s; want the old failures forgotten", wait)
+	}
+}
+
+func TestMemory_AttemptConcurrent(t *testing.T) {
+	m := NewMemory(testPolicy)
+	now := time.Date(2025, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	through := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if m.Attempt("k", now) == 0 {
+				mu.Lock()
+				through++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Free attempts and the one that starts the first delay
+	if want := testPolicy.Free + 1; through != want {
+		t.Errorf("%d parallel attempts got through; want %d", through, want)
+	}
+}
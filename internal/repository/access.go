@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// SetAccountAccess gives userID role on the tracker of accountID, replacing the
+// role they had.
+func SetAccountAccess(db DBTX, accountID, userID int, role string) error {
+	_, err := db.Exec(
+		`INSERT INTO account_access (account_id, user_id, role) VALUES ($1, $2, $3)
+		 ON CONFLICT (account_id, user_id) DO UPDATE SET role = EXCLUDED.role`,
+		accountID, userID, role,
+	)
+	return err
+}
+
+// RemoveAccountAccess takes away userID's access to accountID. It reports
+// false when they had none.
+func RemoveAccountAccess(db DBTX, accountID, userID int) (bool, error) {
+	res, err := db.Exec("DELETE FROM account_access WHERE account_id = $1 AND user_id = $2", accountID, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// GetAccountRole returns userID's role on accountID, or "" without access.
+func GetAccountRole(db DBTX, accountID, userID int) (string, error) {
+	var role string
+	err := db.QueryRow(
+		"SELECT role FROM account_access WHERE account_id = $1 AND user_id = $2",
+		accountID, userID,
+	).Scan(&role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return role, err
+}
+
+// ListAccessToAccount returns who has access to accountID, by login.
+func ListAccessToAccount(db DBTX, accountID int) ([]AccountAccess, error) {
+	return listAccess(db,
+		`SELECT g.account_id, g.user_id, a.login, g.role, g.granted_at
+		   FROM account_access g
+		   JOIN admin a ON a.id = g.user_id
+		  WHERE g.account_id = $1
+		  ORDER BY a.login`,
+		accountID)
+}
+
+// ListAccessOfUser returns the accounts userID has access to, by login.
+func ListAccessOfUser(db DBTX, userID int) ([]AccountAccess, error) {
+	return listAccess(db,
+		`SELECT g.account_id, g.user_id, a.login, g.role, g.granted_at
+		   FROM account_access g
+		   JOIN admin a ON a.id = g.account_id
+		  WHERE g.user_id = $1
+		  ORDER BY a.login`,
+		userID)
+}
+
+func listAccess(db DBTX, query string, id int) ([]AccountAccess, error) {
+	rows, err := db.Query(query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var access []AccountAccess
+	for rows.Next() {
+		var g AccountAccess
+		if err := rows.Scan(&g.AccountId, &g.UserId, &g.Login, &g.Role, &g.GrantedAt); err != nil {
+			return nil, err
+		}
+		access = append(access, g)
+	}
+	return access, rows.Err()
+}
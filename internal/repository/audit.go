@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AuditEntry is one row of the append-only audit_log.
+type AuditEntry struct {
+	Id         int64
+	At         time.Time
+	AccountId  int // whose data changed
+	ActorId    sql.NullInt64
+	ActorLogin string
+	IP         string
+	Action     string
+	Entity     string
+	EntityId   string
+	Before     sql.NullString // JSON
+	After      sql.NullString // JSON
+}
+
+// AuditFilter narrows a listing of the audit log. Empty fields and zero times
+// match everything; BeforeId pages back through entries older than that id.
+type AuditFilter struct {
+	Action   string
+	Entity   string
+	Actor    string // login
+	From     time.Time
+	To       time.Time
+	BeforeId int64
+	Limit    int
+}
+
+// AddAuditEntry appends e to the log and returns its id. An empty ActorLogin
+// is filled in from the actor's account.
+func AddAuditEntry(db DBTX, e AuditEntry) (int64, error) {
+	var id int64
+	err := db.QueryRow(
+		`INSERT INTO audit_log (account_id, actor_id, actor_login, ip, action, entity, entity_id, before_json, after_json)
+		 VALUES ($1, $2, COALESCE(NULLIF($3, ''), (SELECT login FROM admin WHERE id = $2), ''), $4, $5, $6, $7, $8, $9)
+		 RETURNING id`,
+		e.AccountId, e.ActorId, e.ActorLogin, e.IP, e.Action, e.Entity, e.EntityId, e.Before, e.After,
+	).Scan(&id)
+	return id, err
+}
+
+// ListAuditEntries returns accountID's entries matching filter, newest first.
+func ListAuditEntries(db DBTX, accountID int, filter AuditFilter) ([]AuditEntry, error) {
+	conds := []string{"account_id = $1"}
+	args := []any{accountID}
+	add := func(cond string, v any) {
+		args = append(args, v)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+	if filter.Action != "" {
+		add("action = $%d", filter.Action)
+	}
+	if filter.Entity != "" {
+		add("entity = $%d", filter.Entity)
+	}
+	if filter.Actor != "" {
+		add("actor_login = $%d", filter.Actor)
+	}
+	if !filter.From.IsZero() {
+		add("at >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		add("at < $%d", filter.To)
+	}
+	if filter.BeforeId > 0 {
+		add("id < $%d", filter.BeforeId)
+	}
+	args = append(args, sqlLimit(filter.Limit))
+
+	rows, err := db.Query(
+		`SELECT id, at, account_id, actor_id, actor_login, ip, action, entity, entity_id, before_json, after_json
+		   FROM audit_log WHERE `+strings.Join(conds, " AND ")+
+			fmt.Sprintf(" ORDER BY id DESC LIMIT $%d", len(args)),
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []AuditEntry
+	for rows.Next() {
+		var e AuditEntry
+		if err := rows.Scan(&e.Id, &e.At, &e.AccountId, &e.ActorId, &e.ActorLogin, &e.IP,
+			&e.Action, &e.Entity, &e.EntityId, &e.Before, &e.After); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// GetAuditEntry returns accountID's entry id.
+func GetAuditEntry(db DBTX, accountID int, id int64) (AuditEntry, error) {
+	var e AuditEntry
+	err := db.QueryRow(
+		`SELECT id, at, account_id, actor_id, actor_login, ip, action, entity, entity_id, before_json, after_json
+		   FROM audit_log WHERE id = $1 AND account_id = $2`,
+		id, accountID,
+	).Scan(&e.Id, &e.At, &e.AccountId, &e.ActorId, &e.ActorLogin, &e.IP, &e.Action, &e.Entity, &e.EntityId, &e.Before, &e.After)
+	return e, err
+}
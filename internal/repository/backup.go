@@ -0,0 +1,210 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// BackupTables lists every table that holds tracker data, parents before children.
+var BackupTables = []string{"admin", "admin_recovery_codes", "account_access", "teams", "team_members", "projects",
+	"work_sessions", "tasks", "goals", "settings", "audit_log"}
+
+// DumpTables reads every row of BackupTables from one consistent snapshot and
+// passes it to fn as a column to value map.
+func DumpTables(db *sql.DB, fn func(table string, row map[string]any) error) error {
+	tx, err := db.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, table := range BackupTables {
+		if err := dumpTable(tx, table, fn); err != nil {
+			return fmt.Errorf("dumping %s: %w", table, err)
+		}
+	}
+	return tx.Commit()
+}
+
+func dumpTable(tx *sql.Tx, table string, fn func(table string, row map[string]any) error) error {
+	rows, err := tx.Query("SELECT * FROM " + pq.QuoteIdentifier(table) + " ORDER BY 1")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	values := make([]any, len(cols))
+	ptrs := make([]any, len(cols))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return err
+		}
+		row := make(map[string]any, len(cols))
+		for i, c := range cols {
+			row[c] = values[i]
+		}
+		if err := fn(table, row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// HasTrackerData reports whether any of BackupTables has rows. Tables the
+// schema does not have yet count as empty.
+func HasTrackerData(db *sql.DB) (bool, error) {
+	for _, table := range BackupTables {
+		found, err := tableHasRows(db, table)
+		if err != nil || found {
+			return found, err
+		}
+	}
+	return false, nil
+}
+
+// tableHasRows reports whether table exists and has any rows.
+func tableHasRows(db DBTX, table string) (bool, error) {
+	var found bool
+	if err := db.QueryRow("SELECT to_regclass($1) IS NOT NULL", table).Scan(&found); err != nil || !found {
+		return false, err
+	}
+	err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM " + pq.QuoteIdentifier(table) + ")").Scan(&found)
+	return found, err
+}
+
+// HasTrackerDataFor reports whether userID owns any tasks, goals or work sessions.
+func HasTrackerDataFor(db *sql.DB, userID int) (bool, error) {
+	var found bool
+	err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM tasks WHERE user_id = $1)
+		OR EXISTS (SELECT 1 FROM goals WHERE user_id = $1)
+		OR EXISTS (SELECT 1 FROM work_sessions WHERE user_id = $1)`, userID).Scan(&found)
+	return found, err
+}
+
+// AssignUnownedData gives tasks, goals and work sessions without an owner to the
+// first account. Such rows come from before there were accounts, or from a backup
+// taken before data was per user. Tasks and goals shared through a project have
+// no owner on purpose and are left alone.
+func AssignUnownedData(db *sql.DB) error {
+	for _, table := range []string{"tasks", "goals", "work_sessions"} {
+		query := "UPDATE " + pq.QuoteIdentifier(table) +
+			" SET user_id = (SELECT MIN(id) FROM admin) WHERE user_id IS NULL"
+		if table != "work_sessions" {
+			query += " AND project_id IS NULL"
+		}
+		if _, err := db.Exec(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RestoreTables replaces the contents of BackupTables with rows in a single transaction.
+// The audit log is append-only, so it is only added to and should be empty.
+// Values for columns the current schema does not have are dropped and returned as
+// "table.column"; columns missing from the rows get their defaults. Tables an older
+// schema does not have yet are left out.
+func RestoreTables(db *sql.DB, rows map[string][]map[string]any) (dropped []string, err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	tableCols := make(map[string]map[string]bool, len(BackupTables))
+	for _, table := range BackupTables {
+		if tableCols[table], err = tableColumns(tx, table); err != nil {
+			return nil, err
+		}
+	}
+
+	for i := len(BackupTables) - 1; i >= 0; i-- {
+		if len(tableCols[BackupTables[i]]) == 0 || BackupTables[i] == "audit_log" {
+			continue
+		}
+		if _, err := tx.Exec("DELETE FROM " + pq.QuoteIdentifier(BackupTables[i])); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, table := range BackupTables {
+		columns := tableCols[table]
+		if len(columns) == 0 {
+			if len(rows[table]) > 0 {
+				return nil, fmt.Errorf("restoring %s: table does not exist", table)
+			}
+			continue
+		}
+		skipped := make(map[string]bool)
+		for _, row := range rows[table] {
+			var names, params []string
+			var args []any
+			for _, c := range sortedKeys(row) {
+				if !columns[c] {
+					skipped[c] = true
+					continue
+				}
+				args = append(args, row[c])
+				names = append(names, pq.QuoteIdentifier(c))
+				params = append(params, fmt.Sprintf("$%d", len(args)))
+			}
+			query := "INSERT INTO " + pq.QuoteIdentifier(table) +
+				" (" + strings.Join(names, ", ") + ") VALUES (" + strings.Join(params, ", ") + ")"
+			if _, err := tx.Exec(query, args...); err != nil {
+				return nil, fmt.Errorf("restoring %s: %w", table, err)
+			}
+		}
+		for _, c := range sortedKeys(skipped) {
+			dropped = append(dropped, table+"."+c)
+		}
+
+		if columns["id"] {
+			// keep SERIAL ids ahead of the restored rows
+			q := fmt.Sprintf(`SELECT setval(pg_get_serial_sequence('%[1]s', 'id'), COALESCE(MAX(id), 1), MAX(id) IS NOT NULL) FROM %[1]s`, table)
+			if _, err := tx.Exec(q); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return dropped, tx.Commit()
+}
+
+func tableColumns(tx *sql.Tx, table string) (map[string]bool, error) {
+	rows, err := tx.Query(`SELECT column_name FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = $1`, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var c string
+		if err := rows.Scan(&c); err != nil {
+			return nil, err
+		}
+		columns[c] = true
+	}
+	return columns, rows.Err()
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
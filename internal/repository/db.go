@@ -3,18 +3,17 @@ package repository
 import (
 	"database/sql"
 	"errors"
-	"golang.org/x/crypto/bcrypt"
 	"log"
-	"os"
 	"time"
 )
 
-func GetDoneTasks(db *sql.DB, start, end time.Time) ([]Task, error) {
+func GetDoneTasks(db DBTX, userID int, start, end time.Time) ([]Task, error) {
 	rows, err := db.Query(
-		`SELECT name, description, status, done_at
+		`SELECT id, name, description, status, done_at, estimate_minutes, session_id, created_at, visibility
 		 FROM tasks
-		 WHERE status = 'done' AND done_at >= $1 AND done_at < $2`,
-		start, end,
+		 WHERE user_id = $1 AND status = 'done' AND done_at >= $2 AND done_at < $3
+		 ORDER BY done_at`,
+		userID, start, end,
 	)
 	if err != nil {
 		return nil, err
@@ -24,7 +23,7 @@ func GetDoneTasks(db *sql.DB, start, end time.Time) ([]Task, error) {
 	var tasks []Task
 	for rows.Next() {
 		var t Task
-		if err := rows.Scan(&t.Name, &t.Description, &t.Status, &t.DoneAt); err != nil {
+		if err := rows.Scan(&t.Id, &t.Name, &t.Description, &t.Status, &t.DoneAt, &t.Estimate, &t.SessionId, &t.CreatedAt, &t.Visibility); err != nil {
 			continue
 		}
 		tasks = append(tasks, t)
@@ -32,8 +31,18 @@ func GetDoneTasks(db *sql.DB, start, end time.Time) ([]Task, error) {
 	return tasks, rows.Err()
 }
 
-func GetTodoTasks(db *sql.DB) ([]Task, error) {
-	rows, err := db.Query("SELECT name, description, status FROM tasks WHERE status = 'todo'")
+// GetLastDoneBefore returns when userID last completed a task before t.
+func GetLastDoneBefore(db DBTX, userID int, t time.Time) (sql.NullTime, error) {
+	var doneAt sql.NullTime
+	err := db.QueryRow(
+		"SELECT MAX(done_at) FROM tasks WHERE user_id = $1 AND status = 'done' AND done_at < $2",
+		userID, t,
+	).Scan(&doneAt)
+	return doneAt, err
+}
+
+func GetTodoTasks(db DBTX, userID int) ([]Task, error) {
+	rows, err := db.Query("SELECT id, name, description, status, estimate_minutes, created_at, visibility FROM tasks WHERE user_id = $1 AND status = 'todo'", userID)
 	if err != nil {
 		return nil, err
 	}
@@ -42,7 +51,7 @@ func GetTodoTasks(db *sql.DB) ([]Task, error) {
 	var tasks []Task
 	for rows.Next() {
 		var t Task
-		if err := rows.Scan(&t.Name, &t.Description, &t.Status); err != nil {
+		if err := rows.Scan(&t.Id, &t.Name, &t.Description, &t.Status, &t.Estimate, &t.CreatedAt, &t.Visibility); err != nil {
 			continue
 		}
 		tasks = append(tasks, t)
@@ -50,37 +59,102 @@ func GetTodoTasks(db *sql.DB) ([]Task, error) {
 	return tasks, rows.Err()
 }
 
-func AddTask(db *sql.DB, name, description string) error {
+// AddTask adds a todo task and returns its id; a NULL visibility follows the
+// account's default.
+func AddTask(db DBTX, userID int, name, description string, estimate sql.NullInt64, visibility sql.NullString) (int, error) {
+	var id int
+	err := db.QueryRow(
+		"INSERT INTO tasks(user_id, name, description, status, estimate_minutes, visibility) VALUES ($1, $2, $3, 'todo', $4, $5) RETURNING id",
+		userID, name, description, estimate, visibility,
+	).Scan(&id)
+	return id, err
+}
+
+// GetTask returns userID's task id.
+func GetTask(db DBTX, userID, id int) (Task, error) {
+	var t Task
+	err := db.QueryRow(
+		`SELECT id, name, description, status, done_at, estimate_minutes, session_id, created_at, visibility
+		 FROM tasks WHERE id = $1 AND user_id = $2`,
+		id, userID,
+	).Scan(&t.Id, &t.Name, &t.Description, &t.Status, &t.DoneAt, &t.Estimate, &t.SessionId, &t.CreatedAt, &t.Visibility)
+	return t, err
+}
+
+// FindTaskByName returns userID's task called name, the oldest open one if
+// there is one, and whether any was found.
+func FindTaskByName(db DBTX, userID int, name string) (Task, bool, error) {
+	var id int
+	err := db.QueryRow(
+		"SELECT id FROM tasks WHERE user_id = $1 AND name = $2 ORDER BY status <> 'todo', id LIMIT 1",
+		userID, name,
+	).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Task{}, false, nil
+		}
+		return Task{}, false, err
+	}
+	t, err := GetTask(db, userID, id)
+	return t, err == nil, err
+}
+
+// ImportTask inserts a task as it was in another tool, keeping its status and dates.
+// A zero createdAt leaves the creation time to the database.
+func ImportTask(db DBTX, userID int, name, description, status string, doneAt sql.NullTime, createdAt time.Time, sessionID sql.NullInt64) error {
 	_, err := db.Exec(
-		"INSERT INTO tasks(name, description, status) VALUES ($1, $2, 'todo')",
-		name, description,
+		`INSERT INTO tasks(user_id, name, description, status, done_at, created_at, session_id)
+		 VALUES ($1, $2, $3, $4, $5, COALESCE($6, NOW()), $7)`,
+		userID, name, description, status, doneAt, sql.NullTime{Time: createdAt, Valid: !createdAt.IsZero()}, sessionID,
 	)
 	return err
 }
 
-func CompleteTask(db *sql.DB, name string) error {
-	isActive, session, err := CheckIfActiveSessions(db)
+func GetTaskNames(db DBTX, userID int) ([]string, error) {
+	rows, err := db.Query("SELECT name FROM tasks WHERE user_id = $1", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// CompleteTask marks userID's task id done and attributes it to the running
+// work session.
+func CompleteTask(db DBTX, userID, id int) error {
+	isActive, session, err := CheckIfActiveSessions(db, userID)
 	if !isActive || session == nil {
 		log.Printf("attempting to end a task without active session: %v", err)
 		return err
 	}
 	result, err := db.Exec(
-		"UPDATE tasks SET status = 'done', done_at = NOW(), session_id = $1 WHERE name = $2",
+		"UPDATE tasks SET status = 'done', done_at = NOW(), session_id = $1 WHERE user_id = $2 AND id = $3",
 		session.Id,
-		name,
+		userID,
+		id,
 	)
 
 	log.Printf("sql.Result: %#v", result)
 	return err
 }
 
-func GetWorkingSessionsForDay(db *sql.DB, start, end time.Time) ([]WorkSession, error) {
+func GetWorkingSessionsForDay(db DBTX, userID int, start, end time.Time) ([]WorkSession, error) {
 	rows, err := db.Query(
 		`SELECT start_time, end_time
 		   FROM work_sessions
-		  WHERE start_time >= $1
-		    AND (end_time < $2 OR end_time IS NULL)`,
-		start, end,
+		  WHERE user_id = $1
+		    AND start_time >= $2
+		    AND (end_time < $3 OR end_time IS NULL)`,
+		userID, start, end,
 	)
 	if err != nil {
 		return nil, err
@@ -98,13 +172,14 @@ func GetWorkingSessionsForDay(db *sql.DB, start, end time.Time) ([]WorkSession,
 	return workSessions, rows.Err()
 }
 
-func GetWorkingSessions(db *sql.DB, start, end time.Time) ([]WorkSession, error) {
+func GetWorkingSessions(db DBTX, userID int, start, end time.Time) ([]WorkSession, error) {
 	rows, err := db.Query(
 		`SELECT start_time, end_time
 		   FROM work_sessions
-		  WHERE start_time >= $1
-		    AND (end_time < $2 OR end_time IS NULL)`,
-		start, end,
+		  WHERE user_id = $1
+		    AND start_time >= $2
+		    AND (end_time < $3 OR end_time IS NULL)`,
+		userID, start, end,
 	)
 	if err != nil {
 		return nil, err
@@ -122,14 +197,57 @@ func GetWorkingSessions(db *sql.DB, start, end time.Time) ([]WorkSession, error)
 	return workSessions, rows.Err()
 }
 
-func StartWorkSession(db *sql.DB) error {
-	isActive, _, err := CheckIfActiveSessions(db)
+// GetSessionsOverlapping returns every session that shares time with [start, end).
+// Running sessions are treated as lasting until now.
+func GetSessionsOverlapping(db DBTX, userID int, start, end time.Time) ([]WorkSession, error) {
+	rows, err := db.Query(
+		`SELECT id, start_time, end_time
+		   FROM work_sessions
+		  WHERE user_id = $1
+		    AND start_time < $3
+		    AND COALESCE(end_time, NOW()) > $2
+		  ORDER BY start_time`,
+		userID, start, end,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var workSessions []WorkSession
+	for rows.Next() {
+		var ws WorkSession
+		if err := rows.Scan(&ws.Id, &ws.StartTime, &ws.EndTime); err != nil {
+			return nil, err
+		}
+		workSessions = append(workSessions, ws)
+	}
+	return workSessions, rows.Err()
+}
+
+// InsertWorkSession records a finished session and returns its id.
+func InsertWorkSession(db DBTX, userID int, start, end time.Time) (int, error) {
+	var id int
+	err := db.QueryRow(
+		"INSERT INTO work_sessions(user_id, start_time, end_time) VALUES ($1, $2, $3) RETURNING id",
+		userID, start, end,
+	).Scan(&id)
+	return id, err
+}
+
+func UpdateWorkSessionTimes(db DBTX, userID, id int, start, end time.Time) error {
+	_, err := db.Exec("UPDATE work_sessions SET start_time = $1, end_time = $2 WHERE id = $3 AND user_id = $4", start, end, id, userID)
+	return err
+}
+
+func StartWorkSession(db DBTX, userID int) error {
+	isActive, _, err := CheckIfActiveSessions(db, userID)
 	if isActive {
 		log.Printf("Attepmpting to create another worksession, while active sessions exist %v", err)
 		return err
 	}
 
-	_, err = db.Exec("INSERT INTO work_sessions(start_time) VALUES ($1)", time.Now())
+	_, err = db.Exec("INSERT INTO work_sessions(user_id, start_time) VALUES ($1, $2)", userID, time.Now())
 	if err != nil {
 		log.Printf("Error inserting work session: %v", err)
 		return err
@@ -137,14 +255,14 @@ func StartWorkSession(db *sql.DB) error {
 	return err
 }
 
-func EndWorkSession(db *sql.DB) error {
-	isActive, s, err := CheckIfActiveSessions(db)
+func EndWorkSession(db DBTX, userID int) error {
+	isActive, s, err := CheckIfActiveSessions(db, userID)
 	if !isActive {
 		log.Printf("Attepmpting to end worksession, while active sessions does not exist %v", err)
 		return err
 	}
 
-	_, err = db.Exec("UPDATE work_sessions set end_time = $1 WHERE ID=$2", time.Now(), s.Id)
+	_, err = db.Exec("UPDATE work_sessions set end_time = $1 WHERE ID=$2 AND user_id = $3", time.Now(), s.Id, userID)
 	if err != nil {
 		log.Printf("Error inserting work session: %v", err)
 		return err
@@ -152,8 +270,16 @@ func EndWorkSession(db *sql.DB) error {
 	return nil
 }
 
-func CheckIfActiveSessions(db *sql.DB) (bool, *WorkSession, error) {
-	row := db.QueryRow("SELECT id, start_time, end_time, created_at FROM work_sessions WHERE end_time IS NULL")
+// GetWorkSession returns userID's work session id.
+func GetWorkSession(db DBTX, userID, id int) (WorkSession, error) {
+	var ws WorkSession
+	err := db.QueryRow("SELECT id, start_time, end_time, created_at FROM work_sessions WHERE id = $1 AND user_id = $2", id, userID).
+		Scan(&ws.Id, &ws.StartTime, &ws.EndTime, &ws.CreatedAt)
+	return ws, err
+}
+
+func CheckIfActiveSessions(db DBTX, userID int) (bool, *WorkSession, error) {
+	row := db.QueryRow("SELECT id, start_time, end_time, created_at FROM work_sessions WHERE user_id = $1 AND end_time IS NULL", userID)
 
 	var ws WorkSession
 	err := row.Scan(&ws.Id, &ws.StartTime, &ws.EndTime, &ws.CreatedAt)
@@ -168,7 +294,7 @@ func CheckIfActiveSessions(db *sql.DB) (bool, *WorkSession, error) {
 	return true, &ws, nil
 }
 
-func CheckIfAdminExists(db *sql.DB) (bool, error) {
+func CheckIfAdminExists(db DBTX) (bool, error) {
 	row := db.QueryRow("SELECT id, login, created_at FROM admin")
 
 	var admin Admin
@@ -185,11 +311,11 @@ func CheckIfAdminExists(db *sql.DB) (bool, error) {
 	return true, nil
 }
 
-func GetAdminByLogin(db *sql.DB, login string) (Admin, error) {
-	row := db.QueryRow("SELECT id, login, password_hash, created_at FROM admin where login = $1", login)
+func GetAdminByLogin(db DBTX, login string) (Admin, error) {
+	row := db.QueryRow("SELECT id, login, password_hash, must_change_password, totp_enabled, created_at FROM admin where login = $1", login)
 
 	var admin Admin
-	err := row.Scan(&admin.Id, &admin.Login, &admin.PasswordHash, &admin.CreatedAt)
+	err := row.Scan(&admin.Id, &admin.Login, &admin.PasswordHash, &admin.MustChangePassword, &admin.TOTPEnabled, &admin.CreatedAt)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			log.Printf("Admin not found")
@@ -201,38 +327,19 @@ func GetAdminByLogin(db *sql.DB, login string) (Admin, error) {
 	return admin, nil
 }
 
-func InitDefaultAdmin(db *sql.DB) error {
-	login := os.Getenv("LOGIN")
-	if login == "" {
-		login = "admin"
-	}
-	password := os.Getenv("PASSWORD")
-	if password == "" {
-		password = "admin"
-	}
-
-	exists, err := CheckIfAdminExists(db)
-	if err != nil {
-		return err
-	}
-	if exists {
-		return nil
-	}
-
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return err
-	}
-
-	if err := GenerateAdmin(db, login, hash); err != nil {
-		return err
+// GetOwner returns the first account, whose data the pages at / show, or an
+// Admin with a zero Id when there are no accounts yet.
+func GetOwner(db DBTX) (Admin, error) {
+	var admin Admin
+	err := db.QueryRow("SELECT id, login, created_at FROM admin ORDER BY id LIMIT 1").
+		Scan(&admin.Id, &admin.Login, &admin.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Admin{}, nil
 	}
-
-	log.Println("Default admin created successfully")
-	return nil
+	return admin, err
 }
 
-func GenerateAdmin(db *sql.DB, login string, hash []byte) error {
+func GenerateAdmin(db DBTX, login string, hash []byte) error {
 	_, err := db.Exec("INSERT INTO admin (login, password_hash) VALUES ($1, $2)", login, string(hash))
 	if err != nil {
 		return err
@@ -240,8 +347,8 @@ func GenerateAdmin(db *sql.DB, login string, hash []byte) error {
 	return nil
 }
 
-func GetGoals(db *sql.DB) ([]Goal, error) {
-	rows, err := db.Query("SELECT id, name, description, status, done_at, due_at FROM goals")
+func GetGoals(db DBTX, userID int) ([]Goal, error) {
+	rows, err := db.Query("SELECT id, name, description, status, done_at, due_at, visibility FROM goals WHERE user_id = $1", userID)
 	if err != nil {
 		log.Printf("Error getting goals: %v", err)
 		return nil, err
@@ -257,7 +364,7 @@ func GetGoals(db *sql.DB) ([]Goal, error) {
 	var goals []Goal
 	for rows.Next() {
 		var goal Goal
-		if err := rows.Scan(&goal.Id, &goal.Name, &goal.Description, &goal.Status, &goal.DoneAt, &goal.DueAt); err != nil {
+		if err := rows.Scan(&goal.Id, &goal.Name, &goal.Description, &goal.Status, &goal.DoneAt, &goal.DueAt, &goal.Visibility); err != nil {
 			log.Printf("Error scanning goal: %v", err)
 		}
 
@@ -267,8 +374,8 @@ func GetGoals(db *sql.DB) ([]Goal, error) {
 	return goals, rows.Err()
 }
 
-func GetTodoGoals(db *sql.DB) ([]Goal, error) {
-	rows, err := db.Query("SELECT id, name, description, status, done_at, due_at FROM goals WHERE status = 'todo'")
+func GetTodoGoals(db DBTX, userID int) ([]Goal, error) {
+	rows, err := db.Query("SELECT id, name, description, status, done_at, due_at, visibility FROM goals WHERE user_id = $1 AND status = 'todo'", userID)
 	if err != nil {
 		log.Printf("Error getting todo goals: %v", err)
 		return nil, err
@@ -284,7 +391,7 @@ func GetTodoGoals(db *sql.DB) ([]Goal, error) {
 	var todoGoals []Goal
 	for rows.Next() {
 		var goal Goal
-		if err := rows.Scan(&goal.Id, &goal.Name, &goal.Description, &goal.Status, &goal.DoneAt, &goal.DueAt); err != nil {
+		if err := rows.Scan(&goal.Id, &goal.Name, &goal.Description, &goal.Status, &goal.DoneAt, &goal.DueAt, &goal.Visibility); err != nil {
 			log.Printf("Error scanning goal: %v", err)
 		}
 
@@ -294,27 +401,185 @@ func GetTodoGoals(db *sql.DB) ([]Goal, error) {
 	return todoGoals, rows.Err()
 }
 
-func CompleteGoal(db *sql.DB, id int) error {
-	isActive, session, err := CheckIfActiveSessions(db)
+func GetGoalsDueBetween(db DBTX, userID int, start, end time.Time) ([]Goal, error) {
+	rows, err := db.Query(
+		`SELECT id, name, description, status, done_at, due_at, visibility
+		   FROM goals
+		  WHERE user_id = $1 AND due_at >= $2 AND due_at < $3
+		  ORDER BY due_at`,
+		userID, start, end,
+	)
+	if err != nil {
+		log.Printf("Error getting goals due between: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var goals []Goal
+	for rows.Next() {
+		var goal Goal
+		if err := rows.Scan(&goal.Id, &goal.Name, &goal.Description, &goal.Status, &goal.DoneAt, &goal.DueAt, &goal.Visibility); err != nil {
+			log.Printf("Error scanning goal: %v", err)
+			continue
+		}
+		goals = append(goals, goal)
+	}
+	return goals, rows.Err()
+}
+
+func CompleteGoal(db DBTX, userID, id int) error {
+	isActive, session, err := CheckIfActiveSessions(db, userID)
 	if !isActive || session == nil {
 		log.Printf("attempting to end a goal without active session: %v", err)
 		return err
 	}
 
 	result, err := db.Exec(
-		"UPDATE goals SET status = 'done', done_at = NOW() WHERE id = $1",
-		id,
+		"UPDATE goals SET status = 'done', done_at = NOW() WHERE id = $1 AND user_id = $2",
+		id, userID,
 	)
 
 	log.Printf("sql.Result: %#v", result)
 	return err
 }
 
-func CreateGoal(db *sql.DB, name string, description string, dueAt time.Time) error {
-	_, err := db.Exec("INSERT INTO goals (name, description, due_at) VALUES ($1, $2, $3)", name, description, dueAt)
+func CreateGoal(db DBTX, userID int, name string, description string, dueAt time.Time, visibility sql.NullString) (int, error) {
+	var id int
+	err := db.QueryRow("INSERT INTO goals (user_id, name, description, due_at, visibility) VALUES ($1, $2, $3, $4, $5) RETURNING id", userID, name, description, dueAt, visibility).Scan(&id)
 	if err != nil {
 		log.Printf("Error inserting goal: %v", err)
-		return err
+		return 0, err
 	}
-	return nil
+	return id, nil
+}
+
+// GetGoal returns userID's goal id.
+func GetGoal(db DBTX, userID, id int) (Goal, error) {
+	var g Goal
+	err := db.QueryRow(
+		"SELECT id, name, description, status, done_at, due_at, created_at, visibility FROM goals WHERE id = $1 AND user_id = $2",
+		id, userID,
+	).Scan(&g.Id, &g.Name, &g.Description, &g.Status, &g.DoneAt, &g.DueAt, &g.CreatedAt, &g.Visibility)
+	return g, err
+}
+
+// SetTaskVisibility changes the visibility of userID's task id; NULL makes it
+// follow the account's default again.
+func SetTaskVisibility(db DBTX, userID, id int, visibility sql.NullString) error {
+	_, err := db.Exec("UPDATE tasks SET visibility = $1 WHERE id = $2 AND user_id = $3", visibility, id, userID)
+	return err
+}
+
+// SetGoalVisibility is SetTaskVisibility for goals.
+func SetGoalVisibility(db DBTX, userID, id int, visibility sql.NullString) error {
+	_, err := db.Exec("UPDATE goals SET visibility = $1 WHERE id = $2 AND user_id = $3", visibility, id, userID)
+	return err
+}
+
+// GetRecentDoneTasks returns the last limit completed tasks, newest first.
+// With defaultVisibility set, private tasks are left out before the limit is
+// applied; tasks without a visibility of their own follow defaultVisibility.
+func GetRecentDoneTasks(db DBTX, userID, limit int, defaultVisibility sql.NullString) ([]Task, error) {
+	rows, err := db.Query(
+		`SELECT id, name, description, status, done_at, estimate_minutes, created_at, visibility
+		 FROM tasks
+		 WHERE user_id = $1 AND status = 'done' AND done_at IS NOT NULL
+		   AND ($3::text IS NULL OR COALESCE(visibility, $3) <> 'private')
+		 ORDER BY done_at DESC, id DESC
+		 LIMIT $2`,
+		userID, limit, defaultVisibility,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []Task
+	for rows.Next() {
+		var t Task
+		if err := rows.Scan(&t.Id, &t.Name, &t.Description, &t.Status, &t.DoneAt, &t.Estimate, &t.CreatedAt, &t.Visibility); err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks, rows.Err()
+}
+
+// GetRecentDoneGoals is GetRecentDoneTasks for goals.
+func GetRecentDoneGoals(db DBTX, userID, limit int, defaultVisibility sql.NullString) ([]Goal, error) {
+	rows, err := db.Query(
+		`SELECT id, name, description, status, done_at, due_at, visibility
+		 FROM goals
+		 WHERE user_id = $1 AND status = 'done' AND done_at IS NOT NULL
+		   AND ($3::text IS NULL OR COALESCE(visibility, $3) <> 'private')
+		 ORDER BY done_at DESC, id DESC
+		 LIMIT $2`,
+		userID, limit, defaultVisibility,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var goals []Goal
+	for rows.Next() {
+		var g Goal
+		if err := rows.Scan(&g.Id, &g.Name, &g.Description, &g.Status, &g.DoneAt, &g.DueAt, &g.Visibility); err != nil {
+			return nil, err
+		}
+		goals = append(goals, g)
+	}
+	return goals, rows.Err()
+}
+
+func GetGoalsDoneBetween(db DBTX, userID int, start, end time.Time) ([]Goal, error) {
+	rows, err := db.Query(
+		`SELECT id, name, description, status, done_at, due_at, visibility
+		   FROM goals
+		  WHERE user_id = $1 AND status = 'done' AND done_at >= $2 AND done_at < $3
+		  ORDER BY done_at`,
+		userID, start, end,
+	)
+	if err != nil {
+		log.Printf("Error getting goals done between: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var goals []Goal
+	for rows.Next() {
+		var goal Goal
+		if err := rows.Scan(&goal.Id, &goal.Name, &goal.Description, &goal.Status, &goal.DoneAt, &goal.DueAt, &goal.Visibility); err != nil {
+			log.Printf("Error scanning goal: %v", err)
+			continue
+		}
+		goals = append(goals, goal)
+	}
+	return goals, rows.Err()
+}
+
+func ListAdmins(db DBTX) ([]Admin, error) {
+	rows, err := db.Query("SELECT id, login, created_at FROM admin ORDER BY login")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var admins []Admin
+	for rows.Next() {
+		var a Admin
+		if err := rows.Scan(&a.Id, &a.Login, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		admins = append(admins, a)
+	}
+	return admins, rows.Err()
+}
+
+func InsertGoal(db DBTX, userID int, name, description, status string, dueAt, doneAt sql.NullTime, createdAt time.Time) error {
+	_, err := db.Exec(
+		"INSERT INTO goals (user_id, name, description, status, due_at, done_at, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+		userID, name, description, status, dueAt, doneAt, createdAt,
+	)
+	return err
 }
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ExportFilter narrows an export. Zero times leave that end of the range open
+// and an empty Status matches every row.
+type ExportFilter struct {
+	From   time.Time
+	To     time.Time
+	Status string
+}
+
+// where builds the WHERE clause for userID's rows matching filter, with dateExpr
+// as the column the range applies to.
+func (f ExportFilter) where(userID int, dateExpr, statusExpr string) (string, []any) {
+	conds := []string{"user_id = $1"}
+	args := []any{userID}
+	if !f.From.IsZero() {
+		args = append(args, f.From)
+		conds = append(conds, fmt.Sprintf("%s >= $%d", dateExpr, len(args)))
+	}
+	if !f.To.IsZero() {
+		args = append(args, f.To)
+		conds = append(conds, fmt.Sprintf("%s < $%d", dateExpr, len(args)))
+	}
+	if f.Status != "" && statusExpr != "" {
+		args = append(args, f.Status)
+		conds = append(conds, fmt.Sprintf("%s = $%d", statusExpr, len(args)))
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// StreamTasks calls fn for every matching task without loading them all into memory.
+// The date range applies to done_at for finished tasks and created_at otherwise.
+func StreamTasks(db DBTX, userID int, filter ExportFilter, fn func(Task) error) error {
+	where, args := filter.where(userID, "COALESCE(done_at, created_at)", "status")
+	rows, err := db.Query(
+		`SELECT id, name, description, status, done_at, estimate_minutes, session_id, created_at
+		   FROM tasks`+where+` ORDER BY id`,
+		args...,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var t Task
+		if err := rows.Scan(&t.Id, &t.Name, &t.Description, &t.Status, &t.DoneAt, &t.Estimate, &t.SessionId, &t.CreatedAt); err != nil {
+			return err
+		}
+		if err := fn(t); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// StreamGoals calls fn for every matching goal. The date range applies to due_at,
+// falling back to created_at for goals without a deadline.
+func StreamGoals(db DBTX, userID int, filter ExportFilter, fn func(Goal) error) error {
+	where, args := filter.where(userID, "COALESCE(due_at, created_at)", "status")
+	rows, err := db.Query(
+		`SELECT id, name, description, status, done_at, due_at, created_at
+		   FROM goals`+where+` ORDER BY id`,
+		args...,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var g Goal
+		if err := rows.Scan(&g.Id, &g.Name, &g.Description, &g.Status, &g.DoneAt, &g.DueAt, &g.CreatedAt); err != nil {
+			return err
+		}
+		if err := fn(g); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// StreamWorkSessions calls fn for every matching session. The date range applies
+// to start_time; status is "active" for running sessions and "finished" otherwise.
+func StreamWorkSessions(db DBTX, userID int, filter ExportFilter, fn func(WorkSession) error) error {
+	where, args := filter.where(userID, "start_time",
+		"CASE WHEN end_time IS NULL THEN 'active' ELSE 'finished' END")
+	rows, err := db.Query(
+		`SELECT id, start_time, end_time, created_at
+		   FROM work_sessions`+where+` ORDER BY start_time`,
+		args...,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ws WorkSession
+		if err := rows.Scan(&ws.Id, &ws.StartTime, &ws.EndTime, &ws.CreatedAt); err != nil {
+			return err
+		}
+		ws.Status = "finished"
+		if !ws.EndTime.Valid {
+			ws.Status = "active"
+		}
+		if err := fn(ws); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
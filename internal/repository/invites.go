@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Invites, like sessions, are stored by the SHA-256 of their token.
+
+func CreateInvite(db DBTX, tokenHash string, createdBy int, expiresAt time.Time) error {
+	_, err := db.Exec(
+		"INSERT INTO invites (token_hash, created_by, expires_at) VALUES ($1, $2, $3)",
+		tokenHash, createdBy, expiresAt,
+	)
+	return err
+}
+
+// ListInvites returns the invites createdBy issued, newest first.
+func ListInvites(db DBTX, createdBy int) ([]Invite, error) {
+	rows, err := db.Query(
+		`SELECT i.created_by, i.created_at, i.expires_at, a.login, i.used_at
+		   FROM invites i
+		   LEFT JOIN admin a ON a.id = i.used_by
+		  WHERE i.created_by = $1
+		  ORDER BY i.created_at DESC`,
+		createdBy,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var invites []Invite
+	for rows.Next() {
+		var inv Invite
+		if err := rows.Scan(&inv.CreatedBy, &inv.CreatedAt, &inv.ExpiresAt, &inv.UsedBy, &inv.UsedAt); err != nil {
+			return nil, err
+		}
+		invites = append(invites, inv)
+	}
+	return invites, rows.Err()
+}
+
+// InviteValid reports whether tokenHash is an unused, unexpired invite.
+func InviteValid(db DBTX, tokenHash string) (bool, error) {
+	var valid bool
+	err := db.QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM invites WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW())",
+		tokenHash,
+	).Scan(&valid)
+	return valid, err
+}
+
+// RegisterWithInvite creates the account login and uses up the invite in one
+// transaction. It reports false, creating nothing, when the invite is unknown,
+// used or expired.
+func RegisterWithInvite(db DBTX, tokenHash, login string, hash []byte) (bool, error) {
+	registered := false
+	err := inTx(db, func(tx *sql.Tx) error {
+		res, err := tx.Exec(
+			"UPDATE invites SET used_at = NOW() WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()",
+			tokenHash,
+		)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			return err
+		}
+
+		var id int
+		err = tx.QueryRow(
+			"INSERT INTO admin (login, password_hash, password_changed_at) VALUES ($1, $2, NOW()) RETURNING id",
+			login, string(hash),
+		).Scan(&id)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec("UPDATE invites SET used_by = $1 WHERE token_hash = $2", id, tokenHash); err != nil {
+			return err
+		}
+		registered = true
+		return nil
+	})
+	return registered, err
+}
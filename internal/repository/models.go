@@ -11,7 +11,10 @@ type Task struct {
 	Description string
 	Status      string
 	DoneAt      sql.NullTime
+	Estimate    sql.NullInt64 // minutes
+	SessionId   sql.NullInt64
 	CreatedAt   time.Time
+	Visibility  sql.NullString // NULL follows the account's default
 }
 
 type WorkSession struct {
@@ -27,10 +30,46 @@ type WorkSession struct {
 }
 
 type Admin struct {
-	Id           int
-	Login        string
-	PasswordHash string
-	CreatedAt    time.Time
+	Id                 int
+	Login              string
+	PasswordHash       string
+	MustChangePassword bool
+	TOTPEnabled        bool
+	CreatedAt          time.Time
+}
+
+type AdminTOTP struct {
+	Secret      string // empty when no enrollment was started
+	Enabled     bool
+	LastCounter int64 // last time step accepted, to refuse replays
+}
+
+type AdminSession struct {
+	AdminId            int
+	Login              string
+	MustChangePassword bool
+	Owner              bool // the first account, which runs the instance
+	ExpiresAt          time.Time
+	AccountId          int // the account the session acts for
+	AccountLogin       string
+	Role               string // on AccountId; empty if access was taken away
+}
+
+// AccountAccess is a role UserId has on the tracker of AccountId.
+type AccountAccess struct {
+	AccountId int
+	UserId    int
+	Login     string // of the other side of the grant
+	Role      string
+	GrantedAt time.Time
+}
+
+type Invite struct {
+	CreatedBy int
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedBy    sql.NullString // login of the account it created
+	UsedAt    sql.NullTime
 }
 
 type Goal struct {
@@ -41,4 +80,40 @@ type Goal struct {
 	DoneAt      sql.NullTime
 	DueAt       sql.NullTime
 	CreatedAt   time.Time
+	Visibility  sql.NullString // NULL follows the account's default
+}
+
+type Team struct {
+	Id        int
+	Name      string
+	Role      string // the role of the user the team was looked up for
+	CreatedAt time.Time
+}
+
+type TeamMember struct {
+	UserId   int
+	Login    string
+	Role     string
+	JoinedAt time.Time
+}
+
+type Project struct {
+	Id        int
+	TeamId    int
+	Name      string
+	CreatedAt time.Time
+}
+
+// SharedTask is a task that belongs to a team project instead of a user.
+type SharedTask struct {
+	Task
+	Project string
+	DoneBy  sql.NullString // login of the member who completed it
+}
+
+// SharedGoal is a goal that belongs to a team project instead of a user.
+type SharedGoal struct {
+	Goal
+	Project string
+	DoneBy  sql.NullString // login of the member who completed it
 }
@@ -0,0 +1,251 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+)
+
+// migrations are applied in order and recorded in schema_migrations.
+// Never edit an entry once it has shipped; append a new one instead.
+var migrations = []string{
+	// 1: base tables
+	`CREATE TABLE IF NOT EXISTS admin (
+		id            SERIAL PRIMARY KEY,
+		login         TEXT NOT NULL UNIQUE,
+		password_hash TEXT NOT NULL,
+		created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+	CREATE TABLE IF NOT EXISTS work_sessions (
+		id         SERIAL PRIMARY KEY,
+		start_time TIMESTAMPTZ NOT NULL,
+		end_time   TIMESTAMPTZ,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+	CREATE TABLE IF NOT EXISTS tasks (
+		id          SERIAL PRIMARY KEY,
+		name        TEXT NOT NULL,
+		description TEXT NOT NULL DEFAULT '',
+		status      TEXT NOT NULL DEFAULT 'todo',
+		done_at     TIMESTAMPTZ,
+		session_id  INT REFERENCES work_sessions(id),
+		created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+	CREATE TABLE IF NOT EXISTS goals (
+		id          SERIAL PRIMARY KEY,
+		name        TEXT NOT NULL,
+		description TEXT NOT NULL DEFAULT '',
+		status      TEXT NOT NULL DEFAULT 'todo',
+		done_at     TIMESTAMPTZ,
+		due_at      TIMESTAMPTZ,
+		created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);`,
+
+	// 2: key/value settings
+	`CREATE TABLE IF NOT EXISTS settings (
+		key   TEXT PRIMARY KEY,
+		value TEXT NOT NULL
+	);`,
+
+	// 3: task time estimates
+	`ALTER TABLE tasks ADD COLUMN IF NOT EXISTS estimate_minutes INT;`,
+
+	// 4: server-side admin sessions and forced password changes
+	`ALTER TABLE admin ADD COLUMN IF NOT EXISTS must_change_password BOOLEAN NOT NULL DEFAULT FALSE;
+	ALTER TABLE admin ADD COLUMN IF NOT EXISTS password_changed_at TIMESTAMPTZ;
+	CREATE TABLE IF NOT EXISTS admin_sessions (
+		token_hash TEXT PRIMARY KEY,
+		admin_id   INT NOT NULL REFERENCES admin(id) ON DELETE CASCADE,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		expires_at TIMESTAMPTZ NOT NULL
+	);`,
+
+	// 5: TOTP two-factor authentication
+	`ALTER TABLE admin ADD COLUMN IF NOT EXISTS totp_secret TEXT;
+	ALTER TABLE admin ADD COLUMN IF NOT EXISTS totp_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+	ALTER TABLE admin ADD COLUMN IF NOT EXISTS totp_last_counter BIGINT NOT NULL DEFAULT 0;
+	ALTER TABLE admin_sessions ADD COLUMN IF NOT EXISTS totp_pending BOOLEAN NOT NULL DEFAULT FALSE;
+	CREATE TABLE IF NOT EXISTS admin_recovery_codes (
+		admin_id  INT NOT NULL REFERENCES admin(id) ON DELETE CASCADE,
+		code_hash TEXT NOT NULL,
+		PRIMARY KEY (admin_id, code_hash)
+	);`,
+
+	// 6: per-user ownership of tracker data, and invites. Existing rows go to
+	// the first account; settings saved before any account existed are dropped.
+	`ALTER TABLE tasks ADD COLUMN IF NOT EXISTS user_id INT REFERENCES admin(id) ON DELETE CASCADE;
+	ALTER TABLE goals ADD COLUMN IF NOT EXISTS user_id INT REFERENCES admin(id) ON DELETE CASCADE;
+	ALTER TABLE work_sessions ADD COLUMN IF NOT EXISTS user_id INT REFERENCES admin(id) ON DELETE CASCADE;
+	UPDATE tasks SET user_id = (SELECT MIN(id) FROM admin) WHERE user_id IS NULL;
+	UPDATE goals SET user_id = (SELECT MIN(id) FROM admin) WHERE user_id IS NULL;
+	UPDATE work_sessions SET user_id = (SELECT MIN(id) FROM admin) WHERE user_id IS NULL;
+	CREATE INDEX IF NOT EXISTS tasks_user_status_idx ON tasks (user_id, status);
+	CREATE INDEX IF NOT EXISTS goals_user_idx ON goals (user_id);
+	CREATE INDEX IF NOT EXISTS work_sessions_user_start_idx ON work_sessions (user_id, start_time);
+	ALTER TABLE settings ADD COLUMN IF NOT EXISTS user_id INT REFERENCES admin(id) ON DELETE CASCADE;
+	UPDATE settings SET user_id = (SELECT MIN(id) FROM admin) WHERE user_id IS NULL;
+	DELETE FROM settings WHERE user_id IS NULL;
+	ALTER TABLE settings DROP CONSTRAINT IF EXISTS settings_pkey;
+	ALTER TABLE settings ALTER COLUMN user_id SET NOT NULL;
+	ALTER TABLE settings ADD PRIMARY KEY (user_id, key);
+	CREATE TABLE IF NOT EXISTS invites (
+		token_hash TEXT PRIMARY KEY,
+		created_by INT NOT NULL REFERENCES admin(id) ON DELETE CASCADE,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		expires_at TIMESTAMPTZ NOT NULL,
+		used_by    INT REFERENCES admin(id) ON DELETE SET NULL,
+		used_at    TIMESTAMPTZ
+	);`,
+
+	// 7: teams, their projects, and tasks and goals shared through a project.
+	// Shared items have no user_id; done_by records who completed them.
+	`CREATE TABLE IF NOT EXISTS teams (
+		id         SERIAL PRIMARY KEY,
+		name       TEXT NOT NULL UNIQUE,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);
+	CREATE TABLE IF NOT EXISTS team_members (
+		team_id   INT NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+		user_id   INT NOT NULL REFERENCES admin(id) ON DELETE CASCADE,
+		role      TEXT NOT NULL CHECK (role IN ('owner', 'member', 'viewer')),
+		joined_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		PRIMARY KEY (team_id, user_id)
+	);
+	CREATE INDEX IF NOT EXISTS team_members_user_idx ON team_members (user_id);
+	CREATE TABLE IF NOT EXISTS projects (
+		id         SERIAL PRIMARY KEY,
+		team_id    INT NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+		name       TEXT NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		UNIQUE (team_id, name)
+	);
+	ALTER TABLE tasks ADD COLUMN IF NOT EXISTS project_id INT REFERENCES projects(id) ON DELETE CASCADE;
+	ALTER TABLE tasks ADD COLUMN IF NOT EXISTS created_by INT REFERENCES admin(id) ON DELETE SET NULL;
+	ALTER TABLE tasks ADD COLUMN IF NOT EXISTS done_by INT REFERENCES admin(id) ON DELETE SET NULL;
+	ALTER TABLE goals ADD COLUMN IF NOT EXISTS project_id INT REFERENCES projects(id) ON DELETE CASCADE;
+	ALTER TABLE goals ADD COLUMN IF NOT EXISTS created_by INT REFERENCES admin(id) ON DELETE SET NULL;
+	ALTER TABLE goals ADD COLUMN IF NOT EXISTS done_by INT REFERENCES admin(id) ON DELETE SET NULL;
+	CREATE INDEX IF NOT EXISTS tasks_project_idx ON tasks (project_id) WHERE project_id IS NOT NULL;
+	CREATE INDEX IF NOT EXISTS goals_project_idx ON goals (project_id) WHERE project_id IS NOT NULL;`,
+
+	// 8: access one account gives other accounts to its tracker, and the
+	// account a session currently acts for (NULL for its own).
+	`CREATE TABLE IF NOT EXISTS account_access (
+		account_id INT NOT NULL REFERENCES admin(id) ON DELETE CASCADE,
+		user_id    INT NOT NULL REFERENCES admin(id) ON DELETE CASCADE,
+		role       TEXT NOT NULL CHECK (role IN ('viewer', 'editor', 'owner')),
+		granted_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		PRIMARY KEY (account_id, user_id),
+		CHECK (account_id <> user_id)
+	);
+	CREATE INDEX IF NOT EXISTS account_access_user_idx ON account_access (user_id);
+	ALTER TABLE admin_sessions ADD COLUMN IF NOT EXISTS acting_as INT REFERENCES admin(id) ON DELETE SET NULL;`,
+
+	// 9: who sees each task and goal on the public pages. NULL follows the
+	// account's default, so existing rows stay as public as they were.
+	`ALTER TABLE tasks ADD COLUMN IF NOT EXISTS visibility TEXT
+		CHECK (visibility IN ('public', 'private', 'redacted-title'));
+	ALTER TABLE goals ADD COLUMN IF NOT EXISTS visibility TEXT
+		CHECK (visibility IN ('public', 'private', 'redacted-title'));`,
+
+	// 10: append-only log of every change. Accounts are kept by id and login
+	// without foreign keys, so entries outlive the accounts they name; the
+	// trigger refuses to change or remove an entry once written.
+	`CREATE TABLE IF NOT EXISTS audit_log (
+		id          BIGSERIAL PRIMARY KEY,
+		at          TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		account_id  INT NOT NULL,
+		actor_id    INT,
+		actor_login TEXT NOT NULL DEFAULT '',
+		ip          TEXT NOT NULL DEFAULT '',
+		action      TEXT NOT NULL,
+		entity      TEXT NOT NULL,
+		entity_id   TEXT NOT NULL DEFAULT '',
+		before_json TEXT,
+		after_json  TEXT
+	);
+	CREATE INDEX IF NOT EXISTS audit_log_account_idx ON audit_log (account_id, id);
+	CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+	BEGIN
+		RAISE EXCEPTION 'audit_log is append-only';
+	END;
+	$$ LANGUAGE plpgsql;
+	DROP TRIGGER IF EXISTS audit_log_append_only ON audit_log;
+	CREATE TRIGGER audit_log_append_only BEFORE UPDATE OR DELETE ON audit_log
+		FOR EACH ROW EXECUTE PROCEDURE audit_log_append_only();`,
+
+	// 11: tokens that revert the change an audit log entry records, for a
+	// short while after it was made. Stored by the SHA-256 of the token.
+	`CREATE TABLE IF NOT EXISTS undo_tokens (
+		token_hash TEXT PRIMARY KEY,
+		account_id INT NOT NULL REFERENCES admin(id) ON DELETE CASCADE,
+		audit_id   BIGINT NOT NULL,
+		expires_at TIMESTAMPTZ NOT NULL,
+		used_at    TIMESTAMPTZ
+	);`,
+}
+
+// Migrate brings the schema up to date. It is safe to run on every boot.
+func Migrate(db *sql.DB) error {
+	return MigrateTo(db, len(migrations))
+}
+
+// MigrateTo applies migrations up to and including target. A database already
+// past target is left alone.
+func MigrateTo(db *sql.DB, target int) error {
+	if target > len(migrations) {
+		return fmt.Errorf("schema version %d is newer than this build (%d)", target, len(migrations))
+	}
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INT PRIMARY KEY,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`); err != nil {
+		return err
+	}
+
+	current, err := SchemaVersion(db)
+	if err != nil {
+		return err
+	}
+
+	for i := current; i < target; i++ {
+		version := i + 1
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(migrations[i]); err != nil {
+			tx.Rollback()
+			log.Printf("migration %d failed: %v", version, err)
+			return err
+		}
+		if _, err := tx.Exec("INSERT INTO schema_migrations (version) VALUES ($1)", version); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		log.Printf("applied migration %d", version)
+	}
+	return nil
+}
+
+// SchemaVersion returns the highest applied migration, or 0 for a fresh database.
+func SchemaVersion(db *sql.DB) (int, error) {
+	var migrated bool
+	if err := db.QueryRow("SELECT to_regclass('schema_migrations') IS NOT NULL").Scan(&migrated); err != nil || !migrated {
+		return 0, err
+	}
+	var version sql.NullInt64
+	err := db.QueryRow("SELECT MAX(version) FROM schema_migrations").Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+	return int(version.Int64), nil
+}
+
+// LatestSchemaVersion is the version Migrate brings a database to.
+func LatestSchemaVersion() int {
+	return len(migrations)
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Sessions are looked up by the SHA-256 of their token, so the table alone cannot
+// be used to log in.
+
+// CreateAdminSession stores a session. A totpPending session has only passed the
+// password check and is waiting for the second factor.
+func CreateAdminSession(db DBTX, tokenHash string, adminID int, expiresAt time.Time, totpPending bool) error {
+	_, err := db.Exec(
+		"INSERT INTO admin_sessions (token_hash, admin_id, expires_at, totp_pending) VALUES ($1, $2, $3, $4)",
+		tokenHash, adminID, expiresAt, totpPending,
+	)
+	return err
+}
+
+// GetAdminSession returns the unexpired, fully logged in session with tokenHash and its admin.
+func GetAdminSession(db DBTX, tokenHash string) (AdminSession, bool, error) {
+	return getAdminSession(db, tokenHash, false)
+}
+
+// GetPendingAdminSession returns the unexpired session with tokenHash that still
+// waits for a TOTP code.
+func GetPendingAdminSession(db DBTX, tokenHash string) (AdminSession, bool, error) {
+	return getAdminSession(db, tokenHash, true)
+}
+
+func getAdminSession(db DBTX, tokenHash string, totpPending bool) (AdminSession, bool, error) {
+	var s AdminSession
+	err := db.QueryRow(
+		`SELECT a.id, a.login, a.must_change_password, a.id = (SELECT MIN(id) FROM admin), s.expires_at,
+		        acct.id, acct.login, CASE WHEN acct.id = a.id THEN 'owner' ELSE COALESCE(g.role, '') END
+		   FROM admin_sessions s
+		   JOIN admin a ON a.id = s.admin_id
+		   JOIN admin acct ON acct.id = COALESCE(s.acting_as, s.admin_id)
+		   LEFT JOIN account_access g ON g.account_id = acct.id AND g.user_id = a.id
+		  WHERE s.token_hash = $1 AND s.expires_at > NOW() AND s.totp_pending = $2`,
+		tokenHash, totpPending,
+	).Scan(&s.AdminId, &s.Login, &s.MustChangePassword, &s.Owner, &s.ExpiresAt,
+		&s.AccountId, &s.AccountLogin, &s.Role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return s, false, nil
+	}
+	return s, err == nil, err
+}
+
+// SetSessionAccount makes the session tokenHash act for accountID, or for its
+// own account when accountID is not valid.
+func SetSessionAccount(db DBTX, tokenHash string, accountID sql.NullInt64) error {
+	_, err := db.Exec("UPDATE admin_sessions SET acting_as = $2 WHERE token_hash = $1", tokenHash, accountID)
+	return err
+}
+
+func DeleteAdminSession(db DBTX, tokenHash string) error {
+	_, err := db.Exec("DELETE FROM admin_sessions WHERE token_hash = $1", tokenHash)
+	return err
+}
+
+// DeleteOtherAdminSessions logs an admin out everywhere except the session keepHash.
+func DeleteOtherAdminSessions(db DBTX, adminID int, keepHash string) error {
+	_, err := db.Exec("DELETE FROM admin_sessions WHERE admin_id = $1 AND token_hash <> $2", adminID, keepHash)
+	return err
+}
+
+// DeleteAdminSessions logs an admin out everywhere.
+func DeleteAdminSessions(db DBTX, adminID int) error {
+	_, err := db.Exec("DELETE FROM admin_sessions WHERE admin_id = $1", adminID)
+	return err
+}
+
+func DeleteExpiredAdminSessions(db DBTX) error {
+	_, err := db.Exec("DELETE FROM admin_sessions WHERE expires_at <= NOW()")
+	return err
+}
+
+func GetAdminPasswordHash(db DBTX, adminID int) (string, error) {
+	var hash string
+	err := db.QueryRow("SELECT password_hash FROM admin WHERE id = $1", adminID).Scan(&hash)
+	return hash, err
+}
+
+// UpdateAdminPassword stores a new hash for adminID and clears a forced change.
+func UpdateAdminPassword(db DBTX, adminID int, hash []byte) error {
+	_, err := db.Exec(
+		"UPDATE admin SET password_hash = $1, must_change_password = FALSE, password_changed_at = NOW() WHERE id = $2",
+		string(hash), adminID,
+	)
+	return err
+}
+
+func SetMustChangePassword(db DBTX, adminID int) error {
+	_, err := db.Exec("UPDATE admin SET must_change_password = TRUE WHERE id = $1", adminID)
+	return err
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// GetSetting returns the value userID stored for key and whether it was present.
+func GetSetting(db DBTX, userID int, key string) (string, bool, error) {
+	var value string
+	err := db.QueryRow("SELECT value FROM settings WHERE user_id = $1 AND key = $2", userID, key).Scan(&value)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	return value, true, nil
+}
+
+func SetSetting(db DBTX, userID int, key, value string) error {
+	_, err := db.Exec(
+		`INSERT INTO settings (user_id, key, value) VALUES ($1, $2, $3)
+		 ON CONFLICT (user_id, key) DO UPDATE SET value = EXCLUDED.value`,
+		userID, key, value,
+	)
+	return err
+}
+
+// FindSettingOwner returns the account whose key is set to value, with its id
+// and login, for looking up the account a token belongs to.
+func FindSettingOwner(db DBTX, key, value string) (Admin, bool, error) {
+	var a Admin
+	err := db.QueryRow(
+		"SELECT a.id, a.login FROM settings s JOIN admin a ON a.id = s.user_id WHERE s.key = $1 AND s.value = $2",
+		key, value,
+	).Scan(&a.Id, &a.Login)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Admin{}, false, nil
+		}
+		return Admin{}, false, err
+	}
+	return a, true, nil
+}
@@ -0,0 +1,339 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// CreateTeam adds a team with ownerID as its first owner and returns its id.
+func CreateTeam(db DBTX, name string, ownerID int) (int, error) {
+	var id int
+	err := inTx(db, func(tx *sql.Tx) error {
+		if err := tx.QueryRow("INSERT INTO teams (name) VALUES ($1) RETURNING id", name).Scan(&id); err != nil {
+			return err
+		}
+		_, err := tx.Exec(
+			"INSERT INTO team_members (team_id, user_id, role) VALUES ($1, $2, 'owner')",
+			id, ownerID,
+		)
+		return err
+	})
+	return id, err
+}
+
+// TeamNameTaken reports whether a team called name exists.
+func TeamNameTaken(db DBTX, name string) (bool, error) {
+	var taken bool
+	err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM teams WHERE name = $1)", name).Scan(&taken)
+	return taken, err
+}
+
+// ListTeamsForUser returns the teams userID belongs to with their role, by name.
+func ListTeamsForUser(db DBTX, userID int) ([]Team, error) {
+	rows, err := db.Query(
+		`SELECT t.id, t.name, m.role, t.created_at
+		   FROM teams t
+		   JOIN team_members m ON m.team_id = t.id
+		  WHERE m.user_id = $1
+		  ORDER BY t.name`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var teams []Team
+	for rows.Next() {
+		var t Team
+		if err := rows.Scan(&t.Id, &t.Name, &t.Role, &t.CreatedAt); err != nil {
+			return nil, err
+		}
+		teams = append(teams, t)
+	}
+	return teams, rows.Err()
+}
+
+// GetTeamForUser returns the team called name with userID's role in it. The
+// Team has a zero Id when there is no such team or userID is not a member.
+func GetTeamForUser(db DBTX, name string, userID int) (Team, error) {
+	var t Team
+	err := db.QueryRow(
+		`SELECT t.id, t.name, m.role, t.created_at
+		   FROM teams t
+		   JOIN team_members m ON m.team_id = t.id
+		  WHERE t.name = $1 AND m.user_id = $2`,
+		name, userID,
+	).Scan(&t.Id, &t.Name, &t.Role, &t.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Team{}, nil
+	}
+	return t, err
+}
+
+// ListTeamMembers returns the members of a team, owners first.
+func ListTeamMembers(db DBTX, teamID int) ([]TeamMember, error) {
+	rows, err := db.Query(
+		`SELECT m.user_id, a.login, m.role, m.joined_at
+		   FROM team_members m
+		   JOIN admin a ON a.id = m.user_id
+		  WHERE m.team_id = $1
+		  ORDER BY CASE m.role WHEN 'owner' THEN 0 WHEN 'member' THEN 1 ELSE 2 END, a.login`,
+		teamID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []TeamMember
+	for rows.Next() {
+		var m TeamMember
+		if err := rows.Scan(&m.UserId, &m.Login, &m.Role, &m.JoinedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+// AddTeamMember adds userID to a team, or changes their role if they are in it.
+func AddTeamMember(db DBTX, teamID, userID int, role string) error {
+	_, err := db.Exec(
+		`INSERT INTO team_members (team_id, user_id, role) VALUES ($1, $2, $3)
+		 ON CONFLICT (team_id, user_id) DO UPDATE SET role = EXCLUDED.role`,
+		teamID, userID, role,
+	)
+	return err
+}
+
+// RemoveTeamMember takes userID out of a team. It reports false when they were not in it.
+func RemoveTeamMember(db DBTX, teamID, userID int) (bool, error) {
+	res, err := db.Exec("DELETE FROM team_members WHERE team_id = $1 AND user_id = $2", teamID, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func CreateProject(db DBTX, teamID int, name string) error {
+	_, err := db.Exec("INSERT INTO projects (team_id, name) VALUES ($1, $2)", teamID, name)
+	return err
+}
+
+// ListProjects returns the projects of a team by name.
+func ListProjects(db DBTX, teamID int) ([]Project, error) {
+	rows, err := db.Query("SELECT id, team_id, name, created_at FROM projects WHERE team_id = $1 ORDER BY name", teamID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var projects []Project
+	for rows.Next() {
+		var p Project
+		if err := rows.Scan(&p.Id, &p.TeamId, &p.Name, &p.CreatedAt); err != nil {
+			return nil, err
+		}
+		projects = append(projects, p)
+	}
+	return projects, rows.Err()
+}
+
+// ProjectInTeam reports whether projectID belongs to teamID.
+func ProjectInTeam(db DBTX, teamID, projectID int) (bool, error) {
+	var found bool
+	err := db.QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM projects WHERE id = $1 AND team_id = $2)",
+		projectID, teamID,
+	).Scan(&found)
+	return found, err
+}
+
+// Shared tasks and goals have a project_id and no user_id, so the per-user
+// queries never see them.
+
+func AddSharedTask(db DBTX, projectID, createdBy int, name, description string, estimate sql.NullInt64) error {
+	_, err := db.Exec(
+		`INSERT INTO tasks (project_id, created_by, name, description, status, estimate_minutes)
+		 VALUES ($1, $2, $3, $4, 'todo', $5)`,
+		projectID, createdBy, name, description, estimate,
+	)
+	return err
+}
+
+// ListSharedTasks returns the tasks of a team's projects with status, the open
+// ones oldest first and the done ones newest first, at most limit of them or
+// all when limit is negative.
+func ListSharedTasks(db DBTX, teamID int, status string, limit int) ([]SharedTask, error) {
+	order := "t.created_at, t.id"
+	if status == "done" {
+		order = "t.done_at DESC, t.id DESC"
+	}
+	rows, err := db.Query(
+		`SELECT t.id, t.name, t.description, t.status, t.done_at, t.estimate_minutes, t.created_at, p.name, a.login
+		   FROM tasks t
+		   JOIN projects p ON p.id = t.project_id
+		   LEFT JOIN admin a ON a.id = t.done_by
+		  WHERE p.team_id = $1 AND t.status = $2
+		  ORDER BY `+order+`
+		  LIMIT $3`,
+		teamID, status, sqlLimit(limit),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tasks []SharedTask
+	for rows.Next() {
+		var t SharedTask
+		if err := rows.Scan(&t.Id, &t.Name, &t.Description, &t.Status, &t.DoneAt, &t.Estimate, &t.CreatedAt,
+			&t.Project, &t.DoneBy); err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks, rows.Err()
+}
+
+// CompleteSharedTask marks an open task of a team's projects done by doneBy,
+// linking it to doneBy's running session if there is one. It reports false
+// when there is no such open task.
+func CompleteSharedTask(db DBTX, teamID, taskID, doneBy int) (bool, error) {
+	res, err := db.Exec(
+		`UPDATE tasks
+		    SET status = 'done', done_at = NOW(), done_by = $3,
+		        session_id = (SELECT id FROM work_sessions WHERE user_id = $3 AND end_time IS NULL
+		                       ORDER BY start_time DESC LIMIT 1)
+		  WHERE id = $2 AND status = 'todo'
+		    AND project_id IN (SELECT id FROM projects WHERE team_id = $1)`,
+		teamID, taskID, doneBy,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func CreateSharedGoal(db DBTX, projectID, createdBy int, name, description string, dueAt time.Time) error {
+	_, err := db.Exec(
+		"INSERT INTO goals (project_id, created_by, name, description, due_at) VALUES ($1, $2, $3, $4, $5)",
+		projectID, createdBy, name, description, dueAt,
+	)
+	return err
+}
+
+// ListSharedGoals returns the goals of a team's projects with status, the
+// open ones by due date and the done ones newest first, at most limit of them
+// or all when limit is negative.
+func ListSharedGoals(db DBTX, teamID int, status string, limit int) ([]SharedGoal, error) {
+	order := "g.due_at, g.id"
+	if status == "done" {
+		order = "g.done_at DESC, g.id DESC"
+	}
+	rows, err := db.Query(
+		`SELECT g.id, g.name, g.description, g.status, g.done_at, g.due_at, g.created_at, p.name, a.login
+		   FROM goals g
+		   JOIN projects p ON p.id = g.project_id
+		   LEFT JOIN admin a ON a.id = g.done_by
+		  WHERE p.team_id = $1 AND g.status = $2
+		  ORDER BY `+order+`
+		  LIMIT $3`,
+		teamID, status, sqlLimit(limit),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var goals []SharedGoal
+	for rows.Next() {
+		var g SharedGoal
+		if err := rows.Scan(&g.Id, &g.Name, &g.Description, &g.Status, &g.DoneAt, &g.DueAt, &g.CreatedAt,
+			&g.Project, &g.DoneBy); err != nil {
+			return nil, err
+		}
+		goals = append(goals, g)
+	}
+	return goals, rows.Err()
+}
+
+// CompleteSharedGoal marks an open goal of a team's projects done by doneBy.
+// It reports false when there is no such open goal.
+func CompleteSharedGoal(db DBTX, teamID, goalID, doneBy int) (bool, error) {
+	res, err := db.Exec(
+		`UPDATE goals SET status = 'done', done_at = NOW(), done_by = $3
+		  WHERE id = $2 AND status = 'todo'
+		    AND project_id IN (SELECT id FROM projects WHERE team_id = $1)`,
+		teamID, goalID, doneBy,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// GetTeamWorkSessions returns the finished sessions of every team member that
+// started in [start, end), keyed by member.
+func GetTeamWorkSessions(db DBTX, teamID int, start, end time.Time) (map[int][]WorkSession, error) {
+	rows, err := db.Query(
+		`SELECT s.user_id, s.start_time, s.end_time
+		   FROM work_sessions s
+		   JOIN team_members m ON m.user_id = s.user_id
+		  WHERE m.team_id = $1 AND s.start_time >= $2 AND s.start_time < $3 AND s.end_time IS NOT NULL`,
+		teamID, start, end,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := make(map[int][]WorkSession)
+	for rows.Next() {
+		var userID int
+		var ws WorkSession
+		if err := rows.Scan(&userID, &ws.StartTime, &ws.EndTime); err != nil {
+			return nil, err
+		}
+		sessions[userID] = append(sessions[userID], ws)
+	}
+	return sessions, rows.Err()
+}
+
+// CountTeamTasksDone counts, for every team member, the tasks they completed in
+// [start, end): their own tasks and the ones of this team's projects.
+func CountTeamTasksDone(db DBTX, teamID int, start, end time.Time) (map[int]int, error) {
+	rows, err := db.Query(
+		`SELECT m.user_id, COUNT(*)
+		   FROM team_members m
+		   JOIN tasks t ON COALESCE(t.done_by, t.user_id) = m.user_id
+		  WHERE m.team_id = $1 AND t.status = 'done' AND t.done_at >= $2 AND t.done_at < $3
+		    AND (t.project_id IS NULL OR t.project_id IN (SELECT id FROM projects WHERE team_id = $1))
+		  GROUP BY m.user_id`,
+		teamID, start, end,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[int]int)
+	for rows.Next() {
+		var userID, n int
+		if err := rows.Scan(&userID, &n); err != nil {
+			return nil, err
+		}
+		counts[userID] = n
+	}
+	return counts, rows.Err()
+}
+
+// sqlLimit turns a negative limit into NULL, which LIMIT reads as no limit.
+func sqlLimit(limit int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(limit), Valid: limit >= 0}
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"database/sql"
+)
+
+func GetAdminTOTP(db DBTX, adminID int) (AdminTOTP, error) {
+	var t AdminTOTP
+	err := db.QueryRow(
+		"SELECT COALESCE(totp_secret, ''), totp_enabled, totp_last_counter FROM admin WHERE id = $1",
+		adminID,
+	).Scan(&t.Secret, &t.Enabled, &t.LastCounter)
+	return t, err
+}
+
+// SetAdminTOTPSecret starts an enrollment. It does nothing once TOTP is enabled.
+func SetAdminTOTPSecret(db DBTX, adminID int, secret string) error {
+	_, err := db.Exec(
+		"UPDATE admin SET totp_secret = $1 WHERE id = $2 AND NOT totp_enabled",
+		secret, adminID,
+	)
+	return err
+}
+
+// EnableAdminTOTP turns on the enrolled secret and replaces the recovery codes.
+func EnableAdminTOTP(db DBTX, adminID int, counter int64, recoveryHashes []string) error {
+	return inTx(db, func(tx *sql.Tx) error {
+		if _, err := tx.Exec(
+			"UPDATE admin SET totp_enabled = TRUE, totp_last_counter = $1 WHERE id = $2",
+			counter, adminID,
+		); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM admin_recovery_codes WHERE admin_id = $1", adminID); err != nil {
+			return err
+		}
+		for _, h := range recoveryHashes {
+			if _, err := tx.Exec(
+				"INSERT INTO admin_recovery_codes (admin_id, code_hash) VALUES ($1, $2)",
+				adminID, h,
+			); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DisableAdminTOTP forgets the secret and the recovery codes of adminID.
+func DisableAdminTOTP(db DBTX, adminID int) error {
+	return inTx(db, func(tx *sql.Tx) error {
+		if _, err := tx.Exec(
+			"UPDATE admin SET totp_secret = NULL, totp_enabled = FALSE, totp_last_counter = 0 WHERE id = $1",
+			adminID,
+		); err != nil {
+			return err
+		}
+		_, err := tx.Exec("DELETE FROM admin_recovery_codes WHERE admin_id = $1", adminID)
+		return err
+	})
+}
+
+// UseTOTPCounter records counter as used and reports false if it, or a later
+// step, was accepted before.
+func UseTOTPCounter(db DBTX, adminID int, counter int64) (bool, error) {
+	res, err := db.Exec(
+		"UPDATE admin SET totp_last_counter = $1 WHERE id = $2 AND totp_last_counter < $1",
+		counter, adminID,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// UseRecoveryCode deletes the recovery code and reports whether it existed.
+func UseRecoveryCode(db DBTX, adminID int, codeHash string) (bool, error) {
+	res, err := db.Exec(
+		"DELETE FROM admin_recovery_codes WHERE admin_id = $1 AND code_hash = $2",
+		adminID, codeHash,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func CountRecoveryCodes(db DBTX, adminID int) (int, error) {
+	var n int
+	err := db.QueryRow("SELECT COUNT(*) FROM admin_recovery_codes WHERE admin_id = $1", adminID).Scan(&n)
+	return n, err
+}
@@ -0,0 +1,29 @@
+package repository
+
+import "database/sql"
+
+// DBTX is what queries run on: the database, or a transaction that groups a
+// change with its audit log entry. It is a *sql.DB or a *sql.Tx.
+type DBTX interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// inTx runs fn in a transaction: db itself when it is one already, which then
+// commits or rolls back with its caller, or a new one otherwise.
+func inTx(db DBTX, fn func(tx *sql.Tx) error) error {
+	if tx, ok := db.(*sql.Tx); ok {
+		return fn(tx)
+	}
+	tx, err := db.(*sql.DB).Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
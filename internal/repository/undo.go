@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Undo tokens, like invites, are stored by the SHA-256 of their token.
+
+// CreateUndoToken lets tokenHash revert auditID until expiresAt, and clears
+// out the tokens that have expired.
+func CreateUndoToken(db DBTX, tokenHash string, accountID int, auditID int64, expiresAt time.Time) error {
+	if _, err := db.Exec("DELETE FROM undo_tokens WHERE expires_at < NOW()"); err != nil {
+		return err
+	}
+	_, err := db.Exec(
+		"INSERT INTO undo_tokens (token_hash, account_id, audit_id, expires_at) VALUES ($1, $2, $3, $4)",
+		tokenHash, accountID, auditID, expiresAt,
+	)
+	return err
+}
+
+// GetUndoToken returns the audit entry an unused, unexpired token of
+// accountID reverts, and whether there is one.
+func GetUndoToken(db DBTX, tokenHash string, accountID int) (AuditEntry, time.Time, bool, error) {
+	var auditID int64
+	var expiresAt time.Time
+	err := db.QueryRow(
+		`SELECT audit_id, expires_at FROM undo_tokens
+		 WHERE token_hash = $1 AND account_id = $2 AND used_at IS NULL AND expires_at > NOW()`,
+		tokenHash, accountID,
+	).Scan(&auditID, &expiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return AuditEntry{}, time.Time{}, false, nil
+		}
+		return AuditEntry{}, time.Time{}, false, err
+	}
+	e, err := GetAuditEntry(db, accountID, auditID)
+	if err != nil {
+		return AuditEntry{}, time.Time{}, false, err
+	}
+	return e, expiresAt, true, nil
+}
+
+// UseUndoToken uses up the token, reporting false when it was unknown, used
+// or expired. Of two concurrent uses only one gets true.
+func UseUndoToken(db DBTX, tokenHash string, accountID int) (bool, error) {
+	res, err := db.Exec(
+		`UPDATE undo_tokens SET used_at = NOW()
+		 WHERE token_hash = $1 AND account_id = $2 AND used_at IS NULL AND expires_at > NOW()`,
+		tokenHash, accountID,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// RestoreTask puts the state of userID's task id back as it was.
+func RestoreTask(db DBTX, userID, id int, status string, doneAt sql.NullTime, sessionID sql.NullInt64) error {
+	_, err := db.Exec(
+		"UPDATE tasks SET status = $1, done_at = $2, session_id = $3 WHERE id = $4 AND user_id = $5",
+		status, doneAt, sessionID, id, userID,
+	)
+	return err
+}
+
+// RestoreGoal puts the state of userID's goal id back as it was.
+func RestoreGoal(db DBTX, userID, id int, status string, doneAt sql.NullTime) error {
+	_, err := db.Exec(
+		"UPDATE goals SET status = $1, done_at = $2 WHERE id = $3 AND user_id = $4",
+		status, doneAt, id, userID,
+	)
+	return err
+}
+
+// DeleteActiveWorkSession removes userID's running session id, as long as no
+// task was completed in it. It reports whether the session was removed.
+func DeleteActiveWorkSession(db DBTX, userID, id int) (bool, error) {
+	res, err := db.Exec(
+		`DELETE FROM work_sessions
+		 WHERE id = $1 AND user_id = $2 AND end_time IS NULL
+		   AND NOT EXISTS (SELECT 1 FROM tasks WHERE session_id = $1)`,
+		id, userID,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// ReopenWorkSession makes userID's ended session id run again, unless another
+// session is running. It reports whether the session was reopened.
+func ReopenWorkSession(db DBTX, userID, id int) (bool, error) {
+	res, err := db.Exec(
+		`UPDATE work_sessions SET end_time = NULL
+		 WHERE id = $1 AND user_id = $2 AND end_time IS NOT NULL
+		   AND NOT EXISTS (SELECT 1 FROM work_sessions WHERE user_id = $2 AND end_time IS NULL)`,
+		id, userID,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
@@ -0,0 +1,106 @@
+package totp
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 key of the RFC 6238 test vectors, "12345678901234567890", in base32.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCode_RFC6238Vectors(t *testing.T) {
+	// The RFC lists 8-digit codes; 6-digit ones are their last six digits.
+	tests := []struct {
+		unix int64
+		want string // the RFC's 8-digit value
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+	for _, tt := range tests {
+		got, err := Code(rfcSecret, Counter(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := tt.want[2:]; got != want {
+			t.Errorf("Code at %d = %s; want %s", tt.unix, got, want)
+		}
+	}
+}
+
+func TestVerify_Skew(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := Counter(now)
+	for offset := int64(-3); offset <= 3; offset++ {
+		code, err := Code(rfcSecret, step+offset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		counter, ok := Verify(rfcSecret, code, now)
+		want := offset >= -Skew && offset <= Skew
+		if ok != want {
+			t.Errorf("code %+d steps away: accepted %v; want %v", offset, ok, want)
+		}
+		// the matched counter is what callers store to refuse a replay
+		if ok && counter != step+offset {
+			t.Errorf("code %+d steps away matched counter %d; want %d", offset, counter, step+offset)
+		}
+	}
+}
+
+func TestVerify_Input(t *testing.T) {
+	now := time.Unix(59, 0)
+	tests := []struct {
+		secret, input string
+		want          bool
+	}{
+		{rfcSecret, "287082", true},
+		{rfcSecret, " 287 082 ", true},
+		{strings.ToLower(rfcSecret), "287082", true},
+		{rfcSecret, "287083", false},
+		{rfcSecret, "94287082", false},
+		{rfcSecret, "", false},
+		{"not base32!", "287082", false},
+	}
+	for _, tt := range tests {
+		if _, ok := Verify(tt.secret, tt.input, now); ok != tt.want {
+			t.Errorf("Verify(%q, %q) = %v; want %v", tt.secret, tt.input, ok, tt.want)
+		}
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := decodeSecret(secret)
+	if err != nil || len(key) != 20 {
+		t.Errorf("secret %q decodes to %d bytes, %v; want 20", secret, len(key), err)
+	}
+	if other, _ := GenerateSecret(); other == secret {
+		t.Error("two secrets are the same")
+	}
+}
+
+func TestURI(t *testing.T) {
+	u, err := url.Parse(URI("abtprj", "alice", rfcSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" || u.Path != "/abtprj:alice" {
+		t.Errorf("URI = %s; want otpauth://totp/abtprj:alice", u)
+	}
+	q := u.Query()
+	for key, want := range map[string]string{"secret": rfcSecret, "issuer": "abtprj", "algorithm": "SHA1", "digits": "6", "period": "30"} {
+		if got := q.Get(key); got != want {
+			t.Errorf("%s = %q; want %q", key, got, want)
+		}
+	}
+}
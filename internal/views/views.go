@@ -0,0 +1,52 @@
+// Package views loads the HTML pages. Every page in the templates directory is
+// parsed together with the layouts in layout/ and the partials in partials/,
+// so pages only define the blocks that differ.
+package views
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"path/filepath"
+)
+
+// Set holds one template per page, keyed by file name.
+type Set map[string]*template.Template
+
+// Load parses dir/layout/*.html and dir/partials/*.html once, then each
+// dir/*.html on top of a clone of them.
+func Load(dir string) (Set, error) {
+	shared, err := template.ParseGlob(filepath.Join(dir, "layout", "*.html"))
+	if err != nil {
+		return nil, err
+	}
+	if shared, err = shared.ParseGlob(filepath.Join(dir, "partials", "*.html")); err != nil {
+		return nil, err
+	}
+
+	pages, err := filepath.Glob(filepath.Join(dir, "*.html"))
+	if err != nil {
+		return nil, err
+	}
+	set := make(Set, len(pages))
+	for _, page := range pages {
+		t, err := shared.Clone()
+		if err != nil {
+			return nil, err
+		}
+		if set[filepath.Base(page)], err = t.ParseFiles(page); err != nil {
+			return nil, err
+		}
+	}
+	return set, nil
+}
+
+// ExecuteTemplate renders the page name, the way *template.Template does for
+// a single set.
+func (s Set) ExecuteTemplate(w io.Writer, name string, data any) error {
+	t, ok := s[name]
+	if !ok {
+		return fmt.Errorf("views: no page %q", name)
+	}
+	return t.ExecuteTemplate(w, name, data)
+}
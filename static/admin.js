@@ -1,14 +1,18 @@
 // static/admin.js
 
+// how long the server keeps an undo token, app.UndoWindow
+const UNDO_WINDOW_MS = 5 * 60 * 1000;
+
 document.addEventListener("DOMContentLoaded", () => {
     const indicator = document.getElementById("working-indicator");
     const button    = document.getElementById("work-toggle-btn");
+    const csrfToken = document.querySelector('meta[name="csrf-token"]').content;
 
     // 0) Initialise from server-rendered span ("YES"/"NO")
     let working = indicator.textContent.trim().toUpperCase() === "YES";
 
-    // 1) Wire the toggle
-    button.addEventListener("click", async () => {
+    // 1) Wire the toggle; roles that cannot edit get no button
+    button?.addEventListener("click", async () => {
         const url = working
             ? "/admin/end-work-session"
             : "/admin/start-work-session";
@@ -16,7 +20,8 @@ document.addEventListener("DOMContentLoaded", () => {
         try {
             const res = await fetch(url, {
                 method:      "POST",
-                credentials: "same-origin"
+                credentials: "same-origin",
+                headers:     { "X-CSRF-Token": csrfToken }
             });
             if (!res.ok) throw new Error(`HTTP ${res.status}`);
 
@@ -27,13 +32,31 @@ document.addEventListener("DOMContentLoaded", () => {
             indicator.textContent = working ? "YES" : "NO";
             button.textContent    = working ? "Stop Working" : "Start Working";
 
+            showUndo(res.headers.get("Undo-Token"),
+                     working ? "Work session started" : "Work session ended");
+
         } catch (err) {
             console.error("Toggle failed:", err);
             alert("Couldn’t toggle session. Check console.");
         }
     });
 
-    // 3) (Optional) Prevent completing tasks when not working
+    // 3) Offer to undo the change just made; the toast posts a plain form
+    const toast = document.getElementById("undo-toast");
+    let undoTimer;
+    function showUndo(token, summary) {
+        if (!toast || !token) return;
+        document.getElementById("undo-token").value = token;
+        document.getElementById("undo-summary").textContent = summary;
+        toast.hidden = false;
+        clearTimeout(undoTimer);
+        undoTimer = setTimeout(() => { toast.hidden = true; }, UNDO_WINDOW_MS);
+    }
+    if (toast && !toast.hidden) {
+        undoTimer = setTimeout(() => { toast.hidden = true; }, UNDO_WINDOW_MS);
+    }
+
+    // 4) (Optional) Prevent completing tasks when not working
     document.querySelectorAll(".complete-form").forEach(form => {
         form.addEventListener("submit", e => {
             if (!working) {
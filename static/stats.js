@@ -16,6 +16,7 @@ document.addEventListener("DOMContentLoaded", () => {
                 name: g.dataset.name,
                 desc: g.dataset.desc,
                 status: g.dataset.status,
+                state: g.dataset.state,
                 done: g.dataset.done,
                 due: g.dataset.due
             }));
@@ -31,9 +32,10 @@ document.addEventListener("DOMContentLoaded", () => {
 
     function showDetails(goals, cell) {
         detailBox.innerHTML = goals.map(g => {
-            return `<div class="goal-entry"><strong>${escapeHTML(g.name)}</strong>`+
+            const stateClass = g.state ? ` goal-state-${g.state.replace(/ /g, '-')}` : '';
+            return `<div class="goal-entry${stateClass}"><strong>${escapeHTML(g.name)}</strong>`+
                 `<div>${escapeHTML(g.desc)}</div>`+
-                `<div>Status: ${escapeHTML(g.status)}</div>`+
+                `<div>Status: ${escapeHTML(g.status)}${g.state ? ` (${escapeHTML(g.state)})` : ''}</div>`+
                 `${g.done ? `<div>Done at: ${escapeHTML(g.done)}</div>` : `<div>Due at: ${escapeHTML(g.due)}</div>`}</div>`;
         }).join('');
         const rect = cell.getBoundingClientRect();
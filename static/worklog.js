@@ -22,7 +22,7 @@ document.addEventListener("DOMContentLoaded", async () => {
     const today = new Date().toISOString().split("T")[0];
     picker.value = params.get("date") || today;
     picker.addEventListener("change", function () {
-        window.location.href = `/worklog/?date=${this.value}`;
+        window.location.search = `?date=${this.value}`;
     });
     
 })